@@ -0,0 +1,1516 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/approvals"
+	"github.com/gioCuesta25/employees-manager-backend/attendance"
+	"github.com/gioCuesta25/employees-manager-backend/models"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
+)
+
+const approvalPolicyColumns = `id, company_id, subject, steps, created_at, updated_at`
+
+const approvalColumns = `id, company_id, subject, subject_id, employee_id, amount, payload, status, current_step, requested_by, created_at, updated_at`
+
+const approvalStepColumns = `step, approver_role, approver_level, approver_user_id, status, timeout_hours, due_at, escalated_at, decided_by, decided_at, comment`
+
+const approvalDelegationColumns = `id, company_id, user_id, delegate_id, starts_on, ends_on, created_at`
+
+var approvalSubjectNames = map[string]string{
+	approvals.SubjectSalaryChange:  "salary change",
+	approvals.SubjectTermination:   "termination",
+	approvals.SubjectProfileChange: "profile change",
+}
+
+// approvalRequest is a change submitted for approval. The payload holds
+// what the change applies once approved.
+type approvalRequest struct {
+	CompanyId   string
+	Subject     string
+	SubjectId   *string
+	EmployeeId  string
+	Amount      *float64
+	Payload     map[string]any
+	RequestedBy string
+}
+
+// conflictError is returned when an approved change no longer applies, as
+// when the employee was terminated in the meantime.
+type conflictError string
+
+func (e conflictError) Error() string {
+	return string(e)
+}
+
+// invalidError is returned when a change can't be made as it was asked, as
+// a termination before the admission date.
+type invalidError string
+
+func (e invalidError) Error() string {
+	return string(e)
+}
+
+// forbiddenError is returned when the user can't ask for a change, as the
+// termination of an employee of a company where they have no role.
+type forbiddenError string
+
+func (e forbiddenError) Error() string {
+	return string(e)
+}
+
+func (s *Server) createApprovalPolicy(ctx *gin.Context) {
+	var body models.CreateApprovalPolicyBody
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	steps, err := approvals.Validate(body.Steps)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if !s.requireCompanyRole(ctx, body.CompanyId, models.RoleHR) {
+		return
+	}
+
+	encoded, err := json.Marshal(steps)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	query := `INSERT INTO approval_policies (company_id, subject, steps)
+	VALUES ($1, $2, $3)
+	RETURNING ` + approvalPolicyColumns
+
+	policy, err := scanRowIntoApprovalPolicy(s.db.QueryRow(query, body.CompanyId, body.Subject, encoded))
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			utils.ErrorResponse(ctx, fmt.Errorf("the company already has a %s policy", body.Subject), http.StatusConflict)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"approval_policy": policy})
+}
+
+func (s *Server) listApprovalPolicies(ctx *gin.Context) {
+	companyId := ctx.DefaultQuery("company_id", "")
+
+	if companyId == "" {
+		utils.ErrorResponse(ctx, fmt.Errorf("company_id is required"), http.StatusBadRequest)
+		return
+	}
+
+	rows, err := s.db.Query(`SELECT `+approvalPolicyColumns+` FROM approval_policies WHERE company_id = $1 ORDER BY subject`, companyId)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	policies := make([]*models.ApprovalPolicyResponse, 0)
+
+	for rows.Next() {
+		policy, err := scanRowIntoApprovalPolicy(rows)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		policies = append(policies, policy)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"approval_policies": policies})
+}
+
+// updateApprovalPolicy changes the chain of the requests made from now on.
+// The requests in progress keep the steps they started with.
+func (s *Server) updateApprovalPolicy(ctx *gin.Context) {
+	var params models.GetApprovalPolicyParams
+	var body models.UpdateApprovalPolicyBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	steps, err := approvals.Validate(body.Steps)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	policy, err := scanRowIntoApprovalPolicy(s.db.QueryRow(`SELECT `+approvalPolicyColumns+` FROM approval_policies WHERE id = $1`, params.ID))
+
+	if err != nil {
+		approvalPolicyErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if !s.requireCompanyRole(ctx, policy.CompanyId, models.RoleHR) {
+		return
+	}
+
+	encoded, err := json.Marshal(steps)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	query := `UPDATE approval_policies SET steps = $1, updated_at = $2 WHERE id = $3 RETURNING ` + approvalPolicyColumns
+
+	policy, err = scanRowIntoApprovalPolicy(s.db.QueryRow(query, encoded, time.Now(), params.ID))
+
+	if err != nil {
+		approvalPolicyErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"approval_policy": policy})
+}
+
+// deleteApprovalPolicy makes the subject fall back to the default chain
+func (s *Server) deleteApprovalPolicy(ctx *gin.Context) {
+	var params models.GetApprovalPolicyParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	policy, err := scanRowIntoApprovalPolicy(s.db.QueryRow(`SELECT `+approvalPolicyColumns+` FROM approval_policies WHERE id = $1`, params.ID))
+
+	if err != nil {
+		approvalPolicyErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if !s.requireCompanyRole(ctx, policy.CompanyId, models.RoleHR) {
+		return
+	}
+
+	if _, err := s.db.Exec(`DELETE FROM approval_policies WHERE id = $1`, params.ID); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+func (s *Server) listApprovals(ctx *gin.Context) {
+	companyId := ctx.DefaultQuery("company_id", "")
+
+	if companyId == "" {
+		utils.ErrorResponse(ctx, fmt.Errorf("company_id is required"), http.StatusBadRequest)
+		return
+	}
+
+	status := ctx.DefaultQuery("status", "")
+	subject := ctx.DefaultQuery("subject", "")
+	employeeId := ctx.DefaultQuery("employee_id", "")
+
+	query := `SELECT ` + approvalColumns + ` FROM approvals
+	WHERE company_id = $1
+		AND ($2 = '' OR status = $2)
+		AND ($3 = '' OR subject = $3)
+		AND ($4 = '' OR employee_id::text = $4)
+	ORDER BY created_at DESC`
+
+	rows, err := s.db.Query(query, companyId, status, subject, employeeId)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	list := make([]*models.ApprovalResponse, 0)
+
+	for rows.Next() {
+		approval, err := scanRowIntoApproval(rows)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		list = append(list, approval)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"approvals": list})
+}
+
+func (s *Server) getApproval(ctx *gin.Context) {
+	var params models.GetApprovalParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	approval, err := scanRowIntoApproval(s.db.QueryRow(`SELECT `+approvalColumns+` FROM approvals WHERE id = $1`, params.ID))
+
+	if err != nil {
+		approvalErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	approval.Steps, err = findApprovalSteps(s.db, approval.ID)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"approval": approval})
+}
+
+// getApprovalInbox lists what waits for a decision of the user, including
+// the steps of the users that delegated to them and the leave requests,
+// which keep the chain of their leave type.
+func (s *Server) getApprovalInbox(ctx *gin.Context) {
+	userId := ctx.GetString("userId")
+
+	query := `WITH acting AS (
+		SELECT $1::uuid AS user_id, NULL::uuid AS on_behalf_of
+		UNION
+		SELECT user_id, user_id FROM approval_delegations
+		WHERE delegate_id = $1::uuid AND starts_on <= $2 AND ends_on >= $2
+	),
+	items AS (
+		SELECT 'approval' AS kind, a.id, a.company_id, a.subject, a.employee_id,
+			e.name || ' ' || e.last_name AS employee_name, s.step, s.approver_role, a.status, s.due_at,
+			acting.on_behalf_of, a.created_at
+		FROM approvals a
+		JOIN approval_steps s ON s.approval_id = a.id AND s.step = a.current_step
+		JOIN employees e ON e.id = a.employee_id
+		JOIN acting ON s.approver_user_id = acting.user_id
+			OR (s.approver_user_id IS NULL AND s.approver_role = 'hr' AND EXISTS (
+				SELECT 1 FROM company_members m WHERE m.company_id = a.company_id AND m.user_id = acting.user_id AND m.role = 'hr'))
+			OR EXISTS (SELECT 1 FROM companies c WHERE c.id = a.company_id AND c.owner = acting.user_id)
+		WHERE a.status IN ('pending', 'escalated')
+		UNION ALL
+		SELECT 'leave_request', l.id, l.company_id, t.name, l.employee_id,
+			e.name || ' ' || e.last_name, la.step, la.role, l.status, NULL,
+			acting.on_behalf_of, l.created_at
+		FROM leave_requests l
+		JOIN leave_request_approvals la ON la.leave_request_id = l.id AND la.step = l.current_step
+		JOIN leave_types t ON t.id = l.leave_type_id
+		JOIN employees e ON e.id = l.employee_id
+		LEFT JOIN departments d ON d.id = e.department_id
+		JOIN acting ON (la.role = 'manager' AND d.manager_id = acting.user_id)
+			OR (la.role = 'hr' AND EXISTS (
+				SELECT 1 FROM company_members m WHERE m.company_id = l.company_id AND m.user_id = acting.user_id AND m.role = 'hr'))
+			OR EXISTS (SELECT 1 FROM companies c WHERE c.id = l.company_id AND c.owner = acting.user_id)
+		WHERE l.status = 'pending'
+	)
+	SELECT * FROM (
+		-- A request the user can decide both directly and as a delegate shows once
+		SELECT DISTINCT ON (kind, id) * FROM items ORDER BY kind, id, on_behalf_of NULLS FIRST
+	) inbox
+	ORDER BY due_at NULLS LAST, created_at`
+
+	rows, err := s.db.Query(query, userId, attendance.Day(time.Now()))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	inbox := make([]*models.ApprovalInboxItem, 0)
+
+	for rows.Next() {
+		item := new(models.ApprovalInboxItem)
+
+		err := rows.Scan(
+			&item.Kind,
+			&item.ID,
+			&item.CompanyId,
+			&item.Subject,
+			&item.EmployeeId,
+			&item.EmployeeName,
+			&item.Step,
+			&item.ApproverRole,
+			&item.Status,
+			&item.DueAt,
+			&item.OnBehalfOf,
+			&item.CreatedAt,
+		)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		item.Link = fmt.Sprintf("/approvals/%s", item.ID)
+		if item.Kind == "leave_request" {
+			item.Link = fmt.Sprintf("/leave-requests/%s", item.ID)
+		}
+
+		inbox = append(inbox, item)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"inbox": inbox})
+}
+
+func (s *Server) approveApproval(ctx *gin.Context) {
+	s.decideApprovalFromRequest(ctx, approvals.StatusApproved)
+}
+
+func (s *Server) rejectApproval(ctx *gin.Context) {
+	s.decideApprovalFromRequest(ctx, approvals.StatusRejected)
+}
+
+func (s *Server) decideApprovalFromRequest(ctx *gin.Context, decision string) {
+	var params models.GetApprovalParams
+	var body models.DecideApprovalBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	// The comment is optional
+	if err := ctx.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	s.decideApproval(ctx, params.ID, decision, body.Comment)
+}
+
+// decideApproval records the decision of the current step. An approval
+// moves to the next pending step, or applies the change after the last one;
+// a rejection ends the approval.
+func (s *Server) decideApproval(ctx *gin.Context, id string, decision string, comment *string) {
+	userId := ctx.GetString("userId")
+	now := time.Now()
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	approval, err := scanRowIntoApproval(tx.QueryRow(`SELECT `+approvalColumns+` FROM approvals WHERE id = $1 FOR UPDATE`, id))
+
+	if err != nil {
+		approvalErrorResponse(ctx, err, id)
+		return
+	}
+
+	if approval.Status != approvals.StatusPending && approval.Status != approvals.StatusEscalated {
+		utils.ErrorResponse(ctx, fmt.Errorf("approval %s was already %s", id, approval.Status), http.StatusConflict)
+		return
+	}
+
+	step, err := findApprovalStep(tx, approval.ID, approval.CurrentStep)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	allowed, err := canDecideApprovalStep(tx, approval, step, userId)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if !allowed {
+		utils.ErrorResponse(ctx, fmt.Errorf("you can't decide step %d of approval %s", step.Step, id), http.StatusForbidden)
+		return
+	}
+
+	_, err = tx.Exec(`UPDATE approval_steps
+	SET status = $1, decided_by = $2, decided_at = $3, comment = $4, due_at = NULL
+	WHERE approval_id = $5 AND step = $6`,
+		decision, userId, now, comment, approval.ID, step.Step)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	var result any
+
+	if decision == approvals.StatusRejected {
+		approval, err = scanRowIntoApproval(tx.QueryRow(`UPDATE approvals SET status = $1, updated_at = $2 WHERE id = $3 RETURNING `+approvalColumns,
+			approvals.StatusRejected, now, approval.ID))
+
+		if err == nil {
+			result, err = endApproval(tx, approval, userId, comment)
+		}
+	} else {
+		approval, err = advanceApproval(tx, approval, step.Step, "")
+
+		if err == nil && approval.Status == approvals.StatusApproved {
+			err = auditEmployeeChange(tx, ctx, approval.EmployeeId, approval.Subject, func() error {
+				var applyErr error
+				result, applyErr = applyApproval(tx, approval, userId, comment)
+				return applyErr
+			})
+		}
+	}
+
+	if err != nil {
+		var conflict conflictError
+		if errors.As(err, &conflict) {
+			utils.ErrorResponse(ctx, err, http.StatusConflict)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	approval.Steps, err = findApprovalSteps(tx, approval.ID)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"approval": approval, "result": result})
+}
+
+// cancelApproval lets the requester or HR withdraw an undecided approval
+func (s *Server) cancelApproval(ctx *gin.Context) {
+	var params models.GetApprovalParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	userId := ctx.GetString("userId")
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	approval, err := scanRowIntoApproval(tx.QueryRow(`SELECT `+approvalColumns+` FROM approvals WHERE id = $1 FOR UPDATE`, params.ID))
+
+	if err != nil {
+		approvalErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if approval.RequestedBy != userId {
+		allowed, err := hasCompanyRole(tx, approval.CompanyId, userId, models.RoleHR)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		if !allowed {
+			utils.ErrorResponse(ctx, fmt.Errorf("only the requester or HR can cancel an approval"), http.StatusForbidden)
+			return
+		}
+	}
+
+	if approval.Status != approvals.StatusPending && approval.Status != approvals.StatusEscalated {
+		utils.ErrorResponse(ctx, fmt.Errorf("approval %s was already %s", params.ID, approval.Status), http.StatusConflict)
+		return
+	}
+
+	approval, err = scanRowIntoApproval(tx.QueryRow(`UPDATE approvals SET status = $1, updated_at = $2 WHERE id = $3 RETURNING `+approvalColumns,
+		approvals.StatusCancelled, time.Now(), params.ID))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := endApproval(tx, approval, userId, nil); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"approval": approval})
+}
+
+// createApprovalDelegation lets another user decide the steps of the
+// current user in the company between the dates
+func (s *Server) createApprovalDelegation(ctx *gin.Context) {
+	var body models.CreateApprovalDelegationBody
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	userId := ctx.GetString("userId")
+	startsOn := calendarDate(body.StartsOn)
+	endsOn := calendarDate(body.EndsOn)
+
+	if endsOn.Before(startsOn) {
+		utils.ErrorResponse(ctx, fmt.Errorf("ends_on can't be before starts_on"), http.StatusBadRequest)
+		return
+	}
+
+	if body.DelegateId == userId {
+		utils.ErrorResponse(ctx, fmt.Errorf("you can't delegate to yourself"), http.StatusBadRequest)
+		return
+	}
+
+	var exists bool
+
+	if err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE id::text = $1)`, body.DelegateId).Scan(&exists); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if !exists {
+		utils.ErrorResponse(ctx, fmt.Errorf("user %s not found", body.DelegateId), http.StatusBadRequest)
+		return
+	}
+
+	query := `INSERT INTO approval_delegations (company_id, user_id, delegate_id, starts_on, ends_on)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING ` + approvalDelegationColumns
+
+	delegation, err := scanRowIntoApprovalDelegation(s.db.QueryRow(query, body.CompanyId, userId, body.DelegateId, startsOn, endsOn))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"approval_delegation": delegation})
+}
+
+// listApprovalDelegations lists the delegations the user gave and received
+// that haven't ended.
+func (s *Server) listApprovalDelegations(ctx *gin.Context) {
+	userId := ctx.GetString("userId")
+
+	query := `SELECT ` + approvalDelegationColumns + ` FROM approval_delegations
+	WHERE (user_id::text = $1 OR delegate_id::text = $1) AND ends_on >= $2
+	ORDER BY starts_on`
+
+	rows, err := s.db.Query(query, userId, attendance.Day(time.Now()))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	delegations := make([]*models.ApprovalDelegationResponse, 0)
+
+	for rows.Next() {
+		delegation, err := scanRowIntoApprovalDelegation(rows)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		delegations = append(delegations, delegation)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"approval_delegations": delegations})
+}
+
+func (s *Server) deleteApprovalDelegation(ctx *gin.Context) {
+	var params models.GetApprovalDelegationParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.db.Exec(`DELETE FROM approval_delegations WHERE id = $1 AND user_id::text = $2`, params.ID, ctx.GetString("userId"))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		utils.ErrorResponse(ctx, fmt.Errorf("approval delegation %s not found", params.ID), http.StatusNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// escalateApprovals hands the steps undecided past their timeout to the
+// next approver up: the manager above, then HR, then the owner.
+func (s *Server) escalateApprovals(ctx context.Context) error {
+	query := `SELECT a.id FROM approvals a
+	JOIN approval_steps s ON s.approval_id = a.id AND s.step = a.current_step
+	WHERE a.status IN ('pending', 'escalated') AND s.due_at <= $1`
+
+	rows, err := s.db.QueryContext(ctx, query, time.Now())
+
+	if err != nil {
+		return err
+	}
+
+	var ids []string
+
+	for rows.Next() {
+		var id string
+
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err := s.escalateApproval(id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) escalateApproval(id string) error {
+	now := time.Now()
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	approval, err := scanRowIntoApproval(tx.QueryRow(`SELECT `+approvalColumns+` FROM approvals WHERE id = $1 FOR UPDATE`, id))
+
+	if err != nil {
+		return err
+	}
+
+	if approval.Status != approvals.StatusPending && approval.Status != approvals.StatusEscalated {
+		return nil
+	}
+
+	step, err := findApprovalStep(tx, approval.ID, approval.CurrentStep)
+
+	if err != nil {
+		return err
+	}
+
+	// Decided since the job listed it
+	if step.DueAt == nil || step.DueAt.After(now) {
+		return nil
+	}
+
+	role := step.ApproverRole
+	level := 0
+	var approver *string
+
+	if role == approvals.ApproverManager {
+		approver, level, err = resolveManager(tx, approval.CompanyId, approval.EmployeeId, step.ApproverLevel+1, attendance.Day(now))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if approver == nil {
+		next, ok := approvals.EscalateRole(role)
+
+		if !ok {
+			// No one above the owner, stop checking the step
+			_, err := tx.Exec(`UPDATE approval_steps SET due_at = NULL WHERE approval_id = $1 AND step = $2`, approval.ID, step.Step)
+
+			if err != nil {
+				return err
+			}
+
+			return tx.Commit()
+		}
+
+		role = next
+	}
+
+	dueAt := approvals.DueAt(approvals.Step{TimeoutHours: step.TimeoutHours}, now)
+
+	_, err = tx.Exec(`UPDATE approval_steps
+	SET approver_role = $1, approver_level = $2, approver_user_id = $3, status = $4, escalated_at = $5, due_at = $6
+	WHERE approval_id = $7 AND step = $8`,
+		role, level, approver, approvals.StatusEscalated, now, dueAt, approval.ID, step.Step)
+
+	if err != nil {
+		return err
+	}
+
+	approval, err = scanRowIntoApproval(tx.QueryRow(`UPDATE approvals SET status = $1, updated_at = $2 WHERE id = $3 RETURNING `+approvalColumns,
+		approvals.StatusEscalated, now, approval.ID))
+
+	if err != nil {
+		return err
+	}
+
+	step, err = findApprovalStep(tx, approval.ID, step.Step)
+
+	if err != nil {
+		return err
+	}
+
+	if err := notifyApprovers(tx, approval, step, models.NotificationKindApprovalEscalated); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// startApproval creates the approval of the change with the chain of the
+// company for its subject. The leading steps the requester can decide
+// themselves are approved right away, so the returned approval can be
+// approved already; the caller then applies the change. Nobody approves
+// their own change or a step only they could decide, see advanceApproval.
+func startApproval(tx *sql.Tx, request approvalRequest) (*models.ApprovalResponse, error) {
+	steps, err := loadApprovalChain(tx, request.CompanyId, request.Subject)
+
+	if err != nil {
+		return nil, err
+	}
+
+	payload := request.Payload
+	if payload == nil {
+		payload = map[string]any{}
+	}
+
+	encoded, err := json.Marshal(payload)
+
+	if err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO approvals (company_id, subject, subject_id, employee_id, amount, payload, status, current_step, requested_by)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, 0, $8)
+	RETURNING ` + approvalColumns
+
+	approval, err := scanRowIntoApproval(tx.QueryRow(query,
+		request.CompanyId,
+		request.Subject,
+		request.SubjectId,
+		request.EmployeeId,
+		request.Amount,
+		encoded,
+		approvals.StatusPending,
+		request.RequestedBy))
+
+	if err != nil {
+		return nil, err
+	}
+
+	today := attendance.Day(time.Now())
+
+	for i, step := range approvals.Plan(steps, request.Amount) {
+		status := approvals.StatusPending
+		level := step.Level
+		var approver *string
+
+		// Manager steps without a manager are skipped, as for leave
+		if step.Approver == approvals.ApproverManager {
+			approver, level, err = resolveManager(tx, request.CompanyId, request.EmployeeId, step.Level, today)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if approver == nil {
+				status = approvals.StatusSkipped
+			}
+		}
+
+		_, err := tx.Exec(`INSERT INTO approval_steps (approval_id, step, approver_role, approver_level, approver_user_id, status, timeout_hours)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			approval.ID, i+1, step.Approver, level, approver, status, step.TimeoutHours)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return advanceApproval(tx, approval, 0, request.RequestedBy)
+}
+
+// advanceApproval moves the approval to the first pending step after the
+// given one, or approves it when there is none. Steps the requester can
+// decide are approved on their behalf, unless they are the only approver of
+// the step: those steps are handed over to the next approver up, as if they
+// escalated.
+func advanceApproval(tx *sql.Tx, approval *models.ApprovalResponse, after int, requester string) (*models.ApprovalResponse, error) {
+	now := time.Now()
+	status := approvals.StatusApproved
+	current := after
+
+	for {
+		var next int
+
+		err := tx.QueryRow(`SELECT COALESCE(MIN(step), 0) FROM approval_steps WHERE approval_id = $1 AND step > $2 AND status = $3`,
+			approval.ID, current, approvals.StatusPending).Scan(&next)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if next == 0 {
+			break
+		}
+
+		step, err := findApprovalStep(tx, approval.ID, next)
+
+		if err != nil {
+			return nil, err
+		}
+
+		current = next
+
+		if requester != "" {
+			allowed, err := canDecideApprovalStep(tx, approval, step, requester)
+
+			if err != nil {
+				return nil, err
+			}
+
+			sole, err := isSoleApprover(tx, approval, step.ApproverRole, step.ApproverUserId, requester)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if allowed && !sole {
+				_, err := tx.Exec(`UPDATE approval_steps SET status = $1, decided_by = $2, decided_at = $3 WHERE approval_id = $4 AND step = $5`,
+					approvals.StatusApproved, requester, now, approval.ID, next)
+
+				if err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
+			if sole {
+				step, err = handOverApprovalStep(tx, approval, step, requester)
+
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		dueAt := approvals.DueAt(approvals.Step{TimeoutHours: step.TimeoutHours}, now)
+
+		if _, err := tx.Exec(`UPDATE approval_steps SET due_at = $1 WHERE approval_id = $2 AND step = $3`, dueAt, approval.ID, next); err != nil {
+			return nil, err
+		}
+
+		step.DueAt = dueAt
+
+		if err := notifyApprovers(tx, approval, step, models.NotificationKindApprovalRequested); err != nil {
+			return nil, err
+		}
+
+		status = approvals.StatusPending
+		break
+	}
+
+	query := `UPDATE approvals SET status = $1, current_step = $2, updated_at = $3 WHERE id = $4 RETURNING ` + approvalColumns
+
+	return scanRowIntoApproval(tx.QueryRow(query, status, current, now, approval.ID))
+}
+
+// applyApproval makes the change of an approval that was just approved and
+// returns the changed record.
+func applyApproval(tx *sql.Tx, approval *models.ApprovalResponse, userId string, comment *string) (any, error) {
+	switch approval.Subject {
+	case approvals.SubjectSalaryChange:
+		return applySalaryChange(tx, approval)
+	case approvals.SubjectTermination:
+		return applyTerminationApproval(tx, approval)
+	case approvals.SubjectProfileChange:
+		return closeProfileChangeRequest(tx, *approval.SubjectId, models.ProfileChangeStatusApproved, userId, comment)
+	}
+
+	return nil, nil
+}
+
+// endApproval closes the records of an approval rejected or cancelled
+func endApproval(tx *sql.Tx, approval *models.ApprovalResponse, userId string, comment *string) (any, error) {
+	if approval.Subject != approvals.SubjectProfileChange {
+		return nil, nil
+	}
+
+	status := models.ProfileChangeStatusRejected
+	if approval.Status == approvals.StatusCancelled {
+		status = models.ProfileChangeStatusCancelled
+	}
+
+	return closeProfileChangeRequest(tx, *approval.SubjectId, status, userId, comment)
+}
+
+// loadApprovalChain returns the chain of the company for the subject or the
+// default one
+func loadApprovalChain(db queryer, companyId string, subject string) ([]approvals.Step, error) {
+	var encoded []byte
+
+	err := db.QueryRow(`SELECT steps FROM approval_policies WHERE company_id = $1 AND subject = $2`, companyId, subject).Scan(&encoded)
+
+	if err == sql.ErrNoRows {
+		return approvals.DefaultSteps, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []approvals.Step
+	err = json.Unmarshal(encoded, &steps)
+
+	return steps, err
+}
+
+// resolveManager finds the manager of the employee from the level up. A
+// manager away on leave without a delegate is passed over for the one
+// above. It returns nil when the hierarchy ends first.
+func resolveManager(db queryer, companyId string, employeeId string, level int, day time.Time) (*string, int, error) {
+	for ; level <= approvals.MaxManagerLevel; level++ {
+		manager, err := managerAt(db, employeeId, level)
+
+		if err != nil || manager == nil {
+			return nil, 0, err
+		}
+
+		away, err := awayWithoutDelegate(db, companyId, *manager, day)
+
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if !away {
+			return manager, level, nil
+		}
+	}
+
+	return nil, 0, nil
+}
+
+// managerAt returns the user that manages the employee at the level of the
+// hierarchy: 1 is the manager of their department, 2 the manager of that
+// manager and so on. The hierarchy goes up through the employee record
+// linked to each manager's user.
+func managerAt(db queryer, employeeId string, level int) (*string, error) {
+	var manager *string
+
+	for i := 0; i < level; i++ {
+		if manager != nil {
+			err := db.QueryRow(`SELECT id FROM employees WHERE user_id::text = $1`, *manager).Scan(&employeeId)
+
+			if err == sql.ErrNoRows {
+				return nil, nil
+			}
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		var next *string
+
+		err := db.QueryRow(`SELECT d.manager_id FROM employees e JOIN departments d ON d.id = e.department_id WHERE e.id = $1`, employeeId).Scan(&next)
+
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		// The top manager manages their own department
+		if next == nil || (manager != nil && *next == *manager) {
+			return nil, nil
+		}
+
+		manager = next
+	}
+
+	return manager, nil
+}
+
+// awayWithoutDelegate reports whether the user is on approved leave on the
+// day without someone deciding for them.
+func awayWithoutDelegate(db queryer, companyId string, userId string, day time.Time) (bool, error) {
+	query := `SELECT EXISTS (
+		SELECT 1 FROM leave_requests l JOIN employees e ON e.id = l.employee_id
+		WHERE e.user_id::text = $1 AND l.status = 'approved' AND l.start_date <= $2 AND l.end_date >= $2
+	) AND NOT EXISTS (
+		SELECT 1 FROM approval_delegations
+		WHERE company_id = $3 AND user_id::text = $1 AND starts_on <= $2 AND ends_on >= $2
+	)`
+
+	var away bool
+	err := db.QueryRow(query, userId, day, companyId).Scan(&away)
+
+	return away, err
+}
+
+// actingUsers returns the user and the users who delegated their approvals
+// in the company to them on the day.
+func actingUsers(db queryer, companyId string, userId string, day time.Time) ([]string, error) {
+	rows, err := db.Query(`SELECT user_id FROM approval_delegations
+	WHERE company_id = $1 AND delegate_id::text = $2 AND starts_on <= $3 AND ends_on >= $3`, companyId, userId, day)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []string{userId}
+
+	for rows.Next() {
+		var delegator string
+
+		if err := rows.Scan(&delegator); err != nil {
+			return nil, err
+		}
+
+		users = append(users, delegator)
+	}
+
+	return users, rows.Err()
+}
+
+// canDecideApprovalStep reports whether the user, or someone who delegated
+// to them, is the approver of the step. The company owner can decide any
+// step. Nobody decides the approvals of their own employee record.
+func canDecideApprovalStep(db queryer, approval *models.ApprovalResponse, step *models.ApprovalStepResponse, userId string) (bool, error) {
+	subject, err := approvalSubjectUser(db, approval)
+
+	if err != nil || subject == userId {
+		return false, err
+	}
+
+	users, err := actingUsers(db, approval.CompanyId, userId, attendance.Day(time.Now()))
+
+	if err != nil {
+		return false, err
+	}
+
+	for _, user := range users {
+		if user == subject {
+			continue
+		}
+
+		if step.ApproverUserId != nil && *step.ApproverUserId == user {
+			return true, nil
+		}
+
+		var roles []string
+		if step.ApproverUserId == nil && step.ApproverRole == approvals.ApproverHR {
+			roles = []string{models.RoleHR}
+		}
+
+		allowed, err := hasCompanyRole(db, approval.CompanyId, user, roles...)
+
+		if err != nil || allowed {
+			return allowed, err
+		}
+	}
+
+	return false, nil
+}
+
+// approvalSubjectUser returns the user of the employee the approval is about,
+// or an empty string when the employee has no account
+func approvalSubjectUser(db queryer, approval *models.ApprovalResponse) (string, error) {
+	var userId *string
+
+	err := db.QueryRow(`SELECT user_id FROM employees WHERE id = $1`, approval.EmployeeId).Scan(&userId)
+
+	if err == sql.ErrNoRows || userId == nil {
+		return "", nil
+	}
+
+	return *userId, err
+}
+
+// isSoleApprover reports whether nobody but the user, leaving out the
+// employee the approval is about and the owner deciding any step, is an
+// approver of a step of the role or of the given approver.
+func isSoleApprover(db queryer, approval *models.ApprovalResponse, role string, approver *string, userId string) (bool, error) {
+	subject, err := approvalSubjectUser(db, approval)
+
+	if err != nil {
+		return false, err
+	}
+
+	if approver != nil {
+		return *approver == userId || *approver == subject, nil
+	}
+
+	var others bool
+
+	switch role {
+	case approvals.ApproverHR:
+		err = db.QueryRow(`SELECT EXISTS (
+			SELECT 1 FROM company_members WHERE company_id = $1 AND role = $2 AND user_id::text <> $3 AND user_id::text <> $4
+		)`, approval.CompanyId, models.RoleHR, userId, subject).Scan(&others)
+	default:
+		err = db.QueryRow(`SELECT EXISTS (SELECT 1 FROM companies WHERE id = $1 AND owner::text <> $2 AND owner::text <> $3)`,
+			approval.CompanyId, userId, subject).Scan(&others)
+	}
+
+	return !others, err
+}
+
+// handOverApprovalStep passes the step up the way its escalation would,
+// until it reaches an approver other than the user, and returns it. The
+// owner keeps the step when there is no one else.
+func handOverApprovalStep(tx *sql.Tx, approval *models.ApprovalResponse, step *models.ApprovalStepResponse, userId string) (*models.ApprovalStepResponse, error) {
+	role := step.ApproverRole
+	level := step.ApproverLevel
+	approver := step.ApproverUserId
+	today := attendance.Day(time.Now())
+
+	for {
+		sole, err := isSoleApprover(tx, approval, role, approver, userId)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !sole {
+			break
+		}
+
+		var next *string
+
+		if role == approvals.ApproverManager {
+			next, level, err = resolveManager(tx, approval.CompanyId, approval.EmployeeId, level+1, today)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if next == nil {
+			escalated, ok := approvals.EscalateRole(role)
+
+			if !ok {
+				break
+			}
+
+			role = escalated
+			level = 0
+		}
+
+		approver = next
+	}
+
+	_, err := tx.Exec(`UPDATE approval_steps SET approver_role = $1, approver_level = $2, approver_user_id = $3 WHERE approval_id = $4 AND step = $5`,
+		role, level, approver, approval.ID, step.Step)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return findApprovalStep(tx, approval.ID, step.Step)
+}
+
+// notifyApprovers tells the approvers of the step that it waits for them
+func notifyApprovers(db queryer, approval *models.ApprovalResponse, step *models.ApprovalStepResponse, kind string) error {
+	var users []string
+	var err error
+
+	switch {
+	case step.ApproverUserId != nil:
+		users = []string{*step.ApproverUserId}
+	case step.ApproverRole == approvals.ApproverHR:
+		users, err = findExpiryRecipients(db, approval.CompanyId, "")
+	default:
+		var owner string
+		err = db.QueryRow(`SELECT owner FROM companies WHERE id = $1`, approval.CompanyId).Scan(&owner)
+		users = []string{owner}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var employeeName string
+
+	if err := db.QueryRow(`SELECT name || ' ' || last_name FROM employees WHERE id = $1`, approval.EmployeeId).Scan(&employeeName); err != nil {
+		return err
+	}
+
+	title := fmt.Sprintf("The %s of %s needs your approval", approvalSubjectNames[approval.Subject], employeeName)
+	if kind == models.NotificationKindApprovalEscalated {
+		title = fmt.Sprintf("The %s of %s was escalated to you", approvalSubjectNames[approval.Subject], employeeName)
+	}
+
+	body := fmt.Sprintf("Step %d of the approval waits for a decision.", step.Step)
+	if step.DueAt != nil {
+		body += fmt.Sprintf(" It escalates if undecided by %s.", step.DueAt.Format("2006-01-02 15:04"))
+	}
+
+	link := fmt.Sprintf("/approvals/%s", approval.ID)
+	dedupKey := fmt.Sprintf("approval:%s:%d:%s:%d", approval.ID, step.Step, step.ApproverRole, step.ApproverLevel)
+
+	data := map[string]any{
+		"approval_id":   approval.ID,
+		"subject":       approval.Subject,
+		"employee_id":   approval.EmployeeId,
+		"employee_name": employeeName,
+		"step":          step.Step,
+	}
+
+	if step.DueAt != nil {
+		data["due_at"] = step.DueAt.Format("2006-01-02 15:04")
+	}
+
+	for _, userId := range users {
+		err := notify(db, models.Notification{
+			UserId:    userId,
+			CompanyId: &approval.CompanyId,
+			Kind:      kind,
+			Title:     title,
+			Body:      body,
+			Link:      &link,
+			Data:      data,
+			DedupKey:  &dedupKey,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// requireCompanyRole responds with 403 unless the user has one of the roles
+// in the company.
+func (s *Server) requireCompanyRole(ctx *gin.Context, companyId string, roles ...string) bool {
+	allowed, err := hasCompanyRole(s.db, companyId, ctx.GetString("userId"), roles...)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return false
+	}
+
+	if !allowed {
+		utils.ErrorResponse(ctx, fmt.Errorf("you don't have access to this company"), http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+// requireEmployeeCompanyRole responds with 404 when the employee doesn't
+// exist and with 403 unless the user has one of the roles in its company,
+// which it returns.
+func (s *Server) requireEmployeeCompanyRole(ctx *gin.Context, employeeId string, roles ...string) (string, bool) {
+	var companyId string
+
+	if err := s.db.QueryRow(`SELECT company_id FROM employees WHERE id::text = $1`, employeeId).Scan(&companyId); err != nil {
+		employeeErrorResponse(ctx, err, employeeId)
+		return "", false
+	}
+
+	return companyId, s.requireCompanyRole(ctx, companyId, roles...)
+}
+
+func findApprovalStep(db queryer, approvalId string, step int) (*models.ApprovalStepResponse, error) {
+	query := `SELECT ` + approvalStepColumns + ` FROM approval_steps WHERE approval_id = $1 AND step = $2`
+
+	return scanRowIntoApprovalStep(db.QueryRow(query, approvalId, step))
+}
+
+func findApprovalSteps(db queryer, approvalId string) ([]*models.ApprovalStepResponse, error) {
+	rows, err := db.Query(`SELECT `+approvalStepColumns+` FROM approval_steps WHERE approval_id = $1 ORDER BY step`, approvalId)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	steps := make([]*models.ApprovalStepResponse, 0)
+
+	for rows.Next() {
+		step, err := scanRowIntoApprovalStep(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		steps = append(steps, step)
+	}
+
+	return steps, rows.Err()
+}
+
+func approvalErrorResponse(ctx *gin.Context, err error, id string) {
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(ctx, fmt.Errorf("approval %s not found", id), http.StatusNotFound)
+		return
+	}
+	utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+}
+
+func approvalPolicyErrorResponse(ctx *gin.Context, err error, id string) {
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(ctx, fmt.Errorf("approval policy %s not found", id), http.StatusNotFound)
+		return
+	}
+	utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+}
+
+func scanRowIntoApprovalPolicy(row rowScanner) (*models.ApprovalPolicyResponse, error) {
+	policy := new(models.ApprovalPolicyResponse)
+	var steps []byte
+
+	err := row.Scan(
+		&policy.ID,
+		&policy.CompanyId,
+		&policy.Subject,
+		&steps,
+		&policy.CreatedAt,
+		&policy.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(steps, &policy.Steps); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+func scanRowIntoApproval(row rowScanner) (*models.ApprovalResponse, error) {
+	approval := new(models.ApprovalResponse)
+	var payload []byte
+
+	err := row.Scan(
+		&approval.ID,
+		&approval.CompanyId,
+		&approval.Subject,
+		&approval.SubjectId,
+		&approval.EmployeeId,
+		&approval.Amount,
+		&payload,
+		&approval.Status,
+		&approval.CurrentStep,
+		&approval.RequestedBy,
+		&approval.CreatedAt,
+		&approval.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(payload, &approval.Payload); err != nil {
+		return nil, err
+	}
+
+	return approval, nil
+}
+
+func scanRowIntoApprovalStep(row rowScanner) (*models.ApprovalStepResponse, error) {
+	step := new(models.ApprovalStepResponse)
+
+	err := row.Scan(
+		&step.Step,
+		&step.ApproverRole,
+		&step.ApproverLevel,
+		&step.ApproverUserId,
+		&step.Status,
+		&step.TimeoutHours,
+		&step.DueAt,
+		&step.EscalatedAt,
+		&step.DecidedBy,
+		&step.DecidedAt,
+		&step.Comment,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return step, nil
+}
+
+func scanRowIntoApprovalDelegation(row rowScanner) (*models.ApprovalDelegationResponse, error) {
+	delegation := new(models.ApprovalDelegationResponse)
+
+	err := row.Scan(
+		&delegation.ID,
+		&delegation.CompanyId,
+		&delegation.UserId,
+		&delegation.DelegateId,
+		&delegation.StartsOn,
+		&delegation.EndsOn,
+		&delegation.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return delegation, nil
+}
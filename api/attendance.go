@@ -0,0 +1,725 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/attendance"
+	"github.com/gioCuesta25/employees-manager-backend/models"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
+)
+
+const attendanceEventColumns = `id, company_id, employee_id, kind, occurred_at, source, created_by, created_at`
+
+const timesheetColumns = `id, company_id, employee_id, date, intervals, worked_minutes, missing_punch, corrected, status, approved_by, approved_at, created_at, updated_at`
+
+func (s *Server) clockIn(ctx *gin.Context) {
+	s.recordAttendanceEvent(ctx, attendance.KindIn)
+}
+
+func (s *Server) clockOut(ctx *gin.Context) {
+	s.recordAttendanceEvent(ctx, attendance.KindOut)
+}
+
+// recordAttendanceEvent stores the punch and rebuilds the timesheet of its
+// day and of the day before, where a shift crossing midnight started.
+func (s *Server) recordAttendanceEvent(ctx *gin.Context, kind string) {
+	var body models.ClockBody
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	occurredAt := time.Now()
+	if body.OccurredAt != nil {
+		occurredAt = *body.OccurredAt
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var companyId string
+	err = tx.QueryRow(`SELECT company_id FROM employees WHERE id = $1 FOR UPDATE`, body.EmployeeId).Scan(&companyId)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(ctx, fmt.Errorf("employee %s not found", body.EmployeeId), http.StatusNotFound)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	day := attendance.Day(occurredAt)
+
+	var approved bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM timesheets WHERE employee_id = $1 AND date = $2 AND status = 'approved')`,
+		body.EmployeeId, day).Scan(&approved)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if approved {
+		utils.ErrorResponse(ctx, fmt.Errorf("the timesheet of %s is already approved", day.Format("2006-01-02")), http.StatusConflict)
+		return
+	}
+
+	query := `INSERT INTO attendance_events (company_id, employee_id, kind, occurred_at, source, created_by)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING ` + attendanceEventColumns
+
+	event, err := scanRowIntoAttendanceEvent(tx.QueryRow(query, companyId, body.EmployeeId, kind, occurredAt, body.Source, ctx.GetString("userId")))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := rebuildTimesheet(tx, companyId, body.EmployeeId, day.AddDate(0, 0, -1)); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	timesheet, err := rebuildTimesheet(tx, companyId, body.EmployeeId, day)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"event": event, "timesheet": timesheet})
+}
+
+func (s *Server) listTimesheets(ctx *gin.Context) {
+	companyId := ctx.DefaultQuery("company_id", "")
+	employeeId := ctx.DefaultQuery("employee_id", "")
+	status := ctx.DefaultQuery("status", "")
+
+	if companyId == "" && employeeId == "" {
+		utils.ErrorResponse(ctx, fmt.Errorf("company_id or employee_id is required"), http.StatusBadRequest)
+		return
+	}
+
+	from, to, err := parsePeriod(ctx)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	query := `SELECT ` + timesheetColumns + `
+	FROM timesheets
+	WHERE ($1 = '' OR company_id::text = $1)
+		AND ($2 = '' OR employee_id::text = $2)
+		AND ($3 = '' OR status = $3)
+		AND date BETWEEN $4 AND $5
+	ORDER BY employee_id, date`
+
+	rows, err := s.db.Query(query, companyId, employeeId, status, from, to)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	timesheets := make([]*models.TimesheetResponse, 0)
+	totalMinutes := 0
+
+	for rows.Next() {
+		timesheet, err := scanRowIntoTimesheet(rows)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		totalMinutes += timesheet.WorkedMinutes
+		timesheets = append(timesheets, timesheet)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"timesheets": timesheets, "worked_minutes": totalMinutes})
+}
+
+func (s *Server) getTimesheet(ctx *gin.Context) {
+	var params models.GetTimesheetParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	timesheet, err := scanRowIntoTimesheet(s.db.QueryRow(`SELECT `+timesheetColumns+` FROM timesheets WHERE id = $1`, params.ID))
+
+	if err != nil {
+		timesheetErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	start := time.Date(timesheet.Date.Year(), timesheet.Date.Month(), timesheet.Date.Day(), 0, 0, 0, 0, attendance.Location)
+
+	eventsQuery := `SELECT ` + attendanceEventColumns + `
+	FROM attendance_events
+	WHERE employee_id = $1 AND occurred_at >= $2 AND occurred_at < $3
+	ORDER BY occurred_at`
+
+	rows, err := s.db.Query(eventsQuery, timesheet.EmployeeId, start, start.AddDate(0, 0, 1))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	timesheet.Events = make([]*models.AttendanceEventResponse, 0)
+
+	for rows.Next() {
+		event, err := scanRowIntoAttendanceEvent(rows)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		timesheet.Events = append(timesheet.Events, event)
+	}
+
+	timesheet.Corrections, err = findTimesheetCorrections(s.db, timesheet.ID)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"timesheet": timesheet})
+}
+
+// correctTimesheet replaces the intervals of an open timesheet. The previous
+// and new intervals are kept in the corrections trail, and later punches no
+// longer overwrite the corrected day.
+func (s *Server) correctTimesheet(ctx *gin.Context) {
+	var params models.GetTimesheetParams
+	var body models.CorrectTimesheetBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	intervals := body.Intervals
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].Start.Before(intervals[j].Start)
+	})
+
+	for i, interval := range intervals {
+		if !interval.End.After(interval.Start) {
+			utils.ErrorResponse(ctx, fmt.Errorf("interval %d must end after it starts", i+1), http.StatusBadRequest)
+			return
+		}
+
+		if i > 0 && interval.Start.Before(intervals[i-1].End) {
+			utils.ErrorResponse(ctx, fmt.Errorf("interval %d overlaps with the previous one", i+1), http.StatusBadRequest)
+			return
+		}
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	timesheet, err := scanRowIntoTimesheet(tx.QueryRow(`SELECT `+timesheetColumns+` FROM timesheets WHERE id = $1 FOR UPDATE`, params.ID))
+
+	if err != nil {
+		timesheetErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if !s.requireTimesheetApprover(ctx, timesheet.CompanyId, timesheet.EmployeeId) {
+		return
+	}
+
+	if timesheet.Status != models.TimesheetStatusOpen {
+		utils.ErrorResponse(ctx, fmt.Errorf("timesheet %s is already approved", timesheet.ID), http.StatusConflict)
+		return
+	}
+
+	for i, interval := range intervals {
+		if !attendance.Day(interval.Start).Equal(timesheet.Date) {
+			utils.ErrorResponse(ctx, fmt.Errorf("interval %d doesn't start on %s", i+1, timesheet.Date.Format("2006-01-02")), http.StatusBadRequest)
+			return
+		}
+	}
+
+	rounding, err := findAttendanceRounding(tx, timesheet.CompanyId)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	before, err := json.Marshal(timesheet.Intervals)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	after, err := json.Marshal(intervals)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec(`INSERT INTO timesheet_corrections (timesheet_id, changed_by, reason, before, after) VALUES ($1, $2, $3, $4, $5)`,
+		timesheet.ID, ctx.GetString("userId"), body.Reason, before, after)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	update := `UPDATE timesheets
+	SET intervals = $1, worked_minutes = $2, missing_punch = false, corrected = true, updated_at = $3
+	WHERE id = $4
+	RETURNING ` + timesheetColumns
+
+	timesheet, err = scanRowIntoTimesheet(tx.QueryRow(update, after, attendance.WorkedMinutes(intervals, rounding), time.Now(), timesheet.ID))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"timesheet": timesheet})
+}
+
+func (s *Server) approveTimesheet(ctx *gin.Context) {
+	var params models.GetTimesheetParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	current, err := scanRowIntoTimesheet(s.db.QueryRow(`SELECT `+timesheetColumns+` FROM timesheets WHERE id = $1`, params.ID))
+
+	if err != nil {
+		timesheetErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if !s.requireTimesheetApprover(ctx, current.CompanyId, current.EmployeeId) {
+		return
+	}
+
+	query := `UPDATE timesheets
+	SET status = 'approved', approved_by = $1, approved_at = $2, updated_at = $2
+	WHERE id = $3 AND status = 'open' AND missing_punch = false
+	RETURNING ` + timesheetColumns
+
+	timesheet, err := scanRowIntoTimesheet(s.db.QueryRow(query, ctx.GetString("userId"), time.Now(), params.ID))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(ctx, fmt.Errorf("timesheet %s not found, already approved or with missing punches", params.ID), http.StatusConflict)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"timesheet": timesheet})
+}
+
+// approveTimesheetPeriod approves every open timesheet of the employee in the
+// pay period. Days with missing punches stay open and are returned so they
+// can be corrected first.
+func (s *Server) approveTimesheetPeriod(ctx *gin.Context) {
+	var body models.ApproveTimesheetsBody
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	var companyId string
+
+	if err := s.db.QueryRow(`SELECT company_id FROM employees WHERE id = $1`, body.EmployeeId).Scan(&companyId); err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(ctx, fmt.Errorf("employee %s not found", body.EmployeeId), http.StatusNotFound)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if !s.requireTimesheetApprover(ctx, companyId, body.EmployeeId) {
+		return
+	}
+
+	query := `UPDATE timesheets
+	SET status = 'approved', approved_by = $1, approved_at = $2, updated_at = $2
+	WHERE employee_id = $3 AND date BETWEEN $4 AND $5 AND status = 'open' AND missing_punch = false`
+
+	result, err := s.db.Exec(query, ctx.GetString("userId"), time.Now(), body.EmployeeId, body.From, body.To)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	approved, _ := result.RowsAffected()
+
+	rows, err := s.db.Query(`SELECT `+timesheetColumns+` FROM timesheets
+	WHERE employee_id = $1 AND date BETWEEN $2 AND $3 AND missing_punch = true
+	ORDER BY date`, body.EmployeeId, body.From, body.To)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	pending := make([]*models.TimesheetResponse, 0)
+
+	for rows.Next() {
+		timesheet, err := scanRowIntoTimesheet(rows)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		pending = append(pending, timesheet)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"approved": approved, "missing_punches": pending})
+}
+
+func (s *Server) getAttendanceSettings(ctx *gin.Context) {
+	var params models.GetCompanyParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	rounding, err := findAttendanceRounding(s.db, params.ID)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"settings": models.AttendanceSettingsResponse{
+		CompanyId:       params.ID,
+		RoundingMinutes: rounding.Minutes,
+		RoundingMode:    rounding.Mode,
+	}})
+}
+
+func (s *Server) updateAttendanceSettings(ctx *gin.Context) {
+	var params models.GetCompanyParams
+	var body models.AttendanceSettingsBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	query := `INSERT INTO attendance_settings (company_id, rounding_minutes, rounding_mode)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (company_id) DO UPDATE
+	SET rounding_minutes = EXCLUDED.rounding_minutes, rounding_mode = EXCLUDED.rounding_mode, updated_at = $4`
+
+	if _, err := s.db.Exec(query, params.ID, body.RoundingMinutes, body.RoundingMode, time.Now()); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"settings": models.AttendanceSettingsResponse{
+		CompanyId:       params.ID,
+		RoundingMinutes: body.RoundingMinutes,
+		RoundingMode:    body.RoundingMode,
+	}})
+}
+
+// rebuildTimesheet derives the timesheet of the day from the punches around
+// it. Approved and manually corrected timesheets are left untouched.
+func rebuildTimesheet(tx *sql.Tx, companyId string, employeeId string, day time.Time) (*models.TimesheetResponse, error) {
+	rounding, err := findAttendanceRounding(tx, companyId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, attendance.Location)
+
+	rows, err := tx.Query(`SELECT kind, occurred_at FROM attendance_events
+	WHERE employee_id = $1 AND occurred_at >= $2 AND occurred_at < $3`,
+		employeeId, start.AddDate(0, 0, -1), start.AddDate(0, 0, 2))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var events []attendance.Event
+
+	for rows.Next() {
+		var event attendance.Event
+
+		if err := rows.Scan(&event.Kind, &event.At); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+	rows.Close()
+
+	intervals, missingPunch := attendance.BuildDay(events, day)
+
+	if len(intervals) == 0 && !missingPunch {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(intervals)
+
+	if err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO timesheets (company_id, employee_id, date, intervals, worked_minutes, missing_punch)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (employee_id, date) DO UPDATE
+	SET intervals = EXCLUDED.intervals,
+		worked_minutes = EXCLUDED.worked_minutes,
+		missing_punch = EXCLUDED.missing_punch,
+		updated_at = now()
+	WHERE timesheets.status = 'open' AND timesheets.corrected = false
+	RETURNING ` + timesheetColumns
+
+	timesheet, err := scanRowIntoTimesheet(tx.QueryRow(query, companyId, employeeId, day, data, attendance.WorkedMinutes(intervals, rounding), missingPunch))
+
+	if err == sql.ErrNoRows {
+		return scanRowIntoTimesheet(tx.QueryRow(`SELECT `+timesheetColumns+` FROM timesheets WHERE employee_id = $1 AND date = $2`, employeeId, day))
+	}
+
+	return timesheet, err
+}
+
+func findAttendanceRounding(db queryer, companyId string) (attendance.Rounding, error) {
+	rounding := attendance.Rounding{Mode: attendance.RoundNearest}
+
+	err := db.QueryRow(`SELECT rounding_minutes, rounding_mode FROM attendance_settings WHERE company_id = $1`, companyId).
+		Scan(&rounding.Minutes, &rounding.Mode)
+
+	if err != nil && err != sql.ErrNoRows {
+		return rounding, err
+	}
+
+	return rounding, nil
+}
+
+func findTimesheetCorrections(db queryer, timesheetId string) ([]*models.TimesheetCorrectionResponse, error) {
+	rows, err := db.Query(`SELECT id, changed_by, reason, before, after, created_at
+	FROM timesheet_corrections
+	WHERE timesheet_id = $1
+	ORDER BY created_at`, timesheetId)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	corrections := make([]*models.TimesheetCorrectionResponse, 0)
+
+	for rows.Next() {
+		correction := new(models.TimesheetCorrectionResponse)
+		var before, after []byte
+
+		err := rows.Scan(&correction.ID, &correction.ChangedBy, &correction.Reason, &before, &after, &correction.CreatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(before, &correction.Before); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(after, &correction.After); err != nil {
+			return nil, err
+		}
+
+		corrections = append(corrections, correction)
+	}
+
+	return corrections, rows.Err()
+}
+
+// parsePeriod reads the from and to query params, defaulting to the current
+// month.
+func parsePeriod(ctx *gin.Context) (time.Time, time.Time, error) {
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, -1)
+
+	if value := ctx.Query("from"); value != "" {
+		date, err := time.Parse("2006-01-02", value)
+
+		if err != nil {
+			return from, to, fmt.Errorf("from must be a date as YYYY-MM-DD")
+		}
+
+		from = date
+	}
+
+	if value := ctx.Query("to"); value != "" {
+		date, err := time.Parse("2006-01-02", value)
+
+		if err != nil {
+			return from, to, fmt.Errorf("to must be a date as YYYY-MM-DD")
+		}
+
+		to = date
+	}
+
+	return from, to, nil
+}
+
+// requireTimesheetApprover checks that the user can correct and approve the
+// timesheets of the employee
+func (s *Server) requireTimesheetApprover(ctx *gin.Context, companyId string, employeeId string) bool {
+	allowed, err := canApproveTimesheet(s.db, companyId, employeeId, ctx.GetString("userId"))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return false
+	}
+
+	if !allowed {
+		utils.ErrorResponse(ctx, fmt.Errorf("you can't approve the timesheets of employee %s", employeeId), http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+// canApproveTimesheet reports whether the user is the manager of the
+// department of the employee, HR or the owner. Nobody approves their own
+// timesheets.
+func canApproveTimesheet(db queryer, companyId string, employeeId string, userId string) (bool, error) {
+	var own bool
+	query := `SELECT EXISTS (SELECT 1 FROM employees WHERE id = $1 AND user_id::text = $2)`
+
+	if err := db.QueryRow(query, employeeId, userId).Scan(&own); err != nil || own {
+		return false, err
+	}
+
+	allowed, err := hasEmployeeRole(db, companyId, employeeId, models.RoleManager, userId)
+
+	if err != nil || allowed {
+		return allowed, err
+	}
+
+	return hasCompanyRole(db, companyId, userId, models.RoleHR)
+}
+
+func timesheetErrorResponse(ctx *gin.Context, err error, id string) {
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(ctx, fmt.Errorf("timesheet %s not found", id), http.StatusNotFound)
+		return
+	}
+	utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+}
+
+func scanRowIntoAttendanceEvent(row rowScanner) (*models.AttendanceEventResponse, error) {
+	event := new(models.AttendanceEventResponse)
+
+	err := row.Scan(
+		&event.ID,
+		&event.CompanyId,
+		&event.EmployeeId,
+		&event.Kind,
+		&event.OccurredAt,
+		&event.Source,
+		&event.CreatedBy,
+		&event.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+func scanRowIntoTimesheet(row rowScanner) (*models.TimesheetResponse, error) {
+	timesheet := new(models.TimesheetResponse)
+	var intervals []byte
+
+	err := row.Scan(
+		&timesheet.ID,
+		&timesheet.CompanyId,
+		&timesheet.EmployeeId,
+		&timesheet.Date,
+		&intervals,
+		&timesheet.WorkedMinutes,
+		&timesheet.MissingPunch,
+		&timesheet.Corrected,
+		&timesheet.Status,
+		&timesheet.ApprovedBy,
+		&timesheet.ApprovedAt,
+		&timesheet.CreatedAt,
+		&timesheet.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(intervals, &timesheet.Intervals); err != nil {
+		return nil, err
+	}
+
+	return timesheet, nil
+}
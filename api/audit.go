@@ -0,0 +1,615 @@
+package api
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/audit"
+	"github.com/gioCuesta25/employees-manager-backend/models"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
+)
+
+const auditEntryColumns = `a.id, a.company_id, a.actor_id, u.full_name, a.ip, a.request_id, a.entity, a.entity_id, a.action, a.changes, a.created_at`
+
+// requestIdHeader carries the id of the request, from the client or
+// generated, so audit entries and logs can be matched with it
+const requestIdHeader = "X-Request-Id"
+
+// auditedEntity tells the audit middleware where the records of an entity
+// live. Key is the column matched with the route parameter; Parent, when
+// set, the column matched with the :id of the nested routes. Created
+// records are found in the Response key of the response body. Secret
+// columns are left out of the entries.
+type auditedEntity struct {
+	Table    string
+	Key      string
+	Parent   string
+	Response string
+	Secret   []string
+}
+
+var auditedEntities = map[string]auditedEntity{
+	"company":             {Table: "companies", Key: "id", Response: "company"},
+	"company_member":      {Table: "company_members", Key: "user_id", Parent: "company_id", Response: "member"},
+	"company_calendar":    {Table: "company_calendars", Key: "company_id"},
+	"calendar_closure":    {Table: "company_calendar_closures", Key: "id", Parent: "company_id", Response: "closure"},
+	"attendance_settings": {Table: "attendance_settings", Key: "company_id"},
+	"overtime_settings":   {Table: "overtime_settings", Key: "company_id"},
+	"roster_settings":     {Table: "roster_settings", Key: "company_id"},
+	"custom_field":        {Table: "custom_fields", Key: "id", Response: "custom_field"},
+	"department":          {Table: "departments", Key: "id", Response: "department"},
+	// Positions are returned under department
+	"position":                    {Table: "positions", Key: "id", Response: "department"},
+	"employee":                    {Table: "employees", Key: "id", Response: "employee"},
+	"shift_feed":                  {Table: "shift_feeds", Key: "employee_id"},
+	"employee_document":           {Table: "employee_documents", Key: "id", Parent: "employee_id", Response: "document"},
+	"emergency_contact":           {Table: "emergency_contacts", Key: "id", Parent: "employee_id", Response: "emergency_contact"},
+	"dependent":                   {Table: "dependents", Key: "id", Parent: "employee_id", Response: "dependent"},
+	"beneficiary":                 {Table: "beneficiaries", Key: "id", Parent: "employee_id", Response: "beneficiary"},
+	"employee_invitation":         {Table: "employee_invitations", Key: "id", Parent: "employee_id", Response: "invitation"},
+	"payroll_run":                 {Table: "payroll_runs", Key: "id", Response: "payroll_run"},
+	"payroll_adjustment":          {Table: "payroll_adjustments", Key: "id", Parent: "payroll_run_id", Response: "adjustment"},
+	"electronic_payroll_document": {Table: "electronic_payroll_documents", Key: "id", Response: "document"},
+	"leave_type":                  {Table: "leave_types", Key: "id", Response: "leave_type"},
+	"leave_request":               {Table: "leave_requests", Key: "id", Response: "leave_request"},
+	"attendance_event":            {Table: "attendance_events", Key: "id", Response: "event"},
+	"timesheet":                   {Table: "timesheets", Key: "id", Response: "timesheet"},
+	"shift_template":              {Table: "shift_templates", Key: "id", Response: "shift_template"},
+	"shift":                       {Table: "shifts", Key: "id"},
+	"contract":                    {Table: "contracts", Key: "id", Response: "contract"},
+	"contract_template":           {Table: "contract_templates", Key: "id", Response: "contract_template"},
+	"checklist_template":          {Table: "checklist_templates", Key: "id", Response: "checklist_template"},
+	"checklist_task":              {Table: "checklist_tasks", Key: "id", Response: "task"},
+	"profile_change_request":      {Table: "profile_change_requests", Key: "id", Response: "change_request"},
+	"approval_policy":             {Table: "approval_policies", Key: "id", Response: "approval_policy"},
+	"approval":                    {Table: "approvals", Key: "id", Response: "approval"},
+	"approval_delegation":         {Table: "approval_delegations", Key: "id", Response: "approval_delegation"},
+	"job":                         {Table: "jobs", Key: "id"},
+	"webhook":                     {Table: "webhook_subscriptions", Key: "id", Response: "webhook", Secret: []string{"secret"}},
+	"webhook_delivery":            {Table: "webhook_deliveries", Key: "id", Parent: "subscription_id", Response: "delivery"},
+}
+
+// heldResponse holds the response of an audited route until its entry is
+// written, so a change that couldn't be logged isn't reported as done
+type heldResponse struct {
+	gin.ResponseWriter
+	status  int
+	written bool
+	body    bytes.Buffer
+}
+
+func (w *heldResponse) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *heldResponse) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *heldResponse) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+
+func (w *heldResponse) WriteString(data string) (int, error) {
+	w.written = true
+	return w.body.WriteString(data)
+}
+
+func (w *heldResponse) Status() int {
+	return w.status
+}
+
+func (w *heldResponse) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *heldResponse) Written() bool {
+	return w.written
+}
+
+func (w *heldResponse) Flush() {}
+
+// release sends the held response
+func (w *heldResponse) release() {
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.WriteHeaderNow()
+
+	if w.body.Len() > 0 {
+		w.ResponseWriter.Write(w.body.Bytes())
+	}
+}
+
+// RequestID tags the request with the id the client sent or a new one
+func RequestID(ctx *gin.Context) {
+	id := ctx.GetHeader(requestIdHeader)
+
+	if id == "" || len(id) > 64 {
+		buf := make([]byte, 16)
+		rand.Read(buf)
+		id = hex.EncodeToString(buf)
+	}
+
+	ctx.Set("requestId", id)
+	ctx.Header(requestIdHeader, id)
+
+	ctx.Next()
+}
+
+// audit records the mutation of the entity made by the route. The record
+// is read by the route parameter before and after the handler runs; routes
+// that create it find it in their response. Failed requests aren't logged.
+// The response is held until the entry is written: when it can't be, the
+// request fails with 500 instead, although the change was saved.
+func (s *Server) audit(entity string, param string) gin.HandlerFunc {
+	spec, ok := auditedEntities[entity]
+
+	if !ok {
+		panic(fmt.Sprintf("audit: unknown entity %s", entity))
+	}
+
+	return func(ctx *gin.Context) {
+		var id, parent string
+
+		if param != "" {
+			id = ctx.Param(param)
+		}
+
+		if spec.Parent != "" {
+			parent = ctx.Param("id")
+		}
+
+		var before map[string]any
+
+		if id != "" {
+			var err error
+			before, err = auditSnapshot(s.db, spec, id, parent)
+
+			if err != nil {
+				utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+				ctx.Abort()
+				return
+			}
+		}
+
+		writer := ctx.Writer
+		held := &heldResponse{ResponseWriter: writer, status: http.StatusOK}
+		ctx.Writer = held
+
+		ctx.Next()
+
+		ctx.Writer = writer
+
+		if err := s.auditResponse(ctx, entity, spec, id, parent, before, held); err != nil {
+			log.Printf("audit %s %s: %v", entity, id, err)
+			utils.ErrorResponse(ctx, fmt.Errorf("the change was saved but couldn't be audited, request %s", ctx.GetString("requestId")), http.StatusInternalServerError)
+			return
+		}
+
+		held.release()
+	}
+}
+
+// auditResponse writes the entry of the change the held response reports
+func (s *Server) auditResponse(ctx *gin.Context, entity string, spec auditedEntity, id string, parent string, before map[string]any, held *heldResponse) error {
+	if held.status >= http.StatusMultipleChoices {
+		return nil
+	}
+
+	if id == "" && spec.Response != "" {
+		id = createdId(held.body.Bytes(), spec)
+	}
+
+	var after map[string]any
+
+	if id != "" {
+		var err error
+		after, err = auditSnapshot(s.db, spec, id, parent)
+
+		if err != nil {
+			return err
+		}
+
+		// Nothing to delete
+		if before == nil && after == nil {
+			return nil
+		}
+	}
+
+	return s.writeAudit(ctx, entity, id, before, after)
+}
+
+func (s *Server) writeAudit(ctx *gin.Context, entity string, id string, before map[string]any, after map[string]any) error {
+	action := audit.ActionUpdate
+
+	switch ctx.Request.Method {
+	case http.MethodDelete:
+		action = audit.ActionDelete
+	case http.MethodPost:
+		// Named after the endpoint, as approve in /payroll-runs/:id/approve
+		segments := strings.Split(ctx.FullPath(), "/")
+		action = segments[len(segments)-1]
+
+		if action == "" {
+			action = audit.ActionCreate
+		}
+	}
+
+	return recordAudit(s.db, ctx, entity, id, audit.Action(before, after, action), before, after)
+}
+
+// recordAudit adds the entry of a change made by the request. Changes made
+// in a transaction record it in the same one, so they're logged only when
+// they're committed.
+func recordAudit(db queryer, ctx *gin.Context, entity string, id string, action string, before map[string]any, after map[string]any) error {
+	encoded, err := json.Marshal(audit.Diff(before, after))
+
+	if err != nil {
+		return err
+	}
+
+	companyId, err := auditCompany(db, entity, id, before, after)
+
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO audit_log (company_id, actor_id, ip, request_id, entity, entity_id, action, changes)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err = db.Exec(query,
+		companyId,
+		nullIfEmpty(ctx.GetString("userId")),
+		ctx.ClientIP(),
+		nullIfEmpty(ctx.GetString("requestId")),
+		entity,
+		nullIfEmpty(id),
+		action,
+		encoded)
+
+	return err
+}
+
+// auditEmployeeChange makes the change of the employee and records its
+// entry in the same transaction. It's for the changes of routes audited as
+// another entity, as approvals applying a salary change.
+func auditEmployeeChange(tx *sql.Tx, ctx *gin.Context, employeeId string, action string, change func() error) error {
+	before, err := auditSnapshot(tx, auditedEntities["employee"], employeeId, "")
+
+	if err != nil {
+		return err
+	}
+
+	if err := change(); err != nil {
+		return err
+	}
+
+	after, err := auditSnapshot(tx, auditedEntities["employee"], employeeId, "")
+
+	if err != nil {
+		return err
+	}
+
+	if len(audit.Diff(before, after)) == 0 {
+		return nil
+	}
+
+	return recordAudit(tx, ctx, "employee", employeeId, action, before, after)
+}
+
+func (s *Server) getCompanyAudit(ctx *gin.Context) {
+	var params models.GetCompanyParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if !s.requireCompanyRole(ctx, params.ID, models.RoleHR) {
+		return
+	}
+
+	from, err := parseAuditTime(ctx.Query("from"), time.Time{}, false)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, fmt.Errorf("from: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	to, err := parseAuditTime(ctx.Query("to"), time.Now(), true)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, fmt.Errorf("to: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	entity := ctx.DefaultQuery("entity", "")
+	actor := ctx.DefaultQuery("actor", "")
+	entityId := ctx.DefaultQuery("entity_id", "")
+
+	where := `a.company_id = $1
+		AND ($2 = '' OR a.entity = $2)
+		AND ($3 = '' OR a.actor_id::text = $3)
+		AND ($4 = '' OR a.entity_id = $4)
+		AND a.created_at >= $5 AND a.created_at < $6`
+
+	s.listAuditEntries(ctx, where, params.ID, entity, actor, entityId, from, to)
+}
+
+// getEmployeeHistory returns the changes made to the employee, newest first
+func (s *Server) getEmployeeHistory(ctx *gin.Context) {
+	var params models.GetEmployeeParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	var companyId string
+	err := s.db.QueryRow(`SELECT company_id FROM employees WHERE id = $1`, params.ID).Scan(&companyId)
+
+	if err != nil {
+		employeeErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if !s.requireCompanyRole(ctx, companyId, models.RoleHR) {
+		return
+	}
+
+	s.listAuditEntries(ctx, `a.entity = 'employee' AND a.entity_id = $1`, params.ID)
+}
+
+// listAuditEntries responds with a page of the entries matching the
+// condition
+func (s *Server) listAuditEntries(ctx *gin.Context, where string, args ...any) {
+	pageNumber, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("size", "50"))
+
+	offset := (pageNumber - 1) * pageSize
+
+	query := `SELECT ` + auditEntryColumns + `
+	FROM audit_log a
+	LEFT JOIN users u ON u.id = a.actor_id
+	WHERE ` + where + `
+	ORDER BY a.created_at DESC, a.id DESC
+	LIMIT $` + strconv.Itoa(len(args)+1) + `
+	OFFSET $` + strconv.Itoa(len(args)+2)
+
+	rows, err := s.db.Query(query, append(args, pageSize, offset)...)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var totalItems int
+	err = s.db.QueryRow(`SELECT COUNT(*) FROM audit_log a WHERE `+where, args...).Scan(&totalItems)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	totalPages := int(math.Ceil(float64(totalItems) / float64(pageSize)))
+	var nextPage, prevPage *int
+
+	if pageNumber < totalPages {
+		nextPageNum := pageNumber + 1
+		nextPage = &nextPageNum
+	}
+
+	if pageNumber > 1 {
+		prevPageNum := pageNumber - 1
+		prevPage = &prevPageNum
+	}
+
+	entries := make([]*models.AuditEntryResponse, 0)
+
+	for rows.Next() {
+		entry, err := scanRowIntoAuditEntry(rows)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		entries = append(entries, entry)
+	}
+
+	result := models.PaginatedResult{
+		Data:       entries,
+		PageNumber: pageNumber,
+		PageSize:   pageSize,
+		TotalItems: totalItems,
+		NextPage:   nextPage,
+		PrevPage:   prevPage,
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// auditSnapshot reads the record as JSON, or nil when it doesn't exist
+func auditSnapshot(db queryer, spec auditedEntity, id string, parent string) (map[string]any, error) {
+	query := fmt.Sprintf(`SELECT to_jsonb(t) FROM %s t WHERE %s::text = $1`, spec.Table, spec.Key)
+	args := []any{id}
+
+	if spec.Parent != "" {
+		query += fmt.Sprintf(` AND %s::text = $2`, spec.Parent)
+		args = append(args, parent)
+	}
+
+	var encoded []byte
+
+	rows, err := db.Query(query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	if err := rows.Scan(&encoded); err != nil {
+		return nil, err
+	}
+
+	var state map[string]any
+
+	if err := json.Unmarshal(encoded, &state); err != nil {
+		return nil, err
+	}
+
+	for _, column := range spec.Secret {
+		delete(state, column)
+	}
+
+	return state, nil
+}
+
+// createdId finds the key of the record in the response of the route that
+// created it
+func createdId(body []byte, spec auditedEntity) string {
+	var response map[string]json.RawMessage
+
+	if err := json.Unmarshal(body, &response); err != nil {
+		return ""
+	}
+
+	var record map[string]any
+
+	if err := json.Unmarshal(response[spec.Response], &record); err != nil {
+		return ""
+	}
+
+	if id, ok := record[spec.Key].(string); ok {
+		return id
+	}
+
+	return ""
+}
+
+// auditCompany finds the company of the record, through its employee,
+// payroll run or webhook for the records that don't keep it.
+func auditCompany(db queryer, entity string, id string, states ...map[string]any) (*string, error) {
+	if entity == "company" && id != "" {
+		return &id, nil
+	}
+
+	for _, state := range states {
+		if companyId, ok := state["company_id"].(string); ok {
+			return &companyId, nil
+		}
+	}
+
+	lookups := []struct{ field, table string }{
+		{"employee_id", "employees"},
+		{"payroll_run_id", "payroll_runs"},
+		{"subscription_id", "webhook_subscriptions"},
+	}
+
+	for _, lookup := range lookups {
+		for _, state := range states {
+			parentId, ok := state[lookup.field].(string)
+
+			if !ok {
+				continue
+			}
+
+			var companyId string
+			err := db.QueryRow(fmt.Sprintf(`SELECT company_id FROM %s WHERE id::text = $1`, lookup.table), parentId).Scan(&companyId)
+
+			if err == sql.ErrNoRows {
+				return nil, nil
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			return &companyId, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// parseAuditTime reads a time or a date. A date ending the range includes
+// the whole day.
+func parseAuditTime(value string, fallback time.Time, end bool) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+
+	if err != nil {
+		return time.Time{}, fmt.Errorf("must be a date as YYYY-MM-DD or a RFC 3339 time")
+	}
+
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+
+	return t, nil
+}
+
+func nullIfEmpty(value string) *string {
+	if value == "" {
+		return nil
+	}
+
+	return &value
+}
+
+func scanRowIntoAuditEntry(row rowScanner) (*models.AuditEntryResponse, error) {
+	entry := new(models.AuditEntryResponse)
+	var changes []byte
+
+	err := row.Scan(
+		&entry.ID,
+		&entry.CompanyId,
+		&entry.ActorId,
+		&entry.ActorName,
+		&entry.IP,
+		&entry.RequestId,
+		&entry.Entity,
+		&entry.EntityId,
+		&entry.Action,
+		&changes,
+		&entry.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(changes, &entry.Changes); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
@@ -0,0 +1,290 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/calendar"
+	"github.com/gioCuesta25/employees-manager-backend/models"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
+	"github.com/lib/pq"
+)
+
+const (
+	// Years the calendar can be read for
+	minCalendarYear = 1900
+	maxCalendarYear = 2200
+
+	// Longest range working days are counted in, as each day is checked
+	maxWorkingDaysRange = 5 * 366
+)
+
+func (s *Server) getCompanyCalendar(ctx *gin.Context) {
+	var params models.GetCompanyParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	year, err := strconv.Atoi(ctx.DefaultQuery("year", strconv.Itoa(time.Now().Year())))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, fmt.Errorf("year must be a number"), http.StatusBadRequest)
+		return
+	}
+
+	if year < minCalendarYear || year > maxCalendarYear {
+		utils.ErrorResponse(ctx, fmt.Errorf("year must be between %d and %d", minCalendarYear, maxCalendarYear), http.StatusBadRequest)
+		return
+	}
+
+	cal, nationalHolidays, err := loadCompanyCalendar(s.db, params.ID)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	closures, err := findCalendarClosures(s.db, params.ID, year)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	holidays := make([]calendar.Holiday, 0)
+	if nationalHolidays {
+		holidays = calendar.ColombianHolidays(year)
+	}
+
+	workingWeek := make([]int, 0, 7)
+	for _, day := range cal.WorkingWeek() {
+		workingWeek = append(workingWeek, int(day))
+	}
+
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+	ctx.JSON(http.StatusOK, gin.H{"calendar": models.CalendarResponse{
+		CompanyId:        params.ID,
+		Year:             year,
+		WorkingWeek:      workingWeek,
+		NationalHolidays: nationalHolidays,
+		Holidays:         holidays,
+		Closures:         closures,
+		WorkingDays:      cal.WorkingDaysBetween(start, end),
+	}})
+}
+
+func (s *Server) updateCompanyCalendar(ctx *gin.Context) {
+	var params models.GetCompanyParams
+	var body models.UpdateCalendarBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	nationalHolidays := true
+	if body.NationalHolidays != nil {
+		nationalHolidays = *body.NationalHolidays
+	}
+
+	query := `INSERT INTO company_calendars (company_id, working_week, national_holidays)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (company_id) DO UPDATE
+	SET working_week = EXCLUDED.working_week, national_holidays = EXCLUDED.national_holidays, updated_at = $4`
+
+	_, err := s.db.Exec(query, params.ID, pq.Array(body.WorkingWeek), nationalHolidays, time.Now())
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	s.getCompanyCalendar(ctx)
+}
+
+func (s *Server) createCalendarClosure(ctx *gin.Context) {
+	var params models.GetCompanyParams
+	var body models.CreateCalendarClosureBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	query := `INSERT INTO company_calendar_closures (company_id, date, name)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (company_id, date) DO UPDATE SET name = EXCLUDED.name
+	RETURNING id, company_id, date, name, created_at`
+
+	closure, err := scanRowIntoCalendarClosure(s.db.QueryRow(query, params.ID, body.Date, body.Name))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"closure": closure})
+}
+
+func (s *Server) deleteCalendarClosure(ctx *gin.Context) {
+	var params models.GetCalendarClosureParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	_, err := s.db.Exec(`DELETE FROM company_calendar_closures WHERE id = $1 AND company_id = $2`, params.ClosureId, params.ID)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+func (s *Server) countWorkingDays(ctx *gin.Context) {
+	var params models.GetCompanyParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	from, err := time.Parse("2006-01-02", ctx.Query("from"))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, fmt.Errorf("from must be a date as YYYY-MM-DD"), http.StatusBadRequest)
+		return
+	}
+
+	to, err := time.Parse("2006-01-02", ctx.Query("to"))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, fmt.Errorf("to must be a date as YYYY-MM-DD"), http.StatusBadRequest)
+		return
+	}
+
+	if to.Sub(from) > maxWorkingDaysRange*24*time.Hour {
+		utils.ErrorResponse(ctx, fmt.Errorf("the range can't be longer than %d days", maxWorkingDaysRange), http.StatusBadRequest)
+		return
+	}
+
+	cal, _, err := loadCompanyCalendar(s.db, params.ID)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.WorkingDaysResponse{
+		From:        from,
+		To:          to,
+		WorkingDays: cal.WorkingDaysBetween(from, to),
+	})
+}
+
+// loadCompanyCalendar builds the working calendar of the company with all its
+// closures. Companies that haven't configured a calendar work Monday to
+// Friday and observe the national holidays.
+func loadCompanyCalendar(db queryer, companyId string) (*calendar.Calendar, bool, error) {
+	var workingWeek []int64
+	nationalHolidays := true
+
+	err := db.QueryRow(`SELECT working_week, national_holidays FROM company_calendars WHERE company_id = $1`, companyId).
+		Scan(pq.Array(&workingWeek), &nationalHolidays)
+
+	var cal *calendar.Calendar
+
+	switch err {
+	case nil:
+		days := make([]time.Weekday, 0, len(workingWeek))
+		for _, day := range workingWeek {
+			days = append(days, time.Weekday(day))
+		}
+		cal = calendar.New(days, nationalHolidays)
+	case sql.ErrNoRows:
+		cal = calendar.Default()
+	default:
+		return nil, false, err
+	}
+
+	closures, err := findCalendarClosures(db, companyId, 0)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	for _, closure := range closures {
+		cal.AddClosure(closure.Date, closure.Name)
+	}
+
+	return cal, nationalHolidays, nil
+}
+
+// findCalendarClosures returns the closures of the year, or all of them when
+// year is 0.
+func findCalendarClosures(db queryer, companyId string, year int) ([]*models.CalendarClosureResponse, error) {
+	query := `SELECT id, company_id, date, name, created_at
+	FROM company_calendar_closures
+	WHERE company_id = $1 AND ($2 = 0 OR EXTRACT(YEAR FROM date) = $2)
+	ORDER BY date`
+
+	rows, err := db.Query(query, companyId, year)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	closures := make([]*models.CalendarClosureResponse, 0)
+
+	for rows.Next() {
+		closure, err := scanRowIntoCalendarClosure(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		closures = append(closures, closure)
+	}
+
+	return closures, rows.Err()
+}
+
+func scanRowIntoCalendarClosure(row rowScanner) (*models.CalendarClosureResponse, error) {
+	closure := new(models.CalendarClosureResponse)
+
+	err := row.Scan(
+		&closure.ID,
+		&closure.CompanyId,
+		&closure.Date,
+		&closure.Name,
+		&closure.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return closure, nil
+}
@@ -0,0 +1,774 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/approvals"
+	"github.com/gioCuesta25/employees-manager-backend/attendance"
+	"github.com/gioCuesta25/employees-manager-backend/models"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
+	"github.com/lib/pq"
+)
+
+const checklistTemplateColumns = `id, company_id, name, kind, created_at, updated_at`
+
+const checklistTaskColumns = `t.id, t.company_id, t.employee_id, e.name || ' ' || e.last_name, t.template_id, t.kind, t.title,
+	t.description, t.assignee_role, t.due_date, t.completed_at, t.completed_by, t.notes, t.created_at`
+
+func (s *Server) createChecklistTemplate(ctx *gin.Context) {
+	var body models.CreateChecklistTemplateBody
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO checklist_templates (company_id, name, kind)
+	VALUES ($1, $2, $3)
+	RETURNING ` + checklistTemplateColumns
+
+	template, err := scanRowIntoChecklistTemplate(tx.QueryRow(query, body.CompanyId, body.Name, body.Kind))
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			utils.ErrorResponse(ctx, fmt.Errorf("a checklist template named %s already exists", body.Name), http.StatusConflict)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	template.Tasks, err = insertChecklistTemplateTasks(tx, template.ID, body.Tasks)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"checklist_template": template})
+}
+
+func (s *Server) listChecklistTemplates(ctx *gin.Context) {
+	companyId := ctx.DefaultQuery("company_id", "")
+
+	if companyId == "" {
+		utils.ErrorResponse(ctx, fmt.Errorf("company_id is required"), http.StatusBadRequest)
+		return
+	}
+
+	kind := ctx.DefaultQuery("kind", "")
+
+	query := `SELECT ` + checklistTemplateColumns + ` FROM checklist_templates
+	WHERE company_id = $1 AND ($2 = '' OR kind = $2)
+	ORDER BY kind, name`
+
+	rows, err := s.db.Query(query, companyId, kind)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	templates := make([]*models.ChecklistTemplateResponse, 0)
+	ids := make([]string, 0)
+
+	for rows.Next() {
+		template, err := scanRowIntoChecklistTemplate(rows)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		templates = append(templates, template)
+		ids = append(ids, template.ID)
+	}
+
+	if err := rows.Err(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	tasks, err := findChecklistTemplateTasks(s.db, ids)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	for _, template := range templates {
+		template.Tasks = tasks[template.ID]
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"checklist_templates": templates})
+}
+
+func (s *Server) getChecklistTemplate(ctx *gin.Context) {
+	var params models.GetChecklistTemplateParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	template, err := scanRowIntoChecklistTemplate(s.db.QueryRow(`SELECT `+checklistTemplateColumns+` FROM checklist_templates WHERE id = $1`, params.ID))
+
+	if err != nil {
+		checklistTemplateErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	tasks, err := findChecklistTemplateTasks(s.db, []string{template.ID})
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	template.Tasks = tasks[template.ID]
+
+	ctx.JSON(http.StatusOK, gin.H{"checklist_template": template})
+}
+
+// updateChecklistTemplate replaces the tasks of the template. The checklists
+// already started keep their tasks.
+func (s *Server) updateChecklistTemplate(ctx *gin.Context) {
+	var params models.GetChecklistTemplateParams
+	var body models.CreateChecklistTemplateBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE checklist_templates
+	SET name = $1, kind = $2, updated_at = $3
+	WHERE id = $4
+	RETURNING ` + checklistTemplateColumns
+
+	template, err := scanRowIntoChecklistTemplate(tx.QueryRow(query, body.Name, body.Kind, time.Now(), params.ID))
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			utils.ErrorResponse(ctx, fmt.Errorf("a checklist template named %s already exists", body.Name), http.StatusConflict)
+			return
+		}
+		checklistTemplateErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if _, err := tx.Exec(`DELETE FROM checklist_template_tasks WHERE template_id = $1`, template.ID); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	template.Tasks, err = insertChecklistTemplateTasks(tx, template.ID, body.Tasks)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"checklist_template": template})
+}
+
+func (s *Server) deleteChecklistTemplate(ctx *gin.Context) {
+	var params models.GetChecklistTemplateParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.db.Exec(`DELETE FROM checklist_templates WHERE id = $1`, params.ID)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		utils.ErrorResponse(ctx, fmt.Errorf("checklist template %s not found", params.ID), http.StatusNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+func (s *Server) listEmployeeChecklist(ctx *gin.Context) {
+	var params models.GetEmployeeParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	kind := ctx.DefaultQuery("kind", "")
+
+	tasks, err := findChecklistTasks(s.db, `t.employee_id = $1 AND ($2 = '' OR t.kind = $2)`, params.ID, kind)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	completed := 0
+	for _, task := range tasks {
+		if task.CompletedAt != nil {
+			completed++
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"tasks": tasks, "completed": completed, "total": len(tasks)})
+}
+
+// terminateEmployee asks for the termination of the employee through the
+// termination approval chain of the company. Once approved it records the
+// last day of the employee, terminates their active contract and starts the
+// offboarding checklists.
+func (s *Server) terminateEmployee(ctx *gin.Context) {
+	var params models.GetEmployeeParams
+	var body models.TerminateEmployeeBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	approval, result, err := requestTermination(tx, params.ID, calendarDate(body.Date), body.Reason, ctx.GetString("userId"))
+
+	if err != nil {
+		terminationErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if result == nil {
+		ctx.JSON(http.StatusAccepted, gin.H{"approval": approval})
+		return
+	}
+
+	result["approval"] = approval
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// requestTermination asks for the termination of the employee through the
+// termination chain of the company and applies it when the chain approves
+// it at once. The result is nil while the termination waits for approval.
+func requestTermination(tx *sql.Tx, employeeId string, date time.Time, reason string, requestedBy string) (*models.ApprovalResponse, gin.H, error) {
+	var companyId string
+	var admissionDate time.Time
+	var terminationDate *time.Time
+
+	err := tx.QueryRow(`SELECT company_id, admission_date, termination_date FROM employees WHERE id = $1 FOR UPDATE`, employeeId).
+		Scan(&companyId, &admissionDate, &terminationDate)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	allowed, err := canRequestTermination(tx, companyId, employeeId, requestedBy)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !allowed {
+		return nil, nil, forbiddenError("you can't ask for the termination of this employee")
+	}
+
+	if terminationDate != nil {
+		return nil, nil, conflictError(fmt.Sprintf("employee %s was already terminated on %s", employeeId, terminationDate.Format("2006-01-02")))
+	}
+
+	if date.Before(calendarDate(admissionDate)) {
+		return nil, nil, invalidError("date can't be before the admission date")
+	}
+
+	approval, err := startApproval(tx, approvalRequest{
+		CompanyId:   companyId,
+		Subject:     approvals.SubjectTermination,
+		EmployeeId:  employeeId,
+		Payload:     map[string]any{"date": date.Format("2006-01-02"), "reason": reason},
+		RequestedBy: requestedBy,
+	})
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, nil, conflictError(fmt.Sprintf("the termination of employee %s is already waiting for approval", employeeId))
+		}
+		return nil, nil, err
+	}
+
+	if approval.Status != approvals.StatusApproved {
+		return approval, nil, nil
+	}
+
+	result, err := applyTermination(tx, companyId, employeeId, date, reason)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return approval, result, nil
+}
+
+// canRequestTermination reports whether the user can ask for the termination
+// of the employee: the owner, HR and the managers of the company, and the
+// manager of the department of the employee.
+func canRequestTermination(db queryer, companyId string, employeeId string, userId string) (bool, error) {
+	allowed, err := hasCompanyRole(db, companyId, userId, models.RoleHR, models.RoleManager)
+
+	if err != nil || allowed {
+		return allowed, err
+	}
+
+	return hasEmployeeRole(db, companyId, employeeId, models.RoleManager, userId)
+}
+
+func terminationErrorResponse(ctx *gin.Context, err error, id string) {
+	var conflict conflictError
+	var invalid invalidError
+	var forbidden forbiddenError
+
+	switch {
+	case err == sql.ErrNoRows:
+		utils.ErrorResponse(ctx, fmt.Errorf("employee %s not found", id), http.StatusNotFound)
+	case errors.As(err, &forbidden):
+		utils.ErrorResponse(ctx, err, http.StatusForbidden)
+	case errors.As(err, &conflict):
+		utils.ErrorResponse(ctx, err, http.StatusConflict)
+	case errors.As(err, &invalid):
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+	default:
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+	}
+}
+
+// applyTerminationApproval terminates the employee with the date and reason
+// of the approved request.
+func applyTerminationApproval(tx *sql.Tx, approval *models.ApprovalResponse) (any, error) {
+	value, _ := approval.Payload["date"].(string)
+	reason, _ := approval.Payload["reason"].(string)
+
+	date, err := time.Parse("2006-01-02", value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return applyTermination(tx, approval.CompanyId, approval.EmployeeId, date, reason)
+}
+
+func applyTermination(tx *sql.Tx, companyId string, employeeId string, date time.Time, reason string) (gin.H, error) {
+	result, err := tx.Exec(`UPDATE employees SET termination_date = $1, termination_reason = $2, updated_at = $3 WHERE id = $4 AND termination_date IS NULL`,
+		date, reason, time.Now(), employeeId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return nil, conflictError(fmt.Sprintf("employee %s was already terminated", employeeId))
+	}
+
+	query := `UPDATE contracts
+	SET status = $1, terminated_at = $2, termination_reason = $3, updated_at = $4
+	WHERE employee_id = $5 AND status = $6
+	RETURNING ` + contractColumns
+
+	terminated, err := scanRowIntoContract(tx.QueryRow(query,
+		models.ContractStatusTerminated,
+		date,
+		reason,
+		time.Now(),
+		employeeId,
+		models.ContractStatusActive))
+
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	tasks, err := startChecklists(tx, companyId, employeeId, models.ChecklistKindOffboarding, date)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"employee_id":      employeeId,
+		"termination_date": date,
+		"contract":         terminated,
+		"checklist":        tasks,
+	}, nil
+}
+
+// completeChecklistTask can be done by whoever holds the assignee role for
+// the employee, or by the company owner.
+func (s *Server) completeChecklistTask(ctx *gin.Context) {
+	var params models.GetChecklistTaskParams
+	var body models.CompleteChecklistTaskBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	// The notes are optional
+	if err := ctx.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	userId := ctx.GetString("userId")
+
+	task, err := findChecklistTask(s.db, params.ID)
+
+	if err != nil {
+		checklistTaskErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if !s.canCompleteChecklistTask(ctx, task, userId) {
+		return
+	}
+
+	query := `UPDATE checklist_tasks t
+	SET completed_at = $1, completed_by = $2, notes = COALESCE($3, t.notes)
+	FROM employees e
+	WHERE e.id = t.employee_id AND t.id = $4 AND t.completed_at IS NULL
+	RETURNING ` + checklistTaskColumns
+
+	task, err = scanRowIntoChecklistTask(s.db.QueryRow(query, time.Now(), userId, body.Notes, params.ID))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(ctx, fmt.Errorf("checklist task %s is already completed", params.ID), http.StatusConflict)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"task": task})
+}
+
+func (s *Server) reopenChecklistTask(ctx *gin.Context) {
+	var params models.GetChecklistTaskParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	task, err := findChecklistTask(s.db, params.ID)
+
+	if err != nil {
+		checklistTaskErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if !s.canCompleteChecklistTask(ctx, task, ctx.GetString("userId")) {
+		return
+	}
+
+	query := `UPDATE checklist_tasks t
+	SET completed_at = NULL, completed_by = NULL
+	FROM employees e
+	WHERE e.id = t.employee_id AND t.id = $1
+	RETURNING ` + checklistTaskColumns
+
+	task, err = scanRowIntoChecklistTask(s.db.QueryRow(query, params.ID))
+
+	if err != nil {
+		checklistTaskErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"task": task})
+}
+
+// getOverdueChecklistTasks is the dashboard of the pending tasks past their
+// due date, with the counts per assignee role and employee.
+func (s *Server) getOverdueChecklistTasks(ctx *gin.Context) {
+	var params models.GetCompanyParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	role := ctx.DefaultQuery("role", "")
+	kind := ctx.DefaultQuery("kind", "")
+	today := attendance.Day(time.Now())
+
+	tasks, err := findChecklistTasks(s.db,
+		`t.company_id = $1 AND t.completed_at IS NULL AND t.due_date < $2 AND ($3 = '' OR t.assignee_role = $3) AND ($4 = '' OR t.kind = $4)`,
+		params.ID, today, role, kind)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	summary := models.OverdueChecklistSummary{
+		Total:      len(tasks),
+		ByRole:     make(map[string]int),
+		ByEmployee: make(map[string]int),
+	}
+
+	for _, task := range tasks {
+		summary.ByRole[task.AssigneeRole]++
+		summary.ByEmployee[task.EmployeeId]++
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"tasks": tasks, "summary": summary})
+}
+
+func (s *Server) canCompleteChecklistTask(ctx *gin.Context, task *models.ChecklistTaskResponse, userId string) bool {
+	allowed, err := hasEmployeeRole(s.db, task.CompanyId, task.EmployeeId, task.AssigneeRole, userId)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return false
+	}
+
+	if !allowed {
+		utils.ErrorResponse(ctx, fmt.Errorf("the task is assigned to the %s role", task.AssigneeRole), http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+// startChecklists creates the tasks of the company templates of the kind for
+// the employee, due relative to the base date. Tasks already created from
+// the same template task are skipped.
+func startChecklists(tx *sql.Tx, companyId string, employeeId string, kind string, base time.Time) ([]*models.ChecklistTaskResponse, error) {
+	query := `INSERT INTO checklist_tasks (company_id, employee_id, template_id, template_task_id, kind, title, description, assignee_role, due_date)
+	SELECT c.company_id, $2, c.id, t.id, c.kind, t.title, t.description, t.assignee_role, $4::date + t.due_offset_days
+	FROM checklist_templates c
+	JOIN checklist_template_tasks t ON t.template_id = c.id
+	WHERE c.company_id = $1 AND c.kind = $3
+	ON CONFLICT (employee_id, template_task_id) DO NOTHING`
+
+	if _, err := tx.Exec(query, companyId, employeeId, kind, base); err != nil {
+		return nil, err
+	}
+
+	return findChecklistTasks(tx, `t.employee_id = $1 AND t.kind = $2`, employeeId, kind)
+}
+
+func insertChecklistTemplateTasks(tx *sql.Tx, templateId string, tasks []models.ChecklistTemplateTaskBody) ([]*models.ChecklistTemplateTaskResponse, error) {
+	query := `INSERT INTO checklist_template_tasks (template_id, position, title, description, assignee_role, due_offset_days)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id`
+
+	inserted := make([]*models.ChecklistTemplateTaskResponse, 0, len(tasks))
+
+	for i, task := range tasks {
+		created := &models.ChecklistTemplateTaskResponse{
+			Position:      i + 1,
+			Title:         task.Title,
+			Description:   task.Description,
+			AssigneeRole:  task.AssigneeRole,
+			DueOffsetDays: task.DueOffsetDays,
+		}
+
+		err := tx.QueryRow(query, templateId, created.Position, task.Title, task.Description, task.AssigneeRole, task.DueOffsetDays).Scan(&created.ID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		inserted = append(inserted, created)
+	}
+
+	return inserted, nil
+}
+
+// findChecklistTemplateTasks returns the tasks of the templates by template
+func findChecklistTemplateTasks(db queryer, templateIds []string) (map[string][]*models.ChecklistTemplateTaskResponse, error) {
+	rows, err := db.Query(`SELECT template_id, id, position, title, description, assignee_role, due_offset_days
+	FROM checklist_template_tasks
+	WHERE template_id = ANY($1)
+	ORDER BY position`, pq.Array(templateIds))
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := make(map[string][]*models.ChecklistTemplateTaskResponse)
+
+	for rows.Next() {
+		var templateId string
+		task := new(models.ChecklistTemplateTaskResponse)
+
+		err := rows.Scan(&templateId, &task.ID, &task.Position, &task.Title, &task.Description, &task.AssigneeRole, &task.DueOffsetDays)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tasks[templateId] = append(tasks[templateId], task)
+	}
+
+	return tasks, rows.Err()
+}
+
+func findChecklistTask(db queryer, id string) (*models.ChecklistTaskResponse, error) {
+	return scanRowIntoChecklistTask(db.QueryRow(`SELECT `+checklistTaskColumns+`
+	FROM checklist_tasks t
+	JOIN employees e ON e.id = t.employee_id
+	WHERE t.id = $1`, id))
+}
+
+func findChecklistTasks(db queryer, where string, args ...any) ([]*models.ChecklistTaskResponse, error) {
+	query := `SELECT ` + checklistTaskColumns + `
+	FROM checklist_tasks t
+	JOIN employees e ON e.id = t.employee_id
+	WHERE ` + where + `
+	ORDER BY t.due_date, t.created_at`
+
+	rows, err := db.Query(query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := make([]*models.ChecklistTaskResponse, 0)
+
+	for rows.Next() {
+		task, err := scanRowIntoChecklistTask(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tasks = append(tasks, task)
+	}
+
+	return tasks, rows.Err()
+}
+
+func checklistTemplateErrorResponse(ctx *gin.Context, err error, id string) {
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(ctx, fmt.Errorf("checklist template %s not found", id), http.StatusNotFound)
+		return
+	}
+	utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+}
+
+func checklistTaskErrorResponse(ctx *gin.Context, err error, id string) {
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(ctx, fmt.Errorf("checklist task %s not found", id), http.StatusNotFound)
+		return
+	}
+	utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+}
+
+func scanRowIntoChecklistTemplate(row rowScanner) (*models.ChecklistTemplateResponse, error) {
+	template := new(models.ChecklistTemplateResponse)
+
+	err := row.Scan(
+		&template.ID,
+		&template.CompanyId,
+		&template.Name,
+		&template.Kind,
+		&template.CreatedAt,
+		&template.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	template.Tasks = make([]*models.ChecklistTemplateTaskResponse, 0)
+
+	return template, nil
+}
+
+func scanRowIntoChecklistTask(row rowScanner) (*models.ChecklistTaskResponse, error) {
+	task := new(models.ChecklistTaskResponse)
+
+	err := row.Scan(
+		&task.ID,
+		&task.CompanyId,
+		&task.EmployeeId,
+		&task.EmployeeName,
+		&task.TemplateId,
+		&task.Kind,
+		&task.Title,
+		&task.Description,
+		&task.AssigneeRole,
+		&task.DueDate,
+		&task.CompletedAt,
+		&task.CompletedBy,
+		&task.Notes,
+		&task.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
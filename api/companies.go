@@ -1,6 +1,8 @@
 package api
 
 import (
+	"database/sql"
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -8,6 +10,8 @@ import (
 	"github.com/gioCuesta25/employees-manager-backend/utils"
 )
 
+const companyColumns = `id, name, owner, address, phone, email, require_if_match, version, created_at, updated_at`
+
 func (s *Server) createCompany(ctx *gin.Context) {
 	var body models.CreateCompanyBody
 
@@ -17,20 +21,13 @@ func (s *Server) createCompany(ctx *gin.Context) {
 	}
 
 	query := `INSERT INTO companies
-			(name, owner, address, phone, email) 
-			VALUES ($1, $2, $3, $4, $5)
-			RETURNING id, name, owner, address, phone, email, created_at, updated_at`
-
-	row := s.db.QueryRow(query, body.Name, body.Owner, body.Address, body.Phone, body.Email)
-	var newCompany models.CompanyResponse
-	err := row.Scan(&newCompany.ID,
-		&newCompany.Name,
-		&newCompany.Owner,
-		&newCompany.Address,
-		&newCompany.Phone,
-		&newCompany.Email,
-		&newCompany.CreatedAt,
-		&newCompany.UpdatedAt)
+			(name, owner, address, phone, email, require_if_match) 
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING ` + companyColumns
+
+	// The company belongs to whoever creates it
+	row := s.db.QueryRow(query, body.Name, ctx.GetString("userId"), body.Address, body.Phone, body.Email, body.RequireIfMatch)
+	newCompany, err := scanRowIntoCompany(row)
 
 	if err != nil {
 		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
@@ -48,25 +45,19 @@ func (s *Server) getCompany(ctx *gin.Context) {
 		return
 	}
 
-	query := "SELECT id, name, owner, address, phone, email, created_at, updated_at FROM companies WHERE id = $1"
-	row := s.db.QueryRow(query, params.ID)
-
-	var company models.CompanyResponse
-	err := row.Scan(&company.ID,
-		&company.Name,
-		&company.Owner,
-		&company.Address,
-		&company.Phone,
-		&company.Email,
-		&company.CreatedAt,
-		&company.UpdatedAt)
+	query := "SELECT " + companyColumns + " FROM companies WHERE id = $1"
+	company, err := scanRowIntoCompany(s.db.QueryRow(query, params.ID))
 
 	if err != nil {
-		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		companyErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if notModified(ctx, company.Version) {
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, gin.H{"company": company})
+	ctx.JSON(http.StatusOK, gin.H{"company": company})
 }
 
 func (s *Server) deleteCompany(ctx *gin.Context) {
@@ -77,14 +68,22 @@ func (s *Server) deleteCompany(ctx *gin.Context) {
 		return
 	}
 
-	query := "DELETE FROM companies WHERE id = $1"
-	_, err := s.db.Query(query, params.ID)
+	if !s.requireCompanyRole(ctx, params.ID, models.RoleOwner) {
+		return
+	}
+
+	query := "DELETE FROM companies WHERE id = $1 AND ($2 = 0 OR version = $2)"
+	result, err := s.db.Exec(query, params.ID, matchedVersion(ctx))
 
 	if err != nil {
 		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
 		return
 	}
 
+	if deleted, _ := result.RowsAffected(); deleted == 0 && versionMismatch(ctx, sql.ErrNoRows) {
+		return
+	}
+
 	ctx.JSON(http.StatusCreated, gin.H{"message": "Company successfully deleted"})
 }
 
@@ -97,36 +96,89 @@ func (s *Server) updateCompany(ctx *gin.Context) {
 		return
 	}
 
-	if err := ctx.ShouldBindJSON(&body); err != nil {
+	if !s.requireCompanyRole(ctx, params.ID, models.RoleOwner) {
+		return
+	}
+
+	query := "SELECT " + companyColumns + " FROM companies WHERE id = $1"
+	current, err := scanRowIntoCompany(s.db.QueryRow(query, params.ID))
+
+	if err != nil {
+		companyErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	changed, ok := bindMergePatch(ctx, models.CreateCompanyBody{
+		Name:           current.Name,
+		Address:        current.Address,
+		Phone:          current.Phone,
+		Email:          current.Email,
+		RequireIfMatch: current.RequireIfMatch,
+	}, &body)
+
+	if !ok {
+		return
+	}
+
+	if len(changed) == 0 {
+		ctx.Header("ETag", etag(current.Version))
+		ctx.JSON(http.StatusCreated, gin.H{"company": current})
+		return
+	}
+
+	query, args, err := patchQuery("companies", params.ID, matchedVersion(ctx), map[string]any{
+		"name":             body.Name,
+		"address":          body.Address,
+		"phone":            body.Phone,
+		"email":            body.Email,
+		"require_if_match": body.RequireIfMatch,
+	}, changed, companyColumns)
+
+	if err != nil {
 		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
 		return
 	}
 
-	query := `UPDATE companies
-			name = $1,
-			owner = $2,
-			address = $3,
-			phone = $4,
-			email = $5
-			WHERE id = $6
-			RETURNING id, name, owner, address, phone, email, created_at, updated_at`
+	newCompany, err := scanRowIntoCompany(s.db.QueryRow(query, args...))
 
-	row := s.db.QueryRow(query, body.Name, body.Owner, body.Address, body.Phone, body.Email, params.ID)
-	var newCompany models.CompanyResponse
-	err := row.Scan(&newCompany.ID,
-		&newCompany.Name,
-		&newCompany.Owner,
-		&newCompany.Address,
-		&newCompany.Phone,
-		&newCompany.Email,
-		&newCompany.CreatedAt,
-		&newCompany.UpdatedAt)
+	if versionMismatch(ctx, err) {
+		return
+	}
 
 	if err != nil {
-		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		companyErrorResponse(ctx, err, params.ID)
 		return
 	}
 
+	ctx.Header("ETag", etag(newCompany.Version))
 	ctx.JSON(http.StatusCreated, gin.H{"company": newCompany})
+}
+
+func companyErrorResponse(ctx *gin.Context, err error, id string) {
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(ctx, fmt.Errorf("company %s not found", id), http.StatusNotFound)
+		return
+	}
+	utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+}
+
+func scanRowIntoCompany(row rowScanner) (*models.CompanyResponse, error) {
+	company := new(models.CompanyResponse)
+
+	err := row.Scan(&company.ID,
+		&company.Name,
+		&company.Owner,
+		&company.Address,
+		&company.Phone,
+		&company.Email,
+		&company.RequireIfMatch,
+		&company.Version,
+		&company.CreatedAt,
+		&company.UpdatedAt)
+
+	if err != nil {
+		return nil, err
+	}
 
+	return company, nil
 }
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/models"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
+	"github.com/lib/pq"
+)
+
+func (s *Server) addCompanyMember(ctx *gin.Context) {
+	var params models.GetCompanyParams
+	var body models.AddCompanyMemberBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if !s.requireCompanyRole(ctx, params.ID, models.RoleOwner) {
+		return
+	}
+
+	query := `INSERT INTO company_members (company_id, user_id, role)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (company_id, user_id) DO UPDATE SET role = EXCLUDED.role, updated_at = now()
+	RETURNING id`
+
+	var id string
+
+	if err := s.db.QueryRow(query, params.ID, body.UserId, body.Role).Scan(&id); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	member, err := scanRowIntoCompanyMember(s.db.QueryRow(companyMembersQuery+` WHERE m.id = $1`, id))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"member": member})
+}
+
+func (s *Server) listCompanyMembers(ctx *gin.Context) {
+	var params models.GetCompanyParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	rows, err := s.db.Query(companyMembersQuery+` WHERE m.company_id = $1 ORDER BY u.full_name`, params.ID)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	members := make([]*models.CompanyMemberResponse, 0)
+
+	for rows.Next() {
+		member, err := scanRowIntoCompanyMember(rows)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		members = append(members, member)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"members": members})
+}
+
+func (s *Server) removeCompanyMember(ctx *gin.Context) {
+	var params models.GetCompanyMemberParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if !s.requireCompanyRole(ctx, params.ID, models.RoleOwner) {
+		return
+	}
+
+	_, err := s.db.Exec(`DELETE FROM company_members WHERE company_id = $1 AND user_id = $2`, params.ID, params.UserId)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// hasCompanyRole reports whether the user is the owner of the company or a
+// member with one of the given roles.
+func hasCompanyRole(db queryer, companyId string, userId string, roles ...string) (bool, error) {
+	query := `SELECT EXISTS (
+		SELECT 1 FROM companies WHERE id = $1 AND owner::text = $2
+		UNION ALL
+		SELECT 1 FROM company_members WHERE company_id = $1 AND user_id::text = $2 AND role = ANY($3)
+	)`
+
+	var allowed bool
+	err := db.QueryRow(query, companyId, userId, pq.Array(roles)).Scan(&allowed)
+
+	return allowed, err
+}
+
+const companyMembersQuery = `SELECT m.id, m.company_id, m.user_id, u.full_name, u.email, m.role, m.created_at, m.updated_at
+	FROM company_members m
+	JOIN users u ON u.id = m.user_id`
+
+func scanRowIntoCompanyMember(row rowScanner) (*models.CompanyMemberResponse, error) {
+	member := new(models.CompanyMemberResponse)
+
+	err := row.Scan(
+		&member.ID,
+		&member.CompanyId,
+		&member.UserId,
+		&member.FullName,
+		&member.Email,
+		&member.Role,
+		&member.CreatedAt,
+		&member.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
@@ -0,0 +1,897 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/attendance"
+	"github.com/gioCuesta25/employees-manager-backend/contract"
+	"github.com/gioCuesta25/employees-manager-backend/models"
+	"github.com/gioCuesta25/employees-manager-backend/pdf"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
+)
+
+const contractColumns = `id, company_id, employee_id, type, start_date, end_date, salary, weekly_hours, probation_days, status,
+	previous_contract_id, renewal_number, notes, terminated_at, termination_reason, created_by, created_at, updated_at`
+
+const contractTemplateColumns = `id, company_id, name, contract_type, body, created_at, updated_at`
+
+var contractTypeLabels = map[string]string{
+	contract.TypeIndefinite:     "Término indefinido",
+	contract.TypeFixedTerm:      "Término fijo",
+	contract.TypeWorkOrLabor:    "Obra o labor",
+	contract.TypeApprenticeship: "Aprendizaje",
+	contract.TypeService:        "Prestación de servicios",
+}
+
+// createContract registers the contract an employee works under. An
+// employee has a single active contract, and its salary becomes the
+// employee salary used by payroll.
+func (s *Server) createContract(ctx *gin.Context) {
+	var body models.CreateContractBody
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	startDate := calendarDate(body.StartDate)
+	var endDate *time.Time
+
+	if body.EndDate != nil {
+		date := calendarDate(*body.EndDate)
+		endDate = &date
+	}
+
+	terms := contract.Terms{Type: body.Type, StartDate: startDate, EndDate: endDate, ProbationDays: body.ProbationDays}
+
+	if err := contract.Validate(terms); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var companyId string
+
+	if err := tx.QueryRow(`SELECT company_id FROM employees WHERE id = $1 FOR UPDATE`, body.EmployeeId).Scan(&companyId); err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(ctx, fmt.Errorf("employee %s not found", body.EmployeeId), http.StatusNotFound)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if !s.requireCompanyRole(ctx, companyId, models.RoleOwner, models.RoleHR) {
+		return
+	}
+
+	query := `INSERT INTO contracts
+	(company_id, employee_id, type, start_date, end_date, salary, weekly_hours, probation_days, notes, created_by)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	RETURNING ` + contractColumns
+
+	created, err := scanRowIntoContract(tx.QueryRow(query,
+		companyId,
+		body.EmployeeId,
+		body.Type,
+		startDate,
+		endDate,
+		body.Salary,
+		body.WeeklyHours,
+		body.ProbationDays,
+		body.Notes,
+		ctx.GetString("userId")))
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			utils.ErrorResponse(ctx, fmt.Errorf("employee %s already has an active contract", body.EmployeeId), http.StatusConflict)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := tx.Exec(`UPDATE employees SET salary = $1, updated_at = $2 WHERE id = $3`, body.Salary, time.Now(), body.EmployeeId); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"contract": created})
+}
+
+// listContracts lists the contracts of a company or of an employee. Only the
+// contracts of the company, or else of the company of the employee, are
+// listed.
+func (s *Server) listContracts(ctx *gin.Context) {
+	companyId := ctx.DefaultQuery("company_id", "")
+	employeeId := ctx.DefaultQuery("employee_id", "")
+	status := ctx.DefaultQuery("status", "")
+
+	if companyId == "" && employeeId == "" {
+		utils.ErrorResponse(ctx, fmt.Errorf("company_id or employee_id is required"), http.StatusBadRequest)
+		return
+	}
+
+	if companyId == "" {
+		var ok bool
+
+		if companyId, ok = s.requireEmployeeCompanyRole(ctx, employeeId, models.RoleOwner, models.RoleHR); !ok {
+			return
+		}
+	} else if !s.requireCompanyRole(ctx, companyId, models.RoleOwner, models.RoleHR) {
+		return
+	}
+
+	pageNumber, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("size", "10"))
+
+	offset := (pageNumber - 1) * pageSize
+
+	filter := `WHERE company_id::text = $1 AND ($2 = '' OR employee_id::text = $2) AND ($3 = '' OR status = $3)`
+
+	query := `SELECT ` + contractColumns + ` FROM contracts ` + filter + `
+	ORDER BY start_date DESC
+	LIMIT $4
+	OFFSET $5`
+
+	rows, err := s.db.Query(query, companyId, employeeId, status, pageSize, offset)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var totalItems int
+	err = s.db.QueryRow(`SELECT COUNT(*) FROM contracts `+filter, companyId, employeeId, status).Scan(&totalItems)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	totalPages := int(math.Ceil(float64(totalItems) / float64(pageSize)))
+	var nextPage, prevPage *int
+
+	if pageNumber < totalPages {
+		nextPageNum := pageNumber + 1
+		nextPage = &nextPageNum
+	}
+
+	if pageNumber > 1 {
+		prevPageNum := pageNumber - 1
+		prevPage = &prevPageNum
+	}
+
+	contracts := make([]*models.ContractResponse, 0)
+
+	for rows.Next() {
+		c, err := scanRowIntoContract(rows)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		contracts = append(contracts, c)
+	}
+
+	result := models.PaginatedResult{
+		Data:       contracts,
+		PageNumber: pageNumber,
+		PageSize:   pageSize,
+		TotalItems: totalItems,
+		NextPage:   nextPage,
+		PrevPage:   prevPage,
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// getContract returns the contract with its renewal history, from the
+// original contract to the one before it.
+func (s *Server) getContract(ctx *gin.Context) {
+	var params models.GetContractParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	c, err := findContract(s.db, params.ID)
+
+	if err != nil {
+		contractErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if !s.requireCompanyRole(ctx, c.CompanyId, models.RoleOwner, models.RoleHR) {
+		return
+	}
+
+	query := `WITH RECURSIVE history AS (
+		SELECT * FROM contracts WHERE id = $1
+		UNION ALL
+		SELECT c.* FROM contracts c JOIN history h ON c.id = h.previous_contract_id
+	)
+	SELECT ` + contractColumns + ` FROM history WHERE id <> $1 ORDER BY renewal_number`
+
+	rows, err := s.db.Query(query, params.ID)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	history := make([]*models.ContractResponse, 0)
+
+	for rows.Next() {
+		previous, err := scanRowIntoContract(rows)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		history = append(history, previous)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"contract": c, "history": history})
+}
+
+func (s *Server) renewContract(ctx *gin.Context) {
+	var params models.GetContractParams
+	var body models.RenewContractBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	// The body is optional, an empty one renews with the same terms
+	if err := ctx.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if !s.requireContractRole(ctx, params.ID) {
+		return
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	current, err := scanRowIntoContract(tx.QueryRow(`SELECT `+contractColumns+` FROM contracts WHERE id = $1 FOR UPDATE`, params.ID))
+
+	if err != nil {
+		contractErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	renewed, err := renewContractTerm(tx, current, body, ctx.GetString("userId"))
+
+	if err != nil {
+		var invalid invalidTermsError
+
+		switch {
+		case err == errContractNotRenewable:
+			utils.ErrorResponse(ctx, err, http.StatusConflict)
+		case errors.As(err, &invalid):
+			utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		default:
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"contract": renewed})
+}
+
+func (s *Server) terminateContract(ctx *gin.Context) {
+	var params models.GetContractParams
+	var body models.TerminateContractBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if !s.requireContractRole(ctx, params.ID) {
+		return
+	}
+
+	query := `UPDATE contracts
+	SET status = $1, terminated_at = $2, termination_reason = $3, updated_at = $4
+	WHERE id = $5 AND status = $6
+	RETURNING ` + contractColumns
+
+	terminated, err := scanRowIntoContract(s.db.QueryRow(query,
+		models.ContractStatusTerminated,
+		calendarDate(body.Date),
+		body.Reason,
+		time.Now(),
+		params.ID,
+		models.ContractStatusActive))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(ctx, fmt.Errorf("contract %s not found or not active", params.ID), http.StatusConflict)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"contract": terminated})
+}
+
+// getContractDocument renders the contract with a company template as PDF.
+// Without template_id it uses the template for the contract type, which
+// must be unique.
+func (s *Server) getContractDocument(ctx *gin.Context) {
+	var params models.GetContractParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	c, err := findContract(s.db, params.ID)
+
+	if err != nil {
+		contractErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if !s.requireCompanyRole(ctx, c.CompanyId, models.RoleOwner, models.RoleHR) {
+		return
+	}
+
+	templateId := ctx.DefaultQuery("template_id", "")
+
+	query := `SELECT ` + contractTemplateColumns + ` FROM contract_templates
+	WHERE company_id = $1 AND (id::text = $2 OR ($2 = '' AND (contract_type IS NULL OR contract_type = $3)))
+	ORDER BY contract_type NULLS LAST
+	LIMIT 2`
+
+	rows, err := s.db.Query(query, c.CompanyId, templateId, c.Type)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	templates := make([]*models.ContractTemplateResponse, 0)
+
+	for rows.Next() {
+		template, err := scanRowIntoContractTemplate(rows)
+
+		if err != nil {
+			rows.Close()
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		templates = append(templates, template)
+	}
+	rows.Close()
+
+	if len(templates) == 0 {
+		utils.ErrorResponse(ctx, fmt.Errorf("no contract template found for the contract"), http.StatusNotFound)
+		return
+	}
+
+	// A template for the type is preferred over a generic one, but two of
+	// the same kind are ambiguous
+	if templateId == "" && len(templates) == 2 && (templates[0].ContractType == nil) == (templates[1].ContractType == nil) {
+		utils.ErrorResponse(ctx, fmt.Errorf("several templates match the contract, choose one with template_id"), http.StatusConflict)
+		return
+	}
+
+	fields, err := contractFields(s.db, c)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	text, err := contract.Render(templates[0].Body, fields)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusUnprocessableEntity)
+		return
+	}
+
+	doc := renderContract(templates[0].Name, text)
+
+	ctx.Header("Content-Disposition", fmt.Sprintf(`inline; filename="contrato-%s.pdf"`, c.ID))
+	ctx.Data(http.StatusOK, "application/pdf", doc.Bytes())
+}
+
+func (s *Server) createContractTemplate(ctx *gin.Context) {
+	var body models.CreateContractTemplateBody
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := contract.Check(body.Body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if !s.requireCompanyRole(ctx, body.CompanyId, models.RoleOwner, models.RoleHR) {
+		return
+	}
+
+	query := `INSERT INTO contract_templates (company_id, name, contract_type, body)
+	VALUES ($1, $2, $3, $4)
+	RETURNING ` + contractTemplateColumns
+
+	template, err := scanRowIntoContractTemplate(s.db.QueryRow(query, body.CompanyId, body.Name, body.ContractType, body.Body))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"contract_template": template})
+}
+
+func (s *Server) listContractTemplates(ctx *gin.Context) {
+	companyId := ctx.DefaultQuery("company_id", "")
+
+	if companyId == "" {
+		utils.ErrorResponse(ctx, fmt.Errorf("company_id is required"), http.StatusBadRequest)
+		return
+	}
+
+	if !s.requireCompanyRole(ctx, companyId, models.RoleOwner, models.RoleHR) {
+		return
+	}
+
+	rows, err := s.db.Query(`SELECT `+contractTemplateColumns+` FROM contract_templates WHERE company_id = $1 ORDER BY name`, companyId)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	templates := make([]*models.ContractTemplateResponse, 0)
+
+	for rows.Next() {
+		template, err := scanRowIntoContractTemplate(rows)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		templates = append(templates, template)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"contract_templates": templates, "placeholders": contract.Placeholders})
+}
+
+func (s *Server) updateContractTemplate(ctx *gin.Context) {
+	var params models.GetContractTemplateParams
+	var body models.CreateContractTemplateBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := contract.Check(body.Body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if !s.requireContractTemplateRole(ctx, params.ID) {
+		return
+	}
+
+	query := `UPDATE contract_templates
+	SET name = $1, contract_type = $2, body = $3, updated_at = $4
+	WHERE id = $5
+	RETURNING ` + contractTemplateColumns
+
+	template, err := scanRowIntoContractTemplate(s.db.QueryRow(query, body.Name, body.ContractType, body.Body, time.Now(), params.ID))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(ctx, fmt.Errorf("contract template %s not found", params.ID), http.StatusNotFound)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"contract_template": template})
+}
+
+func (s *Server) deleteContractTemplate(ctx *gin.Context) {
+	var params models.GetContractTemplateParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if !s.requireContractTemplateRole(ctx, params.ID) {
+		return
+	}
+
+	if _, err := s.db.Exec(`DELETE FROM contract_templates WHERE id = $1`, params.ID); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// renewExpiredContracts applies the end of the contracts whose end date
+// passed. Fixed-term contracts that were neither renewed nor terminated
+// renew automatically for the same term, as the law says; the other
+// contracts with an end date just end.
+func (s *Server) renewExpiredContracts(ctx context.Context) error {
+	today := attendance.Day(time.Now())
+
+	rows, err := s.db.Query(`SELECT id FROM contracts WHERE status = $1 AND end_date < $2`, models.ContractStatusActive, today)
+
+	if err != nil {
+		return err
+	}
+
+	var ids []string
+
+	for rows.Next() {
+		var id string
+
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err := s.closeExpiredContract(id, today); err != nil {
+			log.Printf("closing expired contract %s: %v", id, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) closeExpiredContract(id string, today time.Time) error {
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := scanRowIntoContract(tx.QueryRow(`SELECT `+contractColumns+` FROM contracts WHERE id = $1 FOR UPDATE`, id))
+
+	if err != nil {
+		return err
+	}
+
+	// Another instance of the job got there first
+	if current.Status != models.ContractStatusActive || current.EndDate == nil || !current.EndDate.Before(today) {
+		return nil
+	}
+
+	if current.Type == contract.TypeFixedTerm {
+		// A contract left unattended for several terms renews once per term
+		for current.EndDate.Before(today) {
+			current, err = renewContractTerm(tx, current, models.RenewContractBody{}, current.CreatedBy)
+
+			if err != nil {
+				return err
+			}
+		}
+	} else {
+		_, err := tx.Exec(`UPDATE contracts SET status = $1, updated_at = $2 WHERE id = $3`, models.ContractStatusEnded, time.Now(), id)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+var errContractNotRenewable = fmt.Errorf("only active fixed-term contracts can be renewed")
+
+// invalidTermsError is returned when the renewal breaks the contract rules
+type invalidTermsError struct {
+	error
+}
+
+// renewContractTerm closes the contract as renewed and creates the contract
+// for the next term, linked to it.
+func renewContractTerm(tx *sql.Tx, current *models.ContractResponse, body models.RenewContractBody, userId string) (*models.ContractResponse, error) {
+	if current.Status != models.ContractStatusActive || current.Type != contract.TypeFixedTerm || current.EndDate == nil {
+		return nil, errContractNotRenewable
+	}
+
+	var requested *time.Time
+
+	if body.EndDate != nil {
+		date := calendarDate(*body.EndDate)
+		requested = &date
+	}
+
+	newEnd, err := contract.RenewalEnd(current.StartDate, *current.EndDate, current.RenewalNumber, requested)
+
+	if err != nil {
+		return nil, invalidTermsError{err}
+	}
+
+	newStart := current.EndDate.AddDate(0, 0, 1)
+	terms := contract.Terms{Type: current.Type, StartDate: newStart, EndDate: &newEnd}
+
+	if err := contract.Validate(terms); err != nil {
+		return nil, invalidTermsError{err}
+	}
+
+	salary := current.Salary
+	if body.Salary != nil {
+		salary = *body.Salary
+	}
+
+	_, err = tx.Exec(`UPDATE contracts SET status = $1, updated_at = $2 WHERE id = $3`, models.ContractStatusRenewed, time.Now(), current.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Renewals don't have a new probation period
+	query := `INSERT INTO contracts
+	(company_id, employee_id, type, start_date, end_date, salary, weekly_hours, probation_days, previous_contract_id, renewal_number, notes, created_by)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, 0, $8, $9, $10, $11)
+	RETURNING ` + contractColumns
+
+	renewed, err := scanRowIntoContract(tx.QueryRow(query,
+		current.CompanyId,
+		current.EmployeeId,
+		current.Type,
+		newStart,
+		newEnd,
+		salary,
+		current.WeeklyHours,
+		current.ID,
+		current.RenewalNumber+1,
+		current.Notes,
+		userId))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if salary != current.Salary {
+		if _, err := tx.Exec(`UPDATE employees SET salary = $1, updated_at = $2 WHERE id = $3`, salary, time.Now(), current.EmployeeId); err != nil {
+			return nil, err
+		}
+	}
+
+	return renewed, nil
+}
+
+// contractFields are the values of the template placeholders
+func contractFields(db queryer, c *models.ContractResponse) (map[string]string, error) {
+	var companyName, nit, name, lastName, idNumber, email, position, department string
+
+	query := `SELECT co.name, COALESCE(co.nit, ''), e.name, e.last_name, e.id_number, e.email, COALESCE(p.name, ''), COALESCE(d.name, '')
+	FROM employees e
+	JOIN companies co ON co.id = e.company_id
+	LEFT JOIN positions p ON p.id = e.position_id
+	LEFT JOIN departments d ON d.id = e.department_id
+	WHERE e.id = $1`
+
+	err := db.QueryRow(query, c.EmployeeId).Scan(&companyName, &nit, &name, &lastName, &idNumber, &email, &position, &department)
+
+	if err != nil {
+		return nil, err
+	}
+
+	endDate := ""
+	if c.EndDate != nil {
+		endDate = c.EndDate.Format("2006-01-02")
+	}
+
+	return map[string]string{
+		"company.name":            companyName,
+		"company.nit":             nit,
+		"employee.name":           name,
+		"employee.last_name":      lastName,
+		"employee.full_name":      name + " " + lastName,
+		"employee.id_number":      idNumber,
+		"employee.email":          email,
+		"employee.position":       position,
+		"employee.department":     department,
+		"contract.type":           contractTypeLabels[c.Type],
+		"contract.start_date":     c.StartDate.Format("2006-01-02"),
+		"contract.end_date":       endDate,
+		"contract.salary":         formatMoney(c.Salary),
+		"contract.weekly_hours":   strconv.FormatFloat(c.WeeklyHours, 'f', -1, 64),
+		"contract.probation_days": strconv.Itoa(c.ProbationDays),
+		"today":                   attendance.Day(time.Now()).Format("2006-01-02"),
+	}, nil
+}
+
+func renderContract(title string, text string) *pdf.Document {
+	const margin = 60.0
+	const size = 10.5
+	const leading = 15.0
+
+	doc := pdf.New()
+	page := doc.AddPage()
+
+	page.Text(margin, 70, pdf.FontBold, 14, title)
+	y := 100.0
+
+	for _, line := range pdf.Wrap(pdf.FontRegular, size, text, pdf.PageWidth-2*margin) {
+		if y > pdf.PageHeight-margin {
+			page = doc.AddPage()
+			y = margin
+		}
+
+		page.Text(margin, y, pdf.FontRegular, size, line)
+		y += leading
+	}
+
+	return doc
+}
+
+func findContract(db queryer, id string) (*models.ContractResponse, error) {
+	return scanRowIntoContract(db.QueryRow(`SELECT `+contractColumns+` FROM contracts WHERE id = $1`, id))
+}
+
+// requireContractRole responds with 404 when the contract doesn't exist and
+// with 403 unless the user is owner or HR of its company
+func (s *Server) requireContractRole(ctx *gin.Context, id string) bool {
+	c, err := findContract(s.db, id)
+
+	if err != nil {
+		contractErrorResponse(ctx, err, id)
+		return false
+	}
+
+	return s.requireCompanyRole(ctx, c.CompanyId, models.RoleOwner, models.RoleHR)
+}
+
+// requireContractTemplateRole is requireContractRole for templates
+func (s *Server) requireContractTemplateRole(ctx *gin.Context, id string) bool {
+	var companyId string
+
+	if err := s.db.QueryRow(`SELECT company_id FROM contract_templates WHERE id = $1`, id).Scan(&companyId); err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(ctx, fmt.Errorf("contract template %s not found", id), http.StatusNotFound)
+			return false
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return false
+	}
+
+	return s.requireCompanyRole(ctx, companyId, models.RoleOwner, models.RoleHR)
+}
+
+func contractErrorResponse(ctx *gin.Context, err error, id string) {
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(ctx, fmt.Errorf("contract %s not found", id), http.StatusNotFound)
+		return
+	}
+	utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+}
+
+func scanRowIntoContract(row rowScanner) (*models.ContractResponse, error) {
+	c := new(models.ContractResponse)
+
+	err := row.Scan(
+		&c.ID,
+		&c.CompanyId,
+		&c.EmployeeId,
+		&c.Type,
+		&c.StartDate,
+		&c.EndDate,
+		&c.Salary,
+		&c.WeeklyHours,
+		&c.ProbationDays,
+		&c.Status,
+		&c.PreviousContractId,
+		&c.RenewalNumber,
+		&c.Notes,
+		&c.TerminatedAt,
+		&c.TerminationReason,
+		&c.CreatedBy,
+		&c.CreatedAt,
+		&c.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	c.ProbationEnd = contract.ProbationEnd(c.StartDate, c.ProbationDays)
+
+	if c.Type == contract.TypeFixedTerm && c.EndDate != nil {
+		deadline := contract.NoticeDeadline(*c.EndDate)
+		c.NoticeDeadline = &deadline
+	}
+
+	return c, nil
+}
+
+func scanRowIntoContractTemplate(row rowScanner) (*models.ContractTemplateResponse, error) {
+	template := new(models.ContractTemplateResponse)
+
+	err := row.Scan(
+		&template.ID,
+		&template.CompanyId,
+		&template.Name,
+		&template.ContractType,
+		&template.Body,
+		&template.CreatedAt,
+		&template.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
@@ -0,0 +1,380 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/customfields"
+	"github.com/gioCuesta25/employees-manager-backend/models"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
+	"github.com/lib/pq"
+)
+
+const customFieldColumns = `id, company_id, entity, key, label, type, required, options, pattern, position, created_at, updated_at`
+
+// customFieldFilterPrefix marks the query parameters that filter listings
+// by a custom field, as in ?cf.blood_type=O%2B
+const customFieldFilterPrefix = "cf."
+
+var customFieldTables = map[string]string{
+	customfields.EntityEmployee:   "employees",
+	customfields.EntityDepartment: "departments",
+	customfields.EntityPosition:   "positions",
+}
+
+func (s *Server) createCustomField(ctx *gin.Context) {
+	var body models.CreateCustomFieldBody
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	field := customfields.Field{
+		Key:      body.Key,
+		Label:    body.Label,
+		Type:     body.Type,
+		Required: body.Required,
+		Options:  body.Options,
+		Pattern:  body.Pattern,
+	}
+
+	if err := customfields.ValidateDefinition(field); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	query := `INSERT INTO custom_fields (company_id, entity, key, label, type, required, options, pattern, position)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	RETURNING ` + customFieldColumns
+
+	customField, err := scanRowIntoCustomField(s.db.QueryRow(query,
+		body.CompanyId,
+		body.Entity,
+		body.Key,
+		body.Label,
+		body.Type,
+		body.Required,
+		pq.Array(nonNilStrings(body.Options)),
+		body.Pattern,
+		body.Position))
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			utils.ErrorResponse(ctx, fmt.Errorf("the %s custom field %s already exists", body.Entity, body.Key), http.StatusConflict)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"custom_field": customField})
+}
+
+func (s *Server) listCustomFields(ctx *gin.Context) {
+	companyId := ctx.DefaultQuery("company_id", "")
+
+	if companyId == "" {
+		utils.ErrorResponse(ctx, fmt.Errorf("company_id is required"), http.StatusBadRequest)
+		return
+	}
+
+	entity := ctx.DefaultQuery("entity", "")
+
+	query := `SELECT ` + customFieldColumns + ` FROM custom_fields
+	WHERE company_id = $1 AND ($2 = '' OR entity = $2)
+	ORDER BY entity, position, key`
+
+	rows, err := s.db.Query(query, companyId, entity)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	customFields := make([]*models.CustomFieldResponse, 0)
+
+	for rows.Next() {
+		customField, err := scanRowIntoCustomField(rows)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		customFields = append(customFields, customField)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"custom_fields": customFields})
+}
+
+// updateCustomField changes the definition. The stored values are checked
+// against it the next time the record is saved.
+func (s *Server) updateCustomField(ctx *gin.Context) {
+	var params models.GetCustomFieldParams
+	var body models.UpdateCustomFieldBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	current, err := scanRowIntoCustomField(s.db.QueryRow(`SELECT `+customFieldColumns+` FROM custom_fields WHERE id = $1`, params.ID))
+
+	if err != nil {
+		customFieldErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	field := customfields.Field{
+		Key:      current.Key,
+		Label:    body.Label,
+		Type:     current.Type,
+		Required: body.Required,
+		Options:  body.Options,
+		Pattern:  body.Pattern,
+	}
+
+	if err := customfields.ValidateDefinition(field); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	query := `UPDATE custom_fields
+	SET label = $1, required = $2, options = $3, pattern = $4, position = $5, updated_at = $6
+	WHERE id = $7
+	RETURNING ` + customFieldColumns
+
+	customField, err := scanRowIntoCustomField(s.db.QueryRow(query,
+		body.Label,
+		body.Required,
+		pq.Array(nonNilStrings(body.Options)),
+		body.Pattern,
+		body.Position,
+		time.Now(),
+		params.ID))
+
+	if err != nil {
+		customFieldErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"custom_field": customField})
+}
+
+// deleteCustomField removes the definition and its values from the records
+// of the company.
+func (s *Server) deleteCustomField(ctx *gin.Context) {
+	var params models.GetCustomFieldParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var companyId, entity, key string
+
+	err = tx.QueryRow(`DELETE FROM custom_fields WHERE id = $1 RETURNING company_id, entity, key`, params.ID).Scan(&companyId, &entity, &key)
+
+	if err != nil {
+		customFieldErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET custom_fields = custom_fields - $1 WHERE company_id = $2 AND custom_fields ? $1`, customFieldTables[entity])
+
+	if _, err := tx.Exec(query, key, companyId); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// bindCustomFields validates the values sent for a record of the entity and
+// returns them encoded for the custom_fields column.
+func (s *Server) bindCustomFields(ctx *gin.Context, companyId string, entity string, values map[string]any) ([]byte, bool) {
+	fields, err := loadCustomFields(s.db, companyId, entity)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return nil, false
+	}
+
+	normalized, err := customfields.Validate(fields, values)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return nil, false
+	}
+
+	encoded, err := json.Marshal(normalized)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return encoded, true
+}
+
+// customFieldFilter turns the cf.<key> query parameters into a JSON object
+// for a custom_fields @> condition. Without filters the object is empty and
+// matches every record.
+func (s *Server) customFieldFilter(ctx *gin.Context, companyId string, entity string) (string, bool) {
+	filter := make(map[string]any)
+	var fields map[string]customfields.Field
+
+	for name, values := range ctx.Request.URL.Query() {
+		if !strings.HasPrefix(name, customFieldFilterPrefix) {
+			continue
+		}
+
+		if fields == nil {
+			loaded, err := loadCustomFields(s.db, companyId, entity)
+
+			if err != nil {
+				utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+				return "", false
+			}
+
+			fields = make(map[string]customfields.Field, len(loaded))
+			for _, field := range loaded {
+				fields[field.Key] = field
+			}
+		}
+
+		key := strings.TrimPrefix(name, customFieldFilterPrefix)
+		field, ok := fields[key]
+
+		if !ok {
+			utils.ErrorResponse(ctx, fmt.Errorf("unknown custom field %s", key), http.StatusBadRequest)
+			return "", false
+		}
+
+		value, err := customfields.ParseFilter(field, values[0])
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+			return "", false
+		}
+
+		filter[key] = value
+	}
+
+	encoded, err := json.Marshal(filter)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return "", false
+	}
+
+	return string(encoded), true
+}
+
+func loadCustomFields(db queryer, companyId string, entity string) ([]customfields.Field, error) {
+	query := `SELECT key, label, type, required, options, pattern
+	FROM custom_fields
+	WHERE company_id = $1 AND entity = $2
+	ORDER BY position, key`
+
+	rows, err := db.Query(query, companyId, entity)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fields := make([]customfields.Field, 0)
+
+	for rows.Next() {
+		var field customfields.Field
+
+		if err := rows.Scan(&field.Key, &field.Label, &field.Type, &field.Required, pq.Array(&field.Options), &field.Pattern); err != nil {
+			return nil, err
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, rows.Err()
+}
+
+// decodeCustomFields reads a custom_fields column. The column always holds
+// an object, so a decoding error means the value was written by hand.
+func decodeCustomFields(raw []byte) (map[string]any, error) {
+	values := make(map[string]any)
+
+	if len(raw) == 0 {
+		return values, nil
+	}
+
+	err := json.Unmarshal(raw, &values)
+
+	return values, err
+}
+
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+
+	return values
+}
+
+func customFieldErrorResponse(ctx *gin.Context, err error, id string) {
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(ctx, fmt.Errorf("custom field %s not found", id), http.StatusNotFound)
+		return
+	}
+	utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+}
+
+func scanRowIntoCustomField(row rowScanner) (*models.CustomFieldResponse, error) {
+	customField := new(models.CustomFieldResponse)
+
+	err := row.Scan(
+		&customField.ID,
+		&customField.CompanyId,
+		&customField.Entity,
+		&customField.Key,
+		&customField.Label,
+		&customField.Type,
+		&customField.Required,
+		pq.Array(&customField.Options),
+		&customField.Pattern,
+		&customField.Position,
+		&customField.CreatedAt,
+		&customField.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return customField, nil
+}
@@ -4,14 +4,17 @@ import (
 	"database/sql"
 	"math"
 	"net/http"
+	"slices"
 	"strconv"
-	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/customfields"
 	"github.com/gioCuesta25/employees-manager-backend/models"
 	"github.com/gioCuesta25/employees-manager-backend/utils"
 )
 
+const departmentColumns = `id, name, company_id, manager_id, custom_fields, version, created_at, updated_at`
+
 func (s *Server) createDepartment(ctx *gin.Context) {
 	var body models.CreateDepartmentBody
 
@@ -20,20 +23,20 @@ func (s *Server) createDepartment(ctx *gin.Context) {
 		return
 	}
 
-	query := `INSERT INTO departments
-	(name, company_id)
-	VALUES ($1, $2)
-	RETURNING id, name, company_id, created_at, updated_at`
+	customFields, ok := s.bindCustomFields(ctx, body.CompanyId, customfields.EntityDepartment, body.CustomFields)
 
-	row := s.db.QueryRow(query, body.Name, body.CompanyId)
+	if !ok {
+		return
+	}
 
-	var department models.DepartmentsResponse
+	query := `INSERT INTO departments
+	(name, company_id, manager_id, custom_fields)
+	VALUES ($1, $2, $3, $4)
+	RETURNING ` + departmentColumns
 
-	err := row.Scan(&department.ID,
-		&department.Name,
-		&department.CompanyId,
-		&department.CreatedAt,
-		&department.UpdatedAt)
+	row := s.db.QueryRow(query, body.Name, body.CompanyId, body.ManagerId, customFields)
+
+	department, err := scanRowsIntoDepartment(row)
 
 	if err != nil {
 		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
@@ -46,8 +49,8 @@ func (s *Server) createDepartment(ctx *gin.Context) {
 func (s *Server) getDepartmentsByCompany(ctx *gin.Context) {
 	var params models.GetCompanyDepartmentsParams
 
-	pageNumber, _ := strconv.Atoi(ctx.DefaultQuery("size", "1"))
-	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page", "10"))
+	pageNumber, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("size", "10"))
 
 	offset := (pageNumber - 1) * pageSize
 
@@ -56,18 +59,26 @@ func (s *Server) getDepartmentsByCompany(ctx *gin.Context) {
 		return
 	}
 
-	query := `SELECT id, name, company_id, created_at, updated_at FROM departments WHERE id = $1 ORDER BY id LIMIT $2 OFFSET $3`
+	// Filters on custom fields come as cf.<key>=<value>
+	filter, ok := s.customFieldFilter(ctx, params.CompanyId, customfields.EntityDepartment)
 
-	rows, err := s.db.Query(query, params.CompanyId, pageSize, offset)
+	if !ok {
+		return
+	}
+
+	query := `SELECT ` + departmentColumns + ` FROM departments WHERE company_id = $1 AND custom_fields @> $4::jsonb ORDER BY id LIMIT $2 OFFSET $3`
+
+	rows, err := s.db.Query(query, params.CompanyId, pageSize, offset, filter)
 
 	if err != nil {
 		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
 		return
 	}
+	defer rows.Close()
 
-	totalItemsQuery := `SELECT COUNT(*) FROM departments WHERE company_id = $1`
+	totalItemsQuery := `SELECT COUNT(*) FROM departments WHERE company_id = $1 AND custom_fields @> $2::jsonb`
 	var totalItems int
-	err = s.db.QueryRow(totalItemsQuery, params.CompanyId).Scan(&totalItems)
+	err = s.db.QueryRow(totalItemsQuery, params.CompanyId, filter).Scan(&totalItems)
 
 	if err != nil {
 		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
@@ -111,40 +122,98 @@ func (s *Server) getDepartmentsByCompany(ctx *gin.Context) {
 
 }
 
-func (s *Server) updateDepartment(ctx *gin.Context) {
-	var body models.CreateDepartmentBody
+func (s *Server) getDepartment(ctx *gin.Context) {
 	var params models.GetDepartmentsParams
 
-	if err := ctx.ShouldBindJSON(&body); err != nil {
+	if err := ctx.ShouldBindUri(&params); err != nil {
 		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
 		return
 	}
 
+	query := `SELECT ` + departmentColumns + ` FROM departments WHERE id = $1`
+	department, err := scanRowsIntoDepartment(s.db.QueryRow(query, params.ID))
+
+	if err != nil {
+		departmentErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if notModified(ctx, department.Version) {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"department": department})
+}
+
+func (s *Server) updateDepartment(ctx *gin.Context) {
+	var body models.CreateDepartmentBody
+	var params models.GetDepartmentsParams
+
 	if err := ctx.ShouldBindUri(&params); err != nil {
 		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
 		return
 	}
 
-	query := `UPDATE departments
-	SET name = $1, updated_at = $2
-	WHERE id = $3
-	RETURNING id, name, company_id, created_at, updated_at`
+	query := `SELECT ` + departmentColumns + ` FROM departments WHERE id = $1`
 
-	row := s.db.QueryRow(query, body.Name, body.CompanyId, time.Now(), params.ID)
+	current, err := scanRowsIntoDepartment(s.db.QueryRow(query, params.ID))
 
-	var department models.DepartmentsResponse
+	if err != nil {
+		departmentErrorResponse(ctx, err, params.ID)
+		return
+	}
 
-	err := row.Scan(&department.ID,
-		&department.Name,
-		&department.CompanyId,
-		&department.CreatedAt,
-		&department.UpdatedAt)
+	changed, ok := bindMergePatch(ctx, models.CreateDepartmentBody{
+		Name:         current.Name,
+		CompanyId:    current.CompanyId,
+		ManagerId:    current.ManagerId,
+		CustomFields: current.CustomFields,
+	}, &body)
+
+	if !ok {
+		return
+	}
+
+	if len(changed) == 0 {
+		ctx.Header("ETag", etag(current.Version))
+		ctx.JSON(http.StatusCreated, gin.H{"department": current})
+		return
+	}
+
+	columns := map[string]any{
+		"name":       body.Name,
+		"manager_id": body.ManagerId,
+	}
+
+	if slices.Contains(changed, "custom_fields") {
+		customFields, ok := s.bindCustomFields(ctx, body.CompanyId, customfields.EntityDepartment, body.CustomFields)
+
+		if !ok {
+			return
+		}
+
+		columns["custom_fields"] = customFields
+	}
+
+	query, args, err := patchQuery("departments", params.ID, matchedVersion(ctx), columns, changed, departmentColumns)
 
 	if err != nil {
-		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	department, err := scanRowsIntoDepartment(s.db.QueryRow(query, args...))
+
+	if versionMismatch(ctx, err) {
+		return
+	}
+
+	if err != nil {
+		departmentErrorResponse(ctx, err, params.ID)
 		return
 	}
 
+	ctx.Header("ETag", etag(department.Version))
 	ctx.JSON(http.StatusCreated, gin.H{"department": department})
 }
 
@@ -156,26 +225,34 @@ func (s *Server) deleteDepartment(ctx *gin.Context) {
 		return
 	}
 
-	query := `DELETE FROM departments WHERE id = $1`
+	query := `DELETE FROM departments WHERE id = $1 AND ($2 = 0 OR version = $2)`
 
-	_, err := s.db.Query(query, params.ID)
+	result, err := s.db.Exec(query, params.ID, matchedVersion(ctx))
 
 	if err != nil {
 		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
 		return
 	}
 
+	if deleted, _ := result.RowsAffected(); deleted == 0 && versionMismatch(ctx, sql.ErrNoRows) {
+		return
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{"message": "Department deleted successfully"})
 
 }
 
-func scanRowsIntoDepartment(rows *sql.Rows) (*models.DepartmentsResponse, error) {
+func scanRowsIntoDepartment(row rowScanner) (*models.DepartmentsResponse, error) {
 	department := new(models.DepartmentsResponse)
+	var customFields []byte
 
-	err := rows.Scan(
+	err := row.Scan(
 		&department.ID,
 		&department.Name,
 		&department.CompanyId,
+		&department.ManagerId,
+		&customFields,
+		&department.Version,
 		&department.CreatedAt,
 		&department.UpdatedAt,
 	)
@@ -184,5 +261,11 @@ func scanRowsIntoDepartment(rows *sql.Rows) (*models.DepartmentsResponse, error)
 		return nil, err
 	}
 
+	department.CustomFields, err = decodeCustomFields(customFields)
+
+	if err != nil {
+		return nil, err
+	}
+
 	return department, nil
 }
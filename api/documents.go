@@ -0,0 +1,407 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/models"
+	"github.com/gioCuesta25/employees-manager-backend/storage"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
+)
+
+const documentColumns = `id, company_id, employee_id, category, name, content_type, size, sha256, expires_at, uploaded_by, created_at`
+
+const defaultDocumentMaxBytes = 10 << 20
+
+// allowedDocumentTypes are the sniffed content types accepted on upload
+var allowedDocumentTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/webp":      true,
+}
+
+// uploadDocument stores a file of the employee. The content type is sniffed
+// from the bytes instead of trusting the client, and files are stored once
+// per SHA-256 so the same scan uploaded for several employees takes space
+// only once.
+func (s *Server) uploadDocument(ctx *gin.Context) {
+	var params models.GetEmployeeParams
+	var body models.UploadDocumentBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	companyId, ok := s.requireEmployeeCompanyRole(ctx, params.ID, models.RoleOwner, models.RoleHR)
+
+	if !ok {
+		return
+	}
+
+	maxBytes := s.documentMaxBytes()
+
+	// Leave room for the multipart boundaries and the other fields
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxBytes+64<<10)
+
+	if err := ctx.ShouldBind(&body); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.ErrorResponse(ctx, fmt.Errorf("the file can't be larger than %d bytes", maxBytes), http.StatusRequestEntityTooLarge)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	var expiresAt *time.Time
+
+	if body.ExpiresAt != "" {
+		date, err := time.Parse("2006-01-02", body.ExpiresAt)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, fmt.Errorf("expires_at must be a date as YYYY-MM-DD"), http.StatusBadRequest)
+			return
+		}
+
+		expiresAt = &date
+	}
+
+	fileHeader, err := ctx.FormFile("file")
+
+	if err != nil {
+		utils.ErrorResponse(ctx, fmt.Errorf("file is required"), http.StatusBadRequest)
+		return
+	}
+
+	if fileHeader.Size > maxBytes {
+		utils.ErrorResponse(ctx, fmt.Errorf("the file can't be larger than %d bytes", maxBytes), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	file, err := fileHeader.Open()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if int64(len(data)) > maxBytes {
+		utils.ErrorResponse(ctx, fmt.Errorf("the file can't be larger than %d bytes", maxBytes), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if len(data) == 0 {
+		utils.ErrorResponse(ctx, fmt.Errorf("the file is empty"), http.StatusBadRequest)
+		return
+	}
+
+	contentType := http.DetectContentType(data)
+
+	if !allowedDocumentTypes[contentType] {
+		utils.ErrorResponse(ctx, fmt.Errorf("files of type %s are not allowed", contentType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	name := body.Name
+	if name == "" {
+		name = filepath.Base(fileHeader.Filename)
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// Uploads and deletes of the same content can't interleave, otherwise a
+	// delete could remove the blob a new document relies on
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext($1))`, hash); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	existing, err := scanRowIntoDocument(tx.QueryRow(`SELECT `+documentColumns+` FROM employee_documents WHERE employee_id = $1 AND sha256 = $2 LIMIT 1`, params.ID, hash))
+
+	if err == nil {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "the employee already has this file", "document": existing})
+		return
+	}
+
+	if err != sql.ErrNoRows {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	var stored bool
+
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM employee_documents WHERE sha256 = $1)`, hash).Scan(&stored); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if !stored {
+		err := s.blobs.Put(ctx.Request.Context(), documentKey(hash), bytes.NewReader(data), int64(len(data)), contentType)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusBadGateway)
+			return
+		}
+	}
+
+	query := `INSERT INTO employee_documents
+	(company_id, employee_id, category, name, content_type, size, sha256, expires_at, uploaded_by)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	RETURNING ` + documentColumns
+
+	document, err := scanRowIntoDocument(tx.QueryRow(query,
+		companyId,
+		params.ID,
+		body.Category,
+		name,
+		contentType,
+		len(data),
+		hash,
+		expiresAt,
+		ctx.GetString("userId")))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"document": document})
+}
+
+func (s *Server) listDocuments(ctx *gin.Context) {
+	var params models.GetEmployeeParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if !isOwnEmployee(ctx, params.ID) {
+		if _, ok := s.requireEmployeeCompanyRole(ctx, params.ID, models.RoleOwner, models.RoleHR); !ok {
+			return
+		}
+	}
+
+	category := ctx.DefaultQuery("category", "")
+	expiringBefore := ctx.DefaultQuery("expiring_before", "")
+
+	if expiringBefore != "" {
+		if _, err := time.Parse("2006-01-02", expiringBefore); err != nil {
+			utils.ErrorResponse(ctx, fmt.Errorf("expiring_before must be a date as YYYY-MM-DD"), http.StatusBadRequest)
+			return
+		}
+	}
+
+	query := `SELECT ` + documentColumns + `
+	FROM employee_documents
+	WHERE employee_id = $1
+		AND ($2 = '' OR category = $2)
+		AND ($3 = '' OR expires_at < $3::date)
+	ORDER BY created_at DESC`
+
+	rows, err := s.db.Query(query, params.ID, category, expiringBefore)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	documents := make([]*models.DocumentResponse, 0)
+
+	for rows.Next() {
+		document, err := scanRowIntoDocument(rows)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		documents = append(documents, document)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"documents": documents})
+}
+
+func (s *Server) downloadDocument(ctx *gin.Context) {
+	var params models.GetEmployeeDocumentParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	document, err := findDocument(s.db, params.ID, params.DocumentId)
+
+	if err != nil {
+		documentErrorResponse(ctx, err, params.DocumentId)
+		return
+	}
+
+	if !isOwnEmployee(ctx, params.ID) && !s.requireCompanyRole(ctx, document.CompanyId, models.RoleOwner, models.RoleHR) {
+		return
+	}
+
+	blob, err := s.blobs.Get(ctx.Request.Context(), documentKey(document.Sha256))
+
+	if err != nil {
+		if err == storage.ErrNotFound {
+			utils.ErrorResponse(ctx, fmt.Errorf("the file of document %s is missing", document.ID), http.StatusNotFound)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusBadGateway)
+		return
+	}
+	defer blob.Close()
+
+	headers := map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": document.Name}),
+	}
+
+	ctx.DataFromReader(http.StatusOK, document.Size, document.ContentType, blob, headers)
+}
+
+func (s *Server) deleteDocument(ctx *gin.Context) {
+	var params models.GetEmployeeDocumentParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	document, err := findDocument(s.db, params.ID, params.DocumentId)
+
+	if err != nil {
+		documentErrorResponse(ctx, err, params.DocumentId)
+		return
+	}
+
+	if !s.requireCompanyRole(ctx, document.CompanyId, models.RoleOwner, models.RoleHR) {
+		return
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext($1))`, document.Sha256); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := tx.Exec(`DELETE FROM employee_documents WHERE id = $1`, document.ID); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	var shared bool
+
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM employee_documents WHERE sha256 = $1)`, document.Sha256).Scan(&shared); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	// The blob goes away while the lock is held; if the commit failed after
+	// it the row would point to a missing file, which downloads report.
+	if !shared {
+		if err := s.blobs.Delete(ctx.Request.Context(), documentKey(document.Sha256)); err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusBadGateway)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+func (s *Server) documentMaxBytes() int64 {
+	if s.env.DocumentMaxBytes > 0 {
+		return s.env.DocumentMaxBytes
+	}
+
+	return defaultDocumentMaxBytes
+}
+
+// documentKey is the blob key of a content hash, spread in directories by
+// its first characters.
+func documentKey(hash string) string {
+	return "documents/" + hash[:2] + "/" + hash
+}
+
+func findDocument(db queryer, employeeId string, id string) (*models.DocumentResponse, error) {
+	return scanRowIntoDocument(db.QueryRow(`SELECT `+documentColumns+` FROM employee_documents WHERE id = $1 AND employee_id = $2`, id, employeeId))
+}
+
+func documentErrorResponse(ctx *gin.Context, err error, id string) {
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(ctx, fmt.Errorf("document %s not found", id), http.StatusNotFound)
+		return
+	}
+	utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+}
+
+func scanRowIntoDocument(row rowScanner) (*models.DocumentResponse, error) {
+	document := new(models.DocumentResponse)
+
+	err := row.Scan(
+		&document.ID,
+		&document.CompanyId,
+		&document.EmployeeId,
+		&document.Category,
+		&document.Name,
+		&document.ContentType,
+		&document.Size,
+		&document.Sha256,
+		&document.ExpiresAt,
+		&document.UploadedBy,
+		&document.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return document, nil
+}
@@ -0,0 +1,492 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/jobs"
+	"github.com/gioCuesta25/employees-manager-backend/models"
+	"github.com/gioCuesta25/employees-manager-backend/nomina"
+	"github.com/gioCuesta25/employees-manager-backend/payroll"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
+)
+
+const electronicPayrollColumns = `id, company_id, employee_id, payroll_run_id, period_start, period_end, prefix || consecutive, cune, status, submitted_at, error, created_at, updated_at`
+
+// generateElectronicPayroll starts a job creating the individual payroll
+// document of every employee of a closed run. Employees that already have a
+// document for the run are skipped, so the endpoint can be called again
+// after adding people.
+func (s *Server) generateElectronicPayroll(ctx *gin.Context) {
+	var params models.GetPayrollRunParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	run, err := s.findPayrollRun(params.ID)
+
+	if err != nil {
+		payrollRunErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if !s.requireCompanyRole(ctx, run.CompanyId, models.RoleOwner, models.RoleHR) {
+		return
+	}
+
+	if run.Status != models.PayrollStatusClosed {
+		utils.ErrorResponse(ctx, fmt.Errorf("payroll run %s must be closed to generate electronic payroll", run.ID), http.StatusConflict)
+		return
+	}
+
+	s.enqueueJob(ctx, jobElectronicPayroll, payrollRunPayload{PayrollRunId: run.ID}, run.CompanyId)
+}
+
+type electronicPayrollResult struct {
+	Documents []*models.ElectronicPayrollResponse `json:"documents"`
+}
+
+func (s *Server) generateElectronicPayrollJob(ctx context.Context, job *jobs.Job) (any, error) {
+	var payload payrollRunPayload
+
+	if err := job.Decode(&payload); err != nil {
+		return nil, err
+	}
+
+	run, err := s.findPayrollRun(payload.PayrollRunId)
+
+	if err == sql.ErrNoRows {
+		return nil, jobs.Permanent(fmt.Errorf("payroll run %s not found", payload.PayrollRunId))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if run.Status != models.PayrollStatusClosed {
+		return nil, jobs.Permanent(fmt.Errorf("payroll run %s must be closed to generate electronic payroll", run.ID))
+	}
+
+	documents, err := s.createElectronicPayrollDocuments(ctx, run)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return electronicPayrollResult{Documents: documents}, nil
+}
+
+// createElectronicPayrollDocuments creates the documents of the employees
+// of the run that don't have one yet and returns them
+func (s *Server) createElectronicPayrollDocuments(ctx context.Context, run *models.PayrollRunResponse) ([]*models.ElectronicPayrollResponse, error) {
+	items, err := s.findPayrollItems(run.ID, "")
+
+	if err != nil {
+		return nil, err
+	}
+
+	adjustments, err := s.findPayrollAdjustments(run.ID, "")
+
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Locking the company serializes the numbering of its documents
+	employer, err := lockElectronicPayrollEmployer(tx, run.CompanyId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var consecutive int64
+	err = tx.QueryRow(`SELECT COALESCE(MAX(consecutive), 0) FROM electronic_payroll_documents WHERE company_id = $1 AND prefix = $2`,
+		run.CompanyId, s.env.DianPrefix).Scan(&consecutive)
+
+	if err != nil {
+		return nil, err
+	}
+
+	insert := `INSERT INTO electronic_payroll_documents
+	(company_id, employee_id, payroll_run_id, period_start, period_end, prefix, consecutive, cune, xml, status)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	RETURNING ` + electronicPayrollColumns
+
+	documents := make([]*models.ElectronicPayrollResponse, 0)
+
+	for _, item := range items {
+		var exists bool
+		err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM electronic_payroll_documents WHERE payroll_run_id = $1 AND employee_id = $2)`,
+			run.ID, item.EmployeeId).Scan(&exists)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if exists {
+			continue
+		}
+
+		var idTypeCode string
+		err = tx.QueryRow(`SELECT code FROM id_types WHERE id = $1`, item.Snapshot.IdType).Scan(&idTypeCode)
+
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+
+		consecutive++
+
+		firstName, otherNames := nomina.SplitName(item.Snapshot.Name)
+		firstSurname, secondSurname := nomina.SplitName(item.Snapshot.LastName)
+
+		lastDay := run.PeriodEnd
+		if item.Snapshot.TerminationDate != nil {
+			lastDay = *item.Snapshot.TerminationDate
+		}
+
+		settlement := nomina.Settlement{
+			PeriodStart: run.PeriodStart,
+			PeriodEnd:   run.PeriodEnd,
+			PaymentDate: run.PeriodEnd,
+			WorkedDays:  item.WorkedDays,
+			TotalDays:   payroll.Days360(item.Snapshot.AdmissionDate, lastDay),
+			BasePay:     item.BasePay,
+			Health:      item.HealthContribution,
+			Pension:     item.PensionContribution,
+		}
+
+		for _, adjustment := range adjustments {
+			if adjustment.EmployeeId != item.EmployeeId {
+				continue
+			}
+
+			concept := nomina.Concept{Description: adjustment.Concept, Amount: adjustment.Amount}
+			if adjustment.Kind == models.AdjustmentKindBonus {
+				settlement.Bonuses = append(settlement.Bonuses, concept)
+			} else {
+				settlement.Deductions = append(settlement.Deductions, concept)
+			}
+		}
+
+		doc, err := nomina.Build(nomina.Params{
+			Prefix:      s.env.DianPrefix,
+			Consecutive: consecutive,
+			GeneratedAt: time.Now(),
+			Employer:    *employer,
+			Worker: nomina.Worker{
+				DocumentType:  nomina.DocumentType(idTypeCode),
+				DocumentId:    item.Snapshot.IdNumber,
+				FirstName:     firstName,
+				OtherNames:    otherNames,
+				FirstSurname:  firstSurname,
+				SecondSurname: secondSurname,
+				AdmissionDate: item.Snapshot.AdmissionDate,
+				Salary:        item.Snapshot.Salary,
+			},
+			Settlement: settlement,
+			Software: nomina.Software{
+				ProviderNit:               s.env.DianProviderNit,
+				ProviderVerificationDigit: s.env.DianProviderDv,
+				ID:                        s.env.DianSoftwareId,
+				Pin:                       s.env.DianSoftwarePin,
+				Ambiente:                  s.env.DianEnvironment,
+			},
+		})
+
+		if err != nil {
+			return nil, jobs.Permanent(fmt.Errorf("employee %s: %w", item.EmployeeId, err))
+		}
+
+		xml, err := nomina.Marshal(doc)
+
+		if err != nil {
+			return nil, err
+		}
+
+		row := tx.QueryRow(insert,
+			run.CompanyId,
+			item.EmployeeId,
+			run.ID,
+			run.PeriodStart,
+			run.PeriodEnd,
+			s.env.DianPrefix,
+			consecutive,
+			doc.InformacionGeneral.CUNE,
+			string(xml),
+			models.ElectronicPayrollStatusGenerated)
+
+		document, err := scanRowIntoElectronicPayroll(row)
+
+		if err != nil {
+			return nil, err
+		}
+
+		documents = append(documents, document)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return documents, nil
+}
+
+// listElectronicPayroll returns the documents of an employee or of a payroll
+// run, optionally restricted to the periods starting on or after `from`.
+// Only the documents of the company of the run, or else of the employee, are
+// listed.
+func (s *Server) listElectronicPayroll(ctx *gin.Context) {
+	employeeId := ctx.DefaultQuery("employee_id", "")
+	payrollRunId := ctx.DefaultQuery("payroll_run_id", "")
+	from := ctx.DefaultQuery("from", "")
+
+	if employeeId == "" && payrollRunId == "" {
+		utils.ErrorResponse(ctx, fmt.Errorf("employee_id or payroll_run_id is required"), http.StatusBadRequest)
+		return
+	}
+
+	var companyId string
+	var err error
+
+	if payrollRunId != "" {
+		err = s.db.QueryRow(`SELECT company_id FROM payroll_runs WHERE id::text = $1`, payrollRunId).Scan(&companyId)
+
+		if err != nil {
+			payrollRunErrorResponse(ctx, err, payrollRunId)
+			return
+		}
+	} else {
+		err = s.db.QueryRow(`SELECT company_id FROM employees WHERE id::text = $1`, employeeId).Scan(&companyId)
+
+		if err != nil {
+			employeeErrorResponse(ctx, err, employeeId)
+			return
+		}
+	}
+
+	if !s.requireCompanyRole(ctx, companyId, models.RoleOwner, models.RoleHR) {
+		return
+	}
+
+	query := `SELECT ` + electronicPayrollColumns + `
+	FROM electronic_payroll_documents
+	WHERE company_id = $1
+		AND ($2 = '' OR employee_id::text = $2)
+		AND ($3 = '' OR payroll_run_id::text = $3)
+		AND ($4 = '' OR period_start >= $4::date)
+	ORDER BY period_start DESC, consecutive`
+
+	rows, err := s.db.Query(query, companyId, employeeId, payrollRunId, from)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	documents := make([]*models.ElectronicPayrollResponse, 0)
+
+	for rows.Next() {
+		document, err := scanRowIntoElectronicPayroll(rows)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		documents = append(documents, document)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"documents": documents})
+}
+
+func (s *Server) getElectronicPayroll(ctx *gin.Context) {
+	var params models.GetElectronicPayrollParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	query := `SELECT ` + electronicPayrollColumns + ` FROM electronic_payroll_documents WHERE id = $1`
+
+	document, err := scanRowIntoElectronicPayroll(s.db.QueryRow(query, params.ID))
+
+	if err != nil {
+		electronicPayrollErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if !s.requireCompanyRole(ctx, document.CompanyId, models.RoleOwner, models.RoleHR) {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"document": document})
+}
+
+func (s *Server) getElectronicPayrollXml(ctx *gin.Context) {
+	var params models.GetElectronicPayrollParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	var companyId, number, xml string
+
+	err := s.db.QueryRow(`SELECT company_id, prefix || consecutive, xml FROM electronic_payroll_documents WHERE id = $1`, params.ID).
+		Scan(&companyId, &number, &xml)
+
+	if err != nil {
+		electronicPayrollErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if !s.requireCompanyRole(ctx, companyId, models.RoleOwner, models.RoleHR) {
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", number+".xml"))
+	ctx.Data(http.StatusOK, "application/xml", []byte(xml))
+}
+
+// submitElectronicPayroll hands the document to the configured transmitter
+// and records the outcome. Failed documents can be submitted again.
+func (s *Server) submitElectronicPayroll(ctx *gin.Context) {
+	var params models.GetElectronicPayrollParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	var companyId, number, xml, status, nit string
+
+	query := `SELECT d.company_id, d.prefix || d.consecutive, d.xml, d.status, COALESCE(c.nit, '')
+	FROM electronic_payroll_documents d
+	JOIN companies c ON c.id = d.company_id
+	WHERE d.id = $1`
+
+	err := s.db.QueryRow(query, params.ID).Scan(&companyId, &number, &xml, &status, &nit)
+
+	if err != nil {
+		electronicPayrollErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if !s.requireCompanyRole(ctx, companyId, models.RoleOwner, models.RoleHR) {
+		return
+	}
+
+	if status == models.ElectronicPayrollStatusSubmitted {
+		utils.ErrorResponse(ctx, fmt.Errorf("document %s was already submitted", number), http.StatusConflict)
+		return
+	}
+
+	transmitErr := s.transmitter.Transmit(ctx.Request.Context(), nit, number, []byte(xml))
+
+	status = models.ElectronicPayrollStatusSubmitted
+	var errorMessage *string
+	var submittedAt *time.Time
+
+	if transmitErr != nil {
+		status = models.ElectronicPayrollStatusFailed
+		message := transmitErr.Error()
+		errorMessage = &message
+	} else {
+		now := time.Now()
+		submittedAt = &now
+	}
+
+	update := `UPDATE electronic_payroll_documents
+	SET status = $1, submitted_at = $2, error = $3, updated_at = $4
+	WHERE id = $5
+	RETURNING ` + electronicPayrollColumns
+
+	document, err := scanRowIntoElectronicPayroll(s.db.QueryRow(update, status, submittedAt, errorMessage, time.Now(), params.ID))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if transmitErr != nil {
+		ctx.JSON(http.StatusBadGateway, gin.H{"document": document, "error": transmitErr.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"document": document})
+}
+
+func lockElectronicPayrollEmployer(tx *sql.Tx, companyId string) (*nomina.Employer, error) {
+	query := `SELECT name,
+		COALESCE(nit, ''),
+		COALESCE(verification_digit, ''),
+		COALESCE(department_code, ''),
+		COALESCE(municipality_code, ''),
+		COALESCE(address, '')
+	FROM companies
+	WHERE id = $1
+	FOR UPDATE`
+
+	employer := new(nomina.Employer)
+
+	err := tx.QueryRow(query, companyId).Scan(&employer.Name,
+		&employer.Nit,
+		&employer.VerificationDigit,
+		&employer.DepartmentCode,
+		&employer.MunicipalityCode,
+		&employer.Address)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return employer, nil
+}
+
+func electronicPayrollErrorResponse(ctx *gin.Context, err error, id string) {
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(ctx, fmt.Errorf("electronic payroll document %s not found", id), http.StatusNotFound)
+		return
+	}
+	utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+}
+
+func scanRowIntoElectronicPayroll(row rowScanner) (*models.ElectronicPayrollResponse, error) {
+	document := new(models.ElectronicPayrollResponse)
+
+	err := row.Scan(
+		&document.ID,
+		&document.CompanyId,
+		&document.EmployeeId,
+		&document.PayrollRunId,
+		&document.PeriodStart,
+		&document.PeriodEnd,
+		&document.Number,
+		&document.Cune,
+		&document.Status,
+		&document.SubmittedAt,
+		&document.Error,
+		&document.CreatedAt,
+		&document.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return document, nil
+}
@@ -0,0 +1,349 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/mailer"
+	"github.com/gioCuesta25/employees-manager-backend/models"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
+)
+
+const (
+	// Emails sent by each run of the emails job
+	emailBatchSize = 50
+
+	// A claimed email is tried again after this long if its attempt never
+	// ended, as when the process stopped
+	emailLease = 2 * time.Minute
+)
+
+func (s *Server) getNotificationPreferences(ctx *gin.Context) {
+	preferences, err := findNotificationPreferences(s.db, ctx.GetString("userId"))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"preferences": preferences})
+}
+
+// updateNotificationPreferences sets the language of the emails of the user
+// and the kinds they get by email. Kinds left out keep their preference.
+func (s *Server) updateNotificationPreferences(ctx *gin.Context) {
+	var body models.UpdateNotificationPreferencesBody
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	for kind := range body.Email {
+		if !slices.Contains(models.NotificationKinds, kind) {
+			utils.ErrorResponse(ctx, fmt.Errorf("unknown notification kind %s", kind), http.StatusBadRequest)
+			return
+		}
+	}
+
+	userId := ctx.GetString("userId")
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if body.Language != nil {
+		if _, err := tx.Exec(`UPDATE users SET language = $1, updated_at = now() WHERE id = $2`, *body.Language, userId); err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	for kind, enabled := range body.Email {
+		query := `INSERT INTO notification_preferences (user_id, kind, email) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, kind) DO UPDATE SET email = EXCLUDED.email, updated_at = now()`
+
+		if _, err := tx.Exec(query, userId, kind, enabled); err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	preferences, err := findNotificationPreferences(tx, userId)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"preferences": preferences})
+}
+
+func findNotificationPreferences(db queryer, userId string) (*models.NotificationPreferencesResponse, error) {
+	preferences := &models.NotificationPreferencesResponse{Email: make(map[string]bool)}
+
+	if err := db.QueryRow(`SELECT language FROM users WHERE id = $1`, userId).Scan(&preferences.Language); err != nil {
+		return nil, err
+	}
+
+	for _, kind := range models.NotificationKinds {
+		preferences.Email[kind] = true
+	}
+
+	rows, err := db.Query(`SELECT kind, email FROM notification_preferences WHERE user_id = $1`, userId)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var kind string
+		var enabled bool
+
+		if err := rows.Scan(&kind, &enabled); err != nil {
+			return nil, err
+		}
+
+		if _, ok := preferences.Email[kind]; ok {
+			preferences.Email[kind] = enabled
+		}
+	}
+
+	return preferences, rows.Err()
+}
+
+// enqueueEmail adds the email to the outbox. Emails enqueued in a
+// transaction are only sent once it commits.
+func enqueueEmail(db queryer, email models.Email) error {
+	if !mailer.HasTemplate(email.Template) {
+		return fmt.Errorf("unknown email template %s", email.Template)
+	}
+
+	data := email.Data
+	if data == nil {
+		data = map[string]any{}
+	}
+
+	encoded, err := json.Marshal(data)
+
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO email_outbox (user_id, to_address, template, language, data) VALUES ($1, $2, $3, $4, $5)`
+
+	_, err = db.Exec(query, email.UserId, email.To, email.Template, mailer.Language(email.Language), encoded)
+
+	return err
+}
+
+// emailNotification emails the notification to its user in their language,
+// unless they opted out of its kind
+func emailNotification(db queryer, notification models.Notification) error {
+	var name, address, language string
+	var enabled bool
+
+	query := `SELECT u.full_name, u.email, u.language, COALESCE(p.email, true)
+	FROM users u
+	LEFT JOIN notification_preferences p ON p.user_id = u.id AND p.kind = $2
+	WHERE u.id = $1`
+
+	if err := db.QueryRow(query, notification.UserId, notification.Kind).Scan(&name, &address, &language, &enabled); err != nil {
+		return err
+	}
+
+	if !enabled {
+		return nil
+	}
+
+	data := maps.Clone(notification.Data)
+	if data == nil {
+		data = map[string]any{}
+	}
+
+	if notification.Link != nil {
+		data["link"] = *notification.Link
+	}
+
+	return enqueueEmail(db, models.Email{
+		UserId:   &notification.UserId,
+		To:       (&mail.Address{Name: name, Address: address}).String(),
+		Template: notification.Kind,
+		Language: language,
+		Data:     data,
+	})
+}
+
+// outboxEmail is a claimed email of the outbox
+type outboxEmail struct {
+	ID       string
+	UserId   sql.NullString
+	To       string
+	Template string
+	Language string
+	Data     map[string]any
+	Attempts int
+}
+
+// sendEmails sends the due emails of the outbox, retrying the failed ones
+// with backoff until they are dead
+func (s *Server) sendEmails(ctx context.Context) error {
+	emails, err := claimEmails(s.db)
+
+	if err != nil {
+		return err
+	}
+
+	for _, email := range emails {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		sendErr := s.sendEmail(ctx, email)
+
+		if err := finishEmail(s.db, email, sendErr); err != nil {
+			return err
+		}
+
+		if sendErr != nil {
+			log.Printf("email %s: %v", email.ID, sendErr)
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) sendEmail(ctx context.Context, email *outboxEmail) error {
+	email.Data["app_url"] = strings.TrimSuffix(s.env.AppURL, "/")
+
+	// The tokens of the account emails and invitations are only issued now,
+	// so the outbox never holds them. Each attempt replaces the token of the
+	// one before.
+	if link, ok := userTokenLinks[email.Template]; ok {
+		if !email.UserId.Valid {
+			return mailer.Permanent(fmt.Errorf("the user of the %s email was deleted", email.Template))
+		}
+
+		token, err := issueUserToken(s.db, email.UserId.String, email.Template, link.TTL)
+
+		if err != nil {
+			return err
+		}
+
+		email.Data["link"] = link.Path + token
+	}
+
+	if email.Template == invitationTemplate {
+		invitationId, _ := email.Data["invitation_id"].(string)
+		token, err := issueInvitationToken(s.db, invitationId)
+
+		if err == sql.ErrNoRows {
+			return mailer.Permanent(fmt.Errorf("the invitation %s is no longer pending", invitationId))
+		}
+
+		if err != nil {
+			return err
+		}
+
+		email.Data["link"] = "/invitations/accept?token=" + token
+	}
+
+	if link, ok := email.Data["link"].(string); ok && strings.HasPrefix(link, "/") {
+		email.Data["link"] = email.Data["app_url"].(string) + link
+	}
+
+	message, err := mailer.Render(email.Template, email.Language, email.To, email.Data)
+
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, message)
+}
+
+// claimEmails leases the due emails to this process by moving their next
+// attempt past the lease
+func claimEmails(db *sql.DB) ([]*outboxEmail, error) {
+	query := `UPDATE email_outbox
+	SET attempts = attempts + 1, next_attempt_at = $1, updated_at = now()
+	WHERE id IN (
+		SELECT id FROM email_outbox
+		WHERE status = $2 AND next_attempt_at <= now()
+		ORDER BY next_attempt_at
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING id, user_id, to_address, template, language, data, attempts`
+
+	rows, err := db.Query(query, time.Now().Add(emailLease), mailer.StatusPending, emailBatchSize)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	emails := make([]*outboxEmail, 0)
+
+	for rows.Next() {
+		email := new(outboxEmail)
+		var data []byte
+
+		if err := rows.Scan(&email.ID, &email.UserId, &email.To, &email.Template, &email.Language, &data, &email.Attempts); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(data, &email.Data); err != nil {
+			return nil, err
+		}
+
+		if email.Data == nil {
+			email.Data = map[string]any{}
+		}
+
+		emails = append(emails, email)
+	}
+
+	return emails, rows.Err()
+}
+
+// finishEmail records how the attempt went. The data of the emails that
+// won't be tried again is cleared.
+func finishEmail(db *sql.DB, email *outboxEmail, sendErr error) error {
+	if sendErr == nil {
+		_, err := db.Exec(`UPDATE email_outbox
+		SET status = $1, data = '{}', last_error = NULL, sent_at = now(), updated_at = now()
+		WHERE id = $2`, mailer.StatusSent, email.ID)
+		return err
+	}
+
+	if mailer.IsPermanent(sendErr) || email.Attempts >= mailer.MaxAttempts {
+		_, err := db.Exec(`UPDATE email_outbox SET status = $1, data = '{}', last_error = $2, updated_at = now() WHERE id = $3`,
+			mailer.StatusDead, sendErr.Error(), email.ID)
+		return err
+	}
+
+	_, err := db.Exec(`UPDATE email_outbox SET last_error = $1, next_attempt_at = $2, updated_at = now() WHERE id = $3`,
+		sendErr.Error(), time.Now().Add(mailer.Backoff(email.Attempts)), email.ID)
+
+	return err
+}
@@ -1,16 +1,29 @@
 package api
 
 import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"math"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/approvals"
+	"github.com/gioCuesta25/employees-manager-backend/customfields"
 	"github.com/gioCuesta25/employees-manager-backend/models"
 	"github.com/gioCuesta25/employees-manager-backend/utils"
 )
 
+const employeeColumns = `id, name, last_name, phone_number, email, id_type, id_number, admission_date, salary, position_id,
+	department_id, company_id, picture_url, custom_fields, version, created_at, updated_at`
+
 func (s *Server) createEmployee(ctx *gin.Context) {
 	var body models.CreateEmployeeBody
 
@@ -19,6 +32,12 @@ func (s *Server) createEmployee(ctx *gin.Context) {
 		return
 	}
 
+	customFields, ok := s.bindCustomFields(ctx, body.CompanyId, customfields.EntityEmployee, body.CustomFields)
+
+	if !ok {
+		return
+	}
+
 	query := `INSERT INTO employees (name,
 		last_name,
 		phone_number,
@@ -30,11 +49,20 @@ func (s *Server) createEmployee(ctx *gin.Context) {
 		position_id,
 		department_id,
 		company_id,
-		picture_url)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-		RETURNING id, name, last_name, phone_number, email, id_type, id_number, admission_date, salary, position_id, department_id,company_id, picture_url,created_at, updated_at`
+		picture_url,
+		custom_fields)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING ` + employeeColumns
 
-	row := s.db.QueryRow(
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRow(
 		query,
 		body.Name,
 		body.LastName,
@@ -47,33 +75,29 @@ func (s *Server) createEmployee(ctx *gin.Context) {
 		body.PositionId,
 		body.DepartmentId,
 		body.CompanyId,
-		body.PictureUrl)
+		body.PictureUrl,
+		customFields)
 
-	var employee models.EmployeeResponse
+	employee, err := scanRowIntoEmployee(row)
 
-	err := row.Scan(
-		&employee.ID,
-		&employee.Name,
-		&employee.LastName,
-		&employee.PhoneNumber,
-		&employee.Email,
-		&employee.IdType,
-		&employee.IdNumber,
-		&employee.AdmissionDate,
-		&employee.Salary,
-		&employee.PositionId,
-		&employee.DepartmentId,
-		&employee.CompanyId,
-		&employee.PictureUrl,
-		&employee.CreatedAt,
-		&employee.UpdatedAt)
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	checklist, err := startChecklists(tx, employee.CompanyId, employee.ID, models.ChecklistKindOnboarding, calendarDate(body.AdmissionDate))
 
 	if err != nil {
 		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, gin.H{"employee": employee})
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"employee": employee, "checklist": checklist})
 }
 
 func (s *Server) getEmployeeById(ctx *gin.Context) {
@@ -84,55 +108,48 @@ func (s *Server) getEmployeeById(ctx *gin.Context) {
 		return
 	}
 
-	query := `
-	SELECT
-		id,
-		name,
-		last_name,
-		phone_number,
-		email,
-		id_type,
-		id_number,
-		admission_date,
-		salary,
-		position_id,
-		department_id,
-		company_id,
-		picture_url,
-		created_at,
-		updated_at
-	FROM
-		employees
-	WHERE
-		id = $1
-	`
+	query := `SELECT ` + employeeColumns + ` FROM employees WHERE id = $1`
 
-	row := s.db.QueryRow(query, params.ID)
-
-	var employee models.EmployeeResponse
-
-	err := row.Scan(
-		&employee.ID,
-		&employee.Name,
-		&employee.LastName,
-		&employee.PhoneNumber,
-		&employee.Email,
-		&employee.IdType,
-		&employee.IdNumber,
-		&employee.AdmissionDate,
-		&employee.Salary,
-		&employee.PositionId,
-		&employee.DepartmentId,
-		&employee.CompanyId,
-		&employee.CreatedAt,
-		&employee.UpdatedAt)
+	employee, err := scanRowIntoEmployee(s.db.QueryRow(query, params.ID))
 
 	if err != nil {
-		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		employeeErrorResponse(ctx, err, params.ID)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"employee": employee})
+	// The version doesn't cover the included records
+	if ctx.Query("include") == "" && notModified(ctx, employee.Version) {
+		return
+	}
+
+	response := gin.H{"employee": employee}
+
+	for _, include := range strings.Split(ctx.Query("include"), ",") {
+		var related any
+
+		switch strings.TrimSpace(include) {
+		case "":
+			continue
+		case "emergency_contacts":
+			related, err = findEmergencyContacts(s.db, employee.ID)
+		case "dependents":
+			related, err = findDependents(s.db, employee.ID)
+		case "beneficiaries":
+			related, err = findBeneficiaries(s.db, employee.ID)
+		default:
+			utils.ErrorResponse(ctx, fmt.Errorf("include must be a list of emergency_contacts, dependents and beneficiaries"), http.StatusBadRequest)
+			return
+		}
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		response[strings.TrimSpace(include)] = related
+	}
+
+	ctx.JSON(http.StatusOK, response)
 }
 
 func (s *Server) listCompanyEmployees(ctx *gin.Context) {
@@ -144,6 +161,13 @@ func (s *Server) listCompanyEmployees(ctx *gin.Context) {
 		return
 	}
 
+	// Filters on custom fields come as cf.<key>=<value>
+	filter, ok := s.customFieldFilter(ctx, companyId, customfields.EntityEmployee)
+
+	if !ok {
+		return
+	}
+
 	// DefaultQuery returns the specified default value if the key is not found in the query string.
 	pageNumber, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("size", "10"))
@@ -152,40 +176,26 @@ func (s *Server) listCompanyEmployees(ctx *gin.Context) {
 
 	// Query for get all employees associated to a company
 	query := `
-	SELECT 
-		id, 
-		name, 
-		last_name, 
-		phone_number, 
-		email, 
-		id_type, 
-		id_number, 
-		admission_date, 
-		salary, 
-		position_id, 
-		department_id,
-		company_id, 
-		picture_url,
-		created_at, 
-		updated_at
+	SELECT ` + employeeColumns + `
 	FROM employees
-	WHERE company_id = $1
+	WHERE company_id = $1 AND custom_fields @> $4::jsonb
 	ORDER BY id ASC
 	LIMIT $2
 	OFFSET $3
 	`
 
-	rows, err := s.db.Query(query, companyId, pageSize, offset)
+	rows, err := s.db.Query(query, companyId, pageSize, offset, filter)
 
 	if err != nil {
 		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
 		return
 	}
+	defer rows.Close()
 
 	// Query for get the total number of employees associated to a company
-	totalItemsQuery := `SELECT COUNT(*) FROM employees WHERE company_id = $1`
+	totalItemsQuery := `SELECT COUNT(*) FROM employees WHERE company_id = $1 AND custom_fields @> $2::jsonb`
 	var totalItems int
-	err = s.db.QueryRow(totalItemsQuery, companyId).Scan(&totalItems)
+	err = s.db.QueryRow(totalItemsQuery, companyId, filter).Scan(&totalItems)
 
 	if err != nil {
 		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
@@ -208,31 +218,14 @@ func (s *Server) listCompanyEmployees(ctx *gin.Context) {
 	var employees []models.EmployeeResponse
 
 	for rows.Next() {
-		var employee models.EmployeeResponse
-
-		err := rows.Scan(
-			&employee.ID,
-			&employee.Name,
-			&employee.LastName,
-			&employee.PhoneNumber,
-			&employee.Email,
-			&employee.IdType,
-			&employee.IdNumber,
-			&employee.AdmissionDate,
-			&employee.Salary,
-			&employee.PositionId,
-			&employee.DepartmentId,
-			&employee.CompanyId,
-			&employee.PictureUrl,
-			&employee.CreatedAt,
-			&employee.UpdatedAt)
+		employee, err := scanRowIntoEmployee(rows)
 
 		if err != nil {
 			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
 			return
 		}
 
-		employees = append(employees, employee)
+		employees = append(employees, *employee)
 	}
 
 	result := models.PaginatedResult{
@@ -247,6 +240,117 @@ func (s *Server) listCompanyEmployees(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, result)
 }
 
+// exportEmployees writes the employees of the company as CSV, with a column
+// per custom field. It takes the same cf.<key> filters as the listing. With
+// async=true the file is made by a job and downloaded from it. Only owners
+// and HR export, as the file has the salaries and id numbers.
+func (s *Server) exportEmployees(ctx *gin.Context) {
+	companyId := ctx.DefaultQuery("company_id", "")
+
+	if companyId == "" {
+		utils.ErrorResponse(ctx, fmt.Errorf("company_id is required"), http.StatusBadRequest)
+		return
+	}
+
+	if !s.requireCompanyRole(ctx, companyId, models.RoleOwner, models.RoleHR) {
+		return
+	}
+
+	filter, ok := s.customFieldFilter(ctx, companyId, customfields.EntityEmployee)
+
+	if !ok {
+		return
+	}
+
+	if ctx.Query("async") == "true" {
+		s.enqueueJob(ctx, jobEmployeesExport, employeesExportPayload{CompanyId: companyId, Filter: filter}, companyId)
+		return
+	}
+
+	ctx.Header("Content-Disposition", `attachment; filename="employees.csv"`)
+	ctx.Header("Content-Type", "text/csv; charset=utf-8")
+
+	if _, err := writeEmployeesCSV(s.db, ctx.Writer, companyId, filter); err != nil {
+		if ctx.Writer.Written() {
+			// The status is already sent, the truncated file is all we can do
+			ctx.Error(err)
+			return
+		}
+
+		ctx.Writer.Header().Del("Content-Disposition")
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+	}
+}
+
+// writeEmployeesCSV writes the employees of the company matching the custom
+// fields filter and returns how many it wrote
+func writeEmployeesCSV(db queryer, w io.Writer, companyId string, filter string) (int, error) {
+	fields, err := loadCustomFields(db, companyId, customfields.EntityEmployee)
+
+	if err != nil {
+		return 0, err
+	}
+
+	query := `SELECT ` + employeeColumns + `
+	FROM employees
+	WHERE company_id = $1 AND custom_fields @> $2::jsonb
+	ORDER BY last_name, name`
+
+	rows, err := db.Query(query, companyId, filter)
+
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	header := []string{"id", "name", "last_name", "email", "phone_number", "id_number", "admission_date", "salary", "position_id", "department_id"}
+	for _, field := range fields {
+		header = append(header, field.Label)
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Write(header)
+
+	written := 0
+
+	for rows.Next() {
+		employee, err := scanRowIntoEmployee(rows)
+
+		if err != nil {
+			writer.Flush()
+			return written, err
+		}
+
+		record := []string{
+			employee.ID,
+			employee.Name,
+			employee.LastName,
+			employee.Email,
+			employee.PhoneNumber,
+			employee.IdNumber,
+			employee.AdmissionDate.Format("2006-01-02"),
+			strconv.FormatFloat(employee.Salary, 'f', -1, 64),
+			employee.PositionId,
+			employee.DepartmentId,
+		}
+
+		for _, field := range fields {
+			record = append(record, customfields.Format(employee.CustomFields[field.Key]))
+		}
+
+		writer.Write(record)
+		written++
+	}
+
+	writer.Flush()
+
+	if err := rows.Err(); err != nil {
+		return written, err
+	}
+
+	return written, writer.Error()
+}
+
 func (s *Server) updateEmployee(ctx *gin.Context) {
 	var params models.GetEmployeeParams
 	var body models.CreateEmployeeBody
@@ -256,84 +360,227 @@ func (s *Server) updateEmployee(ctx *gin.Context) {
 		return
 	}
 
+	query := `SELECT ` + employeeColumns + ` FROM employees WHERE id = $1`
+
+	current, err := scanRowIntoEmployee(s.db.QueryRow(query, params.ID))
+
+	if err != nil {
+		employeeErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	changed, ok := bindMergePatch(ctx, employeeBody(current), &body)
+
+	if !ok {
+		return
+	}
+
+	if len(changed) == 0 {
+		ctx.Header("ETag", etag(current.Version))
+		ctx.JSON(http.StatusOK, gin.H{"employee": current})
+		return
+	}
+
+	employee, err := patchEmployee(s.db, params.ID, body, changed, matchedVersion(ctx))
+
+	if versionMismatch(ctx, err) {
+		return
+	}
+
+	if errors.Is(err, errCompanyChange) {
+		utils.ErrorResponse(ctx, err, http.StatusUnprocessableEntity)
+		return
+	}
+
+	if err != nil {
+		var invalid invalidError
+
+		if errors.As(err, &invalid) {
+			utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+			return
+		}
+
+		employeeErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	ctx.Header("ETag", etag(employee.Version))
+	ctx.JSON(http.StatusOK, gin.H{"employee": employee})
+}
+
+// errCompanyChange rejects patches moving an employee to another company,
+// which would take its history out of the reach of the company that owns it
+var errCompanyChange = invalidError("company_id can't be changed")
+
+// patchEmployee writes the members of the merged body of the employee that
+// changed
+func patchEmployee(db queryer, id string, body models.CreateEmployeeBody, changed []string, version int) (*models.EmployeeResponse, error) {
+	if slices.Contains(changed, "company_id") {
+		return nil, errCompanyChange
+	}
+
+	columns := map[string]any{
+		"name":           body.Name,
+		"last_name":      body.LastName,
+		"phone_number":   body.PhoneNumber,
+		"email":          body.Email,
+		"id_type":        body.IdType,
+		"id_number":      body.IdNumber,
+		"admission_date": body.AdmissionDate,
+		"salary":         body.Salary,
+		"position_id":    body.PositionId,
+		"department_id":  body.DepartmentId,
+		"picture_url":    body.PictureUrl,
+	}
+
+	if slices.Contains(changed, "custom_fields") {
+		fields, err := loadCustomFields(db, body.CompanyId, customfields.EntityEmployee)
+
+		if err != nil {
+			return nil, err
+		}
+
+		normalized, err := customfields.Validate(fields, body.CustomFields)
+
+		if err != nil {
+			return nil, invalidError(err.Error())
+		}
+
+		columns["custom_fields"], err = json.Marshal(normalized)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	query, args, err := patchQuery("employees", id, version, columns, changed, employeeColumns)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scanRowIntoEmployee(db.QueryRow(query, args...))
+}
+
+// employeeBody is the employee as the body that creates it, the document
+// a merge patch applies to
+func employeeBody(employee *models.EmployeeResponse) models.CreateEmployeeBody {
+	return models.CreateEmployeeBody{
+		Name:          employee.Name,
+		LastName:      employee.LastName,
+		PhoneNumber:   employee.PhoneNumber,
+		Email:         employee.Email,
+		IdType:        employee.IdType,
+		IdNumber:      employee.IdNumber,
+		AdmissionDate: employee.AdmissionDate,
+		Salary:        employee.Salary,
+		PositionId:    employee.PositionId,
+		DepartmentId:  employee.DepartmentId,
+		CompanyId:     employee.CompanyId,
+		PictureUrl:    employee.PictureUrl,
+		CustomFields:  employee.CustomFields,
+	}
+}
+
+// createSalaryChange asks for a new salary through the salary_change
+// approval chain of the company. Thresholds of the chain compare the raise.
+func (s *Server) createSalaryChange(ctx *gin.Context) {
+	var params models.GetEmployeeParams
+	var body models.CreateSalaryChangeBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
 	if err := ctx.ShouldBindJSON(&body); err != nil {
 		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
 		return
 	}
 
-	query := `
-	UPDATE
-		employees
-	SET
-		name = $1,
-		last_name = $2,
-		phone_number = $3,
-		email = $4,
-		id_type = $5,
-		id_number = $6,
-		admission_date = $7,
-		salary = $8,
-		position_id = $9,
-		department_id = $10,
-		company_id = $11
-		picture_url = $12
-	WHERE
-		id = $13
-	RETURNING id,
-		name,
-		last_name,
-		phone_number,
-		email,
-		id_type,
-		id_number,
-		admission_date,
-		salary,
-		position_id,
-		company_id,
-		picture_url,
-		created_at,
-		updated_at
-	`
+	tx, err := s.db.Begin()
 
-	row := s.db.QueryRow(
-		query,
-		body.Name,
-		body.LastName,
-		body.PhoneNumber,
-		body.Email,
-		body.IdType,
-		body.IdNumber,
-		body.AdmissionDate,
-		body.Salary,
-		body.PositionId,
-		body.CompanyId,
-		body.PictureUrl,
-		params.ID)
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
 
-	var employee models.EmployeeResponse
-	err := row.Scan(
-		&employee.ID,
-		&employee.Name,
-		&employee.LastName,
-		&employee.PhoneNumber,
-		&employee.Email,
-		&employee.IdType,
-		&employee.IdNumber,
-		&employee.AdmissionDate,
-		&employee.Salary,
-		&employee.PositionId,
-		&employee.DepartmentId,
-		&employee.CompanyId,
-		&employee.PictureUrl,
-		&employee.CreatedAt,
-		&employee.UpdatedAt)
+	var companyId string
+	var salary float64
+	var terminationDate *time.Time
+
+	err = tx.QueryRow(`SELECT company_id, salary, termination_date FROM employees WHERE id = $1 FOR UPDATE`, params.ID).
+		Scan(&companyId, &salary, &terminationDate)
+
+	if err != nil {
+		employeeErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if terminationDate != nil {
+		utils.ErrorResponse(ctx, fmt.Errorf("employee %s was terminated", params.ID), http.StatusConflict)
+		return
+	}
+
+	raise := body.Salary - salary
+
+	approval, err := startApproval(tx, approvalRequest{
+		CompanyId:   companyId,
+		Subject:     approvals.SubjectSalaryChange,
+		EmployeeId:  params.ID,
+		Amount:      &raise,
+		Payload:     map[string]any{"salary": body.Salary, "previous_salary": salary, "reason": body.Reason},
+		RequestedBy: ctx.GetString("userId"),
+	})
 
 	if err != nil {
+		if isUniqueViolation(err) {
+			utils.ErrorResponse(ctx, fmt.Errorf("a salary change of employee %s is already waiting for approval", params.ID), http.StatusConflict)
+			return
+		}
 		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"employee": employee})
+	status := http.StatusAccepted
+	var result any
+
+	if approval.Status == approvals.StatusApproved {
+		status = http.StatusOK
+		err = auditEmployeeChange(tx, ctx, params.ID, approvals.SubjectSalaryChange, func() error {
+			var applyErr error
+			result, applyErr = applySalaryChange(tx, approval)
+			return applyErr
+		})
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(status, gin.H{"approval": approval, "result": result})
+}
+
+// applySalaryChange sets the salary of the approved request
+func applySalaryChange(tx *sql.Tx, approval *models.ApprovalResponse) (any, error) {
+	salary, _ := approval.Payload["salary"].(float64)
+
+	query := `UPDATE employees SET salary = $1, updated_at = $2 WHERE id = $3 AND termination_date IS NULL RETURNING ` + employeeColumns
+
+	employee, err := scanRowIntoEmployee(tx.QueryRow(query, salary, time.Now(), approval.EmployeeId))
+
+	if err == sql.ErrNoRows {
+		return nil, conflictError(fmt.Sprintf("employee %s was terminated", approval.EmployeeId))
+	}
+
+	return employee, err
 }
 
 func (s *Server) deleteEmployee(ctx *gin.Context) {
@@ -344,14 +591,67 @@ func (s *Server) deleteEmployee(ctx *gin.Context) {
 		return
 	}
 
-	query := `DELETE FROM employees WHERE id = $1`
+	query := `DELETE FROM employees WHERE id = $1 AND ($2 = 0 OR version = $2)`
 
-	_, err := s.db.Exec(query, params.ID)
+	result, err := s.db.Exec(query, params.ID, matchedVersion(ctx))
 
 	if err != nil {
 		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
 		return
 	}
 
+	if deleted, _ := result.RowsAffected(); deleted == 0 && versionMismatch(ctx, sql.ErrNoRows) {
+		return
+	}
+
 	ctx.JSON(http.StatusNoContent, nil)
 }
+
+func lockEmployee(tx *sql.Tx, id string) error {
+	var locked string
+	return tx.QueryRow(`SELECT id FROM employees WHERE id = $1 FOR UPDATE`, id).Scan(&locked)
+}
+
+func employeeErrorResponse(ctx *gin.Context, err error, id string) {
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(ctx, fmt.Errorf("employee %s not found", id), http.StatusNotFound)
+		return
+	}
+	utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+}
+
+func scanRowIntoEmployee(row rowScanner) (*models.EmployeeResponse, error) {
+	employee := new(models.EmployeeResponse)
+	var customFields []byte
+
+	err := row.Scan(
+		&employee.ID,
+		&employee.Name,
+		&employee.LastName,
+		&employee.PhoneNumber,
+		&employee.Email,
+		&employee.IdType,
+		&employee.IdNumber,
+		&employee.AdmissionDate,
+		&employee.Salary,
+		&employee.PositionId,
+		&employee.DepartmentId,
+		&employee.CompanyId,
+		&employee.PictureUrl,
+		&customFields,
+		&employee.Version,
+		&employee.CreatedAt,
+		&employee.UpdatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	employee.CustomFields, err = decodeCustomFields(customFields)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return employee, nil
+}
@@ -0,0 +1,353 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/audit"
+	"github.com/gioCuesta25/employees-manager-backend/models"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
+	"github.com/lib/pq"
+)
+
+// bulkEmployees applies a list of updates, transfers and terminations to
+// employees of the company. Each operation is validated on its own and
+// reported in the result of its index; each change is recorded in the audit
+// log, in the transaction that applies it.
+func (s *Server) bulkEmployees(ctx *gin.Context) {
+	var body models.BulkEmployeesBody
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if !s.requireCompanyRole(ctx, body.CompanyId, models.RoleHR) {
+		return
+	}
+
+	results := make([]*models.BulkEmployeeResult, len(body.Operations))
+	invalid := false
+
+	for i, operation := range body.Operations {
+		results[i] = &models.BulkEmployeeResult{
+			Index:      i,
+			Type:       operation.Type,
+			EmployeeId: operation.EmployeeId,
+			Status:     models.BulkResultNotApplied,
+		}
+
+		if err := validateBulkOperation(operation); err != nil {
+			failBulkResult(results[i], err)
+			invalid = true
+		}
+	}
+
+	if body.Mode == models.BulkModeTransactional {
+		if invalid {
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"results": results})
+			return
+		}
+
+		s.applyBulkTransaction(ctx, body, results)
+		return
+	}
+
+	applied := 0
+
+	for i, operation := range body.Operations {
+		if results[i].Status == models.BulkResultFailed {
+			continue
+		}
+
+		tx, err := s.db.Begin()
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		err = applyBulkOperation(tx, ctx, body.CompanyId, operation, results[i])
+
+		if err == nil {
+			err = tx.Commit()
+		}
+
+		tx.Rollback()
+
+		if err != nil {
+			if !isBulkItemError(err) {
+				utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+				return
+			}
+
+			failBulkResult(results[i], err)
+			continue
+		}
+
+		applied++
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"results": results,
+		"applied": applied,
+		"failed":  len(results) - applied,
+	})
+}
+
+// applyBulkTransaction applies every operation or, when one of them fails,
+// none of them
+func (s *Server) applyBulkTransaction(ctx *gin.Context, body models.BulkEmployeesBody, results []*models.BulkEmployeeResult) {
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	for i, operation := range body.Operations {
+		err := applyBulkOperation(tx, ctx, body.CompanyId, operation, results[i])
+
+		if err == nil {
+			continue
+		}
+
+		if !isBulkItemError(err) {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		// The operations before it were rolled back with it
+		for _, result := range results {
+			result.Status = models.BulkResultNotApplied
+			result.Employee = nil
+			result.Approval = nil
+		}
+
+		failBulkResult(results[i], err)
+
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"results": results})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"results": results,
+		"applied": len(results),
+		"failed":  0,
+	})
+}
+
+// validateBulkOperation checks what an operation needs without reading the
+// employee
+func validateBulkOperation(operation models.BulkEmployeeOperation) error {
+	if operation.EmployeeId == "" {
+		return invalidError("employee_id is required")
+	}
+
+	switch operation.Type {
+	case models.BulkOperationUpdate:
+		if !bytes.HasPrefix(bytes.TrimSpace(operation.Changes), []byte("{")) {
+			return invalidError("changes must be a JSON object")
+		}
+	case models.BulkOperationTransfer:
+		if operation.DepartmentId == nil && operation.PositionId == nil {
+			return invalidError("department_id or position_id is required")
+		}
+	case models.BulkOperationTerminate:
+		if operation.Date == nil || operation.Reason == "" {
+			return invalidError("date and reason are required")
+		}
+	default:
+		return invalidError("type must be one of update, transfer and terminate")
+	}
+
+	return nil
+}
+
+// applyBulkOperation applies the operation in the transaction and fills its
+// result. Errors that are the operation's fault are bulk item errors, see
+// isBulkItemError.
+func applyBulkOperation(tx *sql.Tx, ctx *gin.Context, companyId string, operation models.BulkEmployeeOperation, result *models.BulkEmployeeResult) error {
+	query := `SELECT ` + employeeColumns + ` FROM employees WHERE id::text = $1 AND company_id = $2 FOR UPDATE`
+
+	current, err := scanRowIntoEmployee(tx.QueryRow(query, operation.EmployeeId, companyId))
+
+	if err == sql.ErrNoRows {
+		return invalidError(fmt.Sprintf("employee %s not found", operation.EmployeeId))
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if operation.Version != 0 && operation.Version != current.Version {
+		return conflictError(errVersionMismatch.Error())
+	}
+
+	before, err := auditSnapshot(tx, auditedEntities["employee"], current.ID, "")
+
+	if err != nil {
+		return err
+	}
+
+	employee := current
+	status := models.BulkResultApplied
+
+	switch operation.Type {
+	case models.BulkOperationUpdate:
+		employee, err = patchEmployeeWith(tx, current, operation.Changes)
+	case models.BulkOperationTransfer:
+		employee, err = transferEmployee(tx, current, operation.DepartmentId, operation.PositionId)
+	case models.BulkOperationTerminate:
+		var terminated gin.H
+		result.Approval, terminated, err = requestTermination(tx, current.ID, calendarDate(*operation.Date), operation.Reason, ctx.GetString("userId"))
+
+		if err == nil && terminated == nil {
+			status = models.BulkResultPendingApproval
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	after, err := auditSnapshot(tx, auditedEntities["employee"], current.ID, "")
+
+	if err != nil {
+		return err
+	}
+
+	if len(audit.Diff(before, after)) > 0 {
+		if err := recordAudit(tx, ctx, "employee", current.ID, "bulk_"+operation.Type, before, after); err != nil {
+			return err
+		}
+
+		if operation.Type == models.BulkOperationTerminate {
+			employee, err = scanRowIntoEmployee(tx.QueryRow(`SELECT `+employeeColumns+` FROM employees WHERE id = $1`, current.ID))
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// A termination waiting for approval doesn't change the employee yet,
+	// the approval it opened is what gets logged
+	if status == models.BulkResultPendingApproval {
+		approval, err := auditSnapshot(tx, auditedEntities["approval"], result.Approval.ID, "")
+
+		if err != nil {
+			return err
+		}
+
+		if err := recordAudit(tx, ctx, "approval", result.Approval.ID, "bulk_"+operation.Type, nil, approval); err != nil {
+			return err
+		}
+	}
+
+	result.Status = status
+	result.Employee = employee
+
+	return nil
+}
+
+// patchEmployeeWith applies a merge patch to the locked employee
+func patchEmployeeWith(tx *sql.Tx, current *models.EmployeeResponse, patch []byte) (*models.EmployeeResponse, error) {
+	var body models.CreateEmployeeBody
+
+	changed, err := applyMergePatch(employeeBody(current), patch, &body)
+
+	if err != nil || len(changed) == 0 {
+		return current, err
+	}
+
+	return patchEmployee(tx, current.ID, body, changed, 0)
+}
+
+// transferEmployee moves the locked employee to the department, the
+// position or both. A position alone moves the employee to its department;
+// a department alone keeps the position, which must belong to it.
+func transferEmployee(tx *sql.Tx, current *models.EmployeeResponse, departmentId *string, positionId *string) (*models.EmployeeResponse, error) {
+	department := current.DepartmentId
+	position := current.PositionId
+
+	if departmentId != nil {
+		err := tx.QueryRow(`SELECT id FROM departments WHERE id::text = $1 AND company_id = $2`, *departmentId, current.CompanyId).
+			Scan(&department)
+
+		if err == sql.ErrNoRows {
+			return nil, invalidError(fmt.Sprintf("department %s not found", *departmentId))
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if positionId != nil {
+		position = *positionId
+	}
+
+	var positionDepartment string
+	err := tx.QueryRow(`SELECT id, department_id FROM positions WHERE id::text = $1 AND company_id = $2`, position, current.CompanyId).
+		Scan(&position, &positionDepartment)
+
+	if err == sql.ErrNoRows {
+		return nil, invalidError(fmt.Sprintf("position %s not found", position))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if departmentId == nil {
+		department = positionDepartment
+	}
+
+	if positionDepartment != department {
+		return nil, invalidError(fmt.Sprintf("position %s doesn't belong to department %s", position, department))
+	}
+
+	patch, err := json.Marshal(map[string]string{"department_id": department, "position_id": position})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return patchEmployeeWith(tx, current, patch)
+}
+
+// isBulkItemError reports whether the error fails the operation instead of
+// the request. Constraint violations count as such, as a patch pointing to
+// a position that doesn't exist.
+func isBulkItemError(err error) bool {
+	var conflict conflictError
+	var invalid invalidError
+	var pqErr *pq.Error
+
+	if errors.As(err, &pqErr) {
+		return pqErr.Code.Class() == "23"
+	}
+
+	return errors.As(err, &conflict) || errors.As(err, &invalid)
+}
+
+func failBulkResult(result *models.BulkEmployeeResult, err error) {
+	message := err.Error()
+
+	result.Status = models.BulkResultFailed
+	result.Error = &message
+	result.Employee = nil
+	result.Approval = nil
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
+)
+
+var errVersionMismatch = fmt.Errorf("the record was modified since it was read, fetch it again and retry")
+
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// etagMatches reports whether a list of If-Match or If-None-Match tags
+// holds the version. Weak tags compare as strong ones.
+func etagMatches(header string, version int) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+
+		if tag == "*" || tag == etag(version) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// notModified tags the response with the version and answers 304 when the
+// client already holds it
+func notModified(ctx *gin.Context, version int) bool {
+	ctx.Header("ETag", etag(version))
+
+	header := ctx.GetHeader("If-None-Match")
+
+	if header == "" || !etagMatches(header, version) {
+		return false
+	}
+
+	ctx.Status(http.StatusNotModified)
+	return true
+}
+
+// ifMatch checks the If-Match of a write against the version of the record
+// of the :id route parameter. The header is only required by companies that
+// enforce it. The checked version is kept in the context so the handler
+// writes only that version, as another request may change it meanwhile.
+func (s *Server) ifMatch(table string) gin.HandlerFunc {
+	query := `SELECT t.version, c.require_if_match FROM ` + table + ` t
+	JOIN companies c ON c.id = t.company_id
+	WHERE t.id = $1`
+
+	if table == "companies" {
+		query = `SELECT version, require_if_match FROM companies WHERE id = $1`
+	}
+
+	return func(ctx *gin.Context) {
+		var version int
+		var required bool
+
+		err := s.db.QueryRow(query, ctx.Param("id")).Scan(&version, &required)
+
+		// The handler answers for records that don't exist
+		if err == sql.ErrNoRows {
+			ctx.Next()
+			return
+		}
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			ctx.Abort()
+			return
+		}
+
+		header := ctx.GetHeader("If-Match")
+
+		if header == "" {
+			if required {
+				utils.ErrorResponse(ctx, fmt.Errorf("If-Match is required, send the ETag of the record"), http.StatusPreconditionRequired)
+				ctx.Abort()
+				return
+			}
+
+			ctx.Next()
+			return
+		}
+
+		if !etagMatches(header, version) {
+			ctx.Header("ETag", etag(version))
+			utils.ErrorResponse(ctx, errVersionMismatch, http.StatusPreconditionFailed)
+			ctx.Abort()
+			return
+		}
+
+		// * only asks for the record to exist
+		if strings.TrimSpace(header) != "*" {
+			ctx.Set("ifMatch", version)
+		}
+
+		ctx.Next()
+	}
+}
+
+// matchedVersion is the version checked by ifMatch, 0 when the write isn't
+// conditional
+func matchedVersion(ctx *gin.Context) int {
+	return ctx.GetInt("ifMatch")
+}
+
+// versionMismatch answers 412 when a conditional write found no record
+// because another request changed it after the check
+func versionMismatch(ctx *gin.Context, err error) bool {
+	if err != sql.ErrNoRows || matchedVersion(ctx) == 0 {
+		return false
+	}
+
+	utils.ErrorResponse(ctx, errVersionMismatch, http.StatusPreconditionFailed)
+	return true
+}
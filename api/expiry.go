@@ -0,0 +1,297 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/attendance"
+	"github.com/gioCuesta25/employees-manager-backend/contract"
+	"github.com/gioCuesta25/employees-manager-backend/expiry"
+	"github.com/gioCuesta25/employees-manager-backend/models"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
+)
+
+// expiredReminderDays is how long after the expiry date an item still gets
+// the expired reminder, so old documents don't flood the notifications the
+// first time the job runs.
+const expiredReminderDays = 7
+
+// getExpiringItems lists what expires in the next days, including what
+// already expired and wasn't replaced.
+func (s *Server) getExpiringItems(ctx *gin.Context) {
+	var params models.GetCompanyParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	within, err := expiry.ParseWithin(ctx.Query("within"))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	today := attendance.Day(time.Now())
+
+	items, err := findExpiringItems(s.db, `company_id = $1 AND due_date <= $2`, params.ID, today.AddDate(0, 0, within))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	for _, item := range items {
+		item.DaysLeft = expiry.DaysLeft(expiryDueDate(item), today)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"items": items, "within_days": within})
+}
+
+// sendExpiryReminders notifies the manager of the employee department and
+// the HR members of the company about the items reaching a reminder stage.
+// It runs on a schedule and relies on the notification dedup key to send
+// every stage only once.
+func (s *Server) sendExpiryReminders(ctx context.Context) error {
+	today := attendance.Day(time.Now())
+	maxThreshold := 0
+
+	for _, threshold := range expiry.Thresholds {
+		if threshold > maxThreshold {
+			maxThreshold = threshold
+		}
+	}
+
+	items, err := findExpiringItems(s.db, `due_date BETWEEN $1 AND $2`,
+		today.AddDate(0, 0, -expiredReminderDays), today.AddDate(0, 0, maxThreshold))
+
+	if err != nil {
+		return err
+	}
+
+	recipients := make(map[string][]string)
+
+	for _, item := range items {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		item.DaysLeft = expiry.DaysLeft(expiryDueDate(item), today)
+		stage, due := expiry.Stage(item.DaysLeft)
+
+		if !due {
+			continue
+		}
+
+		key := item.CompanyId + "/" + item.DepartmentId
+		users, ok := recipients[key]
+
+		if !ok {
+			users, err = findExpiryRecipients(s.db, item.CompanyId, item.DepartmentId)
+
+			if err != nil {
+				return err
+			}
+
+			recipients[key] = users
+		}
+
+		title, body := expiryMessage(item)
+		kind := models.NotificationKindDocumentExpiry
+		link := fmt.Sprintf("/employees/%s/documents", item.EmployeeId)
+
+		if item.Kind == models.ExpiringKindContract {
+			kind = models.NotificationKindContractExpiry
+			link = fmt.Sprintf("/contracts/%s", item.ID)
+		}
+
+		dedupKey := fmt.Sprintf("%s-expiry:%s:%s:%s", item.Kind, item.ID, item.ExpiresAt.Format("2006-01-02"), stage)
+
+		for _, userId := range users {
+			err := notify(s.db, models.Notification{
+				UserId:    userId,
+				CompanyId: &item.CompanyId,
+				Kind:      kind,
+				Title:     title,
+				Body:      body,
+				Link:      &link,
+				Data:      expiryData(item),
+				DedupKey:  &dedupKey,
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// expiryData is what the notification keeps of the item, and what its email
+// is written with
+func expiryData(item *models.ExpiringItemResponse) map[string]any {
+	data := map[string]any{
+		"kind":          item.Kind,
+		"id":            item.ID,
+		"employee_id":   item.EmployeeId,
+		"employee_name": item.EmployeeName,
+		"name":          item.Name,
+		"expires_at":    item.ExpiresAt.Format("2006-01-02"),
+		"days_left":     item.DaysLeft,
+	}
+
+	if item.NoticeBy != nil {
+		data["notice_by"] = item.NoticeBy.Format("2006-01-02")
+	}
+
+	return data
+}
+
+func expiryMessage(item *models.ExpiringItemResponse) (string, string) {
+	subject := fmt.Sprintf("%s of %s", item.Name, item.EmployeeName)
+	event := "expires"
+
+	if item.NoticeBy != nil {
+		subject = fmt.Sprintf("The notice deadline of the %s of %s", item.Name, item.EmployeeName)
+		event = "is"
+	}
+
+	var title string
+
+	switch {
+	case item.DaysLeft < 0 && item.NoticeBy != nil:
+		title = fmt.Sprintf("%s passed", subject)
+	case item.DaysLeft < 0:
+		title = fmt.Sprintf("%s expired", subject)
+	case item.DaysLeft == 0:
+		title = fmt.Sprintf("%s %s today", subject, event)
+	case item.DaysLeft == 1:
+		title = fmt.Sprintf("%s %s tomorrow", subject, event)
+	default:
+		title = fmt.Sprintf("%s %s in %d days", subject, event, item.DaysLeft)
+	}
+
+	body := fmt.Sprintf("The %s %s of %s expires on %s.", item.Category, item.Kind, item.EmployeeName, item.ExpiresAt.Format("2006-01-02"))
+
+	if item.NoticeBy != nil {
+		body += fmt.Sprintf(" Without written notice by %s it renews automatically for the same term.", item.NoticeBy.Format("2006-01-02"))
+	}
+
+	return title, body
+}
+
+func expiryDueDate(item *models.ExpiringItemResponse) time.Time {
+	if item.NoticeBy != nil {
+		return *item.NoticeBy
+	}
+
+	return item.ExpiresAt
+}
+
+// findExpiryRecipients returns the manager of the department, when one is
+// given, and the HR members of the company, or the owner when the company
+// has neither.
+func findExpiryRecipients(db queryer, companyId string, departmentId string) ([]string, error) {
+	query := `SELECT manager_id FROM departments WHERE $2 <> '' AND id::text = $2 AND manager_id IS NOT NULL
+	UNION
+	SELECT user_id FROM company_members WHERE company_id = $1 AND role = $3`
+
+	rows, err := db.Query(query, companyId, departmentId, models.RoleHR)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]string, 0)
+
+	for rows.Next() {
+		var userId string
+
+		if err := rows.Scan(&userId); err != nil {
+			return nil, err
+		}
+
+		users = append(users, userId)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(users) == 0 {
+		var owner string
+
+		if err := db.QueryRow(`SELECT owner FROM companies WHERE id = $1`, companyId).Scan(&owner); err != nil {
+			return nil, err
+		}
+
+		users = append(users, owner)
+	}
+
+	return users, nil
+}
+
+// findExpiringItems filters the documents and active contracts with an
+// expiry date. The condition can use company_id and due_date, the date the
+// item needs action by.
+func findExpiringItems(db queryer, where string, args ...any) ([]*models.ExpiringItemResponse, error) {
+	query := `SELECT kind, id, company_id, employee_id, employee_name, department_id, category, name, expires_at, notice_by
+	FROM (
+		SELECT 'document' AS kind, d.id, d.company_id, d.employee_id, e.name || ' ' || e.last_name AS employee_name,
+			e.department_id, d.category, d.name, d.expires_at, NULL::date AS notice_by, d.expires_at AS due_date
+		FROM employee_documents d
+		JOIN employees e ON e.id = d.employee_id
+		WHERE d.expires_at IS NOT NULL
+		UNION ALL
+		SELECT 'contract', c.id, c.company_id, c.employee_id, e.name || ' ' || e.last_name,
+			e.department_id, c.type, 'contract', c.end_date, n.notice_by, COALESCE(n.notice_by, c.end_date)
+		FROM contracts c
+		JOIN employees e ON e.id = c.employee_id
+		CROSS JOIN LATERAL (SELECT CASE WHEN c.type = $` + fmt.Sprint(len(args)+1) + ` THEN c.end_date - $` + fmt.Sprint(len(args)+2) + `::int END AS notice_by) n
+		WHERE c.status = $` + fmt.Sprint(len(args)+3) + ` AND c.end_date IS NOT NULL
+	) items
+	WHERE ` + where + `
+	ORDER BY due_date, employee_name`
+
+	args = append(args, contract.TypeFixedTerm, contract.NoticeDays, models.ContractStatusActive)
+
+	rows, err := db.Query(query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]*models.ExpiringItemResponse, 0)
+
+	for rows.Next() {
+		item := new(models.ExpiringItemResponse)
+
+		err := rows.Scan(
+			&item.Kind,
+			&item.ID,
+			&item.CompanyId,
+			&item.EmployeeId,
+			&item.EmployeeName,
+			&item.DepartmentId,
+			&item.Category,
+			&item.Name,
+			&item.ExpiresAt,
+			&item.NoticeBy,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
@@ -0,0 +1,358 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/jobs"
+	"github.com/gioCuesta25/employees-manager-backend/mailer"
+	"github.com/gioCuesta25/employees-manager-backend/models"
+	"github.com/gioCuesta25/employees-manager-backend/storage"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
+)
+
+const (
+	jobExpiryReminders     = "expiry_reminders"
+	jobExpiredContracts    = "expired_contracts"
+	jobApprovalEscalations = "approval_escalations"
+	jobEmployeesExport     = "employees_export"
+	jobCleanup             = "jobs_cleanup"
+	jobWebhooksDispatch    = "webhooks_dispatch"
+	jobEmailsSend          = "emails_send"
+	jobPayrollSnapshot     = "payroll_snapshot"
+	jobElectronicPayroll   = "electronic_payroll"
+)
+
+// Finished jobs and the files they made are kept this long. The recurring
+// jobs, which nobody started, only for a day.
+const (
+	jobRetention       = 30 * 24 * time.Hour
+	systemJobRetention = 24 * time.Hour
+)
+
+const jobColumns = `id, kind, status, attempts, max_attempts, run_at, company_id, created_by, result, last_error,
+	cancel_requested, started_at, finished_at, created_at, updated_at`
+
+// newJobQueue registers the jobs the API runs in the background
+func (s *Server) newJobQueue() *jobs.Queue {
+	queue := jobs.NewQueue(s.db, s.env.JobWorkers)
+
+	queue.Handle(jobExpiryReminders, withoutResult(s.sendExpiryReminders))
+	queue.Handle(jobExpiredContracts, withoutResult(s.renewExpiredContracts))
+	queue.Handle(jobApprovalEscalations, withoutResult(s.escalateApprovals))
+	queue.Handle(jobEmployeesExport, s.exportEmployeesJob)
+	queue.Handle(jobCleanup, withoutResult(s.cleanupJobs))
+	queue.Handle(jobWebhooksDispatch, withoutResult(s.dispatchWebhooks))
+	queue.Handle(jobEmailsSend, withoutResult(s.sendEmails))
+	queue.Handle(jobPayrollSnapshot, s.snapshotPayrollRunJob)
+	queue.Handle(jobElectronicPayroll, s.generateElectronicPayrollJob)
+
+	queue.Schedule(jobExpiryReminders, "0 * * * *")
+	queue.Schedule(jobExpiredContracts, "30 * * * *")
+	queue.Schedule(jobApprovalEscalations, "*/15 * * * *")
+	queue.Schedule(jobCleanup, "0 3 * * *")
+	queue.Schedule(jobWebhooksDispatch, "*/10 * * * * *")
+	queue.Schedule(jobEmailsSend, "*/10 * * * * *")
+
+	return queue
+}
+
+func withoutResult(run func(ctx context.Context) error) jobs.Handler {
+	return func(ctx context.Context, job *jobs.Job) (any, error) {
+		return nil, run(ctx)
+	}
+}
+
+// enqueueJob starts a job for the request and responds with its status,
+// which the client polls at /jobs/:id
+func (s *Server) enqueueJob(ctx *gin.Context, kind string, payload any, companyId string) {
+	id, err := jobs.Enqueue(s.db, kind, payload, jobs.Options{
+		CompanyId: companyId,
+		CreatedBy: ctx.GetString("userId"),
+	})
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	job, err := findJob(s.db, id)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.Header("Location", "/jobs/"+id)
+	ctx.JSON(http.StatusAccepted, gin.H{"job": job})
+}
+
+func (s *Server) getJob(ctx *gin.Context) {
+	job, ok := s.bindJob(ctx)
+
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"job": job})
+}
+
+// cancelJob cancels a waiting job right away. A running job is asked to
+// stop and is cancelled once its handler returns.
+func (s *Server) cancelJob(ctx *gin.Context) {
+	job, ok := s.bindJob(ctx)
+
+	if !ok {
+		return
+	}
+
+	query := `UPDATE jobs
+	SET cancel_requested = true,
+		status = CASE WHEN status = $1 THEN $2 ELSE status END,
+		finished_at = CASE WHEN status = $1 THEN now() ELSE finished_at END,
+		updated_at = now()
+	WHERE id = $3 AND status IN ($1, $4)
+	RETURNING ` + jobColumns
+
+	cancelled, err := scanRowIntoJob(s.db.QueryRow(query, jobs.StatusQueued, jobs.StatusCancelled, job.ID, jobs.StatusRunning))
+
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(ctx, fmt.Errorf("job %s already %s", job.ID, job.Status), http.StatusConflict)
+		return
+	}
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"job": cancelled})
+}
+
+// downloadJobFile sends the file made by a job, as an export
+func (s *Server) downloadJobFile(ctx *gin.Context) {
+	job, ok := s.bindJob(ctx)
+
+	if !ok {
+		return
+	}
+
+	var file jobFile
+
+	if job.Status != jobs.StatusSucceeded || json.Unmarshal(job.Result, &file) != nil || file.Key == "" {
+		utils.ErrorResponse(ctx, fmt.Errorf("job %s has no file to download", job.ID), http.StatusNotFound)
+		return
+	}
+
+	blob, err := s.blobs.Get(ctx.Request.Context(), file.Key)
+
+	if err != nil {
+		if err == storage.ErrNotFound {
+			utils.ErrorResponse(ctx, fmt.Errorf("the file of job %s expired", job.ID), http.StatusNotFound)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusBadGateway)
+		return
+	}
+	defer blob.Close()
+
+	headers := map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s"`, file.Filename),
+	}
+
+	ctx.DataFromReader(http.StatusOK, file.Size, file.ContentType, blob, headers)
+}
+
+// bindJob reads the job of the route. Jobs are visible to whoever started
+// them and to HR of their company.
+func (s *Server) bindJob(ctx *gin.Context) (*models.JobResponse, bool) {
+	var params models.GetJobParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return nil, false
+	}
+
+	job, err := findJob(s.db, params.ID)
+
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(ctx, fmt.Errorf("job %s not found", params.ID), http.StatusNotFound)
+		return nil, false
+	}
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if job.CreatedBy != nil && *job.CreatedBy == ctx.GetString("userId") {
+		return job, true
+	}
+
+	if job.CompanyId == nil {
+		utils.ErrorResponse(ctx, fmt.Errorf("job %s not found", params.ID), http.StatusNotFound)
+		return nil, false
+	}
+
+	if !s.requireCompanyRole(ctx, *job.CompanyId, models.RoleHR) {
+		return nil, false
+	}
+
+	return job, true
+}
+
+// jobFile is the result of the jobs that make a file
+type jobFile struct {
+	Key         string `json:"key"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Rows        int    `json:"rows"`
+}
+
+type employeesExportPayload struct {
+	CompanyId string `json:"company_id"`
+	Filter    string `json:"filter"`
+}
+
+func (s *Server) exportEmployeesJob(ctx context.Context, job *jobs.Job) (any, error) {
+	var payload employeesExportPayload
+
+	if err := job.Decode(&payload); err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	rows, err := writeEmployeesCSV(s.db, &buffer, payload.CompanyId, payload.Filter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	file := jobFile{
+		Key:         jobFileKey(job.ID, "csv"),
+		Filename:    "employees.csv",
+		ContentType: "text/csv; charset=utf-8",
+		Size:        int64(buffer.Len()),
+		Rows:        rows,
+	}
+
+	if err := s.blobs.Put(ctx, file.Key, &buffer, file.Size, file.ContentType); err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// cleanupJobs deletes the jobs that finished before the retention and the
+// files they made, the webhook events and emails delivered by then and the
+// expired account tokens
+func (s *Server) cleanupJobs(ctx context.Context) error {
+	cutoff := time.Now().Add(-jobRetention)
+
+	rows, err := s.db.Query(`SELECT id, result FROM jobs WHERE finished_at < $1 AND kind = $2 AND status = $3`,
+		cutoff, jobEmployeesExport, jobs.StatusSucceeded)
+
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0)
+
+	for rows.Next() {
+		var id string
+		var result []byte
+
+		if err := rows.Scan(&id, &result); err != nil {
+			rows.Close()
+			return err
+		}
+
+		var file jobFile
+
+		if json.Unmarshal(result, &file) == nil && file.Key != "" {
+			keys = append(keys, file.Key)
+		}
+	}
+
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := s.blobs.Delete(ctx, key); err != nil && err != storage.ErrNotFound {
+			return err
+		}
+	}
+
+	result, err := s.db.Exec(`DELETE FROM jobs WHERE finished_at < $1 OR (created_by IS NULL AND finished_at < $2)`,
+		cutoff, time.Now().Add(-systemJobRetention))
+
+	if err != nil {
+		return err
+	}
+
+	if deleted, _ := result.RowsAffected(); deleted > 0 {
+		log.Printf("jobs: deleted %d finished jobs", deleted)
+	}
+
+	// Their deliveries go with them
+	if _, err := s.db.Exec(`DELETE FROM outbox_events WHERE processed_at < $1`, cutoff); err != nil {
+		return err
+	}
+
+	if _, err := s.db.Exec(`DELETE FROM email_outbox WHERE status <> $1 AND updated_at < $2`, mailer.StatusPending, cutoff); err != nil {
+		return err
+	}
+
+	if _, err := s.db.Exec(`DELETE FROM user_tokens WHERE expires_at < $1`, time.Now().Add(-systemJobRetention)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func jobFileKey(id string, extension string) string {
+	return "jobs/" + id + "." + extension
+}
+
+func findJob(db queryer, id string) (*models.JobResponse, error) {
+	return scanRowIntoJob(db.QueryRow(`SELECT `+jobColumns+` FROM jobs WHERE id::text = $1`, id))
+}
+
+func scanRowIntoJob(row rowScanner) (*models.JobResponse, error) {
+	job := new(models.JobResponse)
+
+	err := row.Scan(
+		&job.ID,
+		&job.Kind,
+		&job.Status,
+		&job.Attempts,
+		&job.MaxAttempts,
+		&job.RunAt,
+		&job.CompanyId,
+		&job.CreatedBy,
+		&job.Result,
+		&job.LastError,
+		&job.CancelRequested,
+		&job.StartedAt,
+		&job.FinishedAt,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
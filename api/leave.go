@@ -0,0 +1,1029 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/attendance"
+	"github.com/gioCuesta25/employees-manager-backend/leave"
+	"github.com/gioCuesta25/employees-manager-backend/models"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
+	"github.com/lib/pq"
+)
+
+const leaveTypeColumns = `id, company_id, name, category, paid, days_per_year, max_carry_over, approval_chain, created_at, updated_at`
+
+const leaveRequestColumns = `id, company_id, employee_id, leave_type_id, start_date, end_date, days, reason, status, current_step, requested_by, created_at, updated_at`
+
+var defaultApprovalChain = []string{models.RoleManager, models.RoleHR}
+
+func (s *Server) createLeaveType(ctx *gin.Context) {
+	var body models.CreateLeaveTypeBody
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	leaveType := newLeaveType(body)
+
+	query := `INSERT INTO leave_types
+	(company_id, name, category, paid, days_per_year, max_carry_over, approval_chain)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING ` + leaveTypeColumns
+
+	row := s.db.QueryRow(query,
+		leaveType.CompanyId,
+		leaveType.Name,
+		leaveType.Category,
+		leaveType.Paid,
+		leaveType.DaysPerYear,
+		leaveType.MaxCarryOver,
+		pq.Array(leaveType.ApprovalChain))
+
+	leaveType, err := scanRowIntoLeaveType(row)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"leave_type": leaveType})
+}
+
+// createDefaultLeaveTypes adds the leave types every Colombian company needs
+func (s *Server) createDefaultLeaveTypes(ctx *gin.Context) {
+	var body models.CreateDefaultLeaveTypesBody
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	hrOnly := []string{models.RoleHR}
+	defaults := []models.CreateLeaveTypeBody{
+		{Name: "Vacaciones", Category: models.LeaveCategoryVacation},
+		{Name: "Incapacidad", Category: models.LeaveCategorySick, ApprovalChain: hrOnly},
+		{Name: "Licencia de maternidad", Category: models.LeaveCategoryMaternity, ApprovalChain: hrOnly},
+		{Name: "Licencia de paternidad", Category: models.LeaveCategoryPaternity, ApprovalChain: hrOnly},
+		{Name: "Licencia no remunerada", Category: models.LeaveCategoryUnpaid},
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO leave_types
+	(company_id, name, category, paid, days_per_year, max_carry_over, approval_chain)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING ` + leaveTypeColumns
+
+	leaveTypes := make([]*models.LeaveTypeResponse, 0)
+
+	for _, d := range defaults {
+		d.CompanyId = body.CompanyId
+		leaveType := newLeaveType(d)
+
+		row := tx.QueryRow(query,
+			leaveType.CompanyId,
+			leaveType.Name,
+			leaveType.Category,
+			leaveType.Paid,
+			leaveType.DaysPerYear,
+			leaveType.MaxCarryOver,
+			pq.Array(leaveType.ApprovalChain))
+
+		leaveType, err := scanRowIntoLeaveType(row)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		leaveTypes = append(leaveTypes, leaveType)
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"leave_types": leaveTypes})
+}
+
+func (s *Server) listLeaveTypes(ctx *gin.Context) {
+	companyId := ctx.DefaultQuery("company_id", "")
+
+	if companyId == "" {
+		utils.ErrorResponse(ctx, fmt.Errorf("company_id is required"), http.StatusBadRequest)
+		return
+	}
+
+	rows, err := s.db.Query(`SELECT `+leaveTypeColumns+` FROM leave_types WHERE company_id = $1 ORDER BY name`, companyId)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	leaveTypes := make([]*models.LeaveTypeResponse, 0)
+
+	for rows.Next() {
+		leaveType, err := scanRowIntoLeaveType(rows)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		leaveTypes = append(leaveTypes, leaveType)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"leave_types": leaveTypes})
+}
+
+func (s *Server) updateLeaveType(ctx *gin.Context) {
+	var params models.GetLeaveTypeParams
+	var body models.CreateLeaveTypeBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	leaveType := newLeaveType(body)
+
+	query := `UPDATE leave_types
+	SET name = $1,
+		category = $2,
+		paid = $3,
+		days_per_year = $4,
+		max_carry_over = $5,
+		approval_chain = $6,
+		updated_at = $7
+	WHERE id = $8
+	RETURNING ` + leaveTypeColumns
+
+	row := s.db.QueryRow(query,
+		leaveType.Name,
+		leaveType.Category,
+		leaveType.Paid,
+		leaveType.DaysPerYear,
+		leaveType.MaxCarryOver,
+		pq.Array(leaveType.ApprovalChain),
+		time.Now(),
+		params.ID)
+
+	leaveType, err := scanRowIntoLeaveType(row)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(ctx, fmt.Errorf("leave type %s not found", params.ID), http.StatusNotFound)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"leave_type": leaveType})
+}
+
+func (s *Server) deleteLeaveType(ctx *gin.Context) {
+	var params models.GetLeaveTypeParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	var inUse bool
+	err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM leave_requests WHERE leave_type_id = $1)`, params.ID).Scan(&inUse)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if inUse {
+		utils.ErrorResponse(ctx, fmt.Errorf("leave type %s has requests and can't be deleted", params.ID), http.StatusConflict)
+		return
+	}
+
+	if _, err := s.db.Exec(`DELETE FROM leave_types WHERE id = $1`, params.ID); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+func (s *Server) createLeaveRequest(ctx *gin.Context) {
+	var body models.CreateLeaveRequestBody
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if body.EndDate.Before(body.StartDate) {
+		utils.ErrorResponse(ctx, fmt.Errorf("end_date must be after start_date"), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// Locking the employee serializes the overlap and balance checks
+	var companyId, departmentId string
+	var admissionDate time.Time
+	var departmentManager *string
+
+	employeeQuery := `SELECT e.company_id, e.department_id, e.admission_date, d.manager_id
+	FROM employees e
+	LEFT JOIN departments d ON d.id = e.department_id
+	WHERE e.id = $1
+	FOR UPDATE OF e`
+
+	err = tx.QueryRow(employeeQuery, body.EmployeeId).Scan(&companyId, &departmentId, &admissionDate, &departmentManager)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(ctx, fmt.Errorf("employee %s not found", body.EmployeeId), http.StatusNotFound)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	leaveType, err := scanRowIntoLeaveType(tx.QueryRow(`SELECT `+leaveTypeColumns+` FROM leave_types WHERE id = $1`, body.LeaveTypeId))
+
+	if err != nil || leaveType.CompanyId != companyId {
+		if err == nil || err == sql.ErrNoRows {
+			utils.ErrorResponse(ctx, fmt.Errorf("leave type %s not found for the employee company", body.LeaveTypeId), http.StatusBadRequest)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	cal, _, err := loadCompanyCalendar(tx, companyId)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	days := float64(cal.WorkingDaysBetween(body.StartDate, body.EndDate))
+
+	if days == 0 {
+		utils.ErrorResponse(ctx, fmt.Errorf("the request doesn't include any working day"), http.StatusBadRequest)
+		return
+	}
+
+	var overlapping string
+	overlapQuery := `SELECT id FROM leave_requests
+	WHERE employee_id = $1 AND status IN ('pending', 'approved') AND start_date <= $3 AND end_date >= $2
+	LIMIT 1`
+
+	err = tx.QueryRow(overlapQuery, body.EmployeeId, body.StartDate, body.EndDate).Scan(&overlapping)
+
+	if err == nil {
+		utils.ErrorResponse(ctx, fmt.Errorf("the request overlaps with leave request %s", overlapping), http.StatusConflict)
+		return
+	}
+
+	if err != sql.ErrNoRows {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if leaveType.DaysPerYear > 0 {
+		balance, err := leaveBalance(tx, body.EmployeeId, admissionDate, leaveType, body.StartDate)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		if days > balance.Available {
+			utils.ErrorResponse(ctx, fmt.Errorf("insufficient balance: %.2f days requested, %.2f available", days, balance.Available), http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	// Steps without anyone to approve them, like the manager step of a
+	// department without manager, are skipped.
+	steps := make([]string, len(leaveType.ApprovalChain))
+	currentStep := 0
+
+	for i, role := range leaveType.ApprovalChain {
+		steps[i] = models.LeaveStatusPending
+
+		if role == models.RoleManager && departmentManager == nil {
+			steps[i] = models.LeaveStatusSkipped
+			continue
+		}
+
+		if currentStep == 0 {
+			currentStep = i + 1
+		}
+	}
+
+	status := models.LeaveStatusPending
+	if currentStep == 0 {
+		status = models.LeaveStatusApproved
+	}
+
+	insert := `INSERT INTO leave_requests
+	(company_id, employee_id, leave_type_id, start_date, end_date, days, reason, status, current_step, requested_by)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	RETURNING ` + leaveRequestColumns
+
+	row := tx.QueryRow(insert,
+		companyId,
+		body.EmployeeId,
+		leaveType.ID,
+		body.StartDate,
+		body.EndDate,
+		days,
+		body.Reason,
+		status,
+		currentStep,
+		ctx.GetString("userId"))
+
+	request, err := scanRowIntoLeaveRequest(row)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	for i, role := range leaveType.ApprovalChain {
+		_, err := tx.Exec(`INSERT INTO leave_request_approvals (leave_request_id, step, role, status) VALUES ($1, $2, $3, $4)`,
+			request.ID, i+1, role, steps[i])
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	request.Approvals, err = findLeaveApprovals(s.db, request.ID)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"leave_request": request})
+}
+
+func (s *Server) listLeaveRequests(ctx *gin.Context) {
+	companyId := ctx.DefaultQuery("company_id", "")
+	employeeId := ctx.DefaultQuery("employee_id", "")
+	status := ctx.DefaultQuery("status", "")
+
+	if companyId == "" && employeeId == "" {
+		utils.ErrorResponse(ctx, fmt.Errorf("company_id or employee_id is required"), http.StatusBadRequest)
+		return
+	}
+
+	pageNumber, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("size", "10"))
+
+	offset := (pageNumber - 1) * pageSize
+
+	filter := `WHERE ($1 = '' OR company_id::text = $1) AND ($2 = '' OR employee_id::text = $2) AND ($3 = '' OR status = $3)`
+
+	query := `SELECT ` + leaveRequestColumns + ` FROM leave_requests ` + filter + `
+	ORDER BY start_date DESC
+	LIMIT $4
+	OFFSET $5`
+
+	rows, err := s.db.Query(query, companyId, employeeId, status, pageSize, offset)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var totalItems int
+	err = s.db.QueryRow(`SELECT COUNT(*) FROM leave_requests `+filter, companyId, employeeId, status).Scan(&totalItems)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	totalPages := int(math.Ceil(float64(totalItems) / float64(pageSize)))
+	var nextPage, prevPage *int
+
+	if pageNumber < totalPages {
+		nextPageNum := pageNumber + 1
+		nextPage = &nextPageNum
+	}
+
+	if pageNumber > 1 {
+		prevPageNum := pageNumber - 1
+		prevPage = &prevPageNum
+	}
+
+	requests := make([]*models.LeaveRequestResponse, 0)
+
+	for rows.Next() {
+		request, err := scanRowIntoLeaveRequest(rows)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		requests = append(requests, request)
+	}
+
+	result := models.PaginatedResult{
+		Data:       requests,
+		PageNumber: pageNumber,
+		PageSize:   pageSize,
+		TotalItems: totalItems,
+		NextPage:   nextPage,
+		PrevPage:   prevPage,
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+func (s *Server) getLeaveRequest(ctx *gin.Context) {
+	var params models.GetLeaveRequestParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	request, err := scanRowIntoLeaveRequest(s.db.QueryRow(`SELECT `+leaveRequestColumns+` FROM leave_requests WHERE id = $1`, params.ID))
+
+	if err != nil {
+		leaveRequestErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	request.Approvals, err = findLeaveApprovals(s.db, request.ID)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"leave_request": request})
+}
+
+func (s *Server) approveLeaveRequest(ctx *gin.Context) {
+	s.decideLeaveRequest(ctx, models.LeaveStatusApproved)
+}
+
+func (s *Server) rejectLeaveRequest(ctx *gin.Context) {
+	s.decideLeaveRequest(ctx, models.LeaveStatusRejected)
+}
+
+// decideLeaveRequest records the decision of the current step approver. An
+// approval moves the request to the next pending step or approves it when
+// it was the last one, a rejection ends the request.
+func (s *Server) decideLeaveRequest(ctx *gin.Context, decision string) {
+	var params models.GetLeaveRequestParams
+	var body models.DecideLeaveRequestBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	userId := ctx.GetString("userId")
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	request, err := scanRowIntoLeaveRequest(tx.QueryRow(`SELECT `+leaveRequestColumns+` FROM leave_requests WHERE id = $1 FOR UPDATE`, params.ID))
+
+	if err != nil {
+		leaveRequestErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if request.Status != models.LeaveStatusPending {
+		utils.ErrorResponse(ctx, fmt.Errorf("leave request %s is %s", request.ID, request.Status), http.StatusConflict)
+		return
+	}
+
+	var role string
+	err = tx.QueryRow(`SELECT role FROM leave_request_approvals WHERE leave_request_id = $1 AND step = $2`, request.ID, request.CurrentStep).Scan(&role)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	allowed, err := canApproveLeave(tx, request, role, userId)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if !allowed {
+		utils.ErrorResponse(ctx, fmt.Errorf("you are not the %s approver of this request", role), http.StatusForbidden)
+		return
+	}
+
+	_, err = tx.Exec(`UPDATE leave_request_approvals
+	SET status = $1, decided_by = $2, decided_at = $3, comment = $4
+	WHERE leave_request_id = $5 AND step = $6`,
+		decision, userId, time.Now(), body.Comment, request.ID, request.CurrentStep)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	status := models.LeaveStatusRejected
+	nextStep := request.CurrentStep
+
+	if decision == models.LeaveStatusApproved {
+		err = tx.QueryRow(`SELECT COALESCE(MIN(step), 0) FROM leave_request_approvals WHERE leave_request_id = $1 AND step > $2 AND status = 'pending'`,
+			request.ID, request.CurrentStep).Scan(&nextStep)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		status = models.LeaveStatusPending
+		if nextStep == 0 {
+			status = models.LeaveStatusApproved
+			nextStep = request.CurrentStep
+		}
+	}
+
+	update := `UPDATE leave_requests SET status = $1, current_step = $2, updated_at = $3 WHERE id = $4 RETURNING ` + leaveRequestColumns
+
+	request, err = scanRowIntoLeaveRequest(tx.QueryRow(update, status, nextStep, time.Now(), request.ID))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	request.Approvals, err = findLeaveApprovals(tx, request.ID)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if request.Status != models.LeaveStatusPending {
+		if err := notifyLeaveDecision(tx, request, userId, body.Comment); err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"leave_request": request})
+}
+
+// notifyLeaveDecision tells the employee, or whoever requested the leave
+// for them, that the request was approved or rejected
+func notifyLeaveDecision(db queryer, request *models.LeaveRequestResponse, decidedBy string, comment *string) error {
+	var recipient, leaveType string
+
+	query := `SELECT COALESCE(e.user_id, $2::uuid), t.name
+	FROM employees e, leave_types t
+	WHERE e.id = $1 AND t.id = $3`
+
+	if err := db.QueryRow(query, request.EmployeeId, request.RequestedBy, request.LeaveTypeId).Scan(&recipient, &leaveType); err != nil {
+		return err
+	}
+
+	if recipient == decidedBy {
+		return nil
+	}
+
+	startDate := request.StartDate.Format("2006-01-02")
+	endDate := request.EndDate.Format("2006-01-02")
+
+	data := map[string]any{
+		"leave_request_id": request.ID,
+		"leave_type":       leaveType,
+		"start_date":       startDate,
+		"end_date":         endDate,
+		"status":           request.Status,
+	}
+
+	if comment != nil {
+		data["comment"] = *comment
+	}
+
+	link := fmt.Sprintf("/leave-requests/%s", request.ID)
+	dedupKey := fmt.Sprintf("leave-decision:%s", request.ID)
+
+	return notify(db, models.Notification{
+		UserId:    recipient,
+		CompanyId: &request.CompanyId,
+		Kind:      models.NotificationKindLeaveRequestDecided,
+		Title:     fmt.Sprintf("Your %s request was %s", leaveType, request.Status),
+		Body:      fmt.Sprintf("The %s request from %s to %s was %s.", leaveType, startDate, endDate, request.Status),
+		Link:      &link,
+		Data:      data,
+		DedupKey:  &dedupKey,
+	})
+}
+
+// cancelLeaveRequest lets the requester or HR withdraw a request that is
+// still pending or an approved one that hasn't started yet.
+func (s *Server) cancelLeaveRequest(ctx *gin.Context) {
+	var params models.GetLeaveRequestParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	userId := ctx.GetString("userId")
+
+	request, err := scanRowIntoLeaveRequest(s.db.QueryRow(`SELECT `+leaveRequestColumns+` FROM leave_requests WHERE id = $1`, params.ID))
+
+	if err != nil {
+		leaveRequestErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if request.RequestedBy != userId {
+		allowed, err := hasCompanyRole(s.db, request.CompanyId, userId, models.RoleHR)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		if !allowed {
+			utils.ErrorResponse(ctx, fmt.Errorf("only the requester or HR can cancel a leave request"), http.StatusForbidden)
+			return
+		}
+	}
+
+	update := `UPDATE leave_requests
+	SET status = 'cancelled', updated_at = $1
+	WHERE id = $2 AND (status = 'pending' OR (status = 'approved' AND start_date > $1))
+	RETURNING ` + leaveRequestColumns
+
+	request, err = scanRowIntoLeaveRequest(s.db.QueryRow(update, time.Now(), params.ID))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(ctx, fmt.Errorf("leave request %s can no longer be cancelled", params.ID), http.StatusConflict)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"leave_request": request})
+}
+
+func (s *Server) getLeaveBalances(ctx *gin.Context) {
+	var params models.GetEmployeeParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	asOf := time.Now()
+
+	if value := ctx.Query("as_of"); value != "" {
+		date, err := time.Parse("2006-01-02", value)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, fmt.Errorf("as_of must be a date as YYYY-MM-DD"), http.StatusBadRequest)
+			return
+		}
+
+		asOf = date
+	}
+
+	var companyId string
+	var admissionDate time.Time
+
+	err := s.db.QueryRow(`SELECT company_id, admission_date FROM employees WHERE id = $1`, params.ID).Scan(&companyId, &admissionDate)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(ctx, fmt.Errorf("employee %s not found", params.ID), http.StatusNotFound)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := s.db.Query(`SELECT `+leaveTypeColumns+` FROM leave_types WHERE company_id = $1 ORDER BY name`, companyId)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	var leaveTypes []*models.LeaveTypeResponse
+
+	for rows.Next() {
+		leaveType, err := scanRowIntoLeaveType(rows)
+
+		if err != nil {
+			rows.Close()
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		leaveTypes = append(leaveTypes, leaveType)
+	}
+	rows.Close()
+
+	balances := make([]models.LeaveBalanceResponse, 0)
+
+	for _, leaveType := range leaveTypes {
+		balance, err := leaveBalance(s.db, params.ID, admissionDate, leaveType, asOf)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		balances = append(balances, models.LeaveBalanceResponse{
+			LeaveTypeId: leaveType.ID,
+			Name:        leaveType.Name,
+			Category:    leaveType.Category,
+			Balance:     balance,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"balances": balances})
+}
+
+// leaveBalance computes the balance of one leave type from the approved and
+// pending requests of the employee.
+func leaveBalance(db queryer, employeeId string, admissionDate time.Time, leaveType *models.LeaveTypeResponse, asOf time.Time) (leave.Balance, error) {
+	query := `SELECT start_date, days, status FROM leave_requests
+	WHERE employee_id = $1 AND leave_type_id = $2 AND status IN ('pending', 'approved')`
+
+	rows, err := db.Query(query, employeeId, leaveType.ID)
+
+	if err != nil {
+		return leave.Balance{}, err
+	}
+	defer rows.Close()
+
+	var taken, pending []leave.Taken
+
+	for rows.Next() {
+		var t leave.Taken
+		var status string
+
+		if err := rows.Scan(&t.Date, &t.Days, &status); err != nil {
+			return leave.Balance{}, err
+		}
+
+		if status == models.LeaveStatusApproved {
+			taken = append(taken, t)
+		} else {
+			pending = append(pending, t)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return leave.Balance{}, err
+	}
+
+	policy := leave.Policy{DaysPerYear: leaveType.DaysPerYear, MaxCarryOver: leaveType.MaxCarryOver}
+
+	return leave.Calculate(policy, admissionDate, asOf, taken, pending), nil
+}
+
+// canApproveLeave reports whether the user, or someone who delegated their
+// approvals to them, can decide the step with the given role. The company
+// owner can decide any step. Nobody decides their own leave, not even its
+// manager or the owner.
+func canApproveLeave(db queryer, request *models.LeaveRequestResponse, role string, userId string) (bool, error) {
+	if userId == request.RequestedBy {
+		return false, nil
+	}
+
+	var own bool
+	query := `SELECT EXISTS (SELECT 1 FROM employees WHERE id = $1 AND user_id::text = $2)`
+
+	if err := db.QueryRow(query, request.EmployeeId, userId).Scan(&own); err != nil || own {
+		return false, err
+	}
+
+	users, err := actingUsers(db, request.CompanyId, userId, attendance.Day(time.Now()))
+
+	if err != nil {
+		return false, err
+	}
+
+	for _, user := range users {
+		allowed, err := hasEmployeeRole(db, request.CompanyId, request.EmployeeId, role, user)
+
+		if err != nil || allowed {
+			return allowed, err
+		}
+	}
+
+	return false, nil
+}
+
+// hasEmployeeRole reports whether the user acts with the role for the
+// employee: the manager of their department or a company member with the
+// role. The company owner acts with any role.
+func hasEmployeeRole(db queryer, companyId string, employeeId string, role string, userId string) (bool, error) {
+	switch role {
+	case models.RoleManager:
+		var isManager bool
+		query := `SELECT EXISTS (
+			SELECT 1 FROM employees e JOIN departments d ON d.id = e.department_id
+			WHERE e.id = $1 AND d.manager_id::text = $2
+		)`
+
+		if err := db.QueryRow(query, employeeId, userId).Scan(&isManager); err != nil {
+			return false, err
+		}
+
+		if isManager {
+			return true, nil
+		}
+
+		return hasCompanyRole(db, companyId, userId)
+	case models.RoleHR:
+		return hasCompanyRole(db, companyId, userId, models.RoleHR)
+	default:
+		return hasCompanyRole(db, companyId, userId)
+	}
+}
+
+func findLeaveApprovals(db queryer, requestId string) ([]*models.LeaveApprovalResponse, error) {
+	rows, err := db.Query(`SELECT step, role, status, decided_by, decided_at, comment
+	FROM leave_request_approvals
+	WHERE leave_request_id = $1
+	ORDER BY step`, requestId)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	approvals := make([]*models.LeaveApprovalResponse, 0)
+
+	for rows.Next() {
+		approval := new(models.LeaveApprovalResponse)
+
+		err := rows.Scan(&approval.Step,
+			&approval.Role,
+			&approval.Status,
+			&approval.DecidedBy,
+			&approval.DecidedAt,
+			&approval.Comment)
+
+		if err != nil {
+			return nil, err
+		}
+
+		approvals = append(approvals, approval)
+	}
+
+	return approvals, rows.Err()
+}
+
+func leaveRequestErrorResponse(ctx *gin.Context, err error, id string) {
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(ctx, fmt.Errorf("leave request %s not found", id), http.StatusNotFound)
+		return
+	}
+	utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+}
+
+// newLeaveType fills the defaults of the fields the body leaves empty
+func newLeaveType(body models.CreateLeaveTypeBody) *models.LeaveTypeResponse {
+	leaveType := &models.LeaveTypeResponse{
+		CompanyId:     body.CompanyId,
+		Name:          body.Name,
+		Category:      body.Category,
+		Paid:          body.Category != models.LeaveCategoryUnpaid,
+		MaxCarryOver:  body.MaxCarryOver,
+		ApprovalChain: body.ApprovalChain,
+	}
+
+	if body.Paid != nil {
+		leaveType.Paid = *body.Paid
+	}
+
+	if body.DaysPerYear != nil {
+		leaveType.DaysPerYear = *body.DaysPerYear
+	} else if body.Category == models.LeaveCategoryVacation {
+		leaveType.DaysPerYear = leave.DefaultVacationDays
+	}
+
+	if leaveType.ApprovalChain == nil {
+		leaveType.ApprovalChain = defaultApprovalChain
+	}
+
+	return leaveType
+}
+
+func scanRowIntoLeaveType(row rowScanner) (*models.LeaveTypeResponse, error) {
+	leaveType := new(models.LeaveTypeResponse)
+
+	err := row.Scan(
+		&leaveType.ID,
+		&leaveType.CompanyId,
+		&leaveType.Name,
+		&leaveType.Category,
+		&leaveType.Paid,
+		&leaveType.DaysPerYear,
+		&leaveType.MaxCarryOver,
+		pq.Array(&leaveType.ApprovalChain),
+		&leaveType.CreatedAt,
+		&leaveType.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return leaveType, nil
+}
+
+func scanRowIntoLeaveRequest(row rowScanner) (*models.LeaveRequestResponse, error) {
+	request := new(models.LeaveRequestResponse)
+
+	err := row.Scan(
+		&request.ID,
+		&request.CompanyId,
+		&request.EmployeeId,
+		&request.LeaveTypeId,
+		&request.StartDate,
+		&request.EndDate,
+		&request.Days,
+		&request.Reason,
+		&request.Status,
+		&request.CurrentStep,
+		&request.RequestedBy,
+		&request.CreatedAt,
+		&request.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gioCuesta25/employees-manager-backend/mergepatch"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
+)
+
+const mergePatchMIME = "application/merge-patch+json"
+
+// bindMergePatch applies the JSON Merge Patch of the request to current,
+// the body of the record as it is stored, and binds the result into body
+// with the validation of a create. It returns the members the patch
+// changed.
+func bindMergePatch(ctx *gin.Context, current any, body any) ([]string, bool) {
+	switch ctx.ContentType() {
+	case "", binding.MIMEJSON, mergePatchMIME:
+	default:
+		utils.ErrorResponse(ctx, fmt.Errorf("send the changes as a JSON Merge Patch (%s)", mergePatchMIME), http.StatusUnsupportedMediaType)
+		return nil, false
+	}
+
+	patch, err := ctx.GetRawData()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return nil, false
+	}
+
+	if !bytes.HasPrefix(bytes.TrimSpace(patch), []byte("{")) {
+		utils.ErrorResponse(ctx, fmt.Errorf("the patch must be a JSON object"), http.StatusBadRequest)
+		return nil, false
+	}
+
+	changed, err := applyMergePatch(current, patch, body)
+
+	if err != nil {
+		var invalid invalidError
+
+		if errors.As(err, &invalid) {
+			utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+			return nil, false
+		}
+
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return changed, true
+}
+
+// applyMergePatch is bindMergePatch for patches that don't come as the
+// request body. Patches that can't be applied return an invalidError.
+func applyMergePatch(current any, patch []byte, body any) ([]string, error) {
+	document, err := json.Marshal(current)
+
+	if err != nil {
+		return nil, err
+	}
+
+	merged, err := mergepatch.Apply(document, patch)
+
+	if err != nil {
+		return nil, invalidError(err.Error())
+	}
+
+	// Unknown members are rejected instead of ignored, as they are likely a
+	// typo of the client
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(body); err != nil {
+		return nil, invalidError(err.Error())
+	}
+
+	if err := binding.Validator.ValidateStruct(body); err != nil {
+		return nil, invalidError(err.Error())
+	}
+
+	normalized, err := json.Marshal(body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return mergepatch.Changed(document, normalized)
+}
+
+// patchQuery builds the update of the changed columns of a record, guarded
+// by the version when it isn't 0. Columns are the patchable members with
+// their values; changing any other member is an error.
+func patchQuery(table string, id string, version int, columns map[string]any, changed []string, returning string) (string, []any, error) {
+	assignments := make([]string, 0, len(changed)+1)
+	args := make([]any, 0, len(changed)+3)
+
+	for _, column := range changed {
+		value, ok := columns[column]
+
+		if !ok {
+			return "", nil, invalidError(column + " can't be changed")
+		}
+
+		args = append(args, value)
+		assignments = append(assignments, column+" = $"+strconv.Itoa(len(args)))
+	}
+
+	n := len(args)
+	assignments = append(assignments, "updated_at = $"+strconv.Itoa(n+1))
+
+	query := `UPDATE ` + table + `
+	SET ` + strings.Join(assignments, ", ") + `
+	WHERE id = $` + strconv.Itoa(n+2) + ` AND ($` + strconv.Itoa(n+3) + ` = 0 OR version = $` + strconv.Itoa(n+3) + `)
+	RETURNING ` + returning
+
+	return query, append(args, time.Now(), id, version), nil
+}
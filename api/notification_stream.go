@@ -0,0 +1,355 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/database"
+	"github.com/gioCuesta25/employees-manager-backend/models"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
+	"github.com/lib/pq"
+)
+
+// notificationsChannel is where the notifications trigger announces the
+// new and read notifications
+const notificationsChannel = "notifications"
+
+const (
+	// Comments sent on idle streams so proxies don't close them
+	streamHeartbeat = 25 * time.Second
+
+	// Events buffered for a stream. A stream that falls further behind
+	// misses events until it reads again.
+	streamBuffer = 32
+
+	// How long a stream ticket can wait to open its stream
+	streamTicketTTL = time.Minute
+)
+
+// tokenPurposeNotificationStream are the tickets that open a stream
+const tokenPurposeNotificationStream = "notification_stream"
+
+const (
+	notificationCreated = "created"
+	notificationRead    = "read"
+	// Events may have been missed, as while the listener reconnected
+	notificationResync = "resync"
+)
+
+// notificationEvent is the payload of the notifications channel
+type notificationEvent struct {
+	Op     string `json:"op"`
+	ID     string `json:"id"`
+	UserId string `json:"user_id"`
+}
+
+// notificationHub hands the notification events to the streams of their
+// user open in this process
+type notificationHub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan notificationEvent]struct{}
+	closed      bool
+}
+
+func newNotificationHub() *notificationHub {
+	return &notificationHub{subscribers: make(map[string]map[chan notificationEvent]struct{})}
+}
+
+// subscribe returns the events of the user. The channel is closed when the
+// hub closes, as on shutdown.
+func (h *notificationHub) subscribe(userId string) chan notificationEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	events := make(chan notificationEvent, streamBuffer)
+
+	if h.closed {
+		close(events)
+		return events
+	}
+
+	if h.subscribers[userId] == nil {
+		h.subscribers[userId] = make(map[chan notificationEvent]struct{})
+	}
+
+	h.subscribers[userId][events] = struct{}{}
+
+	return events
+}
+
+func (h *notificationHub) unsubscribe(userId string, events chan notificationEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[userId][events]; !ok {
+		return
+	}
+
+	delete(h.subscribers[userId], events)
+
+	if len(h.subscribers[userId]) == 0 {
+		delete(h.subscribers, userId)
+	}
+
+	close(events)
+}
+
+func (h *notificationHub) publish(event notificationEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for events := range h.subscribers[event.UserId] {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+// resync tells every stream to refresh what it shows
+func (h *notificationHub) resync() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for userId, streams := range h.subscribers {
+		for events := range streams {
+			select {
+			case events <- notificationEvent{Op: notificationResync, UserId: userId}:
+			default:
+			}
+		}
+	}
+}
+
+// close ends every stream, so shutting down doesn't wait for them
+func (h *notificationHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+
+	for _, streams := range h.subscribers {
+		for events := range streams {
+			close(events)
+		}
+	}
+
+	h.subscribers = make(map[string]map[chan notificationEvent]struct{})
+}
+
+// listenNotifications relays the notification events committed by any
+// process of the API to the streams open in this one, until the context is
+// cancelled
+func (s *Server) listenNotifications(ctx context.Context) error {
+	listener := pq.NewListener(database.ConnectionString(s.env), 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("notifications listener: %v", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(notificationsChannel); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notification := <-listener.Notify:
+			// Sent after the connection was lost and established again
+			if notification == nil {
+				s.notifications.resync()
+				continue
+			}
+
+			var event notificationEvent
+
+			if err := json.Unmarshal([]byte(notification.Extra), &event); err != nil {
+				log.Printf("notifications listener: %v", err)
+				continue
+			}
+
+			s.notifications.publish(event)
+		case <-time.After(90 * time.Second):
+			go listener.Ping()
+		}
+	}
+}
+
+// streamNotifications sends the notifications of the user as Server-Sent
+// Events while the connection is open: "notification" with each new one
+// and "unread" with the unread count when it changes. Reconnecting clients
+// get the ones created after the Last-Event-ID they send.
+func (s *Server) streamNotifications(ctx *gin.Context) {
+	userId := ctx.GetString("userId")
+
+	// Subscribed before reading the missed ones, so none falls in between
+	events := s.notifications.subscribe(userId)
+	defer s.notifications.unsubscribe(userId, events)
+
+	missed := make([]*models.NotificationResponse, 0)
+
+	if lastId := ctx.GetHeader("Last-Event-ID"); lastId != "" {
+		var err error
+		missed, err = findNotifications(s.db, `user_id = $1
+		AND created_at > (SELECT created_at FROM notifications WHERE id::text = $2 AND user_id = $1)`, userId, lastId)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	// Disables the buffering of nginx
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	for _, notification := range missed {
+		if err := writeServerEvent(ctx, notification.ID, "notification", notification); err != nil {
+			return
+		}
+	}
+
+	if err := s.writeUnreadEvent(ctx, userId); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		var err error
+
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(ctx.Writer, ": ping\n\n")
+			ctx.Writer.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			err = s.writeNotificationEvent(ctx, userId, event)
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) writeNotificationEvent(ctx *gin.Context, userId string, event notificationEvent) error {
+	if event.Op == notificationCreated {
+		notifications, err := findNotifications(s.db, `id::text = $1 AND user_id = $2`, event.ID, userId)
+
+		if err != nil {
+			return err
+		}
+
+		for _, notification := range notifications {
+			if err := writeServerEvent(ctx, notification.ID, "notification", notification); err != nil {
+				return err
+			}
+		}
+	}
+
+	return s.writeUnreadEvent(ctx, userId)
+}
+
+func (s *Server) writeUnreadEvent(ctx *gin.Context, userId string) error {
+	unread, err := countUnreadNotifications(s.db, userId)
+
+	if err != nil {
+		return err
+	}
+
+	return writeServerEvent(ctx, "", "unread", gin.H{"unread": unread})
+}
+
+// writeServerEvent writes an event of the stream and flushes it
+func writeServerEvent(ctx *gin.Context, id string, event string, data any) error {
+	encoded, err := json.Marshal(data)
+
+	if err != nil {
+		return err
+	}
+
+	if id != "" {
+		if _, err := fmt.Fprintf(ctx.Writer, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+
+	if _, err := fmt.Fprintf(ctx.Writer, "event: %s\ndata: %s\n\n", event, encoded); err != nil {
+		return err
+	}
+
+	ctx.Writer.Flush()
+
+	return nil
+}
+
+// createStreamTicket issues a ticket opening one notification stream of the
+// user, for browsers' EventSource, which can't send headers. The ticket goes
+// in the URL, where it's logged, so it's single-use and expires soon instead
+// of being the session token. Reconnecting takes a new one.
+func (s *Server) createStreamTicket(ctx *gin.Context) {
+	ticket, hash, err := newToken()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	expiresAt := time.Now().Add(streamTicketTTL)
+
+	// Other tabs may be opening their streams, so their tickets stay
+	_, err = s.db.Exec(`INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at) VALUES ($1, $2, $3, $4)`,
+		ctx.GetString("userId"), tokenPurposeNotificationStream, hash, expiresAt)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"ticket": ticket, "expires_at": expiresAt})
+}
+
+// RequireStreamTicket lets in the stream of the user of the ticket
+// parameter, spending it. Clients that can send headers use the
+// Authorization header instead.
+func (s *Server) RequireStreamTicket(ctx *gin.Context) {
+	if ctx.GetHeader("Authorization") != "" {
+		s.RequireUser(ctx)
+		return
+	}
+
+	userId, err := useUserToken(s.db, ctx.Query("ticket"), tokenPurposeNotificationStream)
+
+	if err == sql.ErrNoRows {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "the stream ticket is invalid, expired or was already used"})
+		return
+	}
+
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Set("userId", userId)
+
+	ctx.Next()
+}
@@ -0,0 +1,254 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/gioCuesta25/employees-manager-backend/mailer"
+	"github.com/gioCuesta25/employees-manager-backend/models"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
+)
+
+// notify stores a notification for the user and emails the kinds with an
+// email template. Notifications with a dedup key the user already has are
+// skipped, so jobs can notify on every run.
+func notify(db queryer, notification models.Notification) error {
+	data := notification.Data
+	if data == nil {
+		data = map[string]any{}
+	}
+
+	encoded, err := json.Marshal(data)
+
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO notifications (user_id, company_id, kind, title, body, link, data, dedup_key)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT (user_id, dedup_key) DO NOTHING`
+
+	result, err := db.Exec(query,
+		notification.UserId,
+		notification.CompanyId,
+		notification.Kind,
+		notification.Title,
+		notification.Body,
+		notification.Link,
+		encoded,
+		notification.DedupKey)
+
+	if err != nil {
+		return err
+	}
+
+	if inserted, _ := result.RowsAffected(); inserted == 0 || !mailer.HasTemplate(notification.Kind) {
+		return nil
+	}
+
+	return emailNotification(db, notification)
+}
+
+const notificationColumns = `id, company_id, kind, title, body, link, data, read_at, created_at`
+
+// listNotifications responds with a page of the notifications of the user,
+// newest first. unread=true leaves out the read ones.
+func (s *Server) listNotifications(ctx *gin.Context) {
+	userId := ctx.GetString("userId")
+	pageNumber, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("size", "20"))
+	unread := ctx.DefaultQuery("unread", "") == "true"
+	kind := ctx.DefaultQuery("kind", "")
+
+	offset := (pageNumber - 1) * pageSize
+
+	where := `user_id = $1 AND (NOT $2 OR read_at IS NULL) AND ($3 = '' OR kind = $3)`
+
+	rows, err := s.db.Query(`SELECT `+notificationColumns+` FROM notifications
+	WHERE `+where+`
+	ORDER BY created_at DESC, id DESC
+	LIMIT $4
+	OFFSET $5`, userId, unread, kind, pageSize, offset)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var totalItems int
+	err = s.db.QueryRow(`SELECT COUNT(*) FROM notifications WHERE `+where, userId, unread, kind).Scan(&totalItems)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	unreadCount, err := countUnreadNotifications(s.db, userId)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	totalPages := int(math.Ceil(float64(totalItems) / float64(pageSize)))
+	var nextPage, prevPage *int
+
+	if pageNumber < totalPages {
+		nextPageNum := pageNumber + 1
+		nextPage = &nextPageNum
+	}
+
+	if pageNumber > 1 {
+		prevPageNum := pageNumber - 1
+		prevPage = &prevPageNum
+	}
+
+	notifications := make([]*models.NotificationResponse, 0)
+
+	for rows.Next() {
+		notification, err := scanRowIntoNotification(rows)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		notifications = append(notifications, notification)
+	}
+
+	result := models.NotificationsPage{
+		PaginatedResult: models.PaginatedResult{
+			Data:       notifications,
+			PageNumber: pageNumber,
+			PageSize:   pageSize,
+			TotalItems: totalItems,
+			NextPage:   nextPage,
+			PrevPage:   prevPage,
+		},
+		UnreadCount: unreadCount,
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+func (s *Server) getUnreadNotificationsCount(ctx *gin.Context) {
+	unread, err := countUnreadNotifications(s.db, ctx.GetString("userId"))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"unread": unread})
+}
+
+func (s *Server) markNotificationRead(ctx *gin.Context) {
+	var params models.GetNotificationParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	query := `UPDATE notifications SET read_at = COALESCE(read_at, now())
+	WHERE id::text = $1 AND user_id = $2
+	RETURNING ` + notificationColumns
+
+	notification, err := scanRowIntoNotification(s.db.QueryRow(query, params.ID, ctx.GetString("userId")))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(ctx, fmt.Errorf("notification %s not found", params.ID), http.StatusNotFound)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"notification": notification})
+}
+
+// markAllNotificationsRead reads the unread notifications of the user, or
+// only those of the kind
+func (s *Server) markAllNotificationsRead(ctx *gin.Context) {
+	userId := ctx.GetString("userId")
+	kind := ctx.DefaultQuery("kind", "")
+
+	result, err := s.db.Exec(`UPDATE notifications SET read_at = now()
+	WHERE user_id = $1 AND read_at IS NULL AND ($2 = '' OR kind = $2)`, userId, kind)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	read, _ := result.RowsAffected()
+
+	unread, err := countUnreadNotifications(s.db, userId)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"read": read, "unread": unread})
+}
+
+func countUnreadNotifications(db queryer, userId string) (int, error) {
+	var unread int
+	err := db.QueryRow(`SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`, userId).Scan(&unread)
+
+	return unread, err
+}
+
+func findNotifications(db queryer, where string, args ...any) ([]*models.NotificationResponse, error) {
+	rows, err := db.Query(`SELECT `+notificationColumns+` FROM notifications WHERE `+where+` ORDER BY created_at, id`, args...)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := make([]*models.NotificationResponse, 0)
+
+	for rows.Next() {
+		notification, err := scanRowIntoNotification(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, rows.Err()
+}
+
+func scanRowIntoNotification(row rowScanner) (*models.NotificationResponse, error) {
+	notification := new(models.NotificationResponse)
+
+	err := row.Scan(
+		&notification.ID,
+		&notification.CompanyId,
+		&notification.Kind,
+		&notification.Title,
+		&notification.Body,
+		&notification.Link,
+		&notification.Data,
+		&notification.ReadAt,
+		&notification.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return notification, nil
+}
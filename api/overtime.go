@@ -0,0 +1,469 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/attendance"
+	"github.com/gioCuesta25/employees-manager-backend/models"
+	"github.com/gioCuesta25/employees-manager-backend/overtime"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
+	"github.com/lib/pq"
+)
+
+func (s *Server) getEmployeeSurcharges(ctx *gin.Context) {
+	var params models.GetEmployeeParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	from, to, err := parsePeriod(ctx)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	var companyId string
+	var salary float64
+
+	err = s.db.QueryRow(`SELECT company_id, salary FROM employees WHERE id = $1`, params.ID).Scan(&companyId, &salary)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(ctx, fmt.Errorf("employee %s not found", params.ID), http.StatusNotFound)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	surcharges, err := calculateSurcharges(s.db, companyId, params.ID, salary, from, to, true)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"surcharges": surcharges})
+}
+
+func (s *Server) getCompanySurcharges(ctx *gin.Context) {
+	var params models.GetCompanyParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	from, to, err := parsePeriod(ctx)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	query := `SELECT DISTINCT e.id, e.salary
+	FROM employees e
+	JOIN timesheets t ON t.employee_id = e.id
+	WHERE e.company_id = $1 AND t.status = 'approved' AND t.date BETWEEN $2 AND $3`
+
+	rows, err := s.db.Query(query, params.ID, from, to)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	salaries := make(map[string]float64)
+
+	for rows.Next() {
+		var employeeId string
+		var salary float64
+
+		if err := rows.Scan(&employeeId, &salary); err != nil {
+			rows.Close()
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		salaries[employeeId] = salary
+	}
+	rows.Close()
+
+	employees := make([]*models.EmployeeSurchargesResponse, 0)
+	total := 0.0
+
+	for employeeId, salary := range salaries {
+		surcharges, err := calculateSurcharges(s.db, params.ID, employeeId, salary, from, to, false)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		total += surcharges.TotalAmount
+		employees = append(employees, surcharges)
+	}
+
+	sort.Slice(employees, func(i, j int) bool {
+		return employees[i].EmployeeId < employees[j].EmployeeId
+	})
+
+	ctx.JSON(http.StatusOK, gin.H{"employees": employees, "total_amount": math.Round(total*100) / 100})
+}
+
+func (s *Server) getOvertimeSettings(ctx *gin.Context) {
+	var params models.GetCompanyParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	rules, err := loadOvertimeRules(s.db, params.ID)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"settings": overtimeSettingsResponse(params.ID, rules)})
+}
+
+func (s *Server) updateOvertimeSettings(ctx *gin.Context) {
+	var params models.GetCompanyParams
+	var body models.OvertimeSettingsBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	nightStart, err := parseClock(body.NightStart)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, fmt.Errorf("night_start: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	nightEnd, err := parseClock(body.NightEnd)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, fmt.Errorf("night_end: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	rules := overtime.Rules{
+		NightStart:         nightStart,
+		NightEnd:           nightEnd,
+		WeeklyLimitMinutes: int(body.WeeklyLimitHours * 60),
+		NightRate:          body.NightRate,
+		HolidayRate:        body.HolidayRate,
+		OvertimeDayRate:    body.OvertimeDayRate,
+		OvertimeNightRate:  body.OvertimeNightRate,
+	}
+
+	query := `INSERT INTO overtime_settings
+	(company_id, night_start, night_end, weekly_limit_minutes, night_rate, holiday_rate, overtime_day_rate, overtime_night_rate)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT (company_id) DO UPDATE
+	SET night_start = EXCLUDED.night_start,
+		night_end = EXCLUDED.night_end,
+		weekly_limit_minutes = EXCLUDED.weekly_limit_minutes,
+		night_rate = EXCLUDED.night_rate,
+		holiday_rate = EXCLUDED.holiday_rate,
+		overtime_day_rate = EXCLUDED.overtime_day_rate,
+		overtime_night_rate = EXCLUDED.overtime_night_rate,
+		updated_at = $9`
+
+	_, err = s.db.Exec(query,
+		params.ID,
+		rules.NightStart,
+		rules.NightEnd,
+		rules.WeeklyLimitMinutes,
+		rules.NightRate,
+		rules.HolidayRate,
+		rules.OvertimeDayRate,
+		rules.OvertimeNightRate,
+		time.Now())
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"settings": overtimeSettingsResponse(params.ID, rules)})
+}
+
+// importPayrollSurcharges adds the surcharges of the approved timesheets in
+// the period as bonuses of a draft payroll run, replacing the ones imported
+// before.
+func (s *Server) importPayrollSurcharges(ctx *gin.Context) {
+	var params models.GetPayrollRunParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	run, err := lockDraftPayrollRun(tx, params.ID)
+
+	if err != nil {
+		payrollRunErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	concepts := make([]string, 0, len(overtime.Labels))
+	for _, label := range overtime.Labels {
+		concepts = append(concepts, label)
+	}
+
+	_, err = tx.Exec(`DELETE FROM payroll_adjustments WHERE payroll_run_id = $1 AND concept = ANY($2)`, run.ID, pq.Array(concepts))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := tx.Query(`SELECT snapshot FROM payroll_run_items WHERE payroll_run_id = $1`, run.ID)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	var snapshots []models.EmployeeSnapshot
+
+	for rows.Next() {
+		var data []byte
+		var snapshot models.EmployeeSnapshot
+
+		if err := rows.Scan(&data); err != nil {
+			rows.Close()
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			rows.Close()
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+	rows.Close()
+
+	insert := `INSERT INTO payroll_adjustments (payroll_run_id, employee_id, kind, concept, amount, created_by)
+	VALUES ($1, $2, $3, $4, $5, $6)`
+
+	imported := 0
+
+	for _, snapshot := range snapshots {
+		surcharges, err := calculateSurcharges(tx, run.CompanyId, snapshot.ID, snapshot.Salary, run.PeriodStart, run.PeriodEnd, false)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		for _, bucket := range surcharges.Buckets {
+			if bucket.Amount <= 0 {
+				continue
+			}
+
+			_, err := tx.Exec(insert, run.ID, snapshot.ID, models.AdjustmentKindBonus, bucket.Label, bucket.Amount, ctx.GetString("userId"))
+
+			if err != nil {
+				utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+				return
+			}
+
+			imported++
+		}
+	}
+
+	if err := recalculatePayrollItems(tx, run); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"imported": imported})
+}
+
+// calculateSurcharges classifies the approved timesheets of the employee.
+// Timesheets since the Monday of the first week are loaded so the weekly
+// limit is counted correctly, but only the days in the period are reported.
+func calculateSurcharges(db queryer, companyId string, employeeId string, salary float64, from time.Time, to time.Time, withDays bool) (*models.EmployeeSurchargesResponse, error) {
+	rules, err := loadOvertimeRules(db, companyId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	cal, _, err := loadCompanyCalendar(db, companyId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	weekStart := from.AddDate(0, 0, -((int(from.Weekday()) + 6) % 7))
+
+	rows, err := db.Query(`SELECT intervals FROM timesheets
+	WHERE employee_id = $1 AND status = 'approved' AND date BETWEEN $2 AND $3
+	ORDER BY date`, employeeId, weekStart, to)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var intervals []attendance.Interval
+
+	for rows.Next() {
+		var data []byte
+		var dayIntervals []attendance.Interval
+
+		if err := rows.Scan(&data); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		if err := json.Unmarshal(data, &dayIntervals); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		intervals = append(intervals, dayIntervals...)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	days := overtime.Classify(intervals, cal, rules)
+	totals := make(overtime.Minutes)
+
+	result := &models.EmployeeSurchargesResponse{
+		EmployeeId: employeeId,
+		From:       from,
+		To:         to,
+		HourlyRate: math.Round(overtime.HourlyRate(salary, rules)*100) / 100,
+		Buckets:    make([]models.SurchargeBucketResponse, 0, len(overtime.Buckets)),
+	}
+
+	for day, minutes := range days {
+		if day.Before(from) || day.After(to) {
+			continue
+		}
+
+		totals.Add(minutes)
+
+		if withDays {
+			result.Days = append(result.Days, models.DaySurchargesResponse{Date: day, Minutes: minutes})
+		}
+	}
+
+	sort.Slice(result.Days, func(i, j int) bool {
+		return result.Days[i].Date.Before(result.Days[j].Date)
+	})
+
+	hourlyRate := overtime.HourlyRate(salary, rules)
+
+	for _, bucket := range overtime.Buckets {
+		amount := overtime.Amount(bucket, totals[bucket], hourlyRate, rules)
+
+		result.Buckets = append(result.Buckets, models.SurchargeBucketResponse{
+			Bucket:  bucket,
+			Label:   overtime.Labels[bucket],
+			Minutes: totals[bucket],
+			Hours:   math.Round(float64(totals[bucket])/60*100) / 100,
+			Rate:    rules.Rate(bucket),
+			Amount:  amount,
+		})
+
+		result.TotalAmount += amount
+	}
+
+	result.TotalAmount = math.Round(result.TotalAmount*100) / 100
+
+	return result, nil
+}
+
+func loadOvertimeRules(db queryer, companyId string) (overtime.Rules, error) {
+	rules := overtime.DefaultRules()
+
+	query := `SELECT night_start, night_end, weekly_limit_minutes, night_rate, holiday_rate, overtime_day_rate, overtime_night_rate
+	FROM overtime_settings
+	WHERE company_id = $1`
+
+	err := db.QueryRow(query, companyId).Scan(
+		&rules.NightStart,
+		&rules.NightEnd,
+		&rules.WeeklyLimitMinutes,
+		&rules.NightRate,
+		&rules.HolidayRate,
+		&rules.OvertimeDayRate,
+		&rules.OvertimeNightRate)
+
+	if err != nil && err != sql.ErrNoRows {
+		return rules, err
+	}
+
+	return rules, nil
+}
+
+func overtimeSettingsResponse(companyId string, rules overtime.Rules) models.OvertimeSettingsResponse {
+	return models.OvertimeSettingsResponse{
+		CompanyId:         companyId,
+		NightStart:        formatClock(rules.NightStart),
+		NightEnd:          formatClock(rules.NightEnd),
+		WeeklyLimitHours:  float64(rules.WeeklyLimitMinutes) / 60,
+		NightRate:         rules.NightRate,
+		HolidayRate:       rules.HolidayRate,
+		OvertimeDayRate:   rules.OvertimeDayRate,
+		OvertimeNightRate: rules.OvertimeNightRate,
+	}
+}
+
+// parseClock converts a HH:MM time of day to minutes after midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+
+	if err != nil {
+		return 0, fmt.Errorf("must be a time as HH:MM")
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
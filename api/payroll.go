@@ -0,0 +1,1032 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/jobs"
+	"github.com/gioCuesta25/employees-manager-backend/models"
+	"github.com/gioCuesta25/employees-manager-backend/payroll"
+	"github.com/gioCuesta25/employees-manager-backend/pdf"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
+)
+
+const payrollRunColumns = `id, company_id, period_start, period_end, status, created_by, approved_by, approved_at, closed_at, created_at, updated_at`
+
+const payrollItemColumns = `id, payroll_run_id, employee_id, snapshot, worked_days, base_pay, bonuses, deductions, health_contribution, pension_contribution, net_pay`
+
+// createPayrollRun creates the run in draft. Its snapshot of the employees
+// is taken by a job, returned with the run, which the client polls before
+// reviewing the items.
+func (s *Server) createPayrollRun(ctx *gin.Context) {
+	var body models.CreatePayrollRunBody
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if body.PeriodEnd.Before(body.PeriodStart) {
+		utils.ErrorResponse(ctx, fmt.Errorf("period_end must be after period_start"), http.StatusBadRequest)
+		return
+	}
+
+	if !s.requireCompanyRole(ctx, body.CompanyId, models.RoleOwner, models.RoleHR) {
+		return
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO payroll_runs
+	(company_id, period_start, period_end, status, created_by)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING ` + payrollRunColumns
+
+	row := tx.QueryRow(query, body.CompanyId, body.PeriodStart, body.PeriodEnd, models.PayrollStatusDraft, ctx.GetString("userId"))
+
+	run, err := scanRowIntoPayrollRun(row)
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			utils.ErrorResponse(ctx, fmt.Errorf("the company already has a payroll run for the period"), http.StatusConflict)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	jobId, err := jobs.Enqueue(tx, jobPayrollSnapshot, payrollRunPayload{PayrollRunId: run.ID}, jobs.Options{
+		CompanyId: run.CompanyId,
+		CreatedBy: ctx.GetString("userId"),
+	})
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	job, err := findJob(s.db, jobId)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"payroll_run": run, "job": job})
+}
+
+func (s *Server) listPayrollRuns(ctx *gin.Context) {
+	companyId := ctx.DefaultQuery("company_id", "")
+
+	if companyId == "" {
+		utils.ErrorResponse(ctx, fmt.Errorf("company_id is required"), http.StatusBadRequest)
+		return
+	}
+
+	if !s.requireCompanyRole(ctx, companyId, models.RoleOwner, models.RoleHR) {
+		return
+	}
+
+	pageNumber, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("size", "10"))
+
+	offset := (pageNumber - 1) * pageSize
+
+	query := `SELECT ` + payrollRunColumns + `
+	FROM payroll_runs
+	WHERE company_id = $1
+	ORDER BY period_start DESC
+	LIMIT $2
+	OFFSET $3`
+
+	rows, err := s.db.Query(query, companyId, pageSize, offset)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	totalItemsQuery := `SELECT COUNT(*) FROM payroll_runs WHERE company_id = $1`
+	var totalItems int
+	err = s.db.QueryRow(totalItemsQuery, companyId).Scan(&totalItems)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	totalPages := int(math.Ceil(float64(totalItems) / float64(pageSize)))
+	var nextPage, prevPage *int
+
+	if pageNumber < totalPages {
+		nextPageNum := pageNumber + 1
+		nextPage = &nextPageNum
+	}
+
+	if pageNumber > 1 {
+		prevPageNum := pageNumber - 1
+		prevPage = &prevPageNum
+	}
+
+	runs := make([]*models.PayrollRunResponse, 0)
+
+	for rows.Next() {
+		run, err := scanRowIntoPayrollRun(rows)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		runs = append(runs, run)
+	}
+
+	result := models.PaginatedResult{
+		Data:       runs,
+		PageNumber: pageNumber,
+		PageSize:   pageSize,
+		TotalItems: totalItems,
+		NextPage:   nextPage,
+		PrevPage:   prevPage,
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+func (s *Server) getPayrollRun(ctx *gin.Context) {
+	var params models.GetPayrollRunParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	run, err := s.findPayrollRun(params.ID)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(ctx, fmt.Errorf("payroll run %s not found", params.ID), http.StatusNotFound)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if !s.requireCompanyRole(ctx, run.CompanyId, models.RoleOwner, models.RoleHR) {
+		return
+	}
+
+	items, err := s.findPayrollItems(run.ID, "")
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	adjustments, err := s.findPayrollAdjustments(run.ID, "")
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"payroll_run": run,
+		"items":       items,
+		"adjustments": adjustments,
+	})
+}
+
+// refreshPayrollRun starts a job taking a new snapshot of the company
+// employees. Only runs in draft can be refreshed, after that the snapshot is
+// locked.
+func (s *Server) refreshPayrollRun(ctx *gin.Context) {
+	var params models.GetPayrollRunParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	run, err := s.findPayrollRun(params.ID)
+
+	if err != nil {
+		payrollRunErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if !s.requireCompanyRole(ctx, run.CompanyId, models.RoleOwner, models.RoleHR) {
+		return
+	}
+
+	if run.Status != models.PayrollStatusDraft {
+		payrollRunErrorResponse(ctx, errPayrollRunLocked, params.ID)
+		return
+	}
+
+	s.enqueueJob(ctx, jobPayrollSnapshot, payrollRunPayload{PayrollRunId: run.ID}, run.CompanyId)
+}
+
+type payrollRunPayload struct {
+	PayrollRunId string `json:"payroll_run_id"`
+}
+
+type payrollSnapshotResult struct {
+	PayrollRunId string `json:"payroll_run_id"`
+	Items        int    `json:"items"`
+}
+
+// snapshotPayrollRunJob takes the snapshot of a run still in draft
+func (s *Server) snapshotPayrollRunJob(ctx context.Context, job *jobs.Job) (any, error) {
+	var payload payrollRunPayload
+
+	if err := job.Decode(&payload); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	run, err := lockDraftPayrollRun(tx, payload.PayrollRunId)
+
+	if err == sql.ErrNoRows || err == errPayrollRunLocked {
+		return nil, jobs.Permanent(fmt.Errorf("payroll run %s: %w", payload.PayrollRunId, err))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := snapshotPayrollRun(tx, run); err != nil {
+		return nil, err
+	}
+
+	result := payrollSnapshotResult{PayrollRunId: run.ID}
+	err = tx.QueryRow(`SELECT COUNT(*) FROM payroll_run_items WHERE payroll_run_id = $1`, run.ID).Scan(&result.Items)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// payrollSnapshotPending reports whether a job is still taking the snapshot
+// of the run
+func payrollSnapshotPending(db queryer, runId string) (bool, error) {
+	var pending bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM jobs WHERE kind = $1 AND status IN ($2, $3) AND payload ->> 'payroll_run_id' = $4)`,
+		jobPayrollSnapshot, jobs.StatusQueued, jobs.StatusRunning, runId).Scan(&pending)
+
+	return pending, err
+}
+
+func (s *Server) submitPayrollRun(ctx *gin.Context) {
+	s.transitionPayrollRun(ctx, models.PayrollStatusDraft, models.PayrollStatusReview)
+}
+
+func (s *Server) approvePayrollRun(ctx *gin.Context) {
+	s.transitionPayrollRun(ctx, models.PayrollStatusReview, models.PayrollStatusApproved)
+}
+
+func (s *Server) rejectPayrollRun(ctx *gin.Context) {
+	s.transitionPayrollRun(ctx, models.PayrollStatusReview, models.PayrollStatusDraft)
+}
+
+func (s *Server) closePayrollRun(ctx *gin.Context) {
+	s.transitionPayrollRun(ctx, models.PayrollStatusApproved, models.PayrollStatusClosed)
+}
+
+func (s *Server) transitionPayrollRun(ctx *gin.Context, from string, to string) {
+	var params models.GetPayrollRunParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if !s.requirePayrollRunRole(ctx, params.ID) {
+		return
+	}
+
+	// The items of a run leaving the draft must be complete
+	if from == models.PayrollStatusDraft {
+		pending, err := payrollSnapshotPending(s.db, params.ID)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		if pending {
+			utils.ErrorResponse(ctx, fmt.Errorf("payroll run %s is still taking its snapshot", params.ID), http.StatusConflict)
+			return
+		}
+	}
+
+	query := `UPDATE payroll_runs
+	SET status = $1,
+		updated_at = $2,
+		approved_by = CASE WHEN $1 = 'approved' THEN $3::uuid WHEN $1 = 'draft' THEN NULL ELSE approved_by END,
+		approved_at = CASE WHEN $1 = 'approved' THEN $2 WHEN $1 = 'draft' THEN NULL ELSE approved_at END,
+		closed_at = CASE WHEN $1 = 'closed' THEN $2 ELSE closed_at END
+	WHERE id = $4 AND status = $5
+	RETURNING ` + payrollRunColumns
+
+	row := s.db.QueryRow(query, to, time.Now(), ctx.GetString("userId"), params.ID, from)
+
+	run, err := scanRowIntoPayrollRun(row)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(ctx, fmt.Errorf("payroll run %s not found or not in %s status", params.ID, from), http.StatusConflict)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"payroll_run": run})
+}
+
+func (s *Server) createPayrollAdjustment(ctx *gin.Context) {
+	var params models.GetPayrollRunParams
+	var body models.CreatePayrollAdjustmentBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if !s.requirePayrollRunRole(ctx, params.ID) {
+		return
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	run, err := lockDraftPayrollRun(tx, params.ID)
+
+	if err != nil {
+		payrollRunErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	query := `INSERT INTO payroll_adjustments
+	(payroll_run_id, employee_id, kind, concept, amount, created_by)
+	SELECT $1, employee_id, $3, $4, $5, $6 FROM payroll_run_items WHERE payroll_run_id = $1 AND employee_id = $2
+	RETURNING id, payroll_run_id, employee_id, kind, concept, amount, created_by, created_at`
+
+	row := tx.QueryRow(query, run.ID, body.EmployeeId, body.Kind, body.Concept, body.Amount, ctx.GetString("userId"))
+
+	var adjustment models.PayrollAdjustmentResponse
+
+	err = row.Scan(&adjustment.ID,
+		&adjustment.PayrollRunId,
+		&adjustment.EmployeeId,
+		&adjustment.Kind,
+		&adjustment.Concept,
+		&adjustment.Amount,
+		&adjustment.CreatedBy,
+		&adjustment.CreatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(ctx, fmt.Errorf("employee %s is not part of payroll run %s", body.EmployeeId, run.ID), http.StatusBadRequest)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := recalculatePayrollItems(tx, run); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"adjustment": adjustment})
+}
+
+func (s *Server) deletePayrollAdjustment(ctx *gin.Context) {
+	var params models.DeletePayrollAdjustmentParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if !s.requirePayrollRunRole(ctx, params.ID) {
+		return
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	run, err := lockDraftPayrollRun(tx, params.ID)
+
+	if err != nil {
+		payrollRunErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	query := `DELETE FROM payroll_adjustments WHERE id = $1 AND payroll_run_id = $2`
+
+	result, err := tx.Exec(query, params.AdjustmentId, run.ID)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		utils.ErrorResponse(ctx, fmt.Errorf("adjustment %s not found in payroll run %s", params.AdjustmentId, run.ID), http.StatusNotFound)
+		return
+	}
+
+	if err := recalculatePayrollItems(tx, run); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+func (s *Server) getPayslip(ctx *gin.Context) {
+	var params models.GetPayslipParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	run, err := s.findPayrollRun(params.ID)
+
+	if err != nil {
+		payrollRunErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if !isOwnEmployee(ctx, params.EmployeeId) && !s.requireCompanyRole(ctx, run.CompanyId, models.RoleOwner, models.RoleHR) {
+		return
+	}
+
+	items, err := s.findPayrollItems(run.ID, params.EmployeeId)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if len(items) == 0 {
+		utils.ErrorResponse(ctx, fmt.Errorf("employee %s is not part of payroll run %s", params.EmployeeId, run.ID), http.StatusNotFound)
+		return
+	}
+
+	adjustments, err := s.findPayrollAdjustments(run.ID, params.EmployeeId)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	companyName, err := s.findCompanyName(run.CompanyId)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	doc := renderPayslip(companyName, run, items[0], adjustments)
+
+	filename := fmt.Sprintf("payslip-%s-%s.pdf", run.PeriodEnd.Format("2006-01-02"), items[0].Snapshot.IdNumber)
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	ctx.Data(http.StatusOK, "application/pdf", doc.Bytes())
+}
+
+func (s *Server) getPayrollSummary(ctx *gin.Context) {
+	var params models.GetPayrollRunParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	run, err := s.findPayrollRun(params.ID)
+
+	if err != nil {
+		payrollRunErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if !s.requireCompanyRole(ctx, run.CompanyId, models.RoleOwner, models.RoleHR) {
+		return
+	}
+
+	items, err := s.findPayrollItems(run.ID, "")
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	companyName, err := s.findCompanyName(run.CompanyId)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	doc := renderPayrollSummary(companyName, run, items)
+
+	filename := fmt.Sprintf("payroll-summary-%s.pdf", run.PeriodEnd.Format("2006-01-02"))
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	ctx.Data(http.StatusOK, "application/pdf", doc.Bytes())
+}
+
+func (s *Server) findPayrollRun(id string) (*models.PayrollRunResponse, error) {
+	query := `SELECT ` + payrollRunColumns + ` FROM payroll_runs WHERE id = $1`
+
+	return scanRowIntoPayrollRun(s.db.QueryRow(query, id))
+}
+
+// findPayrollItems returns the items of the run, or only the item of the given
+// employee when employeeId isn't empty.
+func (s *Server) findPayrollItems(runId string, employeeId string) ([]*models.PayrollRunItemResponse, error) {
+	query := `SELECT ` + payrollItemColumns + `
+	FROM payroll_run_items
+	WHERE payroll_run_id = $1 AND ($2 = '' OR employee_id::text = $2)
+	ORDER BY snapshot->>'last_name', snapshot->>'name'`
+
+	rows, err := s.db.Query(query, runId, employeeId)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]*models.PayrollRunItemResponse, 0)
+
+	for rows.Next() {
+		item, err := scanRowIntoPayrollItem(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func (s *Server) findPayrollAdjustments(runId string, employeeId string) ([]*models.PayrollAdjustmentResponse, error) {
+	query := `SELECT id, payroll_run_id, employee_id, kind, concept, amount, created_by, created_at
+	FROM payroll_adjustments
+	WHERE payroll_run_id = $1 AND ($2 = '' OR employee_id::text = $2)
+	ORDER BY created_at`
+
+	rows, err := s.db.Query(query, runId, employeeId)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	adjustments := make([]*models.PayrollAdjustmentResponse, 0)
+
+	for rows.Next() {
+		adjustment := new(models.PayrollAdjustmentResponse)
+
+		err := rows.Scan(&adjustment.ID,
+			&adjustment.PayrollRunId,
+			&adjustment.EmployeeId,
+			&adjustment.Kind,
+			&adjustment.Concept,
+			&adjustment.Amount,
+			&adjustment.CreatedBy,
+			&adjustment.CreatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		adjustments = append(adjustments, adjustment)
+	}
+
+	return adjustments, rows.Err()
+}
+
+func (s *Server) findCompanyName(companyId string) (string, error) {
+	var name string
+
+	err := s.db.QueryRow(`SELECT name FROM companies WHERE id = $1`, companyId).Scan(&name)
+
+	return name, err
+}
+
+var errPayrollRunLocked = fmt.Errorf("payroll run is locked")
+
+// lockDraftPayrollRun locks the run row for the rest of the transaction and
+// checks that it is still a draft, the only status where it can be modified.
+func lockDraftPayrollRun(tx *sql.Tx, id string) (*models.PayrollRunResponse, error) {
+	query := `SELECT ` + payrollRunColumns + ` FROM payroll_runs WHERE id = $1 FOR UPDATE`
+
+	run, err := scanRowIntoPayrollRun(tx.QueryRow(query, id))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if run.Status != models.PayrollStatusDraft {
+		return nil, errPayrollRunLocked
+	}
+
+	return run, nil
+}
+
+// requirePayrollRunRole checks that the user is the owner or HR of the
+// company of the run, responding when they aren't or it doesn't exist
+func (s *Server) requirePayrollRunRole(ctx *gin.Context, id string) bool {
+	run, err := s.findPayrollRun(id)
+
+	if err != nil {
+		payrollRunErrorResponse(ctx, err, id)
+		return false
+	}
+
+	return s.requireCompanyRole(ctx, run.CompanyId, models.RoleOwner, models.RoleHR)
+}
+
+func payrollRunErrorResponse(ctx *gin.Context, err error, id string) {
+	switch err {
+	case sql.ErrNoRows:
+		utils.ErrorResponse(ctx, fmt.Errorf("payroll run %s not found", id), http.StatusNotFound)
+	case errPayrollRunLocked:
+		utils.ErrorResponse(ctx, fmt.Errorf("payroll run %s can only be modified in draft status", id), http.StatusConflict)
+	default:
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+	}
+}
+
+// snapshotPayrollRun replaces the items of the run with a copy of the current
+// data of every employee admitted before the end of the period and not
+// terminated before its start.
+func snapshotPayrollRun(tx *sql.Tx, run *models.PayrollRunResponse) error {
+	if _, err := tx.Exec(`DELETE FROM payroll_run_items WHERE payroll_run_id = $1`, run.ID); err != nil {
+		return err
+	}
+
+	query := `SELECT id, name, last_name, email, id_type, id_number, admission_date,
+		CASE WHEN termination_date <= $2 THEN termination_date END, salary, position_id, department_id
+	FROM employees
+	WHERE company_id = $1 AND admission_date <= $2 AND (termination_date IS NULL OR termination_date >= $3)`
+
+	rows, err := tx.Query(query, run.CompanyId, run.PeriodEnd, run.PeriodStart)
+
+	if err != nil {
+		return err
+	}
+
+	var snapshots []models.EmployeeSnapshot
+
+	for rows.Next() {
+		var snapshot models.EmployeeSnapshot
+
+		err := rows.Scan(&snapshot.ID,
+			&snapshot.Name,
+			&snapshot.LastName,
+			&snapshot.Email,
+			&snapshot.IdType,
+			&snapshot.IdNumber,
+			&snapshot.AdmissionDate,
+			&snapshot.TerminationDate,
+			&snapshot.Salary,
+			&snapshot.PositionId,
+			&snapshot.DepartmentId)
+
+		if err != nil {
+			rows.Close()
+			return err
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	insert := `INSERT INTO payroll_run_items
+	(payroll_run_id, employee_id, snapshot, worked_days, base_pay, net_pay)
+	VALUES ($1, $2, $3, 0, 0, 0)`
+
+	for _, snapshot := range snapshots {
+		data, err := json.Marshal(snapshot)
+
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(insert, run.ID, snapshot.ID, data); err != nil {
+			return err
+		}
+	}
+
+	return recalculatePayrollItems(tx, run)
+}
+
+// recalculatePayrollItems computes the pay of every item of the run from its
+// snapshot and the adjustments registered for the employee.
+func recalculatePayrollItems(tx *sql.Tx, run *models.PayrollRunResponse) error {
+	adjustments := make(map[string][]payroll.Adjustment)
+
+	rows, err := tx.Query(`SELECT employee_id, kind, amount FROM payroll_adjustments WHERE payroll_run_id = $1`, run.ID)
+
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		var employeeId string
+		var adjustment payroll.Adjustment
+
+		if err := rows.Scan(&employeeId, &adjustment.Kind, &adjustment.Amount); err != nil {
+			rows.Close()
+			return err
+		}
+
+		adjustments[employeeId] = append(adjustments[employeeId], adjustment)
+	}
+	rows.Close()
+
+	rows, err = tx.Query(`SELECT `+payrollItemColumns+` FROM payroll_run_items WHERE payroll_run_id = $1`, run.ID)
+
+	if err != nil {
+		return err
+	}
+
+	var items []*models.PayrollRunItemResponse
+
+	for rows.Next() {
+		item, err := scanRowIntoPayrollItem(rows)
+
+		if err != nil {
+			rows.Close()
+			return err
+		}
+
+		items = append(items, item)
+	}
+	rows.Close()
+
+	update := `UPDATE payroll_run_items
+	SET worked_days = $1,
+		base_pay = $2,
+		bonuses = $3,
+		deductions = $4,
+		health_contribution = $5,
+		pension_contribution = $6,
+		net_pay = $7,
+		updated_at = $8
+	WHERE id = $9`
+
+	for _, item := range items {
+		result := payroll.Calculate(item.Snapshot, run.PeriodStart, run.PeriodEnd, adjustments[item.EmployeeId])
+
+		_, err := tx.Exec(update,
+			result.WorkedDays,
+			result.BasePay,
+			result.Bonuses,
+			result.Deductions,
+			result.HealthContribution,
+			result.PensionContribution,
+			result.NetPay,
+			time.Now(),
+			item.ID)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func scanRowIntoPayrollRun(row rowScanner) (*models.PayrollRunResponse, error) {
+	run := new(models.PayrollRunResponse)
+
+	err := row.Scan(
+		&run.ID,
+		&run.CompanyId,
+		&run.PeriodStart,
+		&run.PeriodEnd,
+		&run.Status,
+		&run.CreatedBy,
+		&run.ApprovedBy,
+		&run.ApprovedAt,
+		&run.ClosedAt,
+		&run.CreatedAt,
+		&run.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return run, nil
+}
+
+func scanRowIntoPayrollItem(row rowScanner) (*models.PayrollRunItemResponse, error) {
+	item := new(models.PayrollRunItemResponse)
+	var snapshot []byte
+
+	err := row.Scan(
+		&item.ID,
+		&item.PayrollRunId,
+		&item.EmployeeId,
+		&snapshot,
+		&item.WorkedDays,
+		&item.BasePay,
+		&item.Bonuses,
+		&item.Deductions,
+		&item.HealthContribution,
+		&item.PensionContribution,
+		&item.NetPay,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(snapshot, &item.Snapshot); err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+func renderPayslip(companyName string, run *models.PayrollRunResponse, item *models.PayrollRunItemResponse, adjustments []*models.PayrollAdjustmentResponse) *pdf.Document {
+	doc := pdf.New()
+	page := doc.AddPage()
+
+	page.Text(50, 60, pdf.FontBold, 16, companyName)
+	page.Text(50, 80, pdf.FontRegular, 11, "Desprendible de pago")
+	page.Text(50, 96, pdf.FontRegular, 10, fmt.Sprintf("Periodo: %s a %s (%s)",
+		run.PeriodStart.Format("2006-01-02"), run.PeriodEnd.Format("2006-01-02"), run.Status))
+	page.Line(50, 106, pdf.PageWidth-50, 106)
+
+	employee := item.Snapshot
+	page.Text(50, 126, pdf.FontRegular, 10, fmt.Sprintf("Empleado: %s %s", employee.Name, employee.LastName))
+	page.Text(50, 142, pdf.FontRegular, 10, fmt.Sprintf("Identificación: %s", employee.IdNumber))
+	page.Text(50, 158, pdf.FontRegular, 10, fmt.Sprintf("Salario básico: %s", formatMoney(employee.Salary)))
+	page.Text(50, 174, pdf.FontRegular, 10, fmt.Sprintf("Días laborados: %d", item.WorkedDays))
+
+	y := 204.0
+	line := func(label string, amount float64) {
+		page.Text(50, y, pdf.FontRegular, 10, label)
+		page.Text(400, y, pdf.FontRegular, 10, formatMoney(amount))
+		y += 16
+	}
+
+	page.Text(50, y, pdf.FontBold, 11, "Devengados")
+	y += 18
+	line("Salario", item.BasePay)
+	for _, adjustment := range adjustments {
+		if adjustment.Kind == models.AdjustmentKindBonus {
+			line(adjustment.Concept, adjustment.Amount)
+		}
+	}
+
+	y += 8
+	page.Text(50, y, pdf.FontBold, 11, "Deducciones")
+	y += 18
+	line("Salud", item.HealthContribution)
+	line("Pensión", item.PensionContribution)
+	for _, adjustment := range adjustments {
+		if adjustment.Kind == models.AdjustmentKindDeduction {
+			line(adjustment.Concept, adjustment.Amount)
+		}
+	}
+
+	y += 4
+	page.Line(50, y, pdf.PageWidth-50, y)
+	y += 18
+	page.Text(50, y, pdf.FontBold, 12, "Neto a pagar")
+	page.Text(400, y, pdf.FontBold, 12, formatMoney(item.NetPay))
+
+	return doc
+}
+
+func renderPayrollSummary(companyName string, run *models.PayrollRunResponse, items []*models.PayrollRunItemResponse) *pdf.Document {
+	doc := pdf.New()
+
+	const rowsPerPage = 40
+	var page *pdf.Page
+	var y float64
+	var totalBase, totalBonuses, totalDeductions, totalNet float64
+
+	header := func() {
+		page = doc.AddPage()
+		page.Text(40, 50, pdf.FontBold, 14, companyName)
+		page.Text(40, 68, pdf.FontRegular, 10, fmt.Sprintf("Resumen de nómina %s a %s (%s)",
+			run.PeriodStart.Format("2006-01-02"), run.PeriodEnd.Format("2006-01-02"), run.Status))
+		page.Text(40, 92, pdf.FontBold, 9, "Empleado")
+		page.Text(230, 92, pdf.FontBold, 9, "Días")
+		page.Text(270, 92, pdf.FontBold, 9, "Básico")
+		page.Text(350, 92, pdf.FontBold, 9, "Bonos")
+		page.Text(420, 92, pdf.FontBold, 9, "Deducciones")
+		page.Text(500, 92, pdf.FontBold, 9, "Neto")
+		page.Line(40, 98, pdf.PageWidth-40, 98)
+		y = 112
+	}
+
+	header()
+
+	for i, item := range items {
+		if i > 0 && i%rowsPerPage == 0 {
+			header()
+		}
+
+		deductions := item.Deductions + item.HealthContribution + item.PensionContribution
+
+		page.Text(40, y, pdf.FontRegular, 9, item.Snapshot.LastName+" "+item.Snapshot.Name)
+		page.Text(230, y, pdf.FontRegular, 9, strconv.Itoa(item.WorkedDays))
+		page.Text(270, y, pdf.FontRegular, 9, formatMoney(item.BasePay))
+		page.Text(350, y, pdf.FontRegular, 9, formatMoney(item.Bonuses))
+		page.Text(420, y, pdf.FontRegular, 9, formatMoney(deductions))
+		page.Text(500, y, pdf.FontRegular, 9, formatMoney(item.NetPay))
+		y += 16
+
+		totalBase += item.BasePay
+		totalBonuses += item.Bonuses
+		totalDeductions += deductions
+		totalNet += item.NetPay
+	}
+
+	page.Line(40, y-8, pdf.PageWidth-40, y-8)
+	y += 6
+	page.Text(40, y, pdf.FontBold, 9, fmt.Sprintf("Total (%d empleados)", len(items)))
+	page.Text(270, y, pdf.FontBold, 9, formatMoney(totalBase))
+	page.Text(350, y, pdf.FontBold, 9, formatMoney(totalBonuses))
+	page.Text(420, y, pdf.FontBold, 9, formatMoney(totalDeductions))
+	page.Text(500, y, pdf.FontBold, 9, formatMoney(totalNet))
+
+	return doc
+}
+
+func formatMoney(amount float64) string {
+	return "$ " + strconv.FormatFloat(amount, 'f', 2, 64)
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/models"
+	"github.com/gioCuesta25/employees-manager-backend/picture"
+	"github.com/gioCuesta25/employees-manager-backend/storage"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
+)
+
+const pictureMaxBytes = 8 << 20
+
+// updateEmployeePicture accepts the photo as the raw request body or as the
+// "file" field of a multipart form, stores its thumbnails and points
+// picture_url to them. Every upload gets a new version in the URL, so the
+// thumbnails can be cached forever.
+func (s *Server) updateEmployeePicture(ctx *gin.Context) {
+	var params models.GetEmployeeParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, pictureMaxBytes+64<<10)
+
+	data, err := readPictureUpload(ctx)
+
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) || err == errPictureTooLarge {
+			utils.ErrorResponse(ctx, fmt.Errorf("the picture can't be larger than %d bytes", pictureMaxBytes), http.StatusRequestEntityTooLarge)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	img, orientation, err := picture.Decode(data)
+
+	if err != nil {
+		if err == picture.ErrUnsupported {
+			utils.ErrorResponse(ctx, err, http.StatusUnsupportedMediaType)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	var previousVersion *string
+
+	if err := s.db.QueryRow(`SELECT picture_version FROM employees WHERE id = $1`, params.ID).Scan(&previousVersion); err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(ctx, fmt.Errorf("employee %s not found", params.ID), http.StatusNotFound)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	version := hex.EncodeToString(sum[:8])
+	thumbnails := make(map[int]string)
+
+	for _, size := range picture.Sizes {
+		thumbnail, err := picture.Encode(picture.Thumbnail(img, orientation, size))
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		err = s.blobs.Put(ctx.Request.Context(), pictureKey(params.ID, version, size), bytes.NewReader(thumbnail), int64(len(thumbnail)), "image/jpeg")
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusBadGateway)
+			return
+		}
+
+		thumbnails[size] = pictureUrl(params.ID, version, size)
+	}
+
+	pictureUrl := thumbnails[picture.DefaultSize]
+
+	_, err = s.db.Exec(`UPDATE employees SET picture_url = $1, picture_version = $2, updated_at = $3 WHERE id = $4`,
+		pictureUrl, version, time.Now(), params.ID)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if previousVersion != nil && *previousVersion != version {
+		for _, size := range picture.Sizes {
+			if err := s.blobs.Delete(ctx.Request.Context(), pictureKey(params.ID, *previousVersion, size)); err != nil {
+				log.Printf("deleting picture %s of employee %s: %v", *previousVersion, params.ID, err)
+			}
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"picture": models.EmployeePictureResponse{
+		PictureUrl: pictureUrl,
+		Thumbnails: thumbnails,
+	}})
+}
+
+// getEmployeePicture serves a thumbnail. It's public so the URL works in an
+// <img> tag; the version in the path changes with every upload.
+func (s *Server) getEmployeePicture(ctx *gin.Context) {
+	var params models.GetEmployeePictureParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	valid := false
+	for _, size := range picture.Sizes {
+		valid = valid || size == params.Size
+	}
+
+	if !valid {
+		utils.ErrorResponse(ctx, fmt.Errorf("size must be one of %v", picture.Sizes), http.StatusBadRequest)
+		return
+	}
+
+	etag := fmt.Sprintf(`"%s-%d"`, params.Version, params.Size)
+	ctx.Header("Cache-Control", "public, max-age=31536000, immutable")
+	ctx.Header("ETag", etag)
+
+	if ctx.GetHeader("If-None-Match") == etag {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
+	blob, err := s.blobs.Get(ctx.Request.Context(), pictureKey(params.ID, params.Version, params.Size))
+
+	if err != nil {
+		ctx.Header("Cache-Control", "no-store")
+		if err == storage.ErrNotFound {
+			utils.ErrorResponse(ctx, fmt.Errorf("picture not found"), http.StatusNotFound)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusBadGateway)
+		return
+	}
+	defer blob.Close()
+
+	ctx.DataFromReader(http.StatusOK, -1, "image/jpeg", blob, nil)
+}
+
+var errPictureTooLarge = errors.New("picture too large")
+
+func readPictureUpload(ctx *gin.Context) ([]byte, error) {
+	var body io.Reader = ctx.Request.Body
+
+	if strings.HasPrefix(ctx.ContentType(), "multipart/") {
+		fileHeader, err := ctx.FormFile("file")
+
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("file is required")
+		}
+
+		file, err := fileHeader.Open()
+
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		body = file
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, pictureMaxBytes+1))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > pictureMaxBytes {
+		return nil, errPictureTooLarge
+	}
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("the picture is empty")
+	}
+
+	return data, nil
+}
+
+func pictureKey(employeeId string, version string, size int) string {
+	return fmt.Sprintf("pictures/%s/%s/%d.jpg", employeeId, version, size)
+}
+
+func pictureUrl(employeeId string, version string, size int) string {
+	return fmt.Sprintf("/employee-pictures/%s/%s/%d", employeeId, version, size)
+}
@@ -5,14 +5,17 @@ import (
 	"fmt"
 	"math"
 	"net/http"
+	"slices"
 	"strconv"
-	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/customfields"
 	"github.com/gioCuesta25/employees-manager-backend/models"
 	"github.com/gioCuesta25/employees-manager-backend/utils"
 )
 
+const positionColumns = `id, name, company_id, department_id, custom_fields, version, created_at, updated_at`
+
 func (s *Server) createPosition(ctx *gin.Context) {
 	var body models.CreatePositionBody
 
@@ -21,21 +24,20 @@ func (s *Server) createPosition(ctx *gin.Context) {
 		return
 	}
 
-	query := `INSERT INTO position
-	(name, company_id, department_id)
-	VALUES ($1, $2, $3)
-	RETURNING id, name, company_id, department_id, created_at, updated_at`
+	customFields, ok := s.bindCustomFields(ctx, body.CompanyId, customfields.EntityPosition, body.CustomFields)
 
-	row := s.db.QueryRow(query, body.Name, body.CompanyId, body.DepartmentId)
+	if !ok {
+		return
+	}
 
-	var department models.PositionResponse
+	query := `INSERT INTO positions
+	(name, company_id, department_id, custom_fields)
+	VALUES ($1, $2, $3, $4)
+	RETURNING ` + positionColumns
 
-	err := row.Scan(&department.ID,
-		&department.Name,
-		&department.CompanyId,
-		&department.DepartmentId,
-		&department.CreatedAt,
-		&department.UpdatedAt)
+	row := s.db.QueryRow(query, body.Name, body.CompanyId, body.DepartmentId, customFields)
+
+	department, err := scanRowsIntoPosition(row)
 
 	if err != nil {
 		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
@@ -57,26 +59,41 @@ func (s *Server) searchPositions(ctx *gin.Context) {
 		return
 	}
 
-	query := `SELECT id, name, company_id, department_id, created_at, updated_at
+	// Filters on custom fields come as cf.<key>=<value>, which need the
+	// company to know the fields
+	filter := "{}"
+
+	if companyId != "" {
+		var ok bool
+		filter, ok = s.customFieldFilter(ctx, companyId, customfields.EntityPosition)
+
+		if !ok {
+			return
+		}
+	}
+
+	query := `SELECT ` + positionColumns + `
 	FROM positions
-	WHERE ($1 = '' OR department_id = $1) AND ($2 = '' OR company_id = $2)
+	WHERE ($1 = '' OR department_id::text = $1) AND ($2 = '' OR company_id::text = $2) AND custom_fields @> $5::jsonb
 	ORDER BY id
 	LIMIT $3
 	OFFSET $4`
 
 	offset := (pageNumber - 1) * pageSize
 
-	rows, err := s.db.Query(query, departmentId, companyId, pageSize, offset)
+	rows, err := s.db.Query(query, departmentId, companyId, pageSize, offset, filter)
 
 	if err != nil {
 		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
 		return
 
 	}
+	defer rows.Close()
 
-	totalItemsQuery := `SELECT COUNT(*) FROM positions WHERE ($1 = '' OR department_id = $1) AND ($2 = '' OR company_id = $2)`
+	totalItemsQuery := `SELECT COUNT(*) FROM positions
+	WHERE ($1 = '' OR department_id::text = $1) AND ($2 = '' OR company_id::text = $2) AND custom_fields @> $3::jsonb`
 	var totalItems int
-	err = s.db.QueryRow(totalItemsQuery, departmentId, companyId).Scan(&totalItems)
+	err = s.db.QueryRow(totalItemsQuery, departmentId, companyId, filter).Scan(&totalItems)
 
 	if err != nil {
 		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
@@ -120,41 +137,97 @@ func (s *Server) searchPositions(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, result)
 }
 
-func (s *Server) updatePosition(ctx *gin.Context) {
-	var body models.CreatePositionBody
+func (s *Server) getPosition(ctx *gin.Context) {
 	var params models.GetPositionsParams
 
-	if err := ctx.ShouldBindJSON(&body); err != nil {
+	if err := ctx.ShouldBindUri(&params); err != nil {
 		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
 		return
 	}
 
+	query := `SELECT ` + positionColumns + ` FROM positions WHERE id = $1`
+	position, err := scanRowsIntoPosition(s.db.QueryRow(query, params.ID))
+
+	if err != nil {
+		positionErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if notModified(ctx, position.Version) {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"position": position})
+}
+
+func (s *Server) updatePosition(ctx *gin.Context) {
+	var body models.CreatePositionBody
+	var params models.GetPositionsParams
+
 	if err := ctx.ShouldBindUri(&params); err != nil {
 		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
 		return
 	}
 
-	query := `UPDATE positions
-	SET name = $1, updated_at = $2
-	WHERE id = $3
-	RETURNING id, name, company_id, department_id, created_at, updated_at`
+	query := `SELECT ` + positionColumns + ` FROM positions WHERE id = $1`
+
+	current, err := scanRowsIntoPosition(s.db.QueryRow(query, params.ID))
+
+	if err != nil {
+		positionErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	changed, ok := bindMergePatch(ctx, models.CreatePositionBody{
+		Name:         current.Name,
+		CompanyId:    current.CompanyId,
+		DepartmentId: current.DepartmentId,
+		CustomFields: current.CustomFields,
+	}, &body)
 
-	row := s.db.QueryRow(query, body.Name, body.CompanyId, time.Now(), params.ID)
+	if !ok {
+		return
+	}
 
-	var department models.PositionResponse
+	if len(changed) == 0 {
+		ctx.Header("ETag", etag(current.Version))
+		ctx.JSON(http.StatusCreated, gin.H{"department": current})
+		return
+	}
 
-	err := row.Scan(&department.ID,
-		&department.Name,
-		&department.CompanyId,
-		&department.DepartmentId,
-		&department.CreatedAt,
-		&department.UpdatedAt)
+	columns := map[string]any{
+		"name": body.Name,
+	}
+
+	if slices.Contains(changed, "custom_fields") {
+		customFields, ok := s.bindCustomFields(ctx, body.CompanyId, customfields.EntityPosition, body.CustomFields)
+
+		if !ok {
+			return
+		}
+
+		columns["custom_fields"] = customFields
+	}
+
+	query, args, err := patchQuery("positions", params.ID, matchedVersion(ctx), columns, changed, positionColumns)
 
 	if err != nil {
-		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	department, err := scanRowsIntoPosition(s.db.QueryRow(query, args...))
+
+	if versionMismatch(ctx, err) {
+		return
+	}
+
+	if err != nil {
+		positionErrorResponse(ctx, err, params.ID)
 		return
 	}
 
+	ctx.Header("ETag", etag(department.Version))
 	ctx.JSON(http.StatusCreated, gin.H{"department": department})
 }
 
@@ -166,27 +239,42 @@ func (s *Server) deletePosition(ctx *gin.Context) {
 		return
 	}
 
-	query := `DELETE FROM positions WHERE id = $1`
+	query := `DELETE FROM positions WHERE id = $1 AND ($2 = 0 OR version = $2)`
 
-	_, err := s.db.Query(query, params.ID)
+	result, err := s.db.Exec(query, params.ID, matchedVersion(ctx))
 
 	if err != nil {
 		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
 		return
 	}
 
+	if deleted, _ := result.RowsAffected(); deleted == 0 && versionMismatch(ctx, sql.ErrNoRows) {
+		return
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{"message": "Department deleted successfully"})
 
 }
 
-func scanRowsIntoPosition(rows *sql.Rows) (*models.PositionResponse, error) {
+func positionErrorResponse(ctx *gin.Context, err error, id string) {
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(ctx, fmt.Errorf("position %s not found", id), http.StatusNotFound)
+		return
+	}
+	utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+}
+
+func scanRowsIntoPosition(row rowScanner) (*models.PositionResponse, error) {
 	position := new(models.PositionResponse)
+	var customFields []byte
 
-	err := rows.Scan(
+	err := row.Scan(
 		&position.ID,
 		&position.Name,
 		&position.CompanyId,
 		&position.DepartmentId,
+		&customFields,
+		&position.Version,
 		&position.CreatedAt,
 		&position.UpdatedAt,
 	)
@@ -195,5 +283,11 @@ func scanRowsIntoPosition(rows *sql.Rows) (*models.PositionResponse, error) {
 		return nil, err
 	}
 
+	position.CustomFields, err = decodeCustomFields(customFields)
+
+	if err != nil {
+		return nil, err
+	}
+
 	return position, nil
 }
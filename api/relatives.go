@@ -0,0 +1,868 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/attendance"
+	"github.com/gioCuesta25/employees-manager-backend/family"
+	"github.com/gioCuesta25/employees-manager-backend/models"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
+	"github.com/lib/pq"
+)
+
+const emergencyContactColumns = `id, employee_id, name, relationship, phone_number, alternate_phone_number, email, is_primary, created_at, updated_at`
+
+const dependentColumns = `id, employee_id, name, last_name, relationship, id_type, id_number, birth_date, disabled,
+	health_affiliated, compensation_affiliated, created_at, updated_at`
+
+const beneficiaryColumns = `id, employee_id, name, last_name, relationship, id_type, id_number, percentage, created_at, updated_at`
+
+// createEmergencyContact adds a contact to the employee. The first contact
+// is the primary one, and a new primary contact replaces the previous.
+func (s *Server) createEmergencyContact(ctx *gin.Context) {
+	var params models.GetEmployeeParams
+	var body models.CreateEmergencyContactBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := s.requireEmployeeCompanyRole(ctx, params.ID, models.RoleOwner, models.RoleHR); !ok {
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if err := lockEmployee(tx, params.ID); err != nil {
+		employeeErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	var hasPrimary bool
+
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM emergency_contacts WHERE employee_id = $1 AND is_primary)`, params.ID).Scan(&hasPrimary); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	isPrimary := body.IsPrimary || !hasPrimary
+
+	if isPrimary {
+		if _, err := tx.Exec(`UPDATE emergency_contacts SET is_primary = false, updated_at = $1 WHERE employee_id = $2 AND is_primary`, time.Now(), params.ID); err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	query := `INSERT INTO emergency_contacts (employee_id, name, relationship, phone_number, alternate_phone_number, email, is_primary)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING ` + emergencyContactColumns
+
+	contact, err := scanRowIntoEmergencyContact(tx.QueryRow(query,
+		params.ID,
+		body.Name,
+		body.Relationship,
+		body.PhoneNumber,
+		body.AlternatePhoneNumber,
+		body.Email,
+		isPrimary))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"emergency_contact": contact})
+}
+
+func (s *Server) listEmergencyContacts(ctx *gin.Context) {
+	var params models.GetEmployeeParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := s.requireEmployeeCompanyRole(ctx, params.ID, models.RoleOwner, models.RoleHR); !ok {
+		return
+	}
+
+	contacts, err := findEmergencyContacts(s.db, params.ID)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"emergency_contacts": contacts})
+}
+
+// updateEmergencyContact replaces the contact. Making it primary takes the
+// flag from the previous primary contact; the primary contact can only stop
+// being primary when another one takes its place.
+func (s *Server) updateEmergencyContact(ctx *gin.Context) {
+	var params models.GetEmergencyContactParams
+	var body models.CreateEmergencyContactBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := s.requireEmployeeCompanyRole(ctx, params.ID, models.RoleOwner, models.RoleHR); !ok {
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if err := lockEmployee(tx, params.ID); err != nil {
+		employeeErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	var wasPrimary bool
+
+	err = tx.QueryRow(`SELECT is_primary FROM emergency_contacts WHERE id = $1 AND employee_id = $2`, params.ContactId, params.ID).Scan(&wasPrimary)
+
+	if err != nil {
+		emergencyContactErrorResponse(ctx, err, params.ContactId)
+		return
+	}
+
+	if wasPrimary && !body.IsPrimary {
+		utils.ErrorResponse(ctx, fmt.Errorf("make another contact primary instead"), http.StatusConflict)
+		return
+	}
+
+	if body.IsPrimary && !wasPrimary {
+		if _, err := tx.Exec(`UPDATE emergency_contacts SET is_primary = false, updated_at = $1 WHERE employee_id = $2 AND is_primary`, time.Now(), params.ID); err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	query := `UPDATE emergency_contacts
+	SET name = $1,
+		relationship = $2,
+		phone_number = $3,
+		alternate_phone_number = $4,
+		email = $5,
+		is_primary = $6,
+		updated_at = $7
+	WHERE id = $8
+	RETURNING ` + emergencyContactColumns
+
+	contact, err := scanRowIntoEmergencyContact(tx.QueryRow(query,
+		body.Name,
+		body.Relationship,
+		body.PhoneNumber,
+		body.AlternatePhoneNumber,
+		body.Email,
+		body.IsPrimary,
+		time.Now(),
+		params.ContactId))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"emergency_contact": contact})
+}
+
+// deleteEmergencyContact removes the contact. When it was the primary one,
+// the oldest remaining contact becomes primary.
+func (s *Server) deleteEmergencyContact(ctx *gin.Context) {
+	var params models.GetEmergencyContactParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := s.requireEmployeeCompanyRole(ctx, params.ID, models.RoleOwner, models.RoleHR); !ok {
+		return
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if err := lockEmployee(tx, params.ID); err != nil {
+		employeeErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	var wasPrimary bool
+
+	err = tx.QueryRow(`DELETE FROM emergency_contacts WHERE id = $1 AND employee_id = $2 RETURNING is_primary`, params.ContactId, params.ID).Scan(&wasPrimary)
+
+	if err != nil {
+		emergencyContactErrorResponse(ctx, err, params.ContactId)
+		return
+	}
+
+	if wasPrimary {
+		query := `UPDATE emergency_contacts SET is_primary = true, updated_at = $1
+		WHERE id = (SELECT id FROM emergency_contacts WHERE employee_id = $2 ORDER BY created_at LIMIT 1)`
+
+		if _, err := tx.Exec(query, time.Now(), params.ID); err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+func (s *Server) createDependent(ctx *gin.Context) {
+	var params models.GetEmployeeParams
+	var body models.CreateDependentBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := s.requireEmployeeCompanyRole(ctx, params.ID, models.RoleOwner, models.RoleHR); !ok {
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if err := lockEmployee(tx, params.ID); err != nil {
+		employeeErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if !validateDependent(ctx, tx, params.ID, "", body) {
+		return
+	}
+
+	query := `INSERT INTO dependents (employee_id, name, last_name, relationship, id_type, id_number, birth_date, disabled, health_affiliated, compensation_affiliated)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	RETURNING ` + dependentColumns
+
+	dependent, err := scanRowIntoDependent(tx.QueryRow(query,
+		params.ID,
+		body.Name,
+		body.LastName,
+		body.Relationship,
+		body.IdType,
+		body.IdNumber,
+		calendarDate(body.BirthDate),
+		body.Disabled,
+		body.HealthAffiliated,
+		body.CompensationAffiliated))
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			utils.ErrorResponse(ctx, fmt.Errorf("the employee already has a dependent with id number %s", *body.IdNumber), http.StatusConflict)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"dependent": dependent})
+}
+
+func (s *Server) listDependents(ctx *gin.Context) {
+	var params models.GetEmployeeParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := s.requireEmployeeCompanyRole(ctx, params.ID, models.RoleOwner, models.RoleHR); !ok {
+		return
+	}
+
+	dependents, err := findDependents(s.db, params.ID)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"dependents": dependents})
+}
+
+func (s *Server) updateDependent(ctx *gin.Context) {
+	var params models.GetDependentParams
+	var body models.CreateDependentBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := s.requireEmployeeCompanyRole(ctx, params.ID, models.RoleOwner, models.RoleHR); !ok {
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if err := lockEmployee(tx, params.ID); err != nil {
+		employeeErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if !validateDependent(ctx, tx, params.ID, params.DependentId, body) {
+		return
+	}
+
+	query := `UPDATE dependents
+	SET name = $1,
+		last_name = $2,
+		relationship = $3,
+		id_type = $4,
+		id_number = $5,
+		birth_date = $6,
+		disabled = $7,
+		health_affiliated = $8,
+		compensation_affiliated = $9,
+		updated_at = $10
+	WHERE id = $11 AND employee_id = $12
+	RETURNING ` + dependentColumns
+
+	dependent, err := scanRowIntoDependent(tx.QueryRow(query,
+		body.Name,
+		body.LastName,
+		body.Relationship,
+		body.IdType,
+		body.IdNumber,
+		calendarDate(body.BirthDate),
+		body.Disabled,
+		body.HealthAffiliated,
+		body.CompensationAffiliated,
+		time.Now(),
+		params.DependentId,
+		params.ID))
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			utils.ErrorResponse(ctx, fmt.Errorf("the employee already has a dependent with id number %s", *body.IdNumber), http.StatusConflict)
+			return
+		}
+		dependentErrorResponse(ctx, err, params.DependentId)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"dependent": dependent})
+}
+
+func (s *Server) deleteDependent(ctx *gin.Context) {
+	var params models.GetDependentParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := s.requireEmployeeCompanyRole(ctx, params.ID, models.RoleOwner, models.RoleHR); !ok {
+		return
+	}
+
+	result, err := s.db.Exec(`DELETE FROM dependents WHERE id = $1 AND employee_id = $2`, params.DependentId, params.ID)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		dependentErrorResponse(ctx, sql.ErrNoRows, params.DependentId)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+func (s *Server) createBeneficiary(ctx *gin.Context) {
+	var params models.GetEmployeeParams
+	var body models.CreateBeneficiaryBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := s.requireEmployeeCompanyRole(ctx, params.ID, models.RoleOwner, models.RoleHR); !ok {
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if err := lockEmployee(tx, params.ID); err != nil {
+		employeeErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if !validateBeneficiaryShare(ctx, tx, params.ID, "", body.Percentage) {
+		return
+	}
+
+	query := `INSERT INTO beneficiaries (employee_id, name, last_name, relationship, id_type, id_number, percentage)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING ` + beneficiaryColumns
+
+	beneficiary, err := scanRowIntoBeneficiary(tx.QueryRow(query,
+		params.ID,
+		body.Name,
+		body.LastName,
+		body.Relationship,
+		body.IdType,
+		body.IdNumber,
+		body.Percentage))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"beneficiary": beneficiary})
+}
+
+func (s *Server) listBeneficiaries(ctx *gin.Context) {
+	var params models.GetEmployeeParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := s.requireEmployeeCompanyRole(ctx, params.ID, models.RoleOwner, models.RoleHR); !ok {
+		return
+	}
+
+	beneficiaries, err := findBeneficiaries(s.db, params.ID)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"beneficiaries": beneficiaries})
+}
+
+func (s *Server) updateBeneficiary(ctx *gin.Context) {
+	var params models.GetBeneficiaryParams
+	var body models.CreateBeneficiaryBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := s.requireEmployeeCompanyRole(ctx, params.ID, models.RoleOwner, models.RoleHR); !ok {
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if err := lockEmployee(tx, params.ID); err != nil {
+		employeeErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if !validateBeneficiaryShare(ctx, tx, params.ID, params.BeneficiaryId, body.Percentage) {
+		return
+	}
+
+	query := `UPDATE beneficiaries
+	SET name = $1,
+		last_name = $2,
+		relationship = $3,
+		id_type = $4,
+		id_number = $5,
+		percentage = $6,
+		updated_at = $7
+	WHERE id = $8 AND employee_id = $9
+	RETURNING ` + beneficiaryColumns
+
+	beneficiary, err := scanRowIntoBeneficiary(tx.QueryRow(query,
+		body.Name,
+		body.LastName,
+		body.Relationship,
+		body.IdType,
+		body.IdNumber,
+		body.Percentage,
+		time.Now(),
+		params.BeneficiaryId,
+		params.ID))
+
+	if err != nil {
+		beneficiaryErrorResponse(ctx, err, params.BeneficiaryId)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"beneficiary": beneficiary})
+}
+
+func (s *Server) deleteBeneficiary(ctx *gin.Context) {
+	var params models.GetBeneficiaryParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := s.requireEmployeeCompanyRole(ctx, params.ID, models.RoleOwner, models.RoleHR); !ok {
+		return
+	}
+
+	result, err := s.db.Exec(`DELETE FROM beneficiaries WHERE id = $1 AND employee_id = $2`, params.BeneficiaryId, params.ID)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		beneficiaryErrorResponse(ctx, sql.ErrNoRows, params.BeneficiaryId)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// validateDependent checks the affiliation rules and that the employee has
+// a single spouse or partner among their dependents. The dependent being
+// updated, if any, is left out.
+func validateDependent(ctx *gin.Context, tx *sql.Tx, employeeId string, dependentId string, body models.CreateDependentBody) bool {
+	dependent := family.Dependent{
+		Relationship:           body.Relationship,
+		BirthDate:              calendarDate(body.BirthDate),
+		Disabled:               body.Disabled,
+		HealthAffiliated:       body.HealthAffiliated,
+		CompensationAffiliated: body.CompensationAffiliated,
+	}
+
+	if err := family.ValidateDependent(dependent, attendance.Day(time.Now())); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return false
+	}
+
+	if !family.IsSpouse(body.Relationship) {
+		return true
+	}
+
+	var hasSpouse bool
+	query := `SELECT EXISTS (
+		SELECT 1 FROM dependents WHERE employee_id = $1 AND relationship = ANY($2) AND id::text <> $3
+	)`
+
+	err := tx.QueryRow(query, employeeId, pq.Array([]string{family.RelationshipSpouse, family.RelationshipPartner}), dependentId).Scan(&hasSpouse)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return false
+	}
+
+	if hasSpouse {
+		utils.ErrorResponse(ctx, fmt.Errorf("the employee already has a spouse or partner as dependent"), http.StatusConflict)
+		return false
+	}
+
+	return true
+}
+
+// validateBeneficiaryShare checks the percentages of the employee don't add
+// up to more than 100 with the new share.
+func validateBeneficiaryShare(ctx *gin.Context, tx *sql.Tx, employeeId string, beneficiaryId string, percentage float64) bool {
+	var assigned float64
+
+	err := tx.QueryRow(`SELECT COALESCE(SUM(percentage), 0) FROM beneficiaries WHERE employee_id = $1 AND id::text <> $2`, employeeId, beneficiaryId).
+		Scan(&assigned)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return false
+	}
+
+	if assigned+percentage > 100 {
+		utils.ErrorResponse(ctx, fmt.Errorf("the beneficiaries would get %.2f%%, only %.2f%% is left", assigned+percentage, 100-assigned), http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+func findEmergencyContacts(db queryer, employeeId string) ([]*models.EmergencyContactResponse, error) {
+	rows, err := db.Query(`SELECT `+emergencyContactColumns+` FROM emergency_contacts WHERE employee_id = $1 ORDER BY is_primary DESC, created_at`, employeeId)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contacts := make([]*models.EmergencyContactResponse, 0)
+
+	for rows.Next() {
+		contact, err := scanRowIntoEmergencyContact(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		contacts = append(contacts, contact)
+	}
+
+	return contacts, rows.Err()
+}
+
+func findDependents(db queryer, employeeId string) ([]*models.DependentResponse, error) {
+	rows, err := db.Query(`SELECT `+dependentColumns+` FROM dependents WHERE employee_id = $1 ORDER BY birth_date`, employeeId)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dependents := make([]*models.DependentResponse, 0)
+
+	for rows.Next() {
+		dependent, err := scanRowIntoDependent(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		dependents = append(dependents, dependent)
+	}
+
+	return dependents, rows.Err()
+}
+
+func findBeneficiaries(db queryer, employeeId string) ([]*models.BeneficiaryResponse, error) {
+	rows, err := db.Query(`SELECT `+beneficiaryColumns+` FROM beneficiaries WHERE employee_id = $1 ORDER BY percentage DESC, created_at`, employeeId)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	beneficiaries := make([]*models.BeneficiaryResponse, 0)
+
+	for rows.Next() {
+		beneficiary, err := scanRowIntoBeneficiary(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		beneficiaries = append(beneficiaries, beneficiary)
+	}
+
+	return beneficiaries, rows.Err()
+}
+
+func emergencyContactErrorResponse(ctx *gin.Context, err error, id string) {
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(ctx, fmt.Errorf("emergency contact %s not found", id), http.StatusNotFound)
+		return
+	}
+	utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+}
+
+func dependentErrorResponse(ctx *gin.Context, err error, id string) {
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(ctx, fmt.Errorf("dependent %s not found", id), http.StatusNotFound)
+		return
+	}
+	utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+}
+
+func beneficiaryErrorResponse(ctx *gin.Context, err error, id string) {
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(ctx, fmt.Errorf("beneficiary %s not found", id), http.StatusNotFound)
+		return
+	}
+	utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+}
+
+func scanRowIntoEmergencyContact(row rowScanner) (*models.EmergencyContactResponse, error) {
+	contact := new(models.EmergencyContactResponse)
+
+	err := row.Scan(
+		&contact.ID,
+		&contact.EmployeeId,
+		&contact.Name,
+		&contact.Relationship,
+		&contact.PhoneNumber,
+		&contact.AlternatePhoneNumber,
+		&contact.Email,
+		&contact.IsPrimary,
+		&contact.CreatedAt,
+		&contact.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return contact, nil
+}
+
+func scanRowIntoDependent(row rowScanner) (*models.DependentResponse, error) {
+	dependent := new(models.DependentResponse)
+
+	err := row.Scan(
+		&dependent.ID,
+		&dependent.EmployeeId,
+		&dependent.Name,
+		&dependent.LastName,
+		&dependent.Relationship,
+		&dependent.IdType,
+		&dependent.IdNumber,
+		&dependent.BirthDate,
+		&dependent.Disabled,
+		&dependent.HealthAffiliated,
+		&dependent.CompensationAffiliated,
+		&dependent.CreatedAt,
+		&dependent.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dependent.Age = family.Age(dependent.BirthDate, attendance.Day(time.Now()))
+
+	return dependent, nil
+}
+
+func scanRowIntoBeneficiary(row rowScanner) (*models.BeneficiaryResponse, error) {
+	beneficiary := new(models.BeneficiaryResponse)
+
+	err := row.Scan(
+		&beneficiary.ID,
+		&beneficiary.EmployeeId,
+		&beneficiary.Name,
+		&beneficiary.LastName,
+		&beneficiary.Relationship,
+		&beneficiary.IdType,
+		&beneficiary.IdNumber,
+		&beneficiary.Percentage,
+		&beneficiary.CreatedAt,
+		&beneficiary.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return beneficiary, nil
+}
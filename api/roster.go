@@ -0,0 +1,846 @@
+package api
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/attendance"
+	"github.com/gioCuesta25/employees-manager-backend/ics"
+	"github.com/gioCuesta25/employees-manager-backend/models"
+	"github.com/gioCuesta25/employees-manager-backend/roster"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
+	"github.com/lib/pq"
+)
+
+const shiftTemplateColumns = `id, company_id, name, start_minute, end_minute, break_minutes, created_at, updated_at`
+
+const shiftColumns = `id, company_id, department_id, employee_id, shift_template_id, name, date, starts_at, ends_at, break_minutes, created_by, created_at`
+
+// maxAssignDays limits the range of a single assignment
+const maxAssignDays = 92
+
+func (s *Server) createShiftTemplate(ctx *gin.Context) {
+	var body models.CreateShiftTemplateBody
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	template, err := parseShiftTemplate(body)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	query := `INSERT INTO shift_templates (company_id, name, start_minute, end_minute, break_minutes)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING ` + shiftTemplateColumns
+
+	row := s.db.QueryRow(query, body.CompanyId, body.Name, template.StartMinute, template.EndMinute, template.BreakMinutes)
+
+	shiftTemplate, err := scanRowIntoShiftTemplate(row)
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			utils.ErrorResponse(ctx, fmt.Errorf("a shift template named %s already exists", body.Name), http.StatusConflict)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"shift_template": shiftTemplate})
+}
+
+func (s *Server) listShiftTemplates(ctx *gin.Context) {
+	companyId := ctx.DefaultQuery("company_id", "")
+
+	if companyId == "" {
+		utils.ErrorResponse(ctx, fmt.Errorf("company_id is required"), http.StatusBadRequest)
+		return
+	}
+
+	rows, err := s.db.Query(`SELECT `+shiftTemplateColumns+` FROM shift_templates WHERE company_id = $1 ORDER BY start_minute, name`, companyId)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	shiftTemplates := make([]*models.ShiftTemplateResponse, 0)
+
+	for rows.Next() {
+		shiftTemplate, err := scanRowIntoShiftTemplate(rows)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		shiftTemplates = append(shiftTemplates, shiftTemplate)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"shift_templates": shiftTemplates})
+}
+
+// updateShiftTemplate changes the template for future assignments, the
+// shifts already assigned keep their times.
+func (s *Server) updateShiftTemplate(ctx *gin.Context) {
+	var params models.GetShiftTemplateParams
+	var body models.CreateShiftTemplateBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	template, err := parseShiftTemplate(body)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	query := `UPDATE shift_templates
+	SET name = $1,
+		start_minute = $2,
+		end_minute = $3,
+		break_minutes = $4,
+		updated_at = $5
+	WHERE id = $6
+	RETURNING ` + shiftTemplateColumns
+
+	row := s.db.QueryRow(query, body.Name, template.StartMinute, template.EndMinute, template.BreakMinutes, time.Now(), params.ID)
+
+	shiftTemplate, err := scanRowIntoShiftTemplate(row)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(ctx, fmt.Errorf("shift template %s not found", params.ID), http.StatusNotFound)
+			return
+		}
+		if isUniqueViolation(err) {
+			utils.ErrorResponse(ctx, fmt.Errorf("a shift template named %s already exists", body.Name), http.StatusConflict)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"shift_template": shiftTemplate})
+}
+
+func (s *Server) deleteShiftTemplate(ctx *gin.Context) {
+	var params models.GetShiftTemplateParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.db.Exec(`DELETE FROM shift_templates WHERE id = $1`, params.ID); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+func (s *Server) getRosterSettings(ctx *gin.Context) {
+	var params models.GetCompanyParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	rules, err := loadRosterRules(s.db, params.ID)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"settings": rosterSettingsResponse(params.ID, rules)})
+}
+
+func (s *Server) updateRosterSettings(ctx *gin.Context) {
+	var params models.GetCompanyParams
+	var body models.RosterSettingsBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	rules := roster.Rules{
+		MinRestMinutes:   int(body.MinRestHours * 60),
+		MaxWeeklyMinutes: int(body.MaxWeeklyHours * 60),
+	}
+
+	query := `INSERT INTO roster_settings (company_id, min_rest_minutes, max_weekly_minutes)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (company_id) DO UPDATE
+	SET min_rest_minutes = EXCLUDED.min_rest_minutes, max_weekly_minutes = EXCLUDED.max_weekly_minutes, updated_at = $4`
+
+	if _, err := s.db.Exec(query, params.ID, rules.MinRestMinutes, rules.MaxWeeklyMinutes, time.Now()); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"settings": rosterSettingsResponse(params.ID, rules)})
+}
+
+// assignShifts schedules the employees of a department on a template for a
+// range of days. The whole assignment is rejected when any shift breaks the
+// roster rules or falls on approved leave.
+func (s *Server) assignShifts(ctx *gin.Context) {
+	var body models.AssignShiftsBody
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	from := calendarDate(body.From)
+	to := calendarDate(body.To)
+
+	if to.Before(from) {
+		utils.ErrorResponse(ctx, fmt.Errorf("to must be after from"), http.StatusBadRequest)
+		return
+	}
+
+	if to.Sub(from).Hours()/24 >= maxAssignDays {
+		utils.ErrorResponse(ctx, fmt.Errorf("the range can't be longer than %d days", maxAssignDays), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	companyId, err := lockRosterDepartment(tx, body.DepartmentId)
+
+	if err != nil {
+		departmentErrorResponse(ctx, err, body.DepartmentId)
+		return
+	}
+
+	var templateCompanyId, templateName string
+	var template roster.Template
+
+	err = tx.QueryRow(`SELECT company_id, name, start_minute, end_minute, break_minutes FROM shift_templates WHERE id = $1`, body.ShiftTemplateId).
+		Scan(&templateCompanyId, &templateName, &template.StartMinute, &template.EndMinute, &template.BreakMinutes)
+
+	if err != nil || templateCompanyId != companyId {
+		if err == nil || err == sql.ErrNoRows {
+			utils.ErrorResponse(ctx, fmt.Errorf("shift template %s not found for the department company", body.ShiftTemplateId), http.StatusBadRequest)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := tx.Query(`SELECT u.id::text FROM unnest($1::uuid[]) AS u(id)
+	WHERE NOT EXISTS (SELECT 1 FROM employees e WHERE e.id = u.id AND e.department_id = $2)`, pq.Array(body.EmployeeIds), body.DepartmentId)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	var outside []string
+
+	for rows.Next() {
+		var employeeId string
+
+		if err := rows.Scan(&employeeId); err != nil {
+			rows.Close()
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		outside = append(outside, employeeId)
+	}
+	rows.Close()
+
+	if len(outside) > 0 {
+		utils.ErrorResponse(ctx, fmt.Errorf("employees %s don't belong to department %s", strings.Join(outside, ", "), body.DepartmentId), http.StatusBadRequest)
+		return
+	}
+
+	weekdays := make(map[time.Weekday]bool)
+	for _, weekday := range body.Weekdays {
+		weekdays[time.Weekday(weekday)] = true
+	}
+
+	proposed := make([]models.ShiftResponse, 0)
+
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if len(weekdays) > 0 && !weekdays[day.Weekday()] {
+			continue
+		}
+
+		start, end := template.On(day)
+
+		for _, employeeId := range body.EmployeeIds {
+			proposed = append(proposed, models.ShiftResponse{
+				CompanyId:       companyId,
+				DepartmentId:    body.DepartmentId,
+				EmployeeId:      employeeId,
+				ShiftTemplateId: &body.ShiftTemplateId,
+				Name:            templateName,
+				Date:            day,
+				StartsAt:        start,
+				EndsAt:          end,
+				BreakMinutes:    template.BreakMinutes,
+			})
+		}
+	}
+
+	shifts, violations, err := scheduleShifts(tx, companyId, proposed, ctx.GetString("userId"))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if len(violations) > 0 {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "the shifts break the roster rules", "violations": violations})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"shifts": shifts})
+}
+
+// copyRosterWeek repeats the shifts of a department week on the following
+// weeks, keeping the day of the week and the times of every shift.
+func (s *Server) copyRosterWeek(ctx *gin.Context) {
+	var body models.CopyRosterWeekBody
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	week := roster.WeekStart(calendarDate(body.Week))
+	weeks := body.Weeks
+
+	if weeks == 0 {
+		weeks = 1
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	companyId, err := lockRosterDepartment(tx, body.DepartmentId)
+
+	if err != nil {
+		departmentErrorResponse(ctx, err, body.DepartmentId)
+		return
+	}
+
+	source, err := findShifts(tx, `department_id = $1 AND date BETWEEN $2 AND $3`, body.DepartmentId, week, week.AddDate(0, 0, 6))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if len(source) == 0 {
+		utils.ErrorResponse(ctx, fmt.Errorf("the department has no shifts in the week of %s", week.Format("2006-01-02")), http.StatusBadRequest)
+		return
+	}
+
+	if body.Replace {
+		_, err := tx.Exec(`DELETE FROM shifts WHERE department_id = $1 AND date BETWEEN $2 AND $3`,
+			body.DepartmentId, week.AddDate(0, 0, 7), week.AddDate(0, 0, 7*weeks+6))
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	proposed := make([]models.ShiftResponse, 0, len(source)*weeks)
+
+	for i := 1; i <= weeks; i++ {
+		for _, shift := range source {
+			copied := *shift
+			copied.Date = shift.Date.AddDate(0, 0, 7*i)
+			// AddDate keeps the wall clock, which is what a roster means by
+			// the same shift on another week
+			copied.StartsAt = shift.StartsAt.In(attendance.Location).AddDate(0, 0, 7*i)
+			copied.EndsAt = shift.EndsAt.In(attendance.Location).AddDate(0, 0, 7*i)
+			proposed = append(proposed, copied)
+		}
+	}
+
+	shifts, violations, err := scheduleShifts(tx, companyId, proposed, ctx.GetString("userId"))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if len(violations) > 0 {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "the copied shifts break the roster rules", "violations": violations})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"shifts": shifts})
+}
+
+func (s *Server) listShifts(ctx *gin.Context) {
+	departmentId := ctx.DefaultQuery("department_id", "")
+	employeeId := ctx.DefaultQuery("employee_id", "")
+
+	if departmentId == "" && employeeId == "" {
+		utils.ErrorResponse(ctx, fmt.Errorf("department_id or employee_id is required"), http.StatusBadRequest)
+		return
+	}
+
+	from, to, err := parsePeriod(ctx)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	shifts, err := findShifts(s.db, `($1 = '' OR department_id::text = $1) AND ($2 = '' OR employee_id::text = $2) AND date BETWEEN $3 AND $4`,
+		departmentId, employeeId, from, to)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"shifts": shifts})
+}
+
+func (s *Server) deleteShift(ctx *gin.Context) {
+	var params models.GetShiftParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.db.Exec(`DELETE FROM shifts WHERE id = $1`, params.ID); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// createShiftFeed issues the secret token of the employee calendar feed.
+// Calendar applications can't send an Authorization header, so the token in
+// the URL is the credential; creating a new one revokes the previous URL.
+func (s *Server) createShiftFeed(ctx *gin.Context) {
+	var params models.GetEmployeeParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	var exists bool
+
+	if err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM employees WHERE id = $1)`, params.ID).Scan(&exists); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if !exists {
+		utils.ErrorResponse(ctx, fmt.Errorf("employee %s not found", params.ID), http.StatusNotFound)
+		return
+	}
+
+	secret := make([]byte, 24)
+
+	if _, err := rand.Read(secret); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	token := hex.EncodeToString(secret)
+
+	query := `INSERT INTO shift_feeds (employee_id, token)
+	VALUES ($1, $2)
+	ON CONFLICT (employee_id) DO UPDATE
+	SET token = EXCLUDED.token, created_at = now()`
+
+	if _, err := s.db.Exec(query, params.ID, token); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"feed": models.ShiftFeedResponse{
+		EmployeeId: params.ID,
+		Token:      token,
+		Url:        "/shift-feeds/" + token + ".ics",
+	}})
+}
+
+// getShiftFeed serves the upcoming shifts of an employee as an iCalendar
+// feed. It's public, the token identifies the employee.
+func (s *Server) getShiftFeed(ctx *gin.Context) {
+	var params models.GetShiftFeedParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	token := strings.TrimSuffix(params.Token, ".ics")
+
+	var employeeId, name, lastName string
+
+	query := `SELECT e.id, e.name, e.last_name
+	FROM shift_feeds f
+	JOIN employees e ON e.id = f.employee_id
+	WHERE f.token = $1`
+
+	err := s.db.QueryRow(query, token).Scan(&employeeId, &name, &lastName)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(ctx, fmt.Errorf("feed not found"), http.StatusNotFound)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := s.db.Query(`SELECT s.id, s.name, s.starts_at, s.ends_at, d.name, s.created_at
+	FROM shifts s
+	JOIN departments d ON d.id = s.department_id
+	WHERE s.employee_id = $1 AND s.ends_at >= $2
+	ORDER BY s.starts_at
+	LIMIT 500`, employeeId, time.Now())
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	feed := ics.Calendar{Name: fmt.Sprintf("Turnos %s %s", name, lastName)}
+
+	for rows.Next() {
+		var event ics.Event
+		var id, department string
+
+		if err := rows.Scan(&id, &event.Summary, &event.Start, &event.End, &department, &event.Updated); err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		event.UID = id + "@shifts"
+		event.Description = department
+		feed.Events = append(feed.Events, event)
+	}
+
+	ctx.Data(http.StatusOK, "text/calendar; charset=utf-8", feed.Marshal())
+}
+
+// scheduleShifts validates the proposed shifts against the ones the
+// employees already have, their approved leave and the company rules, and
+// inserts them when there are no violations.
+func scheduleShifts(tx *sql.Tx, companyId string, proposed []models.ShiftResponse, userId string) ([]*models.ShiftResponse, []roster.Violation, error) {
+	if len(proposed) == 0 {
+		return make([]*models.ShiftResponse, 0), nil, nil
+	}
+
+	rules, err := loadRosterRules(tx, companyId)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	employeeIds := make([]string, 0)
+	seen := make(map[string]bool)
+	first, last := proposed[0].StartsAt, proposed[0].EndsAt
+	candidates := make([]roster.Shift, 0, len(proposed))
+
+	for _, shift := range proposed {
+		if !seen[shift.EmployeeId] {
+			seen[shift.EmployeeId] = true
+			employeeIds = append(employeeIds, shift.EmployeeId)
+		}
+
+		if shift.StartsAt.Before(first) {
+			first = shift.StartsAt
+		}
+
+		if shift.EndsAt.After(last) {
+			last = shift.EndsAt
+		}
+
+		candidates = append(candidates, roster.Shift{
+			EmployeeId:   shift.EmployeeId,
+			Start:        shift.StartsAt,
+			End:          shift.EndsAt,
+			BreakMinutes: shift.BreakMinutes,
+		})
+	}
+
+	// Whole weeks around the proposed shifts are needed for the weekly hours
+	windowStart := roster.WeekStart(attendance.Day(first))
+	windowEnd := roster.WeekStart(attendance.Day(last)).AddDate(0, 0, 7)
+
+	existingShifts, err := findShifts(tx, `employee_id = ANY($1) AND ends_at > $2 AND starts_at < $3`,
+		pq.Array(employeeIds), windowStart.Add(-24*time.Hour), windowEnd.Add(24*time.Hour))
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	existing := make([]roster.Shift, 0, len(existingShifts))
+
+	for _, shift := range existingShifts {
+		existing = append(existing, roster.Shift{
+			EmployeeId:   shift.EmployeeId,
+			Start:        shift.StartsAt,
+			End:          shift.EndsAt,
+			BreakMinutes: shift.BreakMinutes,
+		})
+	}
+
+	leaveQuery := `SELECT employee_id, start_date, end_date
+	FROM leave_requests
+	WHERE employee_id = ANY($1) AND status = 'approved' AND start_date <= $3 AND end_date >= $2`
+
+	rows, err := tx.Query(leaveQuery, pq.Array(employeeIds), attendance.Day(first), attendance.Day(last))
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	leaves := make([]roster.Leave, 0)
+
+	for rows.Next() {
+		var l roster.Leave
+
+		if err := rows.Scan(&l.EmployeeId, &l.StartDate, &l.EndDate); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+
+		leaves = append(leaves, l)
+	}
+	rows.Close()
+
+	if violations := roster.Check(existing, candidates, leaves, rules); len(violations) > 0 {
+		return nil, violations, nil
+	}
+
+	query := `INSERT INTO shifts
+	(company_id, department_id, employee_id, shift_template_id, name, date, starts_at, ends_at, break_minutes, created_by)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	RETURNING ` + shiftColumns
+
+	shifts := make([]*models.ShiftResponse, 0, len(proposed))
+
+	for _, p := range proposed {
+		row := tx.QueryRow(query,
+			companyId,
+			p.DepartmentId,
+			p.EmployeeId,
+			p.ShiftTemplateId,
+			p.Name,
+			p.Date,
+			p.StartsAt,
+			p.EndsAt,
+			p.BreakMinutes,
+			userId)
+
+		shift, err := scanRowIntoShift(row)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		shifts = append(shifts, shift)
+	}
+
+	return shifts, nil, nil
+}
+
+// lockRosterDepartment serializes the roster changes of a department and
+// returns its company.
+func lockRosterDepartment(tx *sql.Tx, departmentId string) (string, error) {
+	var companyId string
+	err := tx.QueryRow(`SELECT company_id FROM departments WHERE id = $1 FOR UPDATE`, departmentId).Scan(&companyId)
+	return companyId, err
+}
+
+func departmentErrorResponse(ctx *gin.Context, err error, id string) {
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(ctx, fmt.Errorf("department %s not found", id), http.StatusNotFound)
+		return
+	}
+	utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+}
+
+func findShifts(db queryer, where string, args ...any) ([]*models.ShiftResponse, error) {
+	rows, err := db.Query(`SELECT `+shiftColumns+` FROM shifts WHERE `+where+` ORDER BY starts_at, employee_id`, args...)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shifts := make([]*models.ShiftResponse, 0)
+
+	for rows.Next() {
+		shift, err := scanRowIntoShift(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		shifts = append(shifts, shift)
+	}
+
+	return shifts, rows.Err()
+}
+
+func loadRosterRules(db queryer, companyId string) (roster.Rules, error) {
+	rules := roster.DefaultRules()
+
+	err := db.QueryRow(`SELECT min_rest_minutes, max_weekly_minutes FROM roster_settings WHERE company_id = $1`, companyId).
+		Scan(&rules.MinRestMinutes, &rules.MaxWeeklyMinutes)
+
+	if err != nil && err != sql.ErrNoRows {
+		return rules, err
+	}
+
+	return rules, nil
+}
+
+func rosterSettingsResponse(companyId string, rules roster.Rules) models.RosterSettingsResponse {
+	return models.RosterSettingsResponse{
+		CompanyId:      companyId,
+		MinRestHours:   float64(rules.MinRestMinutes) / 60,
+		MaxWeeklyHours: float64(rules.MaxWeeklyMinutes) / 60,
+	}
+}
+
+// calendarDate drops the time of a date sent in a body, keeping the day the
+// client wrote instead of the one it falls on in Colombia.
+func calendarDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func parseShiftTemplate(body models.CreateShiftTemplateBody) (roster.Template, error) {
+	start, err := parseClock(body.StartTime)
+
+	if err != nil {
+		return roster.Template{}, fmt.Errorf("start_time: %w", err)
+	}
+
+	end, err := parseClock(body.EndTime)
+
+	if err != nil {
+		return roster.Template{}, fmt.Errorf("end_time: %w", err)
+	}
+
+	template := roster.Template{StartMinute: start, EndMinute: end, BreakMinutes: body.BreakMinutes}
+	startsAt, endsAt := template.On(time.Now())
+
+	if int(endsAt.Sub(startsAt).Minutes()) <= body.BreakMinutes {
+		return roster.Template{}, fmt.Errorf("break_minutes must be shorter than the shift")
+	}
+
+	return template, nil
+}
+
+func scanRowIntoShiftTemplate(row rowScanner) (*models.ShiftTemplateResponse, error) {
+	shiftTemplate := new(models.ShiftTemplateResponse)
+	var startMinute, endMinute int
+
+	err := row.Scan(
+		&shiftTemplate.ID,
+		&shiftTemplate.CompanyId,
+		&shiftTemplate.Name,
+		&startMinute,
+		&endMinute,
+		&shiftTemplate.BreakMinutes,
+		&shiftTemplate.CreatedAt,
+		&shiftTemplate.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	shiftTemplate.StartTime = formatClock(startMinute)
+	shiftTemplate.EndTime = formatClock(endMinute)
+
+	return shiftTemplate, nil
+}
+
+func scanRowIntoShift(row rowScanner) (*models.ShiftResponse, error) {
+	shift := new(models.ShiftResponse)
+
+	err := row.Scan(
+		&shift.ID,
+		&shift.CompanyId,
+		&shift.DepartmentId,
+		&shift.EmployeeId,
+		&shift.ShiftTemplateId,
+		&shift.Name,
+		&shift.Date,
+		&shift.StartsAt,
+		&shift.EndsAt,
+		&shift.BreakMinutes,
+		&shift.CreatedBy,
+		&shift.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return shift, nil
+}
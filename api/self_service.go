@@ -0,0 +1,651 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/approvals"
+	"github.com/gioCuesta25/employees-manager-backend/mailer"
+	"github.com/gioCuesta25/employees-manager-backend/models"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
+)
+
+// invitationTTL is how long an employee has to accept an invitation
+const invitationTTL = 7 * 24 * time.Hour
+
+const invitationTemplate = "invitation"
+
+const invitationColumns = `id, employee_id, email, expires_at, accepted_at, created_by, created_at`
+
+const profileChangeRequestColumns = `id, company_id, employee_id, changes, status, comment, requested_by, decided_by, decided_at, created_at`
+
+// createEmployeeInvitation invites the employee to create a self-service
+// account and emails them the link. The token is issued when the email is
+// sent and only goes out in it, so nobody else can accept it; the database
+// keeps its hash. A new invitation replaces the pending ones.
+func (s *Server) createEmployeeInvitation(ctx *gin.Context) {
+	var params models.GetEmployeeParams
+	var body models.CreateEmployeeInvitationBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	userId := ctx.GetString("userId")
+
+	var companyId, email, employeeName, companyName string
+	var linkedUser *string
+
+	query := `SELECT e.company_id, e.email, e.user_id, e.name || ' ' || e.last_name, c.name
+	FROM employees e
+	JOIN companies c ON c.id = e.company_id
+	WHERE e.id = $1`
+
+	err := s.db.QueryRow(query, params.ID).Scan(&companyId, &email, &linkedUser, &employeeName, &companyName)
+
+	if err != nil {
+		employeeErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	allowed, err := hasCompanyRole(s.db, companyId, userId, models.RoleHR)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if !allowed {
+		utils.ErrorResponse(ctx, fmt.Errorf("only HR can invite employees"), http.StatusForbidden)
+		return
+	}
+
+	if linkedUser != nil {
+		utils.ErrorResponse(ctx, fmt.Errorf("employee %s already has an account", params.ID), http.StatusConflict)
+		return
+	}
+
+	if body.Email != nil {
+		email = *body.Email
+	}
+
+	// Nobody gets this token, the one of the link replaces it
+	_, hash, err := newToken()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM employee_invitations WHERE employee_id = $1 AND accepted_at IS NULL`, params.ID); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	query = `INSERT INTO employee_invitations (employee_id, email, token_hash, expires_at, created_by)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING ` + invitationColumns
+
+	invitation, err := scanRowIntoInvitation(tx.QueryRow(query, params.ID, email, hash, time.Now().Add(invitationTTL), userId))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	language := mailer.DefaultLanguage
+	if body.Language != nil {
+		language = *body.Language
+	}
+
+	err = enqueueEmail(tx, models.Email{
+		To:       (&mail.Address{Name: employeeName, Address: email}).String(),
+		Template: invitationTemplate,
+		Language: language,
+		Data: map[string]any{
+			"invitation_id": invitation.ID,
+			"employee_name": employeeName,
+			"company_name":  companyName,
+			"expires_at":    invitation.ExpiresAt.Format("2006-01-02"),
+		},
+	})
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"invitation": invitation})
+}
+
+// issueInvitationToken replaces the token of the pending invitation and
+// returns the new one. Accepted, expired and replaced invitations return
+// sql.ErrNoRows.
+func issueInvitationToken(db queryer, invitationId string) (string, error) {
+	token, hash, err := newToken()
+
+	if err != nil {
+		return "", err
+	}
+
+	query := `UPDATE employee_invitations SET token_hash = $1
+	WHERE id::text = $2 AND accepted_at IS NULL AND expires_at > now()
+	RETURNING id`
+
+	var id string
+	err = db.QueryRow(query, hash, invitationId).Scan(&id)
+
+	return token, err
+}
+
+// acceptInvitation creates the account of the employee, or links the
+// existing user with the invitation email, and logs them in.
+func (s *Server) acceptInvitation(ctx *gin.Context) {
+	var body models.AcceptInvitationBody
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + invitationColumns + ` FROM employee_invitations WHERE token_hash = $1 FOR UPDATE`
+
+	invitation, err := scanRowIntoInvitation(tx.QueryRow(query, hashToken(body.Token)))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(ctx, fmt.Errorf("invalid invitation"), http.StatusNotFound)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if invitation.AcceptedAt != nil {
+		utils.ErrorResponse(ctx, fmt.Errorf("the invitation was already accepted"), http.StatusConflict)
+		return
+	}
+
+	if time.Now().After(invitation.ExpiresAt) {
+		utils.ErrorResponse(ctx, fmt.Errorf("the invitation expired, ask HR for a new one"), http.StatusGone)
+		return
+	}
+
+	var employeeName string
+
+	err = tx.QueryRow(`SELECT name || ' ' || last_name FROM employees WHERE id = $1 AND user_id IS NULL FOR UPDATE`, invitation.EmployeeId).Scan(&employeeName)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(ctx, fmt.Errorf("the employee already has an account"), http.StatusConflict)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	var userId, fullName, password string
+
+	err = tx.QueryRow(`SELECT id, full_name, password FROM users WHERE email = $1`, invitation.Email).Scan(&userId, &fullName, &password)
+
+	switch {
+	case err == sql.ErrNoRows:
+		fullName = body.FullName
+		if fullName == "" {
+			fullName = employeeName
+		}
+
+		hashedPassword, err := utils.HashPassword(body.Password)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		err = tx.QueryRow(`INSERT INTO users (full_name, email, password) VALUES ($1, $2, $3) RETURNING id`,
+			fullName, invitation.Email, hashedPassword).Scan(&userId)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+	case err != nil:
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	case !utils.CheckPasswordHash(body.Password, password):
+		utils.ErrorResponse(ctx, fmt.Errorf("a user with email %s exists, use their password", invitation.Email), http.StatusUnauthorized)
+		return
+	}
+
+	if _, err := tx.Exec(`UPDATE employees SET user_id = $1, updated_at = $2 WHERE id = $3`, userId, time.Now(), invitation.EmployeeId); err != nil {
+		if isUniqueViolation(err) {
+			utils.ErrorResponse(ctx, fmt.Errorf("the user is already linked to another employee"), http.StatusConflict)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := tx.Exec(`UPDATE employee_invitations SET accepted_at = $1 WHERE id = $2`, time.Now(), invitation.ID); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	// The invitation was sent to the email of the user
+	if _, err := tx.Exec(`UPDATE users SET email_verified_at = COALESCE(email_verified_at, now()) WHERE id = $1`, userId); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	s.respondWithToken(ctx, http.StatusCreated, userId, fullName, invitation.Email)
+}
+
+// asEmployee runs a handler of the employee API with the path parameter set
+// to the employee of the self-service user, so they only reach their own
+// data.
+func (s *Server) asEmployee(param string, handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		employeeId := ctx.GetString("employeeId")
+
+		for i := range ctx.Params {
+			if ctx.Params[i].Key == param {
+				ctx.Params[i].Value = employeeId
+				handler(ctx)
+				return
+			}
+		}
+
+		ctx.Params = append(ctx.Params, gin.Param{Key: param, Value: employeeId})
+		handler(ctx)
+	}
+}
+
+// isOwnEmployee reports whether the request comes from the self-service
+// account of the employee, through asEmployee
+func isOwnEmployee(ctx *gin.Context, employeeId string) bool {
+	return employeeId != "" && ctx.GetString("employeeId") == employeeId
+}
+
+// listMyPayslips lists the payroll runs of the employee once approved
+func (s *Server) listMyPayslips(ctx *gin.Context) {
+	query := `SELECT r.id, r.period_start, r.period_end, r.status, i.net_pay
+	FROM payroll_run_items i
+	JOIN payroll_runs r ON r.id = i.payroll_run_id
+	WHERE i.employee_id = $1 AND r.status IN ($2, $3)
+	ORDER BY r.period_end DESC`
+
+	rows, err := s.db.Query(query, ctx.GetString("employeeId"), models.PayrollStatusApproved, models.PayrollStatusClosed)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	payslips := make([]*models.MyPayslipResponse, 0)
+
+	for rows.Next() {
+		payslip := new(models.MyPayslipResponse)
+
+		if err := rows.Scan(&payslip.PayrollRunId, &payslip.PeriodStart, &payslip.PeriodEnd, &payslip.Status, &payslip.NetPay); err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		payslips = append(payslips, payslip)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"payslips": payslips})
+}
+
+// getMyPayslip serves the payslip of an approved run; drafts and runs in
+// review can still change.
+func (s *Server) getMyPayslip(ctx *gin.Context) {
+	var params models.GetMyPayslipParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	run, err := s.findPayrollRun(params.ID)
+
+	if err != nil {
+		payrollRunErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	if run.Status != models.PayrollStatusApproved && run.Status != models.PayrollStatusClosed {
+		utils.ErrorResponse(ctx, fmt.Errorf("payroll run %s not found", params.ID), http.StatusNotFound)
+		return
+	}
+
+	s.asEmployee("employeeId", s.getPayslip)(ctx)
+}
+
+// createMyProfileChangeRequest asks for the contact data of the employee
+// to be updated. The change goes through the profile_change approval chain
+// of the company and is applied when approved.
+func (s *Server) createMyProfileChangeRequest(ctx *gin.Context) {
+	var body models.CreateProfileChangeRequestBody
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	changes := make(map[string]string)
+
+	if body.PhoneNumber != nil {
+		changes["phone_number"] = *body.PhoneNumber
+	}
+
+	if body.Email != nil {
+		changes["email"] = *body.Email
+	}
+
+	if len(changes) == 0 {
+		utils.ErrorResponse(ctx, fmt.Errorf("nothing to change"), http.StatusBadRequest)
+		return
+	}
+
+	encoded, err := json.Marshal(changes)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	userId := ctx.GetString("userId")
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO profile_change_requests (company_id, employee_id, changes, requested_by)
+	VALUES ($1, $2, $3, $4)
+	RETURNING ` + profileChangeRequestColumns
+
+	request, err := scanRowIntoProfileChangeRequest(tx.QueryRow(query,
+		ctx.GetString("companyId"),
+		ctx.GetString("employeeId"),
+		encoded,
+		userId))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	approval, err := startApproval(tx, approvalRequest{
+		CompanyId:   request.CompanyId,
+		Subject:     approvals.SubjectProfileChange,
+		SubjectId:   &request.ID,
+		EmployeeId:  request.EmployeeId,
+		RequestedBy: userId,
+	})
+
+	if err == nil && approval.Status == approvals.StatusApproved {
+		request, err = closeProfileChangeRequest(tx, request.ID, models.ProfileChangeStatusApproved, userId, nil)
+	}
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"change_request": request, "approval": approval})
+}
+
+func (s *Server) listMyProfileChangeRequests(ctx *gin.Context) {
+	requests, err := findProfileChangeRequests(s.db, `employee_id = $1`, ctx.GetString("employeeId"))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"change_requests": requests})
+}
+
+func (s *Server) listProfileChangeRequests(ctx *gin.Context) {
+	companyId := ctx.DefaultQuery("company_id", "")
+
+	if companyId == "" {
+		utils.ErrorResponse(ctx, fmt.Errorf("company_id is required"), http.StatusBadRequest)
+		return
+	}
+
+	status := ctx.DefaultQuery("status", models.ProfileChangeStatusPending)
+
+	requests, err := findProfileChangeRequests(s.db, `company_id = $1 AND status = $2`, companyId, status)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"change_requests": requests})
+}
+
+func (s *Server) approveProfileChangeRequest(ctx *gin.Context) {
+	s.decideProfileChangeRequest(ctx, approvals.StatusApproved)
+}
+
+func (s *Server) rejectProfileChangeRequest(ctx *gin.Context) {
+	s.decideProfileChangeRequest(ctx, approvals.StatusRejected)
+}
+
+// decideProfileChangeRequest decides the current step of the approval of
+// the change request.
+func (s *Server) decideProfileChangeRequest(ctx *gin.Context, decision string) {
+	var params models.GetProfileChangeRequestParams
+	var body models.DecideProfileChangeRequestBody
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	var approvalId string
+
+	err := s.db.QueryRow(`SELECT id FROM approvals WHERE subject = $1 AND subject_id = $2`, approvals.SubjectProfileChange, params.ID).Scan(&approvalId)
+
+	if err != nil {
+		profileChangeRequestErrorResponse(ctx, err, params.ID)
+		return
+	}
+
+	s.decideApproval(ctx, approvalId, decision, body.Comment)
+}
+
+// closeProfileChangeRequest records the outcome of the approval of the
+// change request, applying the changes when approved.
+func closeProfileChangeRequest(tx *sql.Tx, id string, status string, userId string, comment *string) (*models.ProfileChangeRequestResponse, error) {
+	request, err := scanRowIntoProfileChangeRequest(tx.QueryRow(`SELECT `+profileChangeRequestColumns+` FROM profile_change_requests WHERE id = $1 FOR UPDATE`, id))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if status == models.ProfileChangeStatusApproved {
+		var phoneNumber, email *string
+
+		if value, ok := request.Changes["phone_number"]; ok {
+			phoneNumber = &value
+		}
+
+		if value, ok := request.Changes["email"]; ok {
+			email = &value
+		}
+
+		_, err := tx.Exec(`UPDATE employees
+		SET phone_number = COALESCE($1, phone_number), email = COALESCE($2, email), updated_at = $3
+		WHERE id = $4`, phoneNumber, email, time.Now(), request.EmployeeId)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	query := `UPDATE profile_change_requests
+	SET status = $1, comment = $2, decided_by = $3, decided_at = $4
+	WHERE id = $5
+	RETURNING ` + profileChangeRequestColumns
+
+	return scanRowIntoProfileChangeRequest(tx.QueryRow(query, status, comment, userId, time.Now(), id))
+}
+
+// newToken returns a random token for a link sent by email and the hash
+// stored for it
+func newToken() (string, string, error) {
+	buf := make([]byte, 32)
+
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+
+	token := hex.EncodeToString(buf)
+
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func findProfileChangeRequests(db queryer, where string, args ...any) ([]*models.ProfileChangeRequestResponse, error) {
+	rows, err := db.Query(`SELECT `+profileChangeRequestColumns+` FROM profile_change_requests WHERE `+where+` ORDER BY created_at DESC`, args...)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := make([]*models.ProfileChangeRequestResponse, 0)
+
+	for rows.Next() {
+		request, err := scanRowIntoProfileChangeRequest(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		requests = append(requests, request)
+	}
+
+	return requests, rows.Err()
+}
+
+func profileChangeRequestErrorResponse(ctx *gin.Context, err error, id string) {
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(ctx, fmt.Errorf("change request %s not found", id), http.StatusNotFound)
+		return
+	}
+	utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+}
+
+func scanRowIntoInvitation(row rowScanner) (*models.EmployeeInvitationResponse, error) {
+	invitation := new(models.EmployeeInvitationResponse)
+
+	err := row.Scan(
+		&invitation.ID,
+		&invitation.EmployeeId,
+		&invitation.Email,
+		&invitation.ExpiresAt,
+		&invitation.AcceptedAt,
+		&invitation.CreatedBy,
+		&invitation.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+func scanRowIntoProfileChangeRequest(row rowScanner) (*models.ProfileChangeRequestResponse, error) {
+	request := new(models.ProfileChangeRequestResponse)
+	var changes []byte
+
+	err := row.Scan(
+		&request.ID,
+		&request.CompanyId,
+		&request.EmployeeId,
+		&changes,
+		&request.Status,
+		&request.Comment,
+		&request.RequestedBy,
+		&request.DecidedBy,
+		&request.DecidedAt,
+		&request.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(changes, &request.Changes); err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
@@ -1,39 +1,162 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gioCuesta25/employees-manager-backend/config"
+	"github.com/gioCuesta25/employees-manager-backend/jobs"
+	"github.com/gioCuesta25/employees-manager-backend/mailer"
+	"github.com/gioCuesta25/employees-manager-backend/nomina"
+	"github.com/gioCuesta25/employees-manager-backend/storage"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
 	"github.com/golang-jwt/jwt"
+	"github.com/lib/pq"
 )
 
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx
+type queryer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// isUniqueViolation reports whether the error comes from a unique constraint
+func isUniqueViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23505"
+}
+
 type Server struct {
-	env    config.Environment
-	db     *sql.DB
-	router *gin.Engine
+	env         config.Environment
+	db          *sql.DB
+	router      *gin.Engine
+	transmitter nomina.Transmitter
+	blobs       storage.BlobStore
+	jobs        *jobs.Queue
+
+	// Sends the webhook deliveries
+	webhookClient *http.Client
+	mailer        mailer.Sender
+	notifications *notificationHub
 }
 
+// Requests in flight get this long to finish on shutdown
+const shutdownTimeout = 15 * time.Second
+
 func NewServer(env config.Environment, db *sql.DB) *Server {
 	r := gin.Default()
+	r.Use(RequestID)
 
 	server := &Server{
-		env:    env,
-		db:     db,
-		router: r,
+		env:         env,
+		db:          db,
+		router:      r,
+		transmitter: nomina.NewFileDropTransmitter(env.DianOutputDir),
+		blobs:       newBlobStore(env),
+
+		webhookClient: newWebhookClient(env.WebhookAllowPrivate),
+		mailer:        newMailer(env),
+		notifications: newNotificationHub(),
 	}
 
+	server.jobs = server.newJobQueue()
+
 	// Routes
 	server.setupRoutes()
 	return server
 }
 
-func (s *Server) Run() {
-	s.router.Run(s.env.ApiPort)
+func newBlobStore(env config.Environment) storage.BlobStore {
+	if env.BlobBackend == "s3" {
+		return storage.NewS3Store(storage.S3Config{
+			Endpoint:  env.S3Endpoint,
+			Region:    env.S3Region,
+			Bucket:    env.S3Bucket,
+			AccessKey: env.S3AccessKey,
+			SecretKey: env.S3SecretKey,
+			PathStyle: env.S3PathStyle,
+		})
+	}
+
+	return storage.NewLocalStore(env.BlobLocalDir)
+}
+
+func newMailer(env config.Environment) mailer.Sender {
+	if env.MailBackend == "smtp" {
+		return mailer.NewSMTPSender(mailer.SMTPConfig{
+			Host:     env.SmtpHost,
+			Port:     env.SmtpPort,
+			Username: env.SmtpUsername,
+			Password: env.SmtpPassword,
+			From:     env.MailFrom,
+			Security: env.SmtpSecurity,
+		})
+	}
+
+	return mailer.NewFileSender(env.MailFileDir, env.MailFrom)
+}
+
+// Run serves the API, works the job queue and relays the notifications to
+// their streams until the context is cancelled. Then it stops taking requests, lets the ones in flight finish
+// and drains the running jobs.
+func (s *Server) Run(ctx context.Context) error {
+	httpServer := &http.Server{
+		Addr:    s.env.ApiPort,
+		Handler: s.router,
+	}
+
+	// Open notification streams would hold the shutdown until its timeout
+	httpServer.RegisterOnShutdown(s.notifications.close)
+
+	go func() {
+		if err := s.listenNotifications(ctx); err != nil {
+			log.Printf("notifications listener: %v", err)
+		}
+	}()
+
+	queueDone := make(chan error, 1)
+
+	go func() {
+		queueDone <- s.jobs.Run(ctx, time.Duration(s.env.JobDrainSeconds)*time.Second)
+	}()
+
+	serverDone := make(chan error, 1)
+
+	go func() {
+		serverDone <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverDone:
+		return err
+	case err := <-queueDone:
+		// The queue only stops before the context when it can't start
+		httpServer.Close()
+		return err
+	case <-ctx.Done():
+	}
+
+	// The queue drains meanwhile, as it watches the same context
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("shutting down the HTTP server: %v", err)
+	}
+
+	return <-queueDone
 }
 
 func (s *Server) setupRoutes() {
@@ -42,51 +165,367 @@ func (s *Server) setupRoutes() {
 	user := s.router.Group("/users")
 	user.POST("/register", s.signUp)
 	user.POST("/login", s.login)
+	user.POST("/verify-email", s.verifyEmail)
+	user.POST("/resend-verification", s.RequireUser, s.resendEmailVerification)
+	user.POST("/forgot-password", s.forgotPassword)
+	user.POST("/reset-password", s.resetPassword)
+	user.GET("/notification-preferences", s.RequireUser, s.getNotificationPreferences)
+	user.PUT("/notification-preferences", s.RequireUser, s.updateNotificationPreferences)
 
 	//Companies
 	// Todo: Get companies by user
 	companies := s.router.Group("/companies")
 	companies.Use(s.RequireAuth)
-	companies.POST("/", s.createCompany)
+	companies.POST("/", s.audit("company", ""), s.createCompany)
 	companies.GET("/:id", s.getCompany)
-	companies.DELETE("/:id", s.deleteCompany)
-	companies.PATCH("/:id", s.updateCompany)
+	companies.DELETE("/:id", s.ifMatch("companies"), s.audit("company", "id"), s.deleteCompany)
+	companies.PATCH("/:id", s.ifMatch("companies"), s.audit("company", "id"), s.updateCompany)
+	companies.POST("/:id/members", s.audit("company_member", ""), s.addCompanyMember)
+	companies.GET("/:id/members", s.listCompanyMembers)
+	companies.GET("/:id/departments", s.getDepartmentsByCompany)
+	companies.GET("/:id/audit", s.getCompanyAudit)
+	companies.DELETE("/:id/members/:userId", s.audit("company_member", "userId"), s.removeCompanyMember)
+	companies.GET("/:id/calendar", s.getCompanyCalendar)
+	companies.PUT("/:id/calendar", s.audit("company_calendar", "id"), s.updateCompanyCalendar)
+	companies.GET("/:id/calendar/working-days", s.countWorkingDays)
+	companies.POST("/:id/calendar/closures", s.audit("calendar_closure", ""), s.createCalendarClosure)
+	companies.DELETE("/:id/calendar/closures/:closureId", s.audit("calendar_closure", "closureId"), s.deleteCalendarClosure)
+	companies.GET("/:id/attendance-settings", s.getAttendanceSettings)
+	companies.PUT("/:id/attendance-settings", s.audit("attendance_settings", "id"), s.updateAttendanceSettings)
+	companies.GET("/:id/overtime-settings", s.getOvertimeSettings)
+	companies.PUT("/:id/overtime-settings", s.audit("overtime_settings", "id"), s.updateOvertimeSettings)
+	companies.GET("/:id/surcharges", s.getCompanySurcharges)
+	companies.GET("/:id/expiring", s.getExpiringItems)
+	companies.GET("/:id/overdue-tasks", s.getOverdueChecklistTasks)
+	companies.GET("/:id/roster-settings", s.getRosterSettings)
+	companies.PUT("/:id/roster-settings", s.audit("roster_settings", "id"), s.updateRosterSettings)
+
+	// Custom fields
+	customFields := s.router.Group("/custom-fields")
+	customFields.Use(s.RequireAuth)
+	customFields.POST("/", s.audit("custom_field", ""), s.createCustomField)
+	customFields.GET("/", s.listCustomFields)
+	customFields.PATCH("/:id", s.audit("custom_field", "id"), s.updateCustomField)
+	customFields.DELETE("/:id", s.audit("custom_field", "id"), s.deleteCustomField)
 
 	// Departments
 	departments := s.router.Group("/departments")
 	departments.Use(s.RequireAuth)
-	departments.POST("/", s.createDepartment)
-	departments.GET("/:companyId", s.getDepartmentsByCompany)
-	departments.PATCH("/:id", s.updateDepartment)
-	departments.DELETE("/:id", s.deleteDepartment)
+	departments.POST("/", s.audit("department", ""), s.createDepartment)
+	departments.GET("/:id", s.getDepartment)
+	departments.PATCH("/:id", s.ifMatch("departments"), s.audit("department", "id"), s.updateDepartment)
+	departments.DELETE("/:id", s.ifMatch("departments"), s.audit("department", "id"), s.deleteDepartment)
 
 	// Positions
 	positions := s.router.Group("/positions")
 	positions.Use(s.RequireAuth)
-	positions.POST("/", s.createPosition)
+	positions.POST("/", s.audit("position", ""), s.createPosition)
 	positions.GET("/", s.searchPositions)
-	positions.PATCH("/:id", s.updatePosition)
-	positions.DELETE("/:id", s.deletePosition)
+	positions.GET("/:id", s.getPosition)
+	positions.PATCH("/:id", s.ifMatch("positions"), s.audit("position", "id"), s.updatePosition)
+	positions.DELETE("/:id", s.ifMatch("positions"), s.audit("position", "id"), s.deletePosition)
 
 	// Employees
 	employees := s.router.Group("/employees")
 	employees.Use(s.RequireAuth)
-	employees.POST("/", s.createEmployee)
+	employees.POST("/", s.audit("employee", ""), s.createEmployee)
+	employees.POST("/bulk", s.bulkEmployees)
 	employees.GET("/", s.listCompanyEmployees)
+	employees.GET("/export", s.exportEmployees)
 	employees.GET("/:id", s.getEmployeeById)
-	employees.PATCH("/:id", s.updateEmployee)
-	employees.DELETE("/:id", s.deleteEmployee)
+	employees.PATCH("/:id", s.ifMatch("employees"), s.audit("employee", "id"), s.updateEmployee)
+	employees.DELETE("/:id", s.ifMatch("employees"), s.audit("employee", "id"), s.deleteEmployee)
+	employees.GET("/:id/leave-balances", s.getLeaveBalances)
+	employees.GET("/:id/surcharges", s.getEmployeeSurcharges)
+	employees.POST("/:id/shift-feed", s.audit("shift_feed", "id"), s.createShiftFeed)
+	employees.PUT("/:id/picture", s.audit("employee", "id"), s.updateEmployeePicture)
+	employees.POST("/:id/documents", s.audit("employee_document", ""), s.uploadDocument)
+	employees.GET("/:id/documents", s.listDocuments)
+	employees.GET("/:id/documents/:documentId", s.downloadDocument)
+	employees.DELETE("/:id/documents/:documentId", s.audit("employee_document", "documentId"), s.deleteDocument)
+	employees.POST("/:id/terminate", s.audit("employee", "id"), s.terminateEmployee)
+	employees.GET("/:id/checklist", s.listEmployeeChecklist)
+	employees.GET("/:id/history", s.getEmployeeHistory)
+	employees.POST("/:id/emergency-contacts", s.audit("emergency_contact", ""), s.createEmergencyContact)
+	employees.GET("/:id/emergency-contacts", s.listEmergencyContacts)
+	employees.PATCH("/:id/emergency-contacts/:contactId", s.audit("emergency_contact", "contactId"), s.updateEmergencyContact)
+	employees.DELETE("/:id/emergency-contacts/:contactId", s.audit("emergency_contact", "contactId"), s.deleteEmergencyContact)
+	employees.POST("/:id/dependents", s.audit("dependent", ""), s.createDependent)
+	employees.GET("/:id/dependents", s.listDependents)
+	employees.PATCH("/:id/dependents/:dependentId", s.audit("dependent", "dependentId"), s.updateDependent)
+	employees.DELETE("/:id/dependents/:dependentId", s.audit("dependent", "dependentId"), s.deleteDependent)
+	employees.POST("/:id/beneficiaries", s.audit("beneficiary", ""), s.createBeneficiary)
+	employees.GET("/:id/beneficiaries", s.listBeneficiaries)
+	employees.PATCH("/:id/beneficiaries/:beneficiaryId", s.audit("beneficiary", "beneficiaryId"), s.updateBeneficiary)
+	employees.DELETE("/:id/beneficiaries/:beneficiaryId", s.audit("beneficiary", "beneficiaryId"), s.deleteBeneficiary)
+	employees.POST("/:id/invitation", s.audit("employee_invitation", ""), s.createEmployeeInvitation)
+	employees.POST("/:id/salary-changes", s.audit("approval", ""), s.createSalaryChange)
+
+	// Payroll runs
+	payrollRuns := s.router.Group("/payroll-runs")
+	payrollRuns.Use(s.RequireAuth)
+	payrollRuns.POST("/", s.audit("payroll_run", ""), s.createPayrollRun)
+	payrollRuns.GET("/", s.listPayrollRuns)
+	payrollRuns.GET("/:id", s.getPayrollRun)
+	payrollRuns.POST("/:id/refresh", s.audit("payroll_run", "id"), s.refreshPayrollRun)
+	payrollRuns.POST("/:id/submit", s.audit("payroll_run", "id"), s.submitPayrollRun)
+	payrollRuns.POST("/:id/approve", s.audit("payroll_run", "id"), s.approvePayrollRun)
+	payrollRuns.POST("/:id/reject", s.audit("payroll_run", "id"), s.rejectPayrollRun)
+	payrollRuns.POST("/:id/close", s.audit("payroll_run", "id"), s.closePayrollRun)
+	payrollRuns.POST("/:id/adjustments", s.audit("payroll_adjustment", ""), s.createPayrollAdjustment)
+	payrollRuns.DELETE("/:id/adjustments/:adjustmentId", s.audit("payroll_adjustment", "adjustmentId"), s.deletePayrollAdjustment)
+	payrollRuns.GET("/:id/payslips", s.getPayrollSummary)
+	payrollRuns.GET("/:id/payslips/:employeeId", s.getPayslip)
+	payrollRuns.POST("/:id/electronic-documents", s.audit("payroll_run", "id"), s.generateElectronicPayroll)
+	payrollRuns.POST("/:id/surcharges", s.audit("payroll_run", "id"), s.importPayrollSurcharges)
+
+	// Electronic payroll documents
+	electronicPayroll := s.router.Group("/electronic-payroll")
+	electronicPayroll.Use(s.RequireAuth)
+	electronicPayroll.GET("/", s.listElectronicPayroll)
+	electronicPayroll.GET("/:id", s.getElectronicPayroll)
+	electronicPayroll.GET("/:id/xml", s.getElectronicPayrollXml)
+	electronicPayroll.POST("/:id/submit", s.audit("electronic_payroll_document", "id"), s.submitElectronicPayroll)
+
+	// Leave types
+	leaveTypes := s.router.Group("/leave-types")
+	leaveTypes.Use(s.RequireAuth)
+	leaveTypes.POST("/", s.audit("leave_type", ""), s.createLeaveType)
+	leaveTypes.POST("/defaults", s.audit("leave_type", ""), s.createDefaultLeaveTypes)
+	leaveTypes.GET("/", s.listLeaveTypes)
+	leaveTypes.PATCH("/:id", s.audit("leave_type", "id"), s.updateLeaveType)
+	leaveTypes.DELETE("/:id", s.audit("leave_type", "id"), s.deleteLeaveType)
+
+	// Leave requests
+	leaveRequests := s.router.Group("/leave-requests")
+	leaveRequests.Use(s.RequireAuth)
+	leaveRequests.POST("/", s.audit("leave_request", ""), s.createLeaveRequest)
+	leaveRequests.GET("/", s.listLeaveRequests)
+	leaveRequests.GET("/:id", s.getLeaveRequest)
+	leaveRequests.POST("/:id/approve", s.audit("leave_request", "id"), s.approveLeaveRequest)
+	leaveRequests.POST("/:id/reject", s.audit("leave_request", "id"), s.rejectLeaveRequest)
+	leaveRequests.POST("/:id/cancel", s.audit("leave_request", "id"), s.cancelLeaveRequest)
+
+	// Attendance
+	attendance := s.router.Group("/attendance")
+	attendance.Use(s.RequireAuth)
+	attendance.POST("/clock-in", s.audit("attendance_event", ""), s.clockIn)
+	attendance.POST("/clock-out", s.audit("attendance_event", ""), s.clockOut)
+
+	// Timesheets
+	timesheets := s.router.Group("/timesheets")
+	timesheets.Use(s.RequireAuth)
+	timesheets.GET("/", s.listTimesheets)
+	timesheets.POST("/approve", s.audit("timesheet", ""), s.approveTimesheetPeriod)
+	timesheets.GET("/:id", s.getTimesheet)
+	timesheets.PATCH("/:id", s.audit("timesheet", "id"), s.correctTimesheet)
+	timesheets.POST("/:id/approve", s.audit("timesheet", "id"), s.approveTimesheet)
+
+	// Shifts
+	shiftTemplates := s.router.Group("/shift-templates")
+	shiftTemplates.Use(s.RequireAuth)
+	shiftTemplates.POST("/", s.audit("shift_template", ""), s.createShiftTemplate)
+	shiftTemplates.GET("/", s.listShiftTemplates)
+	shiftTemplates.PATCH("/:id", s.audit("shift_template", "id"), s.updateShiftTemplate)
+	shiftTemplates.DELETE("/:id", s.audit("shift_template", "id"), s.deleteShiftTemplate)
+
+	rosters := s.router.Group("/rosters")
+	rosters.Use(s.RequireAuth)
+	rosters.GET("/", s.listShifts)
+	rosters.POST("/assign", s.audit("shift", ""), s.assignShifts)
+	rosters.POST("/copy-week", s.audit("shift", ""), s.copyRosterWeek)
+	rosters.DELETE("/shifts/:id", s.audit("shift", "id"), s.deleteShift)
+
+	// Contracts
+	contracts := s.router.Group("/contracts")
+	contracts.Use(s.RequireAuth)
+	contracts.POST("/", s.audit("contract", ""), s.createContract)
+	contracts.GET("/", s.listContracts)
+	contracts.GET("/:id", s.getContract)
+	contracts.POST("/:id/renew", s.audit("contract", "id"), s.renewContract)
+	contracts.POST("/:id/terminate", s.audit("contract", "id"), s.terminateContract)
+	contracts.GET("/:id/document", s.getContractDocument)
+
+	contractTemplates := s.router.Group("/contract-templates")
+	contractTemplates.Use(s.RequireAuth)
+	contractTemplates.POST("/", s.audit("contract_template", ""), s.createContractTemplate)
+	contractTemplates.GET("/", s.listContractTemplates)
+	contractTemplates.PATCH("/:id", s.audit("contract_template", "id"), s.updateContractTemplate)
+	contractTemplates.DELETE("/:id", s.audit("contract_template", "id"), s.deleteContractTemplate)
+
+	// Onboarding and offboarding checklists
+	checklistTemplates := s.router.Group("/checklist-templates")
+	checklistTemplates.Use(s.RequireAuth)
+	checklistTemplates.POST("/", s.audit("checklist_template", ""), s.createChecklistTemplate)
+	checklistTemplates.GET("/", s.listChecklistTemplates)
+	checklistTemplates.GET("/:id", s.getChecklistTemplate)
+	checklistTemplates.PATCH("/:id", s.audit("checklist_template", "id"), s.updateChecklistTemplate)
+	checklistTemplates.DELETE("/:id", s.audit("checklist_template", "id"), s.deleteChecklistTemplate)
+
+	checklistTasks := s.router.Group("/checklist-tasks")
+	checklistTasks.Use(s.RequireAuth)
+	checklistTasks.POST("/:id/complete", s.audit("checklist_task", "id"), s.completeChecklistTask)
+	checklistTasks.POST("/:id/reopen", s.audit("checklist_task", "id"), s.reopenChecklistTask)
+
+	profileChangeRequests := s.router.Group("/profile-change-requests")
+	profileChangeRequests.Use(s.RequireAuth)
+	profileChangeRequests.GET("/", s.listProfileChangeRequests)
+	profileChangeRequests.POST("/:id/approve", s.audit("profile_change_request", "id"), s.approveProfileChangeRequest)
+	profileChangeRequests.POST("/:id/reject", s.audit("profile_change_request", "id"), s.rejectProfileChangeRequest)
+
+	approvalPolicies := s.router.Group("/approval-policies")
+	approvalPolicies.Use(s.RequireAuth)
+	approvalPolicies.POST("/", s.audit("approval_policy", ""), s.createApprovalPolicy)
+	approvalPolicies.GET("/", s.listApprovalPolicies)
+	approvalPolicies.PATCH("/:id", s.audit("approval_policy", "id"), s.updateApprovalPolicy)
+	approvalPolicies.DELETE("/:id", s.audit("approval_policy", "id"), s.deleteApprovalPolicy)
+
+	approvalRequests := s.router.Group("/approvals")
+	approvalRequests.Use(s.RequireAuth)
+	approvalRequests.GET("/", s.listApprovals)
+	approvalRequests.GET("/inbox", s.getApprovalInbox)
+	approvalRequests.GET("/:id", s.getApproval)
+	approvalRequests.POST("/:id/approve", s.audit("approval", "id"), s.approveApproval)
+	approvalRequests.POST("/:id/reject", s.audit("approval", "id"), s.rejectApproval)
+	approvalRequests.POST("/:id/cancel", s.audit("approval", "id"), s.cancelApproval)
+
+	approvalDelegations := s.router.Group("/approval-delegations")
+	approvalDelegations.Use(s.RequireAuth)
+	approvalDelegations.POST("/", s.audit("approval_delegation", ""), s.createApprovalDelegation)
+	approvalDelegations.GET("/", s.listApprovalDelegations)
+	approvalDelegations.DELETE("/:id", s.audit("approval_delegation", "id"), s.deleteApprovalDelegation)
+
+	// Employee self-service
+	me := s.router.Group("/me")
+	me.Use(s.RequireEmployee)
+	me.GET("/profile", s.asEmployee("id", s.getEmployeeById))
+	me.GET("/payslips", s.listMyPayslips)
+	me.GET("/payslips/:id", s.getMyPayslip)
+	me.GET("/leave-balances", s.asEmployee("id", s.getLeaveBalances))
+	me.GET("/documents", s.asEmployee("id", s.listDocuments))
+	me.GET("/documents/:documentId", s.asEmployee("id", s.downloadDocument))
+	me.POST("/change-requests", s.audit("profile_change_request", ""), s.createMyProfileChangeRequest)
+	me.GET("/change-requests", s.listMyProfileChangeRequests)
+	me.GET("/notification-preferences", s.getNotificationPreferences)
+	me.PUT("/notification-preferences", s.updateNotificationPreferences)
+
+	// Invited employees don't have an account yet
+	s.router.POST("/invitations/accept", s.acceptInvitation)
+
+	job := s.router.Group("/jobs")
+	job.Use(s.RequireAuth)
+	job.GET("/:id", s.getJob)
+	job.POST("/:id/cancel", s.audit("job", "id"), s.cancelJob)
+	job.GET("/:id/download", s.downloadJobFile)
+
+	webhooks := s.router.Group("/webhooks")
+	webhooks.Use(s.RequireAuth)
+	webhooks.POST("/", s.audit("webhook", ""), s.createWebhook)
+	webhooks.GET("/", s.listWebhooks)
+	webhooks.GET("/:id", s.getWebhook)
+	webhooks.PATCH("/:id", s.audit("webhook", "id"), s.updateWebhook)
+	webhooks.DELETE("/:id", s.audit("webhook", "id"), s.deleteWebhook)
+	webhooks.POST("/:id/rotate-secret", s.audit("webhook", "id"), s.rotateWebhookSecret)
+	webhooks.GET("/:id/deliveries", s.listWebhookDeliveries)
+	webhooks.POST("/:id/deliveries/:deliveryId/redeliver", s.audit("webhook_delivery", ""), s.redeliverWebhook)
+
+	notifications := s.router.Group("/notifications")
+	notifications.GET("/", s.RequireUser, s.listNotifications)
+	notifications.GET("/unread-count", s.RequireUser, s.getUnreadNotificationsCount)
+	notifications.POST("/stream-tickets", s.RequireUser, s.createStreamTicket)
+	notifications.GET("/stream", s.RequireStreamTicket, s.streamNotifications)
+	notifications.POST("/read-all", s.RequireUser, s.markAllNotificationsRead)
+	notifications.POST("/:id/read", s.RequireUser, s.markNotificationRead)
+
+	// Calendar applications fetch the feed without credentials
+	s.router.GET("/shift-feeds/:token", s.getShiftFeed)
+
+	// Pictures are loaded by <img> tags, which can't send credentials
+	s.router.GET("/employee-pictures/:id/:version/:size", s.getEmployeePicture)
 }
 
 func (s *Server) RequireAuth(ctx *gin.Context) {
+	claims, ok := s.authenticate(ctx)
+
+	if !ok {
+		return
+	}
+
+	// Self-service accounts only reach their own data through /me
+	if scope, _ := claims["scope"].(string); scope == utils.ScopeSelfService {
+		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "self-service accounts can only use /me"})
+		return
+	}
+
+	// Unverified accounts only reach their own settings
+	if !ctx.GetBool("emailVerified") {
+		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "verify your email to continue"})
+		return
+	}
+
+	// Attach to req
+	ctx.Set("userId", claims["sub"])
+
+	// Continue
+	ctx.Next()
+}
+
+// RequireUser lets in any signed in user, self-service accounts included,
+// for the routes about the user themselves
+func (s *Server) RequireUser(ctx *gin.Context) {
+	claims, ok := s.authenticate(ctx)
+
+	if !ok {
+		return
+	}
+
+	ctx.Set("userId", claims["sub"])
+
+	ctx.Next()
+}
+
+// RequireEmployee lets in the users linked to an employee record and
+// attaches the employee and their company to the request.
+func (s *Server) RequireEmployee(ctx *gin.Context) {
+	claims, ok := s.authenticate(ctx)
+
+	if !ok {
+		return
+	}
+
+	userId, _ := claims["sub"].(string)
+	var employeeId, companyId string
+
+	err := s.db.QueryRow(`SELECT id, company_id FROM employees WHERE user_id::text = $1 AND termination_date IS NULL`, userId).
+		Scan(&employeeId, &companyId)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "the user isn't linked to an active employee"})
+			return
+		}
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Set("userId", userId)
+	ctx.Set("employeeId", employeeId)
+	ctx.Set("companyId", companyId)
+
+	ctx.Next()
+}
+
+// authenticate validates the bearer token and returns its claims. It aborts
+// the request when the token is missing or invalid.
+func (s *Server) authenticate(ctx *gin.Context) (jwt.MapClaims, bool) {
 	// Get token from cookies or headers
 	authorizationToken := ctx.GetHeader("Authorization")
 
 	if authorizationToken == "" {
 		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing authorization token"})
-		return
+		return nil, false
 	}
-	tokenString := strings.Split(authorizationToken, "Bearer ")[1]
+	tokenString := strings.TrimPrefix(authorizationToken, "Bearer ")
 
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -97,25 +536,41 @@ func (s *Server) RequireAuth(ctx *gin.Context) {
 
 	if err != nil {
 		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
-		return
+		return nil, false
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
 		// Check the exp
-		if float64(time.Now().Unix()) > claims["exp"].(float64) {
+		if exp, ok := claims["exp"].(float64); !ok || float64(time.Now().Unix()) > exp {
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "expired token"})
-			return
+			return nil, false
 		}
 
-		// Find the user with token sub
+		// Resetting the password ends the sessions started before
+		userId, _ := claims["sub"].(string)
+		session, _ := claims["session"].(float64)
 
-		// Attach to req
-		ctx.Set("userId", claims["sub"])
+		var version int
+		var emailVerified bool
 
-		// Continue
-		ctx.Next()
-	} else {
-		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing authorization token"})
-		return
+		err := s.db.QueryRow(`SELECT session_version, email_verified_at IS NOT NULL FROM users WHERE id::text = $1`, userId).
+			Scan(&version, &emailVerified)
+
+		if err == sql.ErrNoRows || (err == nil && int(session) != version) {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "the session ended, sign in again"})
+			return nil, false
+		}
+
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return nil, false
+		}
+
+		ctx.Set("emailVerified", emailVerified)
+
+		return claims, true
 	}
+
+	ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing authorization token"})
+	return nil, false
 }
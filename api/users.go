@@ -4,12 +4,19 @@ import (
 	"database/sql"
 	"fmt"
 	"net/http"
+	"net/mail"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/mailer"
 	"github.com/gioCuesta25/employees-manager-backend/models"
 	"github.com/gioCuesta25/employees-manager-backend/utils"
 )
 
+// signUp creates the account and emails the link that verifies its email.
+// Until then the account only reaches its own settings. It responds the same
+// whether the email already has an account or not, so it can't be used to
+// find out which emails have one.
 func (s *Server) signUp(ctx *gin.Context) {
 	var body models.CreateUserRequest
 
@@ -25,22 +32,56 @@ func (s *Server) signUp(ctx *gin.Context) {
 		return
 	}
 
-	query := "INSERT INTO users (full_name, email, password) VALUES ($1, $2, $3) RETURNING id, full_name, email"
+	language := mailer.DefaultLanguage
+	if body.Language != nil {
+		language = *body.Language
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
 
-	row := s.db.QueryRow(query, body.FullName, body.Email, hashedPassword)
+	query := "INSERT INTO users (full_name, email, password, language) VALUES ($1, $2, $3, $4) RETURNING id, full_name, email"
+
+	row := tx.QueryRow(query, body.FullName, body.Email, hashedPassword, language)
 
 	var user models.GetUsersResponse
 
 	err = row.Scan(&user.ID, &user.FullName, &user.Email)
 
+	response := gin.H{"message": fmt.Sprintf("a link to verify the account was sent to %s", body.Email)}
+
 	if err != nil {
+		if isUniqueViolation(err) {
+			ctx.JSON(http.StatusAccepted, response)
+			return
+		}
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := sendEmailVerification(tx, user.ID, user.FullName, user.Email, language); err != nil {
 		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, gin.H{"user": user})
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, response)
 }
 
+// dummyPasswordHash is compared when the email of a login has no account, so
+// the response takes as long as with a wrong password
+var dummyPasswordHash, _ = utils.HashPassword("dummy password")
+
+// login responds the same to an unknown email and to a wrong password
 func (s *Server) login(ctx *gin.Context) {
 	var body models.LoginBody
 
@@ -49,7 +90,7 @@ func (s *Server) login(ctx *gin.Context) {
 		return
 	}
 
-	query := "SELECT * FROM users WHERE email = $1"
+	query := "SELECT id, full_name, email, password FROM users WHERE email = $1"
 
 	row := s.db.QueryRow(query, body.Email)
 
@@ -57,29 +98,367 @@ func (s *Server) login(ctx *gin.Context) {
 
 	err := row.Scan(&user.ID, &user.FullName, &user.Email, &user.Password)
 
-	if err != nil {
-		if err == sql.ErrNoRows {
-			utils.ErrorResponse(ctx, fmt.Errorf("not found with email %s", body.Email), http.StatusNotFound)
-			return
-		}
-		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+	if err != nil && err != sql.ErrNoRows {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
 		return
 	}
 
-	matchPassword := utils.CheckPasswordHash(body.Password, user.Password)
+	found := err == nil
+
+	if !found {
+		user.Password = dummyPasswordHash
+	}
+
+	matchPassword := utils.CheckPasswordHash(body.Password, user.Password) && found
 
 	if !matchPassword {
 		utils.ErrorResponse(ctx, fmt.Errorf("invalid credentials"), http.StatusUnauthorized)
 		return
 	}
 
-	//Todo: Implement logic for token generation
+	s.respondWithToken(ctx, http.StatusOK, user.ID, user.FullName, user.Email)
+}
+
+// respondWithToken sends the user with a token for the scope of the user
+func (s *Server) respondWithToken(ctx *gin.Context, status int, userId string, fullName string, email string) {
+	scope, err := userScope(s.db, userId)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	var session int
+	var emailVerified bool
+
+	err = s.db.QueryRow(`SELECT session_version, email_verified_at IS NOT NULL FROM users WHERE id = $1`, userId).Scan(&session, &emailVerified)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
 
-	ctx.JSON(http.StatusOK, gin.H{
+	token, err := utils.GetToken(userId, scope, session, s.env.JwtSecret)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(status, gin.H{
 		"user": &models.CreateUserResponse{
-			FullName: user.FullName,
-			Email:    user.Email,
+			FullName:      fullName,
+			Email:         email,
+			EmailVerified: emailVerified,
 		},
-		"token": "aquí va el token",
+		"token": token,
+		"scope": scope,
 	})
 }
+
+// userScope limits the users linked to an employee record to self-service,
+// unless they also own or are members of a company.
+func userScope(db queryer, userId string) (string, error) {
+	query := `SELECT
+		EXISTS (SELECT 1 FROM employees WHERE user_id::text = $1),
+		EXISTS (
+			SELECT 1 FROM companies WHERE owner::text = $1
+			UNION ALL
+			SELECT 1 FROM company_members WHERE user_id::text = $1
+		)`
+
+	var isEmployee, isMember bool
+
+	if err := db.QueryRow(query, userId).Scan(&isEmployee, &isMember); err != nil {
+		return "", err
+	}
+
+	if isEmployee && !isMember {
+		return utils.ScopeSelfService, nil
+	}
+
+	return utils.ScopeFull, nil
+}
+
+const (
+	tokenPurposeEmailVerification = "email_verification"
+	tokenPurposePasswordReset     = "password_reset"
+
+	emailVerificationTTL = 48 * time.Hour
+	passwordResetTTL     = time.Hour
+
+	// Another email with a token isn't sent sooner than this after the last
+	tokenResendInterval = time.Minute
+)
+
+// userTokenLink is where the token of an email goes and how long it lasts
+type userTokenLink struct {
+	Path string
+	TTL  time.Duration
+}
+
+// userTokenLinks are the links of the emails whose template is named after
+// the purpose of their token. The token is issued when the email is sent, see
+// sendEmail, so the outbox never holds it.
+var userTokenLinks = map[string]userTokenLink{
+	tokenPurposeEmailVerification: {Path: "/verify-email?token=", TTL: emailVerificationTTL},
+	tokenPurposePasswordReset:     {Path: "/reset-password?token=", TTL: passwordResetTTL},
+}
+
+// verifyEmail marks the email of the user of the token as verified
+func (s *Server) verifyEmail(ctx *gin.Context) {
+	var body models.VerifyEmailBody
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	userId, err := useUserToken(tx, body.Token, tokenPurposeEmailVerification)
+
+	if err != nil {
+		userTokenErrorResponse(ctx, err)
+		return
+	}
+
+	if _, err := tx.Exec(`UPDATE users SET email_verified_at = COALESCE(email_verified_at, now()) WHERE id = $1`, userId); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"email_verified": true})
+}
+
+// resendEmailVerification sends a new verification link to the signed in
+// user, replacing the previous one
+func (s *Server) resendEmailVerification(ctx *gin.Context) {
+	userId := ctx.GetString("userId")
+
+	var fullName, email, language string
+	var verified bool
+
+	err := s.db.QueryRow(`SELECT full_name, email, language, email_verified_at IS NOT NULL FROM users WHERE id = $1`, userId).
+		Scan(&fullName, &email, &language, &verified)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if verified {
+		utils.ErrorResponse(ctx, fmt.Errorf("the email %s is already verified", email), http.StatusConflict)
+		return
+	}
+
+	recent, err := hasRecentUserToken(s.db, userId, tokenPurposeEmailVerification)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if recent {
+		utils.ErrorResponse(ctx, fmt.Errorf("a verification email was just sent, wait a minute to ask for another"), http.StatusTooManyRequests)
+		return
+	}
+
+	if err := sendEmailVerification(s.db, userId, fullName, email, language); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"message": fmt.Sprintf("a verification link was sent to %s", email)})
+}
+
+// forgotPassword emails a link to reset the password. It responds the same
+// whether the email has an account or not, so it can't be used to find
+// out which emails have one.
+func (s *Server) forgotPassword(ctx *gin.Context) {
+	var body models.ForgotPasswordBody
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	response := gin.H{"message": "if the email has an account, a link to reset its password was sent to it"}
+
+	var userId, fullName, language string
+
+	err := s.db.QueryRow(`SELECT id, full_name, language FROM users WHERE email = $1`, body.Email).Scan(&userId, &fullName, &language)
+
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusAccepted, response)
+		return
+	}
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	recent, err := hasRecentUserToken(s.db, userId, tokenPurposePasswordReset)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if recent {
+		ctx.JSON(http.StatusAccepted, response)
+		return
+	}
+
+	err = enqueueEmail(s.db, models.Email{
+		UserId:   &userId,
+		To:       (&mail.Address{Name: fullName, Address: body.Email}).String(),
+		Template: tokenPurposePasswordReset,
+		Language: language,
+		Data: map[string]any{
+			"full_name": fullName,
+			"minutes":   int(passwordResetTTL.Minutes()),
+		},
+	})
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, response)
+}
+
+// resetPassword sets the password of the user of the token and ends all
+// their sessions, so whoever had access before has to sign in again
+func (s *Server) resetPassword(ctx *gin.Context) {
+	var body models.ResetPasswordBody
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(body.Password)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	userId, err := useUserToken(tx, body.Token, tokenPurposePasswordReset)
+
+	if err != nil {
+		userTokenErrorResponse(ctx, err)
+		return
+	}
+
+	// The reset link proves the user gets the emails of the address
+	query := `UPDATE users
+	SET password = $1, session_version = session_version + 1, email_verified_at = COALESCE(email_verified_at, now()), updated_at = now()
+	WHERE id = $2`
+
+	if _, err := tx.Exec(query, hashedPassword, userId); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := tx.Exec(`DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`, userId, tokenPurposePasswordReset); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "the password was reset, sign in with the new one"})
+}
+
+// sendEmailVerification enqueues the email with the verification link. The
+// token of the link is issued when the email is sent.
+func sendEmailVerification(db queryer, userId string, fullName string, email string, language string) error {
+	return enqueueEmail(db, models.Email{
+		UserId:   &userId,
+		To:       (&mail.Address{Name: fullName, Address: email}).String(),
+		Template: tokenPurposeEmailVerification,
+		Language: language,
+		Data: map[string]any{
+			"full_name": fullName,
+			"hours":     int(emailVerificationTTL.Hours()),
+		},
+	})
+}
+
+// issueUserToken creates a token for the purpose, replacing the unused ones
+// the user had for it
+func issueUserToken(db queryer, userId string, purpose string, ttl time.Duration) (string, error) {
+	token, hash, err := newToken()
+
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := db.Exec(`DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`, userId, purpose); err != nil {
+		return "", err
+	}
+
+	_, err = db.Exec(`INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at) VALUES ($1, $2, $3, $4)`,
+		userId, purpose, hash, time.Now().Add(ttl))
+
+	return token, err
+}
+
+// useUserToken spends the token and returns its user. Used, expired and
+// unknown tokens return sql.ErrNoRows.
+func useUserToken(db queryer, token string, purpose string) (string, error) {
+	query := `UPDATE user_tokens SET used_at = now()
+	WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > now()
+	RETURNING user_id`
+
+	var userId string
+	err := db.QueryRow(query, hashToken(token), purpose).Scan(&userId)
+
+	return userId, err
+}
+
+// hasRecentUserToken reports whether a token for the purpose was issued or
+// its email enqueued within the resend interval
+func hasRecentUserToken(db queryer, userId string, purpose string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM user_tokens WHERE user_id = $1 AND purpose = $2 AND created_at > $3)
+	OR EXISTS (SELECT 1 FROM email_outbox WHERE user_id = $1 AND template = $2 AND created_at > $3)`
+
+	var recent bool
+	err := db.QueryRow(query, userId, purpose, time.Now().Add(-tokenResendInterval)).Scan(&recent)
+
+	return recent, err
+}
+
+func userTokenErrorResponse(ctx *gin.Context, err error) {
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(ctx, fmt.Errorf("the link is invalid, expired or was already used"), http.StatusBadRequest)
+		return
+	}
+	utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+}
@@ -0,0 +1,697 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gioCuesta25/employees-manager-backend/models"
+	"github.com/gioCuesta25/employees-manager-backend/utils"
+	"github.com/gioCuesta25/employees-manager-backend/webhooks"
+	"github.com/lib/pq"
+)
+
+const webhookColumns = `id, company_id, url, events, description, active, created_by, created_at, updated_at`
+
+const webhookDeliveryColumns = `id, subscription_id, event_id, event_type, status, attempts, next_attempt_at, response_status,
+	last_error, delivered_at, created_at, updated_at`
+
+const (
+	// Deliveries sent by each run of the webhooks job, and at once
+	webhookBatchSize   = 50
+	webhookConcurrency = 8
+
+	// A claimed delivery is tried again after this long if its attempt
+	// never ended, as when the process stopped
+	webhookLease = 2 * time.Minute
+)
+
+func (s *Server) createWebhook(ctx *gin.Context) {
+	var body models.CreateWebhookBody
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if !s.requireCompanyRole(ctx, body.CompanyId, models.RoleHR) {
+		return
+	}
+
+	if err := validateWebhook(body.URL, body.Events); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	secret, err := newWebhookSecret()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	query := `INSERT INTO webhook_subscriptions (company_id, url, secret, events, description, created_by)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING ` + webhookColumns
+
+	webhook, err := scanRowIntoWebhook(s.db.QueryRow(query,
+		body.CompanyId,
+		body.URL,
+		secret,
+		pq.Array(body.Events),
+		body.Description,
+		ctx.GetString("userId")))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"webhook": webhook, "secret": secret})
+}
+
+func (s *Server) listWebhooks(ctx *gin.Context) {
+	companyId := ctx.DefaultQuery("company_id", "")
+
+	if companyId == "" {
+		utils.ErrorResponse(ctx, fmt.Errorf("company_id is required"), http.StatusBadRequest)
+		return
+	}
+
+	if !s.requireCompanyRole(ctx, companyId, models.RoleHR) {
+		return
+	}
+
+	rows, err := s.db.Query(`SELECT `+webhookColumns+` FROM webhook_subscriptions WHERE company_id = $1 ORDER BY created_at`, companyId)
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	webhooks := make([]*models.WebhookResponse, 0)
+
+	for rows.Next() {
+		webhook, err := scanRowIntoWebhook(rows)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		webhooks = append(webhooks, webhook)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"webhooks": webhooks})
+}
+
+func (s *Server) getWebhook(ctx *gin.Context) {
+	webhook, ok := s.bindWebhook(ctx)
+
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"webhook": webhook})
+}
+
+func (s *Server) updateWebhook(ctx *gin.Context) {
+	var body models.UpdateWebhookBody
+
+	webhook, ok := s.bindWebhook(ctx)
+
+	if !ok {
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := validateWebhook(body.URL, body.Events); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	query := `UPDATE webhook_subscriptions
+	SET url = $1, events = $2, description = $3, active = $4, updated_at = $5
+	WHERE id = $6
+	RETURNING ` + webhookColumns
+
+	updated, err := scanRowIntoWebhook(s.db.QueryRow(query,
+		body.URL,
+		pq.Array(body.Events),
+		body.Description,
+		body.Active,
+		time.Now(),
+		webhook.ID))
+
+	if err != nil {
+		webhookErrorResponse(ctx, err, webhook.ID)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"webhook": updated})
+}
+
+// rotateWebhookSecret replaces the secret deliveries are signed with. The
+// deliveries still waiting are signed with the new one.
+func (s *Server) rotateWebhookSecret(ctx *gin.Context) {
+	webhook, ok := s.bindWebhook(ctx)
+
+	if !ok {
+		return
+	}
+
+	secret, err := newWebhookSecret()
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	query := `UPDATE webhook_subscriptions SET secret = $1, updated_at = $2 WHERE id = $3 RETURNING ` + webhookColumns
+
+	updated, err := scanRowIntoWebhook(s.db.QueryRow(query, secret, time.Now(), webhook.ID))
+
+	if err != nil {
+		webhookErrorResponse(ctx, err, webhook.ID)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"webhook": updated, "secret": secret})
+}
+
+func (s *Server) deleteWebhook(ctx *gin.Context) {
+	webhook, ok := s.bindWebhook(ctx)
+
+	if !ok {
+		return
+	}
+
+	if _, err := s.db.Exec(`DELETE FROM webhook_subscriptions WHERE id = $1`, webhook.ID); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// listWebhookDeliveries is the delivery log of the subscription, newest
+// first. status=dead lists the dead letters.
+func (s *Server) listWebhookDeliveries(ctx *gin.Context) {
+	webhook, ok := s.bindWebhook(ctx)
+
+	if !ok {
+		return
+	}
+
+	status := ctx.DefaultQuery("status", "")
+	eventType := ctx.DefaultQuery("event", "")
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "100"))
+
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries
+	WHERE subscription_id = $1 AND ($2 = '' OR status = $2) AND ($3 = '' OR event_type = $3)
+	ORDER BY created_at DESC
+	LIMIT $4`
+
+	rows, err := s.db.Query(query, webhook.ID, status, eventType, min(max(limit, 1), 500))
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	deliveries := make([]*models.WebhookDeliveryResponse, 0)
+
+	for rows.Next() {
+		delivery, err := scanRowIntoWebhookDelivery(rows)
+
+		if err != nil {
+			utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+			return
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// redeliverWebhook sends the event of a delivery again as a new delivery,
+// as a dead letter once the endpoint is fixed. The log keeps the old one.
+func (s *Server) redeliverWebhook(ctx *gin.Context) {
+	var params models.GetWebhookDeliveryParams
+
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	webhook, ok := s.bindWebhook(ctx)
+
+	if !ok {
+		return
+	}
+
+	query := `INSERT INTO webhook_deliveries (subscription_id, event_id, event_type)
+	SELECT subscription_id, event_id, event_type FROM webhook_deliveries
+	WHERE id::text = $1 AND subscription_id = $2
+	RETURNING ` + webhookDeliveryColumns
+
+	delivery, err := scanRowIntoWebhookDelivery(s.db.QueryRow(query, params.DeliveryId, webhook.ID))
+
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(ctx, fmt.Errorf("delivery %s not found", params.DeliveryId), http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"delivery": delivery})
+}
+
+// bindWebhook reads the subscription of the route for HR of its company
+func (s *Server) bindWebhook(ctx *gin.Context) (*models.WebhookResponse, bool) {
+	id := ctx.Param("id")
+
+	webhook, err := scanRowIntoWebhook(s.db.QueryRow(`SELECT `+webhookColumns+` FROM webhook_subscriptions WHERE id::text = $1`, id))
+
+	if err != nil {
+		webhookErrorResponse(ctx, err, id)
+		return nil, false
+	}
+
+	if !s.requireCompanyRole(ctx, webhook.CompanyId, models.RoleHR) {
+		return nil, false
+	}
+
+	return webhook, true
+}
+
+// dispatchWebhooks turns the new outbox events into deliveries and sends
+// the due ones
+func (s *Server) dispatchWebhooks(ctx context.Context) error {
+	if err := relayOutboxEvents(s.db); err != nil {
+		return err
+	}
+
+	deliveries, err := claimWebhookDeliveries(s.db)
+
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, webhookConcurrency)
+
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		slots <- struct{}{}
+
+		go func(delivery *webhookAttempt) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			if err := s.deliverWebhook(ctx, delivery); err != nil {
+				log.Printf("webhook delivery %s: %v", delivery.ID, err)
+			}
+		}(delivery)
+	}
+
+	wg.Wait()
+
+	return nil
+}
+
+// relayOutboxEvents creates a delivery of each new event for every active
+// subscription of its company to it
+func relayOutboxEvents(db *sql.DB) error {
+	tx, err := db.Begin()
+
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT id, company_id, type FROM outbox_events
+	WHERE processed_at IS NULL
+	ORDER BY id
+	LIMIT 500
+	FOR UPDATE SKIP LOCKED`)
+
+	if err != nil {
+		return err
+	}
+
+	type outboxEvent struct {
+		id        int64
+		companyId string
+		eventType string
+	}
+
+	events := make([]outboxEvent, 0)
+
+	for rows.Next() {
+		var event outboxEvent
+
+		if err := rows.Scan(&event.id, &event.companyId, &event.eventType); err != nil {
+			rows.Close()
+			return err
+		}
+
+		events = append(events, event)
+	}
+
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	subscriptions := make(map[string][]*webhookSubscription)
+
+	for _, event := range events {
+		if _, ok := subscriptions[event.companyId]; !ok {
+			subscriptions[event.companyId], err = findActiveSubscriptions(tx, event.companyId)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, subscription := range subscriptions[event.companyId] {
+			if !webhooks.Matches(subscription.events, event.eventType) {
+				continue
+			}
+
+			_, err := tx.Exec(`INSERT INTO webhook_deliveries (subscription_id, event_id, event_type) VALUES ($1, $2, $3)`,
+				subscription.id, event.id, event.eventType)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.Exec(`UPDATE outbox_events SET processed_at = now() WHERE id = $1`, event.id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+type webhookSubscription struct {
+	id     string
+	events []string
+}
+
+func findActiveSubscriptions(db queryer, companyId string) ([]*webhookSubscription, error) {
+	rows, err := db.Query(`SELECT id, events FROM webhook_subscriptions WHERE company_id = $1 AND active`, companyId)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := make([]*webhookSubscription, 0)
+
+	for rows.Next() {
+		subscription := new(webhookSubscription)
+
+		if err := rows.Scan(&subscription.id, pq.Array(&subscription.events)); err != nil {
+			return nil, err
+		}
+
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, rows.Err()
+}
+
+// webhookAttempt is a claimed delivery with what sending it needs
+type webhookAttempt struct {
+	ID       string
+	Attempts int
+	URL      string
+	Secret   string
+	Event    models.WebhookEvent
+}
+
+// claimWebhookDeliveries leases the due deliveries of active subscriptions
+// to this process by moving their next attempt past the lease
+func claimWebhookDeliveries(db *sql.DB) ([]*webhookAttempt, error) {
+	query := `UPDATE webhook_deliveries d
+	SET attempts = d.attempts + 1, next_attempt_at = $1, updated_at = now()
+	FROM webhook_subscriptions s, outbox_events e
+	WHERE d.id IN (
+		SELECT d.id FROM webhook_deliveries d
+		JOIN webhook_subscriptions s ON s.id = d.subscription_id
+		WHERE d.status = $2 AND d.next_attempt_at <= now() AND s.active
+		ORDER BY d.next_attempt_at
+		LIMIT $3
+		FOR UPDATE OF d SKIP LOCKED
+	) AND s.id = d.subscription_id AND e.id = d.event_id
+	RETURNING d.id, d.attempts, s.url, s.secret, e.id, e.type, e.company_id, e.created_at, e.data, e.previous`
+
+	rows, err := db.Query(query, time.Now().Add(webhookLease), webhooks.StatusPending, webhookBatchSize)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := make([]*webhookAttempt, 0)
+
+	for rows.Next() {
+		attempt := new(webhookAttempt)
+		var data, previous []byte
+
+		err := rows.Scan(
+			&attempt.ID,
+			&attempt.Attempts,
+			&attempt.URL,
+			&attempt.Secret,
+			&attempt.Event.ID,
+			&attempt.Event.Type,
+			&attempt.Event.CompanyId,
+			&attempt.Event.CreatedAt,
+			&data,
+			&previous)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(data, &attempt.Event.Data); err != nil {
+			return nil, err
+		}
+
+		if previous != nil {
+			if err := json.Unmarshal(previous, &attempt.Event.Previous); err != nil {
+				return nil, err
+			}
+		}
+
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, rows.Err()
+}
+
+// deliverWebhook sends the attempt and records how it went. Endpoints
+// answering 2xx got it; any other answer is retried with backoff until the
+// delivery is dead.
+func (s *Server) deliverWebhook(ctx context.Context, attempt *webhookAttempt) error {
+	body, err := json.Marshal(attempt.Event)
+
+	if err != nil {
+		return err
+	}
+
+	status, sendErr := s.sendWebhook(ctx, attempt, body)
+
+	var responseStatus *int
+	if status != 0 {
+		responseStatus = &status
+	}
+
+	if sendErr == nil {
+		_, err := s.db.Exec(`UPDATE webhook_deliveries
+		SET status = $1, response_status = $2, last_error = NULL, delivered_at = now(), updated_at = now()
+		WHERE id = $3`, webhooks.StatusDelivered, responseStatus, attempt.ID)
+		return err
+	}
+
+	deliveryStatus := webhooks.StatusPending
+	next := time.Now().Add(webhooks.Backoff(attempt.Attempts))
+
+	if attempt.Attempts >= webhooks.MaxAttempts {
+		deliveryStatus = webhooks.StatusDead
+	}
+
+	_, err = s.db.Exec(`UPDATE webhook_deliveries
+	SET status = $1, response_status = $2, last_error = $3, next_attempt_at = $4, updated_at = now()
+	WHERE id = $5`, deliveryStatus, responseStatus, sendErr.Error(), next, attempt.ID)
+
+	return err
+}
+
+func (s *Server) sendWebhook(ctx context.Context, attempt *webhookAttempt, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, attempt.URL, bytes.NewReader(body))
+
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "employees-manager-webhooks")
+	req.Header.Set("X-Webhook-Id", attempt.ID)
+	req.Header.Set("X-Webhook-Event", attempt.Event.Type)
+	req.Header.Set(webhooks.SignatureHeader, webhooks.Sign(attempt.Secret, time.Now(), body))
+
+	res, err := s.webhookClient.Do(req)
+
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	// Only the start of the answer is kept as the error
+	answer, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("the endpoint answered %d: %s", res.StatusCode, answer)
+	}
+
+	return res.StatusCode, nil
+}
+
+// newWebhookClient is the client deliveries are sent with. Unless allowed,
+// it refuses to connect to loopback, private and link-local addresses, so a
+// subscription can't reach the services next to the API.
+func newWebhookClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+
+	if !allowPrivate {
+		dialer.Control = func(network string, address string, conn syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+
+			if err != nil {
+				return err
+			}
+
+			ip := net.ParseIP(host)
+
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+				return fmt.Errorf("webhooks can't be delivered to %s", host)
+			}
+
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+		},
+		// A redirect would skip the check of the subscribed URL
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func validateWebhook(url string, events []string) error {
+	if err := webhooks.ValidateURL(url); err != nil {
+		return err
+	}
+
+	return webhooks.ValidateEvents(events)
+}
+
+func newWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+func webhookErrorResponse(ctx *gin.Context, err error, id string) {
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(ctx, fmt.Errorf("webhook %s not found", id), http.StatusNotFound)
+		return
+	}
+	utils.ErrorResponse(ctx, err, http.StatusInternalServerError)
+}
+
+func scanRowIntoWebhook(row rowScanner) (*models.WebhookResponse, error) {
+	webhook := new(models.WebhookResponse)
+
+	err := row.Scan(
+		&webhook.ID,
+		&webhook.CompanyId,
+		&webhook.URL,
+		pq.Array(&webhook.Events),
+		&webhook.Description,
+		&webhook.Active,
+		&webhook.CreatedBy,
+		&webhook.CreatedAt,
+		&webhook.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+func scanRowIntoWebhookDelivery(row rowScanner) (*models.WebhookDeliveryResponse, error) {
+	delivery := new(models.WebhookDeliveryResponse)
+
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.SubscriptionId,
+		&delivery.EventId,
+		&delivery.EventType,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.NextAttemptAt,
+		&delivery.ResponseStatus,
+		&delivery.LastError,
+		&delivery.DeliveredAt,
+		&delivery.CreatedAt,
+		&delivery.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
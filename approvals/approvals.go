@@ -0,0 +1,130 @@
+// Package approvals describes the approval chains companies configure for
+// the changes that need sign-off.
+package approvals
+
+import (
+	"fmt"
+	"time"
+)
+
+// Subjects are the kinds of change that go through an approval
+const (
+	SubjectSalaryChange  = "salary_change"
+	SubjectTermination   = "termination"
+	SubjectProfileChange = "profile_change"
+)
+
+// Approvers of a step. A manager step is decided by the manager of the
+// employee at the level of the step in the hierarchy; the other steps by any
+// company member with the role.
+const (
+	ApproverManager = "manager"
+	ApproverHR      = "hr"
+	ApproverOwner   = "owner"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusApproved  = "approved"
+	StatusRejected  = "rejected"
+	StatusEscalated = "escalated"
+	StatusCancelled = "cancelled"
+	StatusSkipped   = "skipped"
+)
+
+// MaxManagerLevel bounds how far up the hierarchy a chain can go
+const MaxManagerLevel = 5
+
+// DefaultSteps is the chain of the subjects a company didn't configure
+var DefaultSteps = []Step{{Approver: ApproverHR}}
+
+// Step is a link of the chain. MinAmount makes the step apply only to
+// requests of at least that amount, as a raise above a threshold. A step
+// with TimeoutHours escalates when it stays undecided for that long.
+type Step struct {
+	Approver     string   `json:"approver"`
+	Level        int      `json:"level,omitempty"`
+	MinAmount    *float64 `json:"min_amount,omitempty"`
+	TimeoutHours int      `json:"timeout_hours,omitempty"`
+}
+
+// Validate checks a chain and fills the level of the manager steps
+func Validate(steps []Step) ([]Step, error) {
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("the chain needs at least one step")
+	}
+
+	validated := make([]Step, len(steps))
+
+	for i, step := range steps {
+		switch step.Approver {
+		case ApproverManager:
+			if step.Level == 0 {
+				step.Level = 1
+			}
+
+			if step.Level < 1 || step.Level > MaxManagerLevel {
+				return nil, fmt.Errorf("step %d: level must be between 1 and %d", i+1, MaxManagerLevel)
+			}
+		case ApproverHR, ApproverOwner:
+			if step.Level != 0 {
+				return nil, fmt.Errorf("step %d: only manager steps have a level", i+1)
+			}
+		default:
+			return nil, fmt.Errorf("step %d: approver must be one of manager, hr and owner", i+1)
+		}
+
+		if step.MinAmount != nil && *step.MinAmount < 0 {
+			return nil, fmt.Errorf("step %d: min_amount can't be negative", i+1)
+		}
+
+		if step.TimeoutHours < 0 {
+			return nil, fmt.Errorf("step %d: timeout_hours can't be negative", i+1)
+		}
+
+		validated[i] = step
+	}
+
+	return validated, nil
+}
+
+// Plan returns the steps that apply to a request of the amount. Steps with
+// a threshold never apply to requests without an amount.
+func Plan(steps []Step, amount *float64) []Step {
+	planned := make([]Step, 0, len(steps))
+
+	for _, step := range steps {
+		if step.MinAmount != nil && (amount == nil || *amount < *step.MinAmount) {
+			continue
+		}
+
+		planned = append(planned, step)
+	}
+
+	return planned
+}
+
+// EscalateRole returns who takes over an undecided step of the role: HR
+// takes over from managers with no one above them, the owner from HR. The
+// owner has no one to escalate to.
+func EscalateRole(approver string) (string, bool) {
+	switch approver {
+	case ApproverManager:
+		return ApproverHR, true
+	case ApproverHR:
+		return ApproverOwner, true
+	default:
+		return "", false
+	}
+}
+
+// DueAt returns when a step that became current at from escalates
+func DueAt(step Step, from time.Time) *time.Time {
+	if step.TimeoutHours == 0 {
+		return nil
+	}
+
+	due := from.Add(time.Duration(step.TimeoutHours) * time.Hour)
+
+	return &due
+}
@@ -0,0 +1,136 @@
+// Package attendance turns clock-in and clock-out events into the worked
+// intervals of a day.
+package attendance
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	KindIn  = "in"
+	KindOut = "out"
+)
+
+const (
+	RoundNearest = "nearest"
+	RoundUp      = "up"
+	RoundDown    = "down"
+)
+
+// Location is the timezone used to decide the day an event belongs to.
+// Colombia has no daylight saving, so a fixed offset is enough.
+var Location = time.FixedZone("COT", -5*60*60)
+
+type Event struct {
+	Kind string
+	At   time.Time
+}
+
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+func (i Interval) Minutes() int {
+	return int(i.End.Sub(i.Start).Minutes())
+}
+
+// Rounding is applied to each interval before adding up the worked time
+type Rounding struct {
+	Minutes int
+	Mode    string
+}
+
+// Day returns the local date an instant belongs to
+func Day(t time.Time) time.Time {
+	local := t.In(Location)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// BuildDay pairs every clock-in with the clock-out that follows it and
+// returns the intervals that start on the given day. The events may include
+// the days around it, so a shift that ends after midnight still belongs to
+// the day it started. A clock-in without clock-out, or a clock-out without a
+// previous clock-in, on that day is a missing punch: it doesn't produce an
+// interval and the day is flagged so someone corrects it.
+func BuildDay(events []Event, day time.Time) ([]Interval, bool) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, Location)
+	end := start.AddDate(0, 0, 1)
+
+	inDay := func(t time.Time) bool {
+		return !t.Before(start) && t.Before(end)
+	}
+
+	sorted := make([]Event, len(events))
+	copy(sorted, events)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].At.Before(sorted[j].At)
+	})
+
+	intervals := make([]Interval, 0)
+	missingPunch := false
+	var open *Event
+
+	for i := range sorted {
+		event := sorted[i]
+
+		switch event.Kind {
+		case KindIn:
+			if open != nil && inDay(open.At) {
+				missingPunch = true
+			}
+			open = &sorted[i]
+		case KindOut:
+			if open == nil {
+				if inDay(event.At) {
+					missingPunch = true
+				}
+				continue
+			}
+			if inDay(open.At) {
+				intervals = append(intervals, Interval{Start: open.At, End: event.At})
+			}
+			open = nil
+		}
+	}
+
+	if open != nil && inDay(open.At) {
+		missingPunch = true
+	}
+
+	return intervals, missingPunch
+}
+
+// WorkedMinutes adds up the intervals applying the rounding rule to each
+func WorkedMinutes(intervals []Interval, rounding Rounding) int {
+	total := 0
+
+	for _, interval := range intervals {
+		total += Round(interval.Minutes(), rounding)
+	}
+
+	return total
+}
+
+// Round rounds the minutes to a multiple of the rule increment
+func Round(minutes int, rounding Rounding) int {
+	if rounding.Minutes <= 0 {
+		return minutes
+	}
+
+	units := float64(minutes) / float64(rounding.Minutes)
+
+	switch rounding.Mode {
+	case RoundUp:
+		units = math.Ceil(units)
+	case RoundDown:
+		units = math.Floor(units)
+	default:
+		units = math.Round(units)
+	}
+
+	return int(units) * rounding.Minutes
+}
@@ -0,0 +1,66 @@
+// Package audit computes the changes recorded in the audit log
+package audit
+
+import "reflect"
+
+// Actions other than these are named after the endpoint, as approve
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// redacted are the fields never copied to the log
+var redacted = map[string]bool{
+	"password":   true,
+	"token":      true,
+	"token_hash": true,
+}
+
+// Change is the value of a field before and after a mutation. Fields of a
+// created record have no before and fields of a deleted one no after.
+type Change struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// Diff returns the fields whose value changed between the states. A nil
+// state stands for a record that doesn't exist.
+func Diff(before map[string]any, after map[string]any) map[string]Change {
+	changes := make(map[string]Change)
+
+	for field, value := range before {
+		if redacted[field] {
+			continue
+		}
+
+		if next, ok := after[field]; !ok || !reflect.DeepEqual(value, next) {
+			changes[field] = Change{Before: value, After: next}
+		}
+	}
+
+	for field, value := range after {
+		if redacted[field] {
+			continue
+		}
+
+		if _, ok := before[field]; !ok {
+			changes[field] = Change{After: value}
+		}
+	}
+
+	return changes
+}
+
+// Action names the mutation from the states, or returns the fallback when
+// the record existed before and after it.
+func Action(before map[string]any, after map[string]any, fallback string) string {
+	switch {
+	case before == nil && after != nil:
+		return ActionCreate
+	case before != nil && after == nil:
+		return ActionDelete
+	default:
+		return fallback
+	}
+}
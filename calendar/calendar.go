@@ -0,0 +1,114 @@
+// Package calendar knows which days are working days for a company: its
+// weekly working pattern, the Colombian national holidays and the custom
+// closures the company defines.
+package calendar
+
+import "time"
+
+// DefaultWorkingWeek is Monday to Friday
+var DefaultWorkingWeek = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+
+type Calendar struct {
+	workingWeek      [7]bool
+	nationalHolidays bool
+	closures         map[time.Time]string
+	holidays         map[int]map[time.Time]string
+}
+
+func New(workingWeek []time.Weekday, nationalHolidays bool) *Calendar {
+	c := &Calendar{
+		nationalHolidays: nationalHolidays,
+		closures:         make(map[time.Time]string),
+		holidays:         make(map[int]map[time.Time]string),
+	}
+
+	for _, day := range workingWeek {
+		c.workingWeek[day] = true
+	}
+
+	return c
+}
+
+// Default is the calendar of a company that hasn't configured one
+func Default() *Calendar {
+	return New(DefaultWorkingWeek, true)
+}
+
+// AddClosure marks a date as non working for the company
+func (c *Calendar) AddClosure(day time.Time, name string) {
+	c.closures[truncate(day)] = name
+}
+
+// Holiday returns the name of the national holiday on the date, if any and
+// only when the calendar observes them.
+func (c *Calendar) Holiday(day time.Time) (string, bool) {
+	if !c.nationalHolidays {
+		return "", false
+	}
+
+	day = truncate(day)
+
+	holidays, ok := c.holidays[day.Year()]
+	if !ok {
+		holidays = make(map[time.Time]string)
+		for _, holiday := range ColombianHolidays(day.Year()) {
+			holidays[holiday.Date] = holiday.Name
+		}
+		c.holidays[day.Year()] = holidays
+	}
+
+	name, ok := holidays[day]
+	return name, ok
+}
+
+func (c *Calendar) Closure(day time.Time) (string, bool) {
+	name, ok := c.closures[truncate(day)]
+	return name, ok
+}
+
+func (c *Calendar) IsWorkingDay(day time.Time) bool {
+	if !c.workingWeek[day.Weekday()] {
+		return false
+	}
+
+	if _, ok := c.Holiday(day); ok {
+		return false
+	}
+
+	if _, ok := c.Closure(day); ok {
+		return false
+	}
+
+	return true
+}
+
+// WorkingDaysBetween counts the working days from start to end, both
+// included. It returns 0 when end is before start.
+func (c *Calendar) WorkingDaysBetween(start time.Time, end time.Time) int {
+	days := 0
+
+	for day := truncate(start); !day.After(truncate(end)); day = day.AddDate(0, 0, 1) {
+		if c.IsWorkingDay(day) {
+			days++
+		}
+	}
+
+	return days
+}
+
+// WorkingWeek returns the weekdays the company works
+func (c *Calendar) WorkingWeek() []time.Weekday {
+	days := make([]time.Weekday, 0, 7)
+
+	for day, working := range c.workingWeek {
+		if working {
+			days = append(days, time.Weekday(day))
+		}
+	}
+
+	return days
+}
+
+func truncate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
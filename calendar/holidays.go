@@ -0,0 +1,81 @@
+package calendar
+
+import (
+	"sort"
+	"time"
+)
+
+type Holiday struct {
+	Date time.Time `json:"date"`
+	Name string    `json:"name"`
+}
+
+// ColombianHolidays returns the national holidays of the year sorted by
+// date. Most religious and civic holidays follow Ley 51 de 1983 (Ley
+// Emiliani) and are moved to the next Monday when they don't fall on one.
+func ColombianHolidays(year int) []Holiday {
+	holidays := []Holiday{
+		{Date: date(year, time.January, 1), Name: "Año Nuevo"},
+		{Date: date(year, time.May, 1), Name: "Día del Trabajo"},
+		{Date: date(year, time.July, 20), Name: "Día de la Independencia"},
+		{Date: date(year, time.August, 7), Name: "Batalla de Boyacá"},
+		{Date: date(year, time.December, 8), Name: "Inmaculada Concepción"},
+		{Date: date(year, time.December, 25), Name: "Navidad"},
+
+		{Date: nextMonday(date(year, time.January, 6)), Name: "Día de los Reyes Magos"},
+		{Date: nextMonday(date(year, time.March, 19)), Name: "Día de San José"},
+		{Date: nextMonday(date(year, time.June, 29)), Name: "San Pedro y San Pablo"},
+		{Date: nextMonday(date(year, time.August, 15)), Name: "Asunción de la Virgen"},
+		{Date: nextMonday(date(year, time.October, 12)), Name: "Día de la Raza"},
+		{Date: nextMonday(date(year, time.November, 1)), Name: "Todos los Santos"},
+		{Date: nextMonday(date(year, time.November, 11)), Name: "Independencia de Cartagena"},
+	}
+
+	easter := Easter(year)
+
+	holidays = append(holidays,
+		Holiday{Date: easter.AddDate(0, 0, -3), Name: "Jueves Santo"},
+		Holiday{Date: easter.AddDate(0, 0, -2), Name: "Viernes Santo"},
+		Holiday{Date: nextMonday(easter.AddDate(0, 0, 39)), Name: "Ascensión del Señor"},
+		Holiday{Date: nextMonday(easter.AddDate(0, 0, 60)), Name: "Corpus Christi"},
+		Holiday{Date: nextMonday(easter.AddDate(0, 0, 68)), Name: "Sagrado Corazón de Jesús"},
+	)
+
+	sort.Slice(holidays, func(i, j int) bool {
+		return holidays[i].Date.Before(holidays[j].Date)
+	})
+
+	return holidays
+}
+
+// Easter returns the Western Easter Sunday of the year using the anonymous
+// Gregorian algorithm (Meeus/Jones/Butcher).
+func Easter(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+
+	return date(year, time.Month(month), day)
+}
+
+// nextMonday returns the date itself when it is a Monday or the Monday that
+// follows it otherwise.
+func nextMonday(t time.Time) time.Time {
+	offset := (int(time.Monday) - int(t.Weekday()) + 7) % 7
+	return t.AddDate(0, 0, offset)
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,139 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+// The calendars published for each year, with the Emiliani holidays already
+// moved to Monday
+var publishedHolidays = map[int][]Holiday{
+	2024: {
+		{Date: date(2024, time.January, 1), Name: "Año Nuevo"},
+		{Date: date(2024, time.January, 8), Name: "Día de los Reyes Magos"},
+		{Date: date(2024, time.March, 25), Name: "Día de San José"},
+		{Date: date(2024, time.March, 28), Name: "Jueves Santo"},
+		{Date: date(2024, time.March, 29), Name: "Viernes Santo"},
+		{Date: date(2024, time.May, 1), Name: "Día del Trabajo"},
+		{Date: date(2024, time.May, 13), Name: "Ascensión del Señor"},
+		{Date: date(2024, time.June, 3), Name: "Corpus Christi"},
+		{Date: date(2024, time.June, 10), Name: "Sagrado Corazón de Jesús"},
+		{Date: date(2024, time.July, 1), Name: "San Pedro y San Pablo"},
+		{Date: date(2024, time.July, 20), Name: "Día de la Independencia"},
+		{Date: date(2024, time.August, 7), Name: "Batalla de Boyacá"},
+		{Date: date(2024, time.August, 19), Name: "Asunción de la Virgen"},
+		{Date: date(2024, time.October, 14), Name: "Día de la Raza"},
+		{Date: date(2024, time.November, 4), Name: "Todos los Santos"},
+		{Date: date(2024, time.November, 11), Name: "Independencia de Cartagena"},
+		{Date: date(2024, time.December, 8), Name: "Inmaculada Concepción"},
+		{Date: date(2024, time.December, 25), Name: "Navidad"},
+	},
+	2025: {
+		{Date: date(2025, time.January, 1), Name: "Año Nuevo"},
+		{Date: date(2025, time.January, 6), Name: "Día de los Reyes Magos"},
+		{Date: date(2025, time.March, 24), Name: "Día de San José"},
+		{Date: date(2025, time.April, 17), Name: "Jueves Santo"},
+		{Date: date(2025, time.April, 18), Name: "Viernes Santo"},
+		{Date: date(2025, time.May, 1), Name: "Día del Trabajo"},
+		{Date: date(2025, time.June, 2), Name: "Ascensión del Señor"},
+		{Date: date(2025, time.June, 23), Name: "Corpus Christi"},
+		{Date: date(2025, time.June, 30), Name: "Sagrado Corazón de Jesús"},
+		{Date: date(2025, time.June, 30), Name: "San Pedro y San Pablo"},
+		{Date: date(2025, time.July, 20), Name: "Día de la Independencia"},
+		{Date: date(2025, time.August, 7), Name: "Batalla de Boyacá"},
+		{Date: date(2025, time.August, 18), Name: "Asunción de la Virgen"},
+		{Date: date(2025, time.October, 13), Name: "Día de la Raza"},
+		{Date: date(2025, time.November, 3), Name: "Todos los Santos"},
+		{Date: date(2025, time.November, 17), Name: "Independencia de Cartagena"},
+		{Date: date(2025, time.December, 8), Name: "Inmaculada Concepción"},
+		{Date: date(2025, time.December, 25), Name: "Navidad"},
+	},
+	2026: {
+		{Date: date(2026, time.January, 1), Name: "Año Nuevo"},
+		{Date: date(2026, time.January, 12), Name: "Día de los Reyes Magos"},
+		{Date: date(2026, time.March, 23), Name: "Día de San José"},
+		{Date: date(2026, time.April, 2), Name: "Jueves Santo"},
+		{Date: date(2026, time.April, 3), Name: "Viernes Santo"},
+		{Date: date(2026, time.May, 1), Name: "Día del Trabajo"},
+		{Date: date(2026, time.May, 18), Name: "Ascensión del Señor"},
+		{Date: date(2026, time.June, 8), Name: "Corpus Christi"},
+		{Date: date(2026, time.June, 15), Name: "Sagrado Corazón de Jesús"},
+		{Date: date(2026, time.June, 29), Name: "San Pedro y San Pablo"},
+		{Date: date(2026, time.July, 20), Name: "Día de la Independencia"},
+		{Date: date(2026, time.August, 7), Name: "Batalla de Boyacá"},
+		{Date: date(2026, time.August, 17), Name: "Asunción de la Virgen"},
+		{Date: date(2026, time.October, 12), Name: "Día de la Raza"},
+		{Date: date(2026, time.November, 2), Name: "Todos los Santos"},
+		{Date: date(2026, time.November, 16), Name: "Independencia de Cartagena"},
+		{Date: date(2026, time.December, 8), Name: "Inmaculada Concepción"},
+		{Date: date(2026, time.December, 25), Name: "Navidad"},
+	},
+}
+
+func TestColombianHolidays(t *testing.T) {
+	for year, want := range publishedHolidays {
+		got := ColombianHolidays(year)
+
+		if len(got) != len(want) {
+			t.Errorf("ColombianHolidays(%d) returned %d holidays, want %d", year, len(got), len(want))
+		}
+
+		dates := make(map[string]time.Time, len(got))
+		for _, holiday := range got {
+			dates[holiday.Name] = holiday.Date
+		}
+
+		for _, holiday := range want {
+			date, ok := dates[holiday.Name]
+
+			if !ok {
+				t.Errorf("ColombianHolidays(%d) is missing %s", year, holiday.Name)
+				continue
+			}
+
+			if !date.Equal(holiday.Date) {
+				t.Errorf("ColombianHolidays(%d): %s on %s, want %s", year, holiday.Name, date.Format("2006-01-02"), holiday.Date.Format("2006-01-02"))
+			}
+		}
+
+		for i := 1; i < len(got); i++ {
+			if got[i].Date.Before(got[i-1].Date) {
+				t.Errorf("ColombianHolidays(%d) is not sorted: %s before %s", year, got[i-1].Name, got[i].Name)
+			}
+		}
+	}
+}
+
+func TestEaster(t *testing.T) {
+	tests := []struct {
+		year int
+		want time.Time
+	}{
+		{2024, date(2024, time.March, 31)},
+		{2025, date(2025, time.April, 20)},
+		{2026, date(2026, time.April, 5)},
+	}
+
+	for _, test := range tests {
+		if got := Easter(test.year); !got.Equal(test.want) {
+			t.Errorf("Easter(%d) = %s, want %s", test.year, got.Format("2006-01-02"), test.want.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestNextMonday(t *testing.T) {
+	tests := []struct {
+		day  time.Time
+		want time.Time
+	}{
+		{date(2026, time.October, 12), date(2026, time.October, 12)},
+		{date(2026, time.October, 13), date(2026, time.October, 19)},
+		{date(2026, time.October, 18), date(2026, time.October, 19)},
+	}
+
+	for _, test := range tests {
+		if got := nextMonday(test.day); !got.Equal(test.want) {
+			t.Errorf("nextMonday(%s) = %s, want %s", test.day.Format("2006-01-02"), got.Format("2006-01-02"), test.want.Format("2006-01-02"))
+		}
+	}
+}
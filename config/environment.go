@@ -12,6 +12,49 @@ type Environment struct {
 	DbPassword string `mapstructure:"DB_PASSWORD"`
 	ApiPort    string `mapstructure:"API_PORT"`
 	JwtSecret  string `mapstructure:"JWT_SECRET"`
+
+	// Electronic payroll (nómina electrónica)
+	DianSoftwareId  string `mapstructure:"DIAN_SOFTWARE_ID"`
+	DianSoftwarePin string `mapstructure:"DIAN_SOFTWARE_PIN"`
+	DianProviderNit string `mapstructure:"DIAN_PROVIDER_NIT"`
+	DianProviderDv  string `mapstructure:"DIAN_PROVIDER_DV"`
+	DianEnvironment string `mapstructure:"DIAN_ENVIRONMENT"`
+	DianPrefix      string `mapstructure:"DIAN_PREFIX"`
+	DianOutputDir   string `mapstructure:"DIAN_OUTPUT_DIR"`
+
+	// Blob storage for uploaded files, "local" or "s3"
+	BlobBackend      string `mapstructure:"BLOB_BACKEND"`
+	BlobLocalDir     string `mapstructure:"BLOB_LOCAL_DIR"`
+	S3Endpoint       string `mapstructure:"S3_ENDPOINT"`
+	S3Region         string `mapstructure:"S3_REGION"`
+	S3Bucket         string `mapstructure:"S3_BUCKET"`
+	S3AccessKey      string `mapstructure:"S3_ACCESS_KEY"`
+	S3SecretKey      string `mapstructure:"S3_SECRET_KEY"`
+	S3PathStyle      bool   `mapstructure:"S3_PATH_STYLE"`
+	DocumentMaxBytes int64  `mapstructure:"DOCUMENT_MAX_BYTES"`
+
+	// Background jobs. On shutdown the running jobs get JOB_DRAIN_SECONDS to
+	// finish before they are interrupted and retried later.
+	JobWorkers      int `mapstructure:"JOB_WORKERS"`
+	JobDrainSeconds int `mapstructure:"JOB_DRAIN_SECONDS"`
+
+	// Webhooks are not delivered to loopback and private addresses unless
+	// allowed, as to test against a local endpoint
+	WebhookAllowPrivate bool `mapstructure:"WEBHOOK_ALLOW_PRIVATE"`
+
+	// Email, "smtp" or "file", which writes the emails to MAIL_FILE_DIR
+	// instead of sending them. SMTP_SECURITY is "starttls", "tls" or "none".
+	MailBackend  string `mapstructure:"MAIL_BACKEND"`
+	MailFrom     string `mapstructure:"MAIL_FROM"`
+	MailFileDir  string `mapstructure:"MAIL_FILE_DIR"`
+	SmtpHost     string `mapstructure:"SMTP_HOST"`
+	SmtpPort     string `mapstructure:"SMTP_PORT"`
+	SmtpUsername string `mapstructure:"SMTP_USERNAME"`
+	SmtpPassword string `mapstructure:"SMTP_PASSWORD"`
+	SmtpSecurity string `mapstructure:"SMTP_SECURITY"`
+
+	// The web application the links in the emails point to
+	AppURL string `mapstructure:"APP_URL"`
 }
 
 func LoadEnvironment() (Environment, error) {
@@ -21,6 +64,23 @@ func LoadEnvironment() (Environment, error) {
 	viper.AddConfigPath(".")
 	viper.SetConfigType("env")
 
+	viper.SetDefault("DIAN_ENVIRONMENT", "2")
+	viper.SetDefault("DIAN_PREFIX", "NE")
+	viper.SetDefault("DIAN_OUTPUT_DIR", "nomina-electronica")
+	viper.SetDefault("BLOB_BACKEND", "local")
+	viper.SetDefault("BLOB_LOCAL_DIR", "uploads")
+	viper.SetDefault("S3_ENDPOINT", "https://s3.amazonaws.com")
+	viper.SetDefault("S3_REGION", "us-east-1")
+	viper.SetDefault("DOCUMENT_MAX_BYTES", 10<<20)
+	viper.SetDefault("JOB_WORKERS", 4)
+	viper.SetDefault("JOB_DRAIN_SECONDS", 30)
+	viper.SetDefault("MAIL_BACKEND", "file")
+	viper.SetDefault("MAIL_FROM", "Employees Manager <no-reply@localhost>")
+	viper.SetDefault("MAIL_FILE_DIR", "mail")
+	viper.SetDefault("SMTP_PORT", "587")
+	viper.SetDefault("SMTP_SECURITY", "starttls")
+	viper.SetDefault("APP_URL", "http://localhost:3000")
+
 	err := viper.ReadInConfig()
 
 	if err != nil {
@@ -0,0 +1,138 @@
+// Package contract holds the rules of the Colombian labor code (Código
+// Sustantivo del Trabajo) for employment contracts: terms, probation periods,
+// renewals and the notice to end a fixed-term contract.
+package contract
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	TypeIndefinite     = "indefinite"
+	TypeFixedTerm      = "fixed_term"
+	TypeWorkOrLabor    = "work_or_labor"
+	TypeApprenticeship = "apprenticeship"
+	TypeService        = "service"
+)
+
+const (
+	// NoticeDays is the written notice needed to not renew a fixed-term
+	// contract; without it the contract renews for the same term (art. 46).
+	NoticeDays = 30
+	// MaxProbationDays is the longest probation period (art. 78)
+	MaxProbationDays = 60
+	// ShortTermRenewals is how many times a contract shorter than a year
+	// can be renewed for the same or a shorter term; after that every
+	// renewal is for at least a year (art. 46).
+	ShortTermRenewals = 3
+)
+
+// Terms are the parts of a contract the rules depend on
+type Terms struct {
+	Type          string
+	StartDate     time.Time
+	EndDate       *time.Time
+	ProbationDays int
+}
+
+// Validate checks the terms against the limits of the law
+func Validate(terms Terms) error {
+	if terms.EndDate != nil && !terms.EndDate.After(terms.StartDate) {
+		return errors.New("end_date must be after start_date")
+	}
+
+	switch terms.Type {
+	case TypeIndefinite:
+		if terms.EndDate != nil {
+			return errors.New("indefinite contracts don't have an end_date")
+		}
+	case TypeFixedTerm:
+		if terms.EndDate == nil {
+			return errors.New("fixed-term contracts need an end_date")
+		}
+		if terms.EndDate.After(terms.StartDate.AddDate(3, 0, 0)) {
+			return errors.New("fixed-term contracts can't be longer than three years")
+		}
+	case TypeApprenticeship:
+		if terms.EndDate == nil {
+			return errors.New("apprenticeship contracts need an end_date")
+		}
+		if terms.EndDate.After(terms.StartDate.AddDate(2, 0, 0)) {
+			return errors.New("apprenticeship contracts can't be longer than two years")
+		}
+	case TypeWorkOrLabor:
+	case TypeService:
+		if terms.ProbationDays > 0 {
+			return errors.New("service contracts don't have a probation period")
+		}
+	default:
+		return fmt.Errorf("unknown contract type %s", terms.Type)
+	}
+
+	if terms.ProbationDays > MaxProbationDays {
+		return fmt.Errorf("the probation period can't be longer than %d days", MaxProbationDays)
+	}
+
+	// Fixed-term contracts shorter than a year can't have a probation
+	// longer than a fifth of the term
+	if terms.Type == TypeFixedTerm && terms.EndDate.Before(terms.StartDate.AddDate(1, 0, 0)) {
+		if limit := TermDays(terms.StartDate, *terms.EndDate) / 5; terms.ProbationDays > limit {
+			return fmt.Errorf("the probation period can't be longer than %d days, a fifth of the term", limit)
+		}
+	}
+
+	return nil
+}
+
+// TermDays counts the days of a term, both dates included
+func TermDays(start time.Time, end time.Time) int {
+	return int(end.Sub(start).Hours()/24) + 1
+}
+
+// NoticeDeadline is the last day to notify that a fixed-term contract won't
+// be renewed.
+func NoticeDeadline(end time.Time) time.Time {
+	return end.AddDate(0, 0, -NoticeDays)
+}
+
+// RenewalEnd returns the end of the term that follows a fixed-term contract.
+// Without a requested end the contract renews for the same term. Contracts
+// shorter than a year renewed ShortTermRenewals times renew for at least a
+// year.
+func RenewalEnd(start time.Time, end time.Time, renewals int, requested *time.Time) (time.Time, error) {
+	next := end.AddDate(0, 0, 1)
+	days := TermDays(start, end)
+	newEnd := next.AddDate(0, 0, days-1)
+
+	if requested != nil {
+		if !requested.After(next) {
+			return time.Time{}, errors.New("end_date must be after the start of the renewal")
+		}
+		newEnd = *requested
+	}
+
+	shortTerm := end.Before(start.AddDate(1, 0, 0))
+
+	if shortTerm && renewals >= ShortTermRenewals {
+		if minimum := next.AddDate(1, 0, -1); newEnd.Before(minimum) {
+			if requested != nil {
+				return time.Time{}, fmt.Errorf("after %d renewals the contract must be renewed for at least a year", ShortTermRenewals)
+			}
+			newEnd = minimum
+		}
+	}
+
+	return newEnd, nil
+}
+
+// ProbationEnd is the last day of the probation period, nil when there is none
+func ProbationEnd(start time.Time, days int) *time.Time {
+	if days <= 0 {
+		return nil
+	}
+
+	end := start.AddDate(0, 0, days-1)
+	return &end
+}
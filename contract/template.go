@@ -0,0 +1,72 @@
+package contract
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var placeholder = regexp.MustCompile(`\{\{\s*([a-z_]+(?:\.[a-z_]+)*)\s*\}\}`)
+
+// Placeholders lists the fields a template can use
+var Placeholders = []string{
+	"company.name",
+	"company.nit",
+	"employee.name",
+	"employee.last_name",
+	"employee.full_name",
+	"employee.id_number",
+	"employee.email",
+	"employee.position",
+	"employee.department",
+	"contract.type",
+	"contract.start_date",
+	"contract.end_date",
+	"contract.salary",
+	"contract.weekly_hours",
+	"contract.probation_days",
+	"today",
+}
+
+// Render replaces the {{placeholders}} of the template with the fields.
+// Placeholders without a value are an error, so a typo in a template
+// doesn't produce a contract with blanks.
+func Render(template string, fields map[string]string) (string, error) {
+	missing := make(map[string]bool)
+
+	result := placeholder.ReplaceAllStringFunc(template, func(match string) string {
+		name := placeholder.FindStringSubmatch(match)[1]
+		value, ok := fields[name]
+
+		if !ok {
+			missing[name] = true
+			return match
+		}
+
+		return value
+	})
+
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return "", fmt.Errorf("unknown placeholders: %s", strings.Join(names, ", "))
+	}
+
+	return result, nil
+}
+
+// Check validates the placeholders of a template without rendering it
+func Check(template string) error {
+	fields := make(map[string]string, len(Placeholders))
+	for _, name := range Placeholders {
+		fields[name] = ""
+	}
+
+	_, err := Render(template, fields)
+	return err
+}
@@ -0,0 +1,215 @@
+// Package customfields validates the attributes companies define on their
+// employees, departments and positions. The values are stored as a JSON
+// object keyed by the field key.
+package customfields
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	EntityEmployee   = "employee"
+	EntityDepartment = "department"
+	EntityPosition   = "position"
+)
+
+const (
+	TypeText    = "text"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+	TypeDate    = "date"
+	TypeEnum    = "enum"
+)
+
+const dateLayout = "2006-01-02"
+
+var keyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// Field is the definition of a custom field. Pattern applies to text fields
+// and has to match the whole value.
+type Field struct {
+	Key      string
+	Label    string
+	Type     string
+	Required bool
+	Options  []string
+	Pattern  *string
+}
+
+// ValidateDefinition checks the field can be used to validate values
+func ValidateDefinition(field Field) error {
+	if !keyPattern.MatchString(field.Key) {
+		return errors.New("key must start with a letter and contain only lowercase letters, digits and underscores")
+	}
+
+	switch field.Type {
+	case TypeEnum:
+		if len(field.Options) == 0 {
+			return errors.New("enum fields need options")
+		}
+
+		seen := make(map[string]bool)
+		for _, option := range field.Options {
+			if option == "" || seen[option] {
+				return errors.New("options must be unique and not empty")
+			}
+			seen[option] = true
+		}
+	case TypeText, TypeNumber, TypeBoolean, TypeDate:
+		if len(field.Options) > 0 {
+			return fmt.Errorf("only enum fields have options")
+		}
+	default:
+		return fmt.Errorf("unknown field type %s", field.Type)
+	}
+
+	if field.Pattern != nil {
+		if field.Type != TypeText {
+			return errors.New("only text fields have a pattern")
+		}
+
+		if _, err := compile(*field.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Validate checks the values against the fields and returns them
+// normalized. Null values are the same as missing ones.
+func Validate(fields []Field, values map[string]any) (map[string]any, error) {
+	byKey := make(map[string]Field, len(fields))
+	for _, field := range fields {
+		byKey[field.Key] = field
+	}
+
+	for key := range values {
+		if _, ok := byKey[key]; !ok {
+			return nil, fmt.Errorf("unknown custom field %s", key)
+		}
+	}
+
+	result := make(map[string]any)
+
+	for _, field := range fields {
+		value, ok := values[field.Key]
+
+		if !ok || value == nil {
+			if field.Required {
+				return nil, fmt.Errorf("custom field %s is required", field.Key)
+			}
+			continue
+		}
+
+		normalized, err := validateValue(field, value)
+
+		if err != nil {
+			return nil, fmt.Errorf("custom field %s %w", field.Key, err)
+		}
+
+		result[field.Key] = normalized
+	}
+
+	return result, nil
+}
+
+// ParseFilter converts a value from a query string to the type of the field
+func ParseFilter(field Field, raw string) (any, error) {
+	switch field.Type {
+	case TypeNumber:
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("custom field %s must be a number", field.Key)
+		}
+		return value, nil
+	case TypeBoolean:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("custom field %s must be true or false", field.Key)
+		}
+		return value, nil
+	default:
+		value, err := validateValue(field, raw)
+		if err != nil {
+			return nil, fmt.Errorf("custom field %s %w", field.Key, err)
+		}
+		return value, nil
+	}
+}
+
+// Format renders a value as text, for exports
+func Format(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func validateValue(field Field, value any) (any, error) {
+	switch field.Type {
+	case TypeNumber:
+		if number, ok := value.(float64); ok {
+			return number, nil
+		}
+		return nil, errors.New("must be a number")
+	case TypeBoolean:
+		if boolean, ok := value.(bool); ok {
+			return boolean, nil
+		}
+		return nil, errors.New("must be true or false")
+	}
+
+	text, ok := value.(string)
+
+	if !ok {
+		return nil, errors.New("must be a string")
+	}
+
+	switch field.Type {
+	case TypeDate:
+		date, err := time.Parse(dateLayout, text)
+		if err != nil {
+			return nil, errors.New("must be a date formatted as YYYY-MM-DD")
+		}
+		return date.Format(dateLayout), nil
+	case TypeEnum:
+		for _, option := range field.Options {
+			if option == text {
+				return text, nil
+			}
+		}
+		return nil, fmt.Errorf("must be one of %v", field.Options)
+	}
+
+	if field.Required && text == "" {
+		return nil, errors.New("is required")
+	}
+
+	if field.Pattern != nil {
+		pattern, err := compile(*field.Pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		if !pattern.MatchString(text) {
+			return nil, fmt.Errorf("doesn't match %s", *field.Pattern)
+		}
+	}
+
+	return text, nil
+}
+
+func compile(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile(`^(?:` + pattern + `)$`)
+}
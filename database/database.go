@@ -9,7 +9,18 @@ import (
 )
 
 func NewDbConnection(env config.Environment) (*sql.DB, error) {
-	// data source name
+	db, err := sql.Open("postgres", ConnectionString(env))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// ConnectionString is the data source name of the database, also used by
+// the listeners of Postgres notifications
+func ConnectionString(env config.Environment) string {
 	dsn := url.URL{
 		Scheme: "postgres",
 		Host:   env.DbHost,
@@ -22,11 +33,5 @@ func NewDbConnection(env config.Environment) (*sql.DB, error) {
 
 	dsn.RawQuery = q.Encode()
 
-	db, err := sql.Open("postgres", dsn.String())
-
-	if err != nil {
-		return nil, err
-	}
-
-	return db, nil
+	return dsn.String()
 }
@@ -0,0 +1,3 @@
+DROP TABLE payroll_adjustments;
+DROP TABLE payroll_run_items;
+DROP TABLE payroll_runs;
@@ -0,0 +1,60 @@
+CREATE TABLE "payroll_runs" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "company_id" UUID NOT NULL,
+  "period_start" date NOT NULL,
+  "period_end" date NOT NULL,
+  "status" varchar(20) NOT NULL DEFAULT 'draft',
+  "created_by" UUID NOT NULL,
+  "approved_by" UUID,
+  "approved_at" timestamptz,
+  "closed_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz
+);
+
+CREATE TABLE "payroll_run_items" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "payroll_run_id" UUID NOT NULL,
+  "employee_id" UUID NOT NULL,
+  "snapshot" jsonb NOT NULL,
+  "worked_days" int NOT NULL,
+  "base_pay" numeric(14, 2) NOT NULL,
+  "bonuses" numeric(14, 2) NOT NULL DEFAULT 0,
+  "deductions" numeric(14, 2) NOT NULL DEFAULT 0,
+  "health_contribution" numeric(14, 2) NOT NULL DEFAULT 0,
+  "pension_contribution" numeric(14, 2) NOT NULL DEFAULT 0,
+  "net_pay" numeric(14, 2) NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz
+);
+
+CREATE TABLE "payroll_adjustments" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "payroll_run_id" UUID NOT NULL,
+  "employee_id" UUID NOT NULL,
+  "kind" varchar(20) NOT NULL,
+  "concept" varchar(200) NOT NULL,
+  "amount" numeric(14, 2) NOT NULL,
+  "created_by" UUID NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "payroll_runs" ("company_id", "period_start", "period_end");
+
+CREATE UNIQUE INDEX ON "payroll_run_items" ("payroll_run_id", "employee_id");
+
+CREATE INDEX ON "payroll_adjustments" ("payroll_run_id", "employee_id");
+
+ALTER TABLE "payroll_runs" ADD FOREIGN KEY ("company_id") REFERENCES "companies" ("id");
+
+ALTER TABLE "payroll_runs" ADD FOREIGN KEY ("created_by") REFERENCES "users" ("id");
+
+ALTER TABLE "payroll_runs" ADD FOREIGN KEY ("approved_by") REFERENCES "users" ("id");
+
+ALTER TABLE "payroll_run_items" ADD FOREIGN KEY ("payroll_run_id") REFERENCES "payroll_runs" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "payroll_run_items" ADD FOREIGN KEY ("employee_id") REFERENCES "employees" ("id");
+
+ALTER TABLE "payroll_adjustments" ADD FOREIGN KEY ("payroll_run_id") REFERENCES "payroll_runs" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "payroll_adjustments" ADD FOREIGN KEY ("employee_id") REFERENCES "employees" ("id");
@@ -0,0 +1,5 @@
+DROP TABLE electronic_payroll_documents;
+ALTER TABLE companies DROP COLUMN municipality_code;
+ALTER TABLE companies DROP COLUMN department_code;
+ALTER TABLE companies DROP COLUMN verification_digit;
+ALTER TABLE companies DROP COLUMN nit;
@@ -0,0 +1,37 @@
+ALTER TABLE "companies" ADD COLUMN "nit" varchar(20);
+
+ALTER TABLE "companies" ADD COLUMN "verification_digit" varchar(1);
+
+ALTER TABLE "companies" ADD COLUMN "department_code" varchar(2);
+
+ALTER TABLE "companies" ADD COLUMN "municipality_code" varchar(5);
+
+CREATE TABLE "electronic_payroll_documents" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "company_id" UUID NOT NULL,
+  "employee_id" UUID NOT NULL,
+  "payroll_run_id" UUID NOT NULL,
+  "period_start" date NOT NULL,
+  "period_end" date NOT NULL,
+  "prefix" varchar(10) NOT NULL,
+  "consecutive" bigint NOT NULL,
+  "cune" varchar(96) NOT NULL,
+  "xml" text NOT NULL,
+  "status" varchar(20) NOT NULL DEFAULT 'generated',
+  "submitted_at" timestamptz,
+  "error" text,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz
+);
+
+CREATE UNIQUE INDEX ON "electronic_payroll_documents" ("company_id", "prefix", "consecutive");
+
+CREATE UNIQUE INDEX ON "electronic_payroll_documents" ("payroll_run_id", "employee_id");
+
+CREATE INDEX ON "electronic_payroll_documents" ("employee_id", "period_start");
+
+ALTER TABLE "electronic_payroll_documents" ADD FOREIGN KEY ("company_id") REFERENCES "companies" ("id");
+
+ALTER TABLE "electronic_payroll_documents" ADD FOREIGN KEY ("employee_id") REFERENCES "employees" ("id");
+
+ALTER TABLE "electronic_payroll_documents" ADD FOREIGN KEY ("payroll_run_id") REFERENCES "payroll_runs" ("id");
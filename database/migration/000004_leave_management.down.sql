@@ -0,0 +1,5 @@
+DROP TABLE leave_request_approvals;
+DROP TABLE leave_requests;
+DROP TABLE leave_types;
+ALTER TABLE departments DROP COLUMN manager_id;
+DROP TABLE company_members;
@@ -0,0 +1,80 @@
+CREATE TABLE "company_members" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "company_id" UUID NOT NULL,
+  "user_id" UUID NOT NULL,
+  "role" varchar(20) NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz
+);
+
+ALTER TABLE "departments" ADD COLUMN "manager_id" UUID;
+
+CREATE TABLE "leave_types" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "company_id" UUID NOT NULL,
+  "name" varchar(100) NOT NULL,
+  "category" varchar(20) NOT NULL,
+  "paid" boolean NOT NULL DEFAULT true,
+  "days_per_year" numeric(5, 2) NOT NULL DEFAULT 0,
+  "max_carry_over" numeric(5, 2),
+  "approval_chain" text[] NOT NULL DEFAULT '{manager,hr}',
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz
+);
+
+CREATE TABLE "leave_requests" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "company_id" UUID NOT NULL,
+  "employee_id" UUID NOT NULL,
+  "leave_type_id" UUID NOT NULL,
+  "start_date" date NOT NULL,
+  "end_date" date NOT NULL,
+  "days" numeric(5, 2) NOT NULL,
+  "reason" varchar,
+  "status" varchar(20) NOT NULL DEFAULT 'pending',
+  "current_step" int NOT NULL DEFAULT 1,
+  "requested_by" UUID NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz
+);
+
+CREATE TABLE "leave_request_approvals" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "leave_request_id" UUID NOT NULL,
+  "step" int NOT NULL,
+  "role" varchar(20) NOT NULL,
+  "status" varchar(20) NOT NULL DEFAULT 'pending',
+  "decided_by" UUID,
+  "decided_at" timestamptz,
+  "comment" varchar
+);
+
+CREATE UNIQUE INDEX ON "company_members" ("company_id", "user_id");
+
+CREATE INDEX ON "leave_types" ("company_id");
+
+CREATE INDEX ON "leave_requests" ("employee_id", "start_date");
+
+CREATE INDEX ON "leave_requests" ("company_id", "status");
+
+CREATE UNIQUE INDEX ON "leave_request_approvals" ("leave_request_id", "step");
+
+ALTER TABLE "company_members" ADD FOREIGN KEY ("company_id") REFERENCES "companies" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "company_members" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
+
+ALTER TABLE "departments" ADD FOREIGN KEY ("manager_id") REFERENCES "users" ("id");
+
+ALTER TABLE "leave_types" ADD FOREIGN KEY ("company_id") REFERENCES "companies" ("id");
+
+ALTER TABLE "leave_requests" ADD FOREIGN KEY ("company_id") REFERENCES "companies" ("id");
+
+ALTER TABLE "leave_requests" ADD FOREIGN KEY ("employee_id") REFERENCES "employees" ("id");
+
+ALTER TABLE "leave_requests" ADD FOREIGN KEY ("leave_type_id") REFERENCES "leave_types" ("id");
+
+ALTER TABLE "leave_requests" ADD FOREIGN KEY ("requested_by") REFERENCES "users" ("id");
+
+ALTER TABLE "leave_request_approvals" ADD FOREIGN KEY ("leave_request_id") REFERENCES "leave_requests" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "leave_request_approvals" ADD FOREIGN KEY ("decided_by") REFERENCES "users" ("id");
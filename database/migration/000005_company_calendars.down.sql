@@ -0,0 +1,2 @@
+DROP TABLE company_calendar_closures;
+DROP TABLE company_calendars;
@@ -0,0 +1,21 @@
+CREATE TABLE "company_calendars" (
+  "company_id" UUID PRIMARY KEY,
+  "working_week" smallint[] NOT NULL DEFAULT '{1,2,3,4,5}',
+  "national_holidays" boolean NOT NULL DEFAULT true,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz
+);
+
+CREATE TABLE "company_calendar_closures" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "company_id" UUID NOT NULL,
+  "date" date NOT NULL,
+  "name" varchar(100) NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "company_calendar_closures" ("company_id", "date");
+
+ALTER TABLE "company_calendars" ADD FOREIGN KEY ("company_id") REFERENCES "companies" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "company_calendar_closures" ADD FOREIGN KEY ("company_id") REFERENCES "companies" ("id") ON DELETE CASCADE;
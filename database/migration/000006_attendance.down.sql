@@ -0,0 +1,4 @@
+DROP TABLE timesheet_corrections;
+DROP TABLE timesheets;
+DROP TABLE attendance_events;
+DROP TABLE attendance_settings;
@@ -0,0 +1,70 @@
+CREATE TABLE "attendance_settings" (
+  "company_id" UUID PRIMARY KEY,
+  "rounding_minutes" int NOT NULL DEFAULT 0,
+  "rounding_mode" varchar(10) NOT NULL DEFAULT 'nearest',
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz
+);
+
+CREATE TABLE "attendance_events" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "company_id" UUID NOT NULL,
+  "employee_id" UUID NOT NULL,
+  "kind" varchar(10) NOT NULL,
+  "occurred_at" timestamptz NOT NULL,
+  "source" varchar(10) NOT NULL,
+  "created_by" UUID NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "timesheets" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "company_id" UUID NOT NULL,
+  "employee_id" UUID NOT NULL,
+  "date" date NOT NULL,
+  "intervals" jsonb NOT NULL DEFAULT '[]',
+  "worked_minutes" int NOT NULL DEFAULT 0,
+  "missing_punch" boolean NOT NULL DEFAULT false,
+  "corrected" boolean NOT NULL DEFAULT false,
+  "status" varchar(20) NOT NULL DEFAULT 'open',
+  "approved_by" UUID,
+  "approved_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz
+);
+
+CREATE TABLE "timesheet_corrections" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "timesheet_id" UUID NOT NULL,
+  "changed_by" UUID NOT NULL,
+  "reason" varchar NOT NULL,
+  "before" jsonb NOT NULL,
+  "after" jsonb NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "attendance_events" ("employee_id", "occurred_at");
+
+CREATE UNIQUE INDEX ON "timesheets" ("employee_id", "date");
+
+CREATE INDEX ON "timesheets" ("company_id", "date");
+
+CREATE INDEX ON "timesheet_corrections" ("timesheet_id");
+
+ALTER TABLE "attendance_settings" ADD FOREIGN KEY ("company_id") REFERENCES "companies" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "attendance_events" ADD FOREIGN KEY ("company_id") REFERENCES "companies" ("id");
+
+ALTER TABLE "attendance_events" ADD FOREIGN KEY ("employee_id") REFERENCES "employees" ("id");
+
+ALTER TABLE "attendance_events" ADD FOREIGN KEY ("created_by") REFERENCES "users" ("id");
+
+ALTER TABLE "timesheets" ADD FOREIGN KEY ("company_id") REFERENCES "companies" ("id");
+
+ALTER TABLE "timesheets" ADD FOREIGN KEY ("employee_id") REFERENCES "employees" ("id");
+
+ALTER TABLE "timesheets" ADD FOREIGN KEY ("approved_by") REFERENCES "users" ("id");
+
+ALTER TABLE "timesheet_corrections" ADD FOREIGN KEY ("timesheet_id") REFERENCES "timesheets" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "timesheet_corrections" ADD FOREIGN KEY ("changed_by") REFERENCES "users" ("id");
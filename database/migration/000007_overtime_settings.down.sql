@@ -0,0 +1 @@
+DROP TABLE overtime_settings;
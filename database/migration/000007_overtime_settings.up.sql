@@ -0,0 +1,14 @@
+CREATE TABLE "overtime_settings" (
+  "company_id" UUID PRIMARY KEY,
+  "night_start" smallint NOT NULL DEFAULT 1140,
+  "night_end" smallint NOT NULL DEFAULT 360,
+  "weekly_limit_minutes" int NOT NULL DEFAULT 2520,
+  "night_rate" numeric(5, 4) NOT NULL DEFAULT 0.35,
+  "holiday_rate" numeric(5, 4) NOT NULL DEFAULT 0.90,
+  "overtime_day_rate" numeric(5, 4) NOT NULL DEFAULT 0.25,
+  "overtime_night_rate" numeric(5, 4) NOT NULL DEFAULT 0.75,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz
+);
+
+ALTER TABLE "overtime_settings" ADD FOREIGN KEY ("company_id") REFERENCES "companies" ("id") ON DELETE CASCADE;
@@ -0,0 +1,4 @@
+DROP TABLE shift_feeds;
+DROP TABLE shifts;
+DROP TABLE roster_settings;
+DROP TABLE shift_templates;
@@ -0,0 +1,61 @@
+CREATE TABLE "shift_templates" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "company_id" UUID NOT NULL,
+  "name" varchar(100) NOT NULL,
+  "start_minute" smallint NOT NULL,
+  "end_minute" smallint NOT NULL,
+  "break_minutes" smallint NOT NULL DEFAULT 0,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz
+);
+
+CREATE TABLE "roster_settings" (
+  "company_id" UUID PRIMARY KEY,
+  "min_rest_minutes" int NOT NULL DEFAULT 600,
+  "max_weekly_minutes" int NOT NULL DEFAULT 3240,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz
+);
+
+CREATE TABLE "shifts" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "company_id" UUID NOT NULL,
+  "department_id" UUID NOT NULL,
+  "employee_id" UUID NOT NULL,
+  "shift_template_id" UUID,
+  "name" varchar(100) NOT NULL,
+  "date" date NOT NULL,
+  "starts_at" timestamptz NOT NULL,
+  "ends_at" timestamptz NOT NULL,
+  "break_minutes" smallint NOT NULL DEFAULT 0,
+  "created_by" UUID NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "shift_feeds" (
+  "employee_id" UUID PRIMARY KEY,
+  "token" varchar(64) UNIQUE NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "shift_templates" ("company_id", "name");
+
+CREATE INDEX ON "shifts" ("employee_id", "starts_at");
+
+CREATE INDEX ON "shifts" ("department_id", "date");
+
+ALTER TABLE "shift_templates" ADD FOREIGN KEY ("company_id") REFERENCES "companies" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "roster_settings" ADD FOREIGN KEY ("company_id") REFERENCES "companies" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "shifts" ADD FOREIGN KEY ("company_id") REFERENCES "companies" ("id");
+
+ALTER TABLE "shifts" ADD FOREIGN KEY ("department_id") REFERENCES "departments" ("id");
+
+ALTER TABLE "shifts" ADD FOREIGN KEY ("employee_id") REFERENCES "employees" ("id");
+
+ALTER TABLE "shifts" ADD FOREIGN KEY ("shift_template_id") REFERENCES "shift_templates" ("id") ON DELETE SET NULL;
+
+ALTER TABLE "shifts" ADD FOREIGN KEY ("created_by") REFERENCES "users" ("id");
+
+ALTER TABLE "shift_feeds" ADD FOREIGN KEY ("employee_id") REFERENCES "employees" ("id") ON DELETE CASCADE;
@@ -0,0 +1,25 @@
+CREATE TABLE "employee_documents" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "company_id" UUID NOT NULL,
+  "employee_id" UUID NOT NULL,
+  "category" varchar(20) NOT NULL,
+  "name" varchar(255) NOT NULL,
+  "content_type" varchar(100) NOT NULL,
+  "size" bigint NOT NULL,
+  "sha256" char(64) NOT NULL,
+  "expires_at" date,
+  "uploaded_by" UUID NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "employee_documents" ("employee_id", "category");
+
+CREATE INDEX ON "employee_documents" ("sha256");
+
+CREATE INDEX ON "employee_documents" ("expires_at");
+
+ALTER TABLE "employee_documents" ADD FOREIGN KEY ("company_id") REFERENCES "companies" ("id");
+
+ALTER TABLE "employee_documents" ADD FOREIGN KEY ("employee_id") REFERENCES "employees" ("id");
+
+ALTER TABLE "employee_documents" ADD FOREIGN KEY ("uploaded_by") REFERENCES "users" ("id");
@@ -0,0 +1 @@
+ALTER TABLE "employees" ADD COLUMN "picture_version" varchar(16);
@@ -0,0 +1,21 @@
+CREATE TABLE "notifications" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "user_id" UUID NOT NULL,
+  "company_id" UUID,
+  "kind" varchar(50) NOT NULL,
+  "title" varchar(200) NOT NULL,
+  "body" text NOT NULL DEFAULT '',
+  "link" varchar,
+  "data" jsonb NOT NULL DEFAULT '{}',
+  "dedup_key" varchar(200),
+  "read_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "notifications" ("user_id", "created_at");
+
+CREATE UNIQUE INDEX ON "notifications" ("user_id", "dedup_key");
+
+ALTER TABLE "notifications" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "notifications" ADD FOREIGN KEY ("company_id") REFERENCES "companies" ("id") ON DELETE CASCADE;
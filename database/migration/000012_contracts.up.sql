@@ -0,0 +1,46 @@
+CREATE TABLE "contract_templates" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "company_id" UUID NOT NULL,
+  "name" varchar(100) NOT NULL,
+  "contract_type" varchar(20),
+  "body" text NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz
+);
+
+CREATE TABLE "contracts" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "company_id" UUID NOT NULL,
+  "employee_id" UUID NOT NULL,
+  "type" varchar(20) NOT NULL,
+  "start_date" date NOT NULL,
+  "end_date" date,
+  "salary" numeric(14, 2) NOT NULL,
+  "weekly_hours" numeric(5, 2) NOT NULL,
+  "probation_days" int NOT NULL DEFAULT 0,
+  "status" varchar(20) NOT NULL DEFAULT 'active',
+  "previous_contract_id" UUID,
+  "renewal_number" int NOT NULL DEFAULT 0,
+  "notes" text,
+  "terminated_at" date,
+  "termination_reason" varchar,
+  "created_by" UUID NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz
+);
+
+CREATE INDEX ON "contracts" ("employee_id", "start_date");
+
+CREATE INDEX ON "contracts" ("company_id", "status", "end_date");
+
+CREATE UNIQUE INDEX ON "contracts" ("employee_id") WHERE status = 'active';
+
+ALTER TABLE "contract_templates" ADD FOREIGN KEY ("company_id") REFERENCES "companies" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "contracts" ADD FOREIGN KEY ("company_id") REFERENCES "companies" ("id");
+
+ALTER TABLE "contracts" ADD FOREIGN KEY ("employee_id") REFERENCES "employees" ("id");
+
+ALTER TABLE "contracts" ADD FOREIGN KEY ("previous_contract_id") REFERENCES "contracts" ("id");
+
+ALTER TABLE "contracts" ADD FOREIGN KEY ("created_by") REFERENCES "users" ("id");
@@ -0,0 +1,7 @@
+DROP TABLE checklist_tasks;
+DROP TABLE checklist_template_tasks;
+DROP TABLE checklist_templates;
+
+ALTER TABLE "employees" DROP COLUMN "termination_reason";
+
+ALTER TABLE "employees" DROP COLUMN "termination_date";
@@ -0,0 +1,61 @@
+ALTER TABLE "employees" ADD COLUMN "termination_date" date;
+
+ALTER TABLE "employees" ADD COLUMN "termination_reason" varchar;
+
+CREATE TABLE "checklist_templates" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "company_id" UUID NOT NULL,
+  "name" varchar(100) NOT NULL,
+  "kind" varchar(20) NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz
+);
+
+CREATE TABLE "checklist_template_tasks" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "template_id" UUID NOT NULL,
+  "position" int NOT NULL,
+  "title" varchar(200) NOT NULL,
+  "description" text,
+  "assignee_role" varchar(20) NOT NULL,
+  "due_offset_days" int NOT NULL DEFAULT 0
+);
+
+CREATE TABLE "checklist_tasks" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "company_id" UUID NOT NULL,
+  "employee_id" UUID NOT NULL,
+  "template_id" UUID,
+  "template_task_id" UUID,
+  "kind" varchar(20) NOT NULL,
+  "title" varchar(200) NOT NULL,
+  "description" text,
+  "assignee_role" varchar(20) NOT NULL,
+  "due_date" date NOT NULL,
+  "completed_at" timestamptz,
+  "completed_by" UUID,
+  "notes" text,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "checklist_templates" ("company_id", "name");
+
+CREATE INDEX ON "checklist_template_tasks" ("template_id", "position");
+
+CREATE UNIQUE INDEX ON "checklist_tasks" ("employee_id", "template_task_id");
+
+CREATE INDEX ON "checklist_tasks" ("company_id", "due_date") WHERE completed_at IS NULL;
+
+ALTER TABLE "checklist_templates" ADD FOREIGN KEY ("company_id") REFERENCES "companies" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "checklist_template_tasks" ADD FOREIGN KEY ("template_id") REFERENCES "checklist_templates" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "checklist_tasks" ADD FOREIGN KEY ("company_id") REFERENCES "companies" ("id");
+
+ALTER TABLE "checklist_tasks" ADD FOREIGN KEY ("employee_id") REFERENCES "employees" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "checklist_tasks" ADD FOREIGN KEY ("template_id") REFERENCES "checklist_templates" ("id") ON DELETE SET NULL;
+
+ALTER TABLE "checklist_tasks" ADD FOREIGN KEY ("template_task_id") REFERENCES "checklist_template_tasks" ("id") ON DELETE SET NULL;
+
+ALTER TABLE "checklist_tasks" ADD FOREIGN KEY ("completed_by") REFERENCES "users" ("id");
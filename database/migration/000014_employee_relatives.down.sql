@@ -0,0 +1,3 @@
+DROP TABLE beneficiaries;
+DROP TABLE dependents;
+DROP TABLE emergency_contacts;
@@ -0,0 +1,61 @@
+CREATE TABLE "emergency_contacts" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "employee_id" UUID NOT NULL,
+  "name" varchar(200) NOT NULL,
+  "relationship" varchar(20) NOT NULL,
+  "phone_number" varchar(100) NOT NULL,
+  "alternate_phone_number" varchar(100),
+  "email" varchar(100),
+  "is_primary" boolean NOT NULL DEFAULT false,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz
+);
+
+CREATE TABLE "dependents" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "employee_id" UUID NOT NULL,
+  "name" varchar(100) NOT NULL,
+  "last_name" varchar(100) NOT NULL,
+  "relationship" varchar(20) NOT NULL,
+  "id_type" UUID,
+  "id_number" varchar(100),
+  "birth_date" date NOT NULL,
+  "disabled" boolean NOT NULL DEFAULT false,
+  "health_affiliated" boolean NOT NULL DEFAULT false,
+  "compensation_affiliated" boolean NOT NULL DEFAULT false,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz
+);
+
+CREATE TABLE "beneficiaries" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "employee_id" UUID NOT NULL,
+  "name" varchar(100) NOT NULL,
+  "last_name" varchar(100) NOT NULL,
+  "relationship" varchar(20) NOT NULL,
+  "id_type" UUID,
+  "id_number" varchar(100),
+  "percentage" numeric(5, 2) NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz
+);
+
+CREATE INDEX ON "emergency_contacts" ("employee_id");
+
+CREATE UNIQUE INDEX ON "emergency_contacts" ("employee_id") WHERE is_primary;
+
+CREATE INDEX ON "dependents" ("employee_id");
+
+CREATE UNIQUE INDEX ON "dependents" ("employee_id", "id_type", "id_number");
+
+CREATE INDEX ON "beneficiaries" ("employee_id");
+
+ALTER TABLE "emergency_contacts" ADD FOREIGN KEY ("employee_id") REFERENCES "employees" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "dependents" ADD FOREIGN KEY ("employee_id") REFERENCES "employees" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "dependents" ADD FOREIGN KEY ("id_type") REFERENCES "id_types" ("id");
+
+ALTER TABLE "beneficiaries" ADD FOREIGN KEY ("employee_id") REFERENCES "employees" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "beneficiaries" ADD FOREIGN KEY ("id_type") REFERENCES "id_types" ("id");
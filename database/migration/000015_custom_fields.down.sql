@@ -0,0 +1,7 @@
+ALTER TABLE "positions" DROP COLUMN "custom_fields";
+
+ALTER TABLE "departments" DROP COLUMN "custom_fields";
+
+ALTER TABLE "employees" DROP COLUMN "custom_fields";
+
+DROP TABLE custom_fields;
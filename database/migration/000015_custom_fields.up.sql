@@ -0,0 +1,30 @@
+CREATE TABLE "custom_fields" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "company_id" UUID NOT NULL,
+  "entity" varchar(20) NOT NULL,
+  "key" varchar(50) NOT NULL,
+  "label" varchar(100) NOT NULL,
+  "type" varchar(20) NOT NULL,
+  "required" boolean NOT NULL DEFAULT false,
+  "options" varchar[] NOT NULL DEFAULT '{}',
+  "pattern" varchar,
+  "position" int NOT NULL DEFAULT 0,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz
+);
+
+CREATE UNIQUE INDEX ON "custom_fields" ("company_id", "entity", "key");
+
+ALTER TABLE "custom_fields" ADD FOREIGN KEY ("company_id") REFERENCES "companies" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "employees" ADD COLUMN "custom_fields" jsonb NOT NULL DEFAULT '{}';
+
+ALTER TABLE "departments" ADD COLUMN "custom_fields" jsonb NOT NULL DEFAULT '{}';
+
+ALTER TABLE "positions" ADD COLUMN "custom_fields" jsonb NOT NULL DEFAULT '{}';
+
+CREATE INDEX ON "employees" USING gin ("custom_fields" jsonb_path_ops);
+
+CREATE INDEX ON "departments" USING gin ("custom_fields" jsonb_path_ops);
+
+CREATE INDEX ON "positions" USING gin ("custom_fields" jsonb_path_ops);
@@ -0,0 +1,4 @@
+DROP TABLE profile_change_requests;
+DROP TABLE employee_invitations;
+
+ALTER TABLE "employees" DROP COLUMN "user_id";
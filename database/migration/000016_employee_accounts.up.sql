@@ -0,0 +1,49 @@
+ALTER TABLE "employees" ADD COLUMN "user_id" UUID;
+
+CREATE UNIQUE INDEX ON "employees" ("user_id");
+
+ALTER TABLE "employees" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE SET NULL;
+
+CREATE TABLE "employee_invitations" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "employee_id" UUID NOT NULL,
+  "email" varchar(100) NOT NULL,
+  "token_hash" char(64) NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "accepted_at" timestamptz,
+  "created_by" UUID NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "profile_change_requests" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "company_id" UUID NOT NULL,
+  "employee_id" UUID NOT NULL,
+  "changes" jsonb NOT NULL,
+  "status" varchar(20) NOT NULL DEFAULT 'pending',
+  "comment" text,
+  "requested_by" UUID NOT NULL,
+  "decided_by" UUID,
+  "decided_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "employee_invitations" ("token_hash");
+
+CREATE INDEX ON "employee_invitations" ("employee_id");
+
+CREATE INDEX ON "profile_change_requests" ("company_id", "status");
+
+CREATE INDEX ON "profile_change_requests" ("employee_id");
+
+ALTER TABLE "employee_invitations" ADD FOREIGN KEY ("employee_id") REFERENCES "employees" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "employee_invitations" ADD FOREIGN KEY ("created_by") REFERENCES "users" ("id");
+
+ALTER TABLE "profile_change_requests" ADD FOREIGN KEY ("company_id") REFERENCES "companies" ("id");
+
+ALTER TABLE "profile_change_requests" ADD FOREIGN KEY ("employee_id") REFERENCES "employees" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "profile_change_requests" ADD FOREIGN KEY ("requested_by") REFERENCES "users" ("id");
+
+ALTER TABLE "profile_change_requests" ADD FOREIGN KEY ("decided_by") REFERENCES "users" ("id");
@@ -0,0 +1,4 @@
+DROP TABLE approval_delegations;
+DROP TABLE approval_steps;
+DROP TABLE approvals;
+DROP TABLE approval_policies;
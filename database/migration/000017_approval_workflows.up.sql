@@ -0,0 +1,85 @@
+CREATE TABLE "approval_policies" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "company_id" UUID NOT NULL,
+  "subject" varchar(30) NOT NULL,
+  "steps" jsonb NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz
+);
+
+CREATE TABLE "approvals" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "company_id" UUID NOT NULL,
+  "subject" varchar(30) NOT NULL,
+  "subject_id" UUID,
+  "employee_id" UUID NOT NULL,
+  "amount" numeric(14, 2),
+  "payload" jsonb NOT NULL DEFAULT '{}',
+  "status" varchar(20) NOT NULL DEFAULT 'pending',
+  "current_step" int NOT NULL DEFAULT 1,
+  "requested_by" UUID NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz
+);
+
+CREATE TABLE "approval_steps" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "approval_id" UUID NOT NULL,
+  "step" int NOT NULL,
+  "approver_role" varchar(20) NOT NULL,
+  "approver_level" int NOT NULL DEFAULT 0,
+  "approver_user_id" UUID,
+  "status" varchar(20) NOT NULL DEFAULT 'pending',
+  "timeout_hours" int NOT NULL DEFAULT 0,
+  "due_at" timestamptz,
+  "escalated_at" timestamptz,
+  "decided_by" UUID,
+  "decided_at" timestamptz,
+  "comment" text
+);
+
+CREATE TABLE "approval_delegations" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "company_id" UUID NOT NULL,
+  "user_id" UUID NOT NULL,
+  "delegate_id" UUID NOT NULL,
+  "starts_on" date NOT NULL,
+  "ends_on" date NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "approval_policies" ("company_id", "subject");
+
+CREATE INDEX ON "approvals" ("company_id", "status");
+
+CREATE INDEX ON "approvals" ("subject", "subject_id");
+
+-- An employee can't have two undecided salary changes or terminations
+CREATE UNIQUE INDEX ON "approvals" ("subject", "employee_id")
+  WHERE "status" IN ('pending', 'escalated') AND "subject" <> 'profile_change';
+
+CREATE UNIQUE INDEX ON "approval_steps" ("approval_id", "step");
+
+CREATE INDEX ON "approval_steps" ("due_at") WHERE "status" IN ('pending', 'escalated');
+
+CREATE INDEX ON "approval_delegations" ("delegate_id", "starts_on", "ends_on");
+
+ALTER TABLE "approval_policies" ADD FOREIGN KEY ("company_id") REFERENCES "companies" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "approvals" ADD FOREIGN KEY ("company_id") REFERENCES "companies" ("id");
+
+ALTER TABLE "approvals" ADD FOREIGN KEY ("employee_id") REFERENCES "employees" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "approvals" ADD FOREIGN KEY ("requested_by") REFERENCES "users" ("id");
+
+ALTER TABLE "approval_steps" ADD FOREIGN KEY ("approval_id") REFERENCES "approvals" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "approval_steps" ADD FOREIGN KEY ("approver_user_id") REFERENCES "users" ("id");
+
+ALTER TABLE "approval_steps" ADD FOREIGN KEY ("decided_by") REFERENCES "users" ("id");
+
+ALTER TABLE "approval_delegations" ADD FOREIGN KEY ("company_id") REFERENCES "companies" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "approval_delegations" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "approval_delegations" ADD FOREIGN KEY ("delegate_id") REFERENCES "users" ("id") ON DELETE CASCADE;
@@ -0,0 +1,30 @@
+-- No foreign keys: entries outlive the companies, users and records they
+-- refer to
+CREATE TABLE "audit_log" (
+  "id" bigserial PRIMARY KEY,
+  "company_id" UUID,
+  "actor_id" UUID,
+  "ip" varchar(45),
+  "request_id" varchar(64),
+  "entity" varchar(50) NOT NULL,
+  "entity_id" varchar(64),
+  "action" varchar(50) NOT NULL,
+  "changes" jsonb NOT NULL DEFAULT '{}',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "audit_log" ("company_id", "created_at");
+
+CREATE INDEX ON "audit_log" ("company_id", "entity", "entity_id");
+
+CREATE INDEX ON "audit_log" ("actor_id", "created_at");
+
+CREATE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+  BEFORE UPDATE OR DELETE ON "audit_log"
+  FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
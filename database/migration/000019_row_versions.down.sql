@@ -0,0 +1,19 @@
+DROP TRIGGER "employees_version" ON "employees";
+
+DROP TRIGGER "positions_version" ON "positions";
+
+DROP TRIGGER "departments_version" ON "departments";
+
+DROP TRIGGER "companies_version" ON "companies";
+
+ALTER TABLE "employees" DROP COLUMN "version";
+
+ALTER TABLE "positions" DROP COLUMN "version";
+
+ALTER TABLE "departments" DROP COLUMN "version";
+
+ALTER TABLE "companies" DROP COLUMN "require_if_match";
+
+ALTER TABLE "companies" DROP COLUMN "version";
+
+DROP FUNCTION bump_row_version();
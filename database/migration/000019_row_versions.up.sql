@@ -0,0 +1,30 @@
+-- Every update of the row bumps its version, which is sent as the ETag
+CREATE FUNCTION bump_row_version() RETURNS trigger AS $$
+BEGIN
+  NEW.version := OLD.version + 1;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE "companies" ADD COLUMN "version" integer NOT NULL DEFAULT 1;
+
+-- Companies that enforce it reject writes without If-Match
+ALTER TABLE "companies" ADD COLUMN "require_if_match" boolean NOT NULL DEFAULT false;
+
+ALTER TABLE "departments" ADD COLUMN "version" integer NOT NULL DEFAULT 1;
+
+ALTER TABLE "positions" ADD COLUMN "version" integer NOT NULL DEFAULT 1;
+
+ALTER TABLE "employees" ADD COLUMN "version" integer NOT NULL DEFAULT 1;
+
+CREATE TRIGGER "companies_version" BEFORE UPDATE ON "companies"
+  FOR EACH ROW EXECUTE FUNCTION bump_row_version();
+
+CREATE TRIGGER "departments_version" BEFORE UPDATE ON "departments"
+  FOR EACH ROW EXECUTE FUNCTION bump_row_version();
+
+CREATE TRIGGER "positions_version" BEFORE UPDATE ON "positions"
+  FOR EACH ROW EXECUTE FUNCTION bump_row_version();
+
+CREATE TRIGGER "employees_version" BEFORE UPDATE ON "employees"
+  FOR EACH ROW EXECUTE FUNCTION bump_row_version();
@@ -0,0 +1,35 @@
+CREATE TABLE "jobs" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "kind" varchar(100) NOT NULL,
+  "payload" jsonb NOT NULL DEFAULT '{}',
+  "status" varchar(20) NOT NULL DEFAULT 'queued',
+  "attempts" integer NOT NULL DEFAULT 0,
+  "max_attempts" integer NOT NULL DEFAULT 5,
+  "run_at" timestamptz NOT NULL DEFAULT (now()),
+  "company_id" UUID REFERENCES "companies" ("id") ON DELETE CASCADE,
+  "created_by" UUID REFERENCES "users" ("id") ON DELETE SET NULL,
+  "result" jsonb,
+  "last_error" text,
+  "cancel_requested" boolean NOT NULL DEFAULT false,
+  "heartbeat_at" timestamptz,
+  "started_at" timestamptz,
+  "finished_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz
+);
+
+-- Workers claim the due jobs in order
+CREATE INDEX ON "jobs" ("run_at") WHERE "status" = 'queued';
+
+CREATE INDEX ON "jobs" ("heartbeat_at") WHERE "status" = 'running';
+
+CREATE INDEX ON "jobs" ("finished_at");
+
+-- Recurring jobs, one row per kind. The instance that locks a due row
+-- enqueues the job, so several API processes run it once.
+CREATE TABLE "job_schedules" (
+  "kind" varchar(100) PRIMARY KEY,
+  "interval_seconds" integer NOT NULL,
+  "next_run_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz
+);
@@ -0,0 +1,13 @@
+DROP TRIGGER "employees_outbox" ON "employees";
+
+DROP TRIGGER "positions_outbox" ON "positions";
+
+DROP TRIGGER "departments_outbox" ON "departments";
+
+DROP TRIGGER "companies_outbox" ON "companies";
+
+DROP FUNCTION emit_outbox_event;
+
+DROP TABLE webhook_deliveries;
+DROP TABLE outbox_events;
+DROP TABLE webhook_subscriptions;
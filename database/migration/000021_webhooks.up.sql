@@ -0,0 +1,113 @@
+CREATE TABLE "webhook_subscriptions" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "company_id" UUID NOT NULL REFERENCES "companies" ("id") ON DELETE CASCADE,
+  "url" varchar(2000) NOT NULL,
+  "secret" varchar(100) NOT NULL,
+  "events" text[] NOT NULL,
+  "description" varchar(200),
+  "active" boolean NOT NULL DEFAULT true,
+  "created_by" UUID REFERENCES "users" ("id") ON DELETE SET NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz
+);
+
+CREATE INDEX ON "webhook_subscriptions" ("company_id");
+
+-- Transactional outbox. Events are written by the triggers below in the
+-- transaction of the change, so they exist only when it commits, and are
+-- turned into deliveries by the webhooks job.
+CREATE TABLE "outbox_events" (
+  "id" bigserial PRIMARY KEY,
+  "company_id" UUID NOT NULL,
+  "type" varchar(100) NOT NULL,
+  "data" jsonb NOT NULL,
+  "previous" jsonb,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "processed_at" timestamptz
+);
+
+CREATE INDEX ON "outbox_events" ("id") WHERE "processed_at" IS NULL;
+
+CREATE INDEX ON "outbox_events" ("processed_at");
+
+CREATE TABLE "webhook_deliveries" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "subscription_id" UUID NOT NULL REFERENCES "webhook_subscriptions" ("id") ON DELETE CASCADE,
+  "event_id" bigint NOT NULL REFERENCES "outbox_events" ("id") ON DELETE CASCADE,
+  "event_type" varchar(100) NOT NULL,
+  "status" varchar(20) NOT NULL DEFAULT 'pending',
+  "attempts" integer NOT NULL DEFAULT 0,
+  "next_attempt_at" timestamptz NOT NULL DEFAULT (now()),
+  "response_status" integer,
+  "last_error" text,
+  "delivered_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz
+);
+
+CREATE INDEX ON "webhook_deliveries" ("next_attempt_at") WHERE "status" = 'pending';
+
+CREATE INDEX ON "webhook_deliveries" ("subscription_id", "created_at");
+
+-- Emits <entity>.created, .updated and .deleted for the rows of the table.
+-- Updates that only bump the version emit nothing. Employees also emit
+-- employee.transferred and employee.terminated.
+CREATE FUNCTION emit_outbox_event() RETURNS trigger AS $$
+DECLARE
+  entity text := TG_ARGV[0];
+  action text := CASE TG_OP WHEN 'INSERT' THEN 'created' WHEN 'UPDATE' THEN 'updated' ELSE 'deleted' END;
+  data jsonb;
+  previous jsonb;
+  company UUID;
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    data := to_jsonb(OLD);
+  ELSE
+    data := to_jsonb(NEW);
+  END IF;
+
+  IF TG_OP = 'UPDATE' THEN
+    previous := to_jsonb(OLD);
+
+    IF previous - 'version' - 'updated_at' = data - 'version' - 'updated_at' THEN
+      RETURN NULL;
+    END IF;
+  END IF;
+
+  IF TG_TABLE_NAME = 'companies' THEN
+    company := (data ->> 'id')::uuid;
+  ELSE
+    company := (data ->> 'company_id')::uuid;
+  END IF;
+
+  INSERT INTO outbox_events (company_id, type, data, previous)
+  VALUES (company, entity || '.' || action, data, previous);
+
+  IF TG_TABLE_NAME = 'employees' AND TG_OP = 'UPDATE' THEN
+    IF previous -> 'departament_id' IS DISTINCT FROM data -> 'departament_id'
+      OR previous -> 'position_id' IS DISTINCT FROM data -> 'position_id' THEN
+      INSERT INTO outbox_events (company_id, type, data, previous)
+      VALUES (company, 'employee.transferred', data, previous);
+    END IF;
+
+    IF previous ->> 'termination_date' IS NULL AND data ->> 'termination_date' IS NOT NULL THEN
+      INSERT INTO outbox_events (company_id, type, data, previous)
+      VALUES (company, 'employee.terminated', data, previous);
+    END IF;
+  END IF;
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "companies_outbox" AFTER UPDATE ON "companies"
+  FOR EACH ROW EXECUTE FUNCTION emit_outbox_event('company');
+
+CREATE TRIGGER "departments_outbox" AFTER INSERT OR UPDATE OR DELETE ON "departments"
+  FOR EACH ROW EXECUTE FUNCTION emit_outbox_event('department');
+
+CREATE TRIGGER "positions_outbox" AFTER INSERT OR UPDATE OR DELETE ON "positions"
+  FOR EACH ROW EXECUTE FUNCTION emit_outbox_event('position');
+
+CREATE TRIGGER "employees_outbox" AFTER INSERT OR UPDATE OR DELETE ON "employees"
+  FOR EACH ROW EXECUTE FUNCTION emit_outbox_event('employee');
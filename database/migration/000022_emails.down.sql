@@ -0,0 +1,4 @@
+DROP TABLE email_outbox;
+DROP TABLE notification_preferences;
+
+ALTER TABLE "users" DROP COLUMN "language";
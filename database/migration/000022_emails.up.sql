@@ -0,0 +1,34 @@
+ALTER TABLE "users" ADD COLUMN "language" varchar(2) NOT NULL DEFAULT 'es';
+
+-- Kinds of notification the user opted in or out of by email. Kinds without
+-- a row are emailed.
+CREATE TABLE "notification_preferences" (
+  "user_id" UUID NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "kind" varchar(50) NOT NULL,
+  "email" boolean NOT NULL,
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("user_id", "kind")
+);
+
+-- Emails are written here in the transaction of what they are about and
+-- rendered and sent by the emails job. The data is cleared once the email
+-- is sent or dead, as it can hold tokens.
+CREATE TABLE "email_outbox" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "user_id" UUID REFERENCES "users" ("id") ON DELETE SET NULL,
+  "to_address" varchar(200) NOT NULL,
+  "template" varchar(50) NOT NULL,
+  "language" varchar(2) NOT NULL,
+  "data" jsonb NOT NULL DEFAULT '{}',
+  "status" varchar(20) NOT NULL DEFAULT 'pending',
+  "attempts" integer NOT NULL DEFAULT 0,
+  "next_attempt_at" timestamptz NOT NULL DEFAULT (now()),
+  "last_error" text,
+  "sent_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz
+);
+
+CREATE INDEX ON "email_outbox" ("next_attempt_at") WHERE "status" = 'pending';
+
+CREATE INDEX ON "email_outbox" ("updated_at") WHERE "status" <> 'pending';
@@ -0,0 +1,5 @@
+DROP TRIGGER "notifications_notify" ON "notifications";
+
+DROP FUNCTION notify_notification;
+
+DROP INDEX notifications_unread_idx;
@@ -0,0 +1,20 @@
+CREATE INDEX "notifications_unread_idx" ON "notifications" ("user_id") WHERE "read_at" IS NULL;
+
+-- Tells the API processes listening on the channel about new and read
+-- notifications, once the transaction commits, so they push them to the
+-- streams of the user. Read events carry no id, so reading many in a
+-- transaction sends one.
+CREATE FUNCTION notify_notification() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    PERFORM pg_notify('notifications', json_build_object('op', 'created', 'id', NEW.id, 'user_id', NEW.user_id)::text);
+  ELSIF OLD.read_at IS DISTINCT FROM NEW.read_at THEN
+    PERFORM pg_notify('notifications', json_build_object('op', 'read', 'user_id', NEW.user_id)::text);
+  END IF;
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "notifications_notify" AFTER INSERT OR UPDATE OF "read_at" ON "notifications"
+  FOR EACH ROW EXECUTE FUNCTION notify_notification();
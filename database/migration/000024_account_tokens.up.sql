@@ -0,0 +1,23 @@
+ALTER TABLE "users" ADD COLUMN "email_verified_at" timestamptz;
+
+-- Sessions carry the version they were started with and end when it is
+-- bumped, as when the password is reset
+ALTER TABLE "users" ADD COLUMN "session_version" integer NOT NULL DEFAULT 0;
+
+-- Accounts from before verification existed keep working
+UPDATE "users" SET "email_verified_at" = "created_at";
+
+-- Single-use tokens sent by email. Only their hash is kept.
+CREATE TABLE "user_tokens" (
+  "id" UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "user_id" UUID NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "purpose" varchar(30) NOT NULL,
+  "token_hash" char(64) NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "user_tokens" ("token_hash");
+
+CREATE INDEX ON "user_tokens" ("user_id", "purpose");
@@ -0,0 +1,49 @@
+ALTER TABLE "employees" RENAME COLUMN "department_id" TO "departament_id";
+
+CREATE OR REPLACE FUNCTION emit_outbox_event() RETURNS trigger AS $$
+DECLARE
+  entity text := TG_ARGV[0];
+  action text := CASE TG_OP WHEN 'INSERT' THEN 'created' WHEN 'UPDATE' THEN 'updated' ELSE 'deleted' END;
+  data jsonb;
+  previous jsonb;
+  company UUID;
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    data := to_jsonb(OLD);
+  ELSE
+    data := to_jsonb(NEW);
+  END IF;
+
+  IF TG_OP = 'UPDATE' THEN
+    previous := to_jsonb(OLD);
+
+    IF previous - 'version' - 'updated_at' = data - 'version' - 'updated_at' THEN
+      RETURN NULL;
+    END IF;
+  END IF;
+
+  IF TG_TABLE_NAME = 'companies' THEN
+    company := (data ->> 'id')::uuid;
+  ELSE
+    company := (data ->> 'company_id')::uuid;
+  END IF;
+
+  INSERT INTO outbox_events (company_id, type, data, previous)
+  VALUES (company, entity || '.' || action, data, previous);
+
+  IF TG_TABLE_NAME = 'employees' AND TG_OP = 'UPDATE' THEN
+    IF previous -> 'departament_id' IS DISTINCT FROM data -> 'departament_id'
+      OR previous -> 'position_id' IS DISTINCT FROM data -> 'position_id' THEN
+      INSERT INTO outbox_events (company_id, type, data, previous)
+      VALUES (company, 'employee.transferred', data, previous);
+    END IF;
+
+    IF previous ->> 'termination_date' IS NULL AND data ->> 'termination_date' IS NOT NULL THEN
+      INSERT INTO outbox_events (company_id, type, data, previous)
+      VALUES (company, 'employee.terminated', data, previous);
+    END IF;
+  END IF;
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
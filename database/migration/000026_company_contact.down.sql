@@ -0,0 +1,5 @@
+ALTER TABLE "companies" DROP COLUMN "email";
+
+ALTER TABLE "companies" DROP COLUMN "phone";
+
+ALTER TABLE "companies" DROP COLUMN "address";
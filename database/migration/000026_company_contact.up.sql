@@ -0,0 +1,7 @@
+-- The company handlers always wrote these columns. Databases migrated when
+-- the audit log added them already have them.
+ALTER TABLE "companies" ADD COLUMN IF NOT EXISTS "address" varchar(200) NOT NULL DEFAULT '';
+
+ALTER TABLE "companies" ADD COLUMN IF NOT EXISTS "phone" varchar(20) NOT NULL DEFAULT '';
+
+ALTER TABLE "companies" ADD COLUMN IF NOT EXISTS "email" varchar(100) NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+DELETE FROM "job_schedules";
+
+ALTER TABLE "job_schedules" DROP COLUMN "cron";
+
+ALTER TABLE "job_schedules" ADD COLUMN "interval_seconds" integer NOT NULL;
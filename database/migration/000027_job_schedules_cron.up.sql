@@ -0,0 +1,7 @@
+-- Schedules follow cron expressions. The queue saves them again when it
+-- starts.
+DELETE FROM "job_schedules";
+
+ALTER TABLE "job_schedules" DROP COLUMN "interval_seconds";
+
+ALTER TABLE "job_schedules" ADD COLUMN "cron" varchar(100) NOT NULL;
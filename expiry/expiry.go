@@ -0,0 +1,71 @@
+// Package expiry decides when to remind about things that expire, like
+// work permits, medical certificates or fixed-term contracts.
+package expiry
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Thresholds are the days before the expiry date a reminder is sent
+var Thresholds = []int{30, 7, 1}
+
+// DefaultWithin is the window used when none is requested
+const DefaultWithin = 30
+
+// DaysLeft counts the days from today to the expiry date, negative when it
+// already expired.
+func DaysLeft(expiresAt time.Time, today time.Time) int {
+	expires := time.Date(expiresAt.Year(), expiresAt.Month(), expiresAt.Day(), 0, 0, 0, 0, time.UTC)
+	day := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+
+	return int(expires.Sub(day).Hours() / 24)
+}
+
+// Stage returns the reminder an item with the given days left is due for.
+// Each stage is sent once, so an item that enters the 30 day window gets a
+// reminder and doesn't get another one until it is 7 days away.
+func Stage(daysLeft int) (string, bool) {
+	switch {
+	case daysLeft < 0:
+		return "expired", true
+	case daysLeft == 0:
+		return "today", true
+	}
+
+	for i := len(Thresholds) - 1; i >= 0; i-- {
+		if daysLeft <= Thresholds[i] {
+			return fmt.Sprintf("%dd", Thresholds[i]), true
+		}
+	}
+
+	return "", false
+}
+
+// ParseWithin reads a window like "30d", "2w" or "45", which is in days
+func ParseWithin(value string) (int, error) {
+	if value == "" {
+		return DefaultWithin, nil
+	}
+
+	multiplier := 1
+	number := value
+
+	switch {
+	case strings.HasSuffix(value, "d"):
+		number = strings.TrimSuffix(value, "d")
+	case strings.HasSuffix(value, "w"):
+		number = strings.TrimSuffix(value, "w")
+		multiplier = 7
+	}
+
+	days, err := strconv.Atoi(number)
+
+	if err != nil || days < 0 || days > 366/multiplier+1 {
+		return 0, fmt.Errorf("within must be a number of days like 30d or weeks like 4w, up to a year")
+	}
+
+	return days * multiplier, nil
+}
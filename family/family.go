@@ -0,0 +1,95 @@
+// Package family holds the rules for the relatives of an employee, mainly
+// who can be affiliated as a dependent to the health system (EPS) and to the
+// family compensation fund (caja de compensación familiar).
+package family
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	RelationshipSpouse      = "spouse"
+	RelationshipPartner     = "partner"
+	RelationshipChild       = "child"
+	RelationshipStepchild   = "stepchild"
+	RelationshipParent      = "parent"
+	RelationshipSibling     = "sibling"
+	RelationshipGrandparent = "grandparent"
+	RelationshipFriend      = "friend"
+	RelationshipOther       = "other"
+)
+
+const (
+	// HealthMaxChildAge is the age up to which children are covered as
+	// dependents of the health system, unless they have a disability.
+	HealthMaxChildAge = 25
+	// CompensationMaxChildAge is the age up to which children and orphan
+	// siblings are beneficiaries of the compensation fund.
+	CompensationMaxChildAge = 18
+	// CompensationMinParentAge is the age from which parents are
+	// beneficiaries of the compensation fund.
+	CompensationMinParentAge = 60
+)
+
+// Dependent is a relative the employee affiliates
+type Dependent struct {
+	Relationship           string
+	BirthDate              time.Time
+	Disabled               bool
+	HealthAffiliated       bool
+	CompensationAffiliated bool
+}
+
+// IsSpouse reports whether the relationship is a spouse or permanent partner,
+// of which an employee can affiliate only one.
+func IsSpouse(relationship string) bool {
+	return relationship == RelationshipSpouse || relationship == RelationshipPartner
+}
+
+// Age is the age in completed years at the date
+func Age(birth time.Time, at time.Time) int {
+	age := at.Year() - birth.Year()
+
+	if at.Month() < birth.Month() || (at.Month() == birth.Month() && at.Day() < birth.Day()) {
+		age--
+	}
+
+	return age
+}
+
+// ValidateDependent checks the dependent can be affiliated where requested
+func ValidateDependent(dependent Dependent, today time.Time) error {
+	if dependent.BirthDate.After(today) {
+		return errors.New("birth_date can't be in the future")
+	}
+
+	age := Age(dependent.BirthDate, today)
+
+	switch dependent.Relationship {
+	case RelationshipSpouse, RelationshipPartner:
+	case RelationshipChild, RelationshipStepchild:
+		if dependent.HealthAffiliated && !dependent.Disabled && age >= HealthMaxChildAge {
+			return fmt.Errorf("children can be health dependents until they are %d, unless they have a disability", HealthMaxChildAge)
+		}
+		if dependent.CompensationAffiliated && !dependent.Disabled && age >= CompensationMaxChildAge {
+			return fmt.Errorf("children can be compensation fund beneficiaries until they are %d, unless they have a disability", CompensationMaxChildAge)
+		}
+	case RelationshipParent:
+		if dependent.CompensationAffiliated && !dependent.Disabled && age < CompensationMinParentAge {
+			return fmt.Errorf("parents can be compensation fund beneficiaries from %d years old, unless they have a disability", CompensationMinParentAge)
+		}
+	case RelationshipSibling:
+		if dependent.HealthAffiliated {
+			return errors.New("siblings can't be health dependents")
+		}
+		if dependent.CompensationAffiliated && !dependent.Disabled && age >= CompensationMaxChildAge {
+			return fmt.Errorf("siblings can be compensation fund beneficiaries until they are %d, unless they have a disability", CompensationMaxChildAge)
+		}
+	default:
+		return fmt.Errorf("%s can't be a dependent", dependent.Relationship)
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+// Package ics writes iCalendar (RFC 5545) feeds that calendar applications
+// can subscribe to.
+package ics
+
+import (
+	"bytes"
+	"strings"
+	"time"
+)
+
+type Event struct {
+	UID         string
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Location    string
+	Updated     time.Time
+}
+
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+const timestampFormat = "20060102T150405Z"
+
+// Marshal returns the calendar as a VCALENDAR object. Times are written in
+// UTC so the feed doesn't need timezone definitions.
+func (c Calendar) Marshal() []byte {
+	var buf bytes.Buffer
+
+	line := func(name string, value string) {
+		writeFolded(&buf, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//employees-manager//shifts//ES")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+
+	if c.Name != "" {
+		line("X-WR-CALNAME", escape(c.Name))
+	}
+
+	for _, event := range c.Events {
+		updated := event.Updated
+		if updated.IsZero() {
+			updated = time.Now()
+		}
+
+		line("BEGIN", "VEVENT")
+		line("UID", event.UID)
+		line("DTSTAMP", updated.UTC().Format(timestampFormat))
+		line("DTSTART", event.Start.UTC().Format(timestampFormat))
+		line("DTEND", event.End.UTC().Format(timestampFormat))
+		line("SUMMARY", escape(event.Summary))
+
+		if event.Description != "" {
+			line("DESCRIPTION", escape(event.Description))
+		}
+
+		if event.Location != "" {
+			line("LOCATION", escape(event.Location))
+		}
+
+		line("END", "VEVENT")
+	}
+
+	line("END", "VCALENDAR")
+
+	return buf.Bytes()
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escape(value string) string {
+	return escaper.Replace(value)
+}
+
+// writeFolded splits content lines longer than 75 octets, continuing them
+// on lines that start with a space. Multi-byte characters are never split.
+func writeFolded(buf *bytes.Buffer, content string) {
+	limit := 75
+
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(content[cut]) {
+			cut--
+		}
+
+		buf.WriteString(content[:cut])
+		buf.WriteString("\r\n ")
+		content = content[cut:]
+
+		// The leading space counts towards the next line length
+		limit = 74
+	}
+
+	buf.WriteString(content)
+	buf.WriteString("\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
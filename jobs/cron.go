@@ -0,0 +1,200 @@
+package jobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed cron expression. It takes the five standard fields,
+// minute hour day-of-month month day-of-week, or six with the seconds
+// first. Fields are *, numbers, ranges as 1-5 and lists of them, each with
+// an optional /step. Sunday is 0 or 7. As in cron, when both days are
+// restricted, not starting with *, a time matching either of them matches.
+type Cron struct {
+	second, minute, hour, day, month, weekday uint64
+
+	anyDay, anyWeekday bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"second", 0, 59},
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+var cronDescriptors = map[string]string{
+	"@yearly":  "0 0 1 1 *",
+	"@monthly": "0 0 1 * *",
+	"@weekly":  "0 0 * * 0",
+	"@daily":   "0 0 * * *",
+	"@hourly":  "0 * * * *",
+}
+
+// ParseCron parses the expression, or one of @yearly, @monthly, @weekly,
+// @daily and @hourly
+func ParseCron(expression string) (*Cron, error) {
+	if descriptor, ok := cronDescriptors[strings.TrimSpace(expression)]; ok {
+		expression = descriptor
+	}
+
+	fields := strings.Fields(expression)
+
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("cron %q: expected 5 or 6 fields, got %d", expression, len(fields))
+	}
+
+	var sets [6]uint64
+
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i])
+
+		if err != nil {
+			return nil, fmt.Errorf("cron %q: %w", expression, err)
+		}
+
+		sets[i] = set
+	}
+
+	// Sunday is both 0 and 7
+	if sets[5]&(1<<7) != 0 {
+		sets[5] |= 1
+	}
+
+	return &Cron{
+		second:     sets[0],
+		minute:     sets[1],
+		hour:       sets[2],
+		day:        sets[3],
+		month:      sets[4],
+		weekday:    sets[5],
+		anyDay:     strings.HasPrefix(fields[3], "*"),
+		anyWeekday: strings.HasPrefix(fields[5], "*"),
+	}, nil
+}
+
+func parseCronField(value string, field cronField) (uint64, error) {
+	var set uint64
+
+	for _, item := range strings.Split(value, ",") {
+		span, stepValue, hasStep := strings.Cut(item, "/")
+		step := 1
+
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepValue)
+
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("%s: invalid step %q", field.name, stepValue)
+			}
+		}
+
+		from, to := field.min, field.max
+
+		if span != "*" {
+			first, last, isRange := strings.Cut(span, "-")
+
+			var err error
+			from, err = strconv.Atoi(first)
+
+			if err != nil {
+				return 0, fmt.Errorf("%s: invalid value %q", field.name, first)
+			}
+
+			to = from
+
+			if isRange {
+				to, err = strconv.Atoi(last)
+
+				if err != nil {
+					return 0, fmt.Errorf("%s: invalid value %q", field.name, last)
+				}
+			} else if hasStep {
+				// 5/15 is every 15 from 5
+				to = field.max
+			}
+		}
+
+		if from < field.min || to > field.max || from > to {
+			return 0, fmt.Errorf("%s: %q is out of %d-%d", field.name, span, field.min, field.max)
+		}
+
+		for i := from; i <= to; i += step {
+			set |= 1 << i
+		}
+	}
+
+	return set, nil
+}
+
+// Next is the first time after the given one matching the expression, in
+// the location of the given time. It's zero when none comes in five years,
+// as for the 30th of February.
+func (c *Cron) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Second).Add(time.Second)
+	limit := t.AddDate(5, 0, 0)
+	location := t.Location()
+
+	for t.Before(limit) {
+		year, month, day := t.Date()
+		hour, minute, _ := t.Clock()
+		var next time.Time
+
+		switch {
+		case !c.has(c.month, int(month)):
+			next = time.Date(year, month+1, 1, 0, 0, 0, 0, location)
+		case !c.matchesDay(t):
+			next = time.Date(year, month, day+1, 0, 0, 0, 0, location)
+		case !c.has(c.hour, hour):
+			next = time.Date(year, month, day, hour+1, 0, 0, 0, location)
+		case !c.has(c.minute, minute):
+			next = time.Date(year, month, day, hour, minute+1, 0, 0, location)
+		case !c.has(c.second, t.Second()):
+			next = t.Add(time.Second)
+		default:
+			return t
+		}
+
+		// Clocks set back can give the same time again
+		if !next.After(t) {
+			next = t.Add(time.Second)
+		}
+
+		t = next
+	}
+
+	return time.Time{}
+}
+
+func (c *Cron) matchesDay(t time.Time) bool {
+	day := c.has(c.day, t.Day())
+	weekday := c.has(c.weekday, int(t.Weekday()))
+
+	switch {
+	case c.anyDay && c.anyWeekday:
+		return true
+	case c.anyDay:
+		return weekday
+	case c.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+func (c *Cron) has(set uint64, value int) bool {
+	return set&(1<<value) != 0
+}
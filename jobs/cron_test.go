@@ -0,0 +1,64 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	after := time.Date(2026, time.October, 19, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		expression string
+		want       time.Time
+	}{
+		{"* * * * *", time.Date(2026, time.October, 19, 10, 8, 0, 0, time.UTC)},
+		{"*/10 * * * * *", time.Date(2026, time.October, 19, 10, 7, 40, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, time.October, 19, 10, 15, 0, 0, time.UTC)},
+		{"30 * * * *", time.Date(2026, time.October, 19, 10, 30, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2026, time.October, 20, 3, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, time.October, 19, 11, 0, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2026, time.October, 20, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 7", time.Date(2026, time.October, 25, 9, 0, 0, 0, time.UTC)},
+		{"0 0 1 */3 *", time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		// Either day matches when both are restricted
+		{"0 0 1 * 5", time.Date(2026, time.October, 23, 0, 0, 0, 0, time.UTC)},
+		{"5/20 8,12 * * *", time.Date(2026, time.October, 19, 12, 5, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, test := range tests {
+		cron, err := ParseCron(test.expression)
+
+		if err != nil {
+			t.Errorf("ParseCron(%q) = %v", test.expression, err)
+			continue
+		}
+
+		if got := cron.Next(after); !got.Equal(test.want) {
+			t.Errorf("%q: Next() = %s, want %s", test.expression, got, test.want)
+		}
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		"* * * *",
+		"* * * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@never",
+	} {
+		if _, err := ParseCron(expression); err == nil {
+			t.Errorf("ParseCron(%q) = nil, want an error", expression)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Package jobs runs work outside of the requests on a queue kept in
+// Postgres. Workers of any API process claim the due jobs with SKIP LOCKED,
+// retry the failed ones with backoff and enqueue the recurring ones.
+package jobs
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+const (
+	DefaultMaxAttempts = 5
+
+	// The wait before the second attempt, doubled on each attempt up to
+	// maxBackoff
+	baseBackoff = 30 * time.Second
+	maxBackoff  = time.Hour
+)
+
+// Job is a claimed job as its handler sees it
+type Job struct {
+	ID          string
+	Kind        string
+	Payload     json.RawMessage
+	Attempts    int
+	MaxAttempts int
+	CompanyId   *string
+	CreatedBy   *string
+}
+
+// Decode reads the payload of the job into value
+func (j *Job) Decode(value any) error {
+	if err := json.Unmarshal(j.Payload, value); err != nil {
+		return Permanent(err)
+	}
+
+	return nil
+}
+
+// Options of a new job. Zero values run it now with the default attempts.
+type Options struct {
+	RunAt       time.Time
+	MaxAttempts int
+	CompanyId   string
+	CreatedBy   string
+}
+
+// RowQueryer is a database or a transaction. Jobs enqueued in a transaction
+// only run once it commits.
+type RowQueryer interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// Enqueue adds a job of the kind and returns its id
+func Enqueue(db RowQueryer, kind string, payload any, options Options) (string, error) {
+	encoded, err := json.Marshal(payload)
+
+	if err != nil {
+		return "", err
+	}
+
+	if options.RunAt.IsZero() {
+		options.RunAt = time.Now()
+	}
+
+	if options.MaxAttempts == 0 {
+		options.MaxAttempts = DefaultMaxAttempts
+	}
+
+	query := `INSERT INTO jobs (kind, payload, max_attempts, run_at, company_id, created_by)
+	VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid, NULLIF($6, '')::uuid)
+	RETURNING id`
+
+	var id string
+	err = db.QueryRow(query, kind, encoded, options.MaxAttempts, options.RunAt, options.CompanyId, options.CreatedBy).Scan(&id)
+
+	return id, err
+}
+
+// Backoff is the wait before retrying a job that failed the attempt
+func Backoff(attempt int) time.Duration {
+	wait := baseBackoff
+
+	for i := 1; i < attempt && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+
+	return min(wait, maxBackoff)
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks an error that retrying won't fix, as a malformed payload.
+// The job fails without using its remaining attempts.
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// IsPermanent reports whether the error was marked with Permanent
+func IsPermanent(err error) bool {
+	var permanent permanentError
+	return errors.As(err, &permanent)
+}
@@ -0,0 +1,400 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	pollInterval      = 2 * time.Second
+	heartbeatInterval = 10 * time.Second
+
+	// Running jobs without a heartbeat for this long lost their worker, as
+	// when the process was killed, and are claimed again
+	staleAfter = 2 * time.Minute
+)
+
+// Handler does the work of a job. The result is kept as JSON for the job
+// status. The context is cancelled when the job is cancelled or the queue
+// stops without draining it.
+type Handler func(ctx context.Context, job *Job) (any, error)
+
+type Queue struct {
+	db        *sql.DB
+	workers   int
+	handlers  map[string]Handler
+	schedules map[string]schedule
+}
+
+// schedule is a recurring kind and its cron expression, or why it didn't
+// parse
+type schedule struct {
+	expression string
+	cron       *Cron
+	err        error
+}
+
+func NewQueue(db *sql.DB, workers int) *Queue {
+	if workers < 1 {
+		workers = 1
+	}
+
+	return &Queue{
+		db:        db,
+		workers:   workers,
+		handlers:  make(map[string]Handler),
+		schedules: make(map[string]schedule),
+	}
+}
+
+// Handle registers the handler of the jobs of the kind. Only the kinds with
+// a handler are claimed by the queue.
+func (q *Queue) Handle(kind string, handler Handler) {
+	q.handlers[kind] = handler
+}
+
+// Schedule enqueues a job of the kind at the times of the cron expression,
+// unless the previous one is still waiting or running. The kind needs a
+// handler; Run fails on a kind without one or an invalid expression.
+func (q *Queue) Schedule(kind string, expression string) {
+	cron, err := ParseCron(expression)
+	q.schedules[kind] = schedule{expression: expression, cron: cron, err: err}
+}
+
+// Run works the queue until the context is cancelled. Jobs running by then
+// get up to drain to finish; after it their contexts are cancelled and they
+// are retried later, so handlers must be safe to repeat. Interrupting them
+// is part of stopping, not an error: Run returns nil once they're cancelled.
+func (q *Queue) Run(ctx context.Context, drain time.Duration) error {
+	if err := q.saveSchedules(); err != nil {
+		return err
+	}
+
+	// Jobs don't stop with ctx, only when the drain runs out
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	var running sync.WaitGroup
+	slots := make(chan struct{}, q.workers)
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := q.requeueStale(); err != nil {
+			log.Printf("jobs: requeue stale: %v", err)
+		}
+
+		if err := q.enqueueScheduled(); err != nil {
+			log.Printf("jobs: schedules: %v", err)
+		}
+
+		// Claim jobs while there are free workers and due jobs
+	claim:
+		for {
+			select {
+			case slots <- struct{}{}:
+			default:
+				break claim
+			}
+
+			job, err := q.claim()
+
+			if job == nil {
+				<-slots
+
+				if err != nil {
+					log.Printf("jobs: claim: %v", err)
+				}
+
+				break
+			}
+
+			running.Add(1)
+
+			go func() {
+				defer running.Done()
+				defer func() { <-slots }()
+
+				q.work(jobsCtx, job)
+			}()
+		}
+
+		select {
+		case <-ctx.Done():
+			q.drain(&running, drain, stopJobs)
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *Queue) drain(running *sync.WaitGroup, timeout time.Duration, stopJobs context.CancelFunc) {
+	done := make(chan struct{})
+
+	go func() {
+		running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		stopJobs()
+		<-done
+		log.Printf("jobs: interrupted the running jobs after draining for %s, they run again later", timeout)
+	}
+}
+
+func (q *Queue) kinds() []string {
+	kinds := make([]string, 0, len(q.handlers))
+
+	for kind := range q.handlers {
+		kinds = append(kinds, kind)
+	}
+
+	return kinds
+}
+
+func (q *Queue) claim() (*Job, error) {
+	query := `UPDATE jobs
+	SET status = $1, attempts = attempts + 1, started_at = now(), heartbeat_at = now(), updated_at = now()
+	WHERE id = (
+		SELECT id FROM jobs
+		WHERE status = $2 AND run_at <= now() AND kind = ANY($3)
+		ORDER BY run_at, created_at
+		FOR UPDATE SKIP LOCKED
+		LIMIT 1
+	)
+	RETURNING id, kind, payload, attempts, max_attempts, company_id, created_by`
+
+	job := new(Job)
+	err := q.db.QueryRow(query, StatusRunning, StatusQueued, pq.Array(q.kinds())).
+		Scan(&job.ID, &job.Kind, &job.Payload, &job.Attempts, &job.MaxAttempts, &job.CompanyId, &job.CreatedBy)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// work runs the job with a heartbeat that also watches for its cancellation
+// and records how it ended
+func (q *Queue) work(ctx context.Context, job *Job) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var cancelled bool
+	var mu sync.Mutex
+	stopHeartbeat := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopHeartbeat:
+				return
+			case <-ticker.C:
+			}
+
+			var requested bool
+			err := q.db.QueryRow(`UPDATE jobs SET heartbeat_at = now() WHERE id = $1 RETURNING cancel_requested`, job.ID).Scan(&requested)
+
+			if err != nil {
+				log.Printf("jobs: heartbeat of %s: %v", job.ID, err)
+				continue
+			}
+
+			if requested {
+				mu.Lock()
+				cancelled = true
+				mu.Unlock()
+				cancel()
+			}
+		}
+	}()
+
+	result, err := q.call(ctx, job)
+	close(stopHeartbeat)
+
+	mu.Lock()
+	wasCancelled := cancelled
+	mu.Unlock()
+
+	if err := q.finish(job, result, err, wasCancelled); err != nil {
+		log.Printf("jobs: finish %s: %v", job.ID, err)
+	}
+}
+
+func (q *Queue) call(ctx context.Context, job *Job) (result any, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+
+	return q.handlers[job.Kind](ctx, job)
+}
+
+func (q *Queue) finish(job *Job, result any, jobErr error, cancelled bool) error {
+	// A job that ended before noticing the cancellation keeps its result
+	if cancelled && jobErr != nil {
+		_, err := q.db.Exec(`UPDATE jobs SET status = $1, finished_at = now(), updated_at = now() WHERE id = $2`,
+			StatusCancelled, job.ID)
+		return err
+	}
+
+	if jobErr == nil {
+		encoded, err := json.Marshal(result)
+
+		if err != nil {
+			jobErr = Permanent(fmt.Errorf("encoding the result: %w", err))
+		} else {
+			_, err = q.db.Exec(`UPDATE jobs SET status = $1, result = $2, last_error = NULL, finished_at = now(), updated_at = now() WHERE id = $3`,
+				StatusSucceeded, encoded, job.ID)
+			return err
+		}
+	}
+
+	if IsPermanent(jobErr) || job.Attempts >= job.MaxAttempts {
+		_, err := q.db.Exec(`UPDATE jobs SET status = $1, last_error = $2, finished_at = now(), updated_at = now() WHERE id = $3`,
+			StatusFailed, jobErr.Error(), job.ID)
+		return err
+	}
+
+	_, err := q.db.Exec(`UPDATE jobs SET status = $1, last_error = $2, run_at = $3, heartbeat_at = NULL, updated_at = now() WHERE id = $4`,
+		StatusQueued, jobErr.Error(), time.Now().Add(Backoff(job.Attempts)), job.ID)
+
+	return err
+}
+
+// requeueStale gives back the jobs of workers that stopped responding, or
+// fails them when they used all their attempts
+func (q *Queue) requeueStale() error {
+	query := `UPDATE jobs
+	SET status = CASE WHEN attempts >= max_attempts THEN $1 ELSE $2 END,
+		finished_at = CASE WHEN attempts >= max_attempts THEN now() END,
+		last_error = 'the worker running the job stopped responding',
+		heartbeat_at = NULL,
+		updated_at = now()
+	WHERE status = $3 AND heartbeat_at < $4`
+
+	_, err := q.db.Exec(query, StatusFailed, StatusQueued, StatusRunning, time.Now().Add(-staleAfter))
+
+	return err
+}
+
+func (q *Queue) saveSchedules() error {
+	for kind, schedule := range q.schedules {
+		if _, ok := q.handlers[kind]; !ok {
+			return fmt.Errorf("jobs: scheduled kind %s has no handler", kind)
+		}
+
+		if schedule.err != nil {
+			return fmt.Errorf("jobs: schedule of %s: %w", kind, schedule.err)
+		}
+
+		next := schedule.cron.Next(time.Now())
+
+		if next.IsZero() {
+			return fmt.Errorf("jobs: schedule of %s never runs", kind)
+		}
+
+		// A changed expression takes effect at its next time
+		query := `INSERT INTO job_schedules (kind, cron, next_run_at) VALUES ($1, $2, $3)
+		ON CONFLICT (kind) DO UPDATE SET cron = EXCLUDED.cron, next_run_at = EXCLUDED.next_run_at, updated_at = now()
+		WHERE job_schedules.cron <> EXCLUDED.cron`
+
+		if _, err := q.db.Exec(query, kind, schedule.expression, next); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// enqueueScheduled enqueues the due recurring jobs. The schedules are
+// locked while doing it, so other processes skip them.
+func (q *Queue) enqueueScheduled() error {
+	if len(q.schedules) == 0 {
+		return nil
+	}
+
+	tx, err := q.db.Begin()
+
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	kinds := make([]string, 0, len(q.schedules))
+
+	for kind := range q.schedules {
+		kinds = append(kinds, kind)
+	}
+
+	rows, err := tx.Query(`SELECT kind FROM job_schedules
+	WHERE next_run_at <= now() AND kind = ANY($1)
+	FOR UPDATE SKIP LOCKED`, pq.Array(kinds))
+
+	if err != nil {
+		return err
+	}
+
+	due := make([]string, 0)
+
+	for rows.Next() {
+		var kind string
+
+		if err := rows.Scan(&kind); err != nil {
+			rows.Close()
+			return err
+		}
+
+		due = append(due, kind)
+	}
+
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, kind := range due {
+		var pending bool
+		err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM jobs WHERE kind = $1 AND status IN ($2, $3))`, kind, StatusQueued, StatusRunning).
+			Scan(&pending)
+
+		if err != nil {
+			return err
+		}
+
+		if !pending {
+			if _, err := Enqueue(tx, kind, struct{}{}, Options{MaxAttempts: 1}); err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec(`UPDATE job_schedules SET next_run_at = $1, updated_at = now() WHERE kind = $2`,
+			q.schedules[kind].cron.Next(time.Now()), kind)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,111 @@
+package leave
+
+import (
+	"math"
+	"time"
+)
+
+// DefaultVacationDays is the paid vacation accrued per year of service
+// under the Colombian labor code.
+const DefaultVacationDays = 15.0
+
+// Policy describes how a leave type accrues
+type Policy struct {
+	DaysPerYear  float64
+	MaxCarryOver *float64
+}
+
+// Taken is a day count attributed to the date it starts on
+type Taken struct {
+	Date time.Time
+	Days float64
+}
+
+type Balance struct {
+	ServiceYearStart time.Time `json:"service_year_start"`
+	Accrued          float64   `json:"accrued"`
+	CarriedOver      float64   `json:"carried_over"`
+	Taken            float64   `json:"taken"`
+	Pending          float64   `json:"pending"`
+	Available        float64   `json:"available"`
+}
+
+// Calculate returns the balance at asOf. Days accrue linearly during each
+// year of service counted from the admission date; at the end of every year
+// the unused days are carried over, up to MaxCarryOver when it is set.
+func Calculate(policy Policy, admission time.Time, asOf time.Time, taken []Taken, pending []Taken) Balance {
+	admission = truncate(admission)
+	asOf = truncate(asOf)
+
+	var balance Balance
+
+	if asOf.Before(admission) {
+		balance.ServiceYearStart = admission
+		return balance
+	}
+
+	carry := 0.0
+	yearStart := admission
+
+	for {
+		yearEnd := yearStart.AddDate(1, 0, 0)
+		takenInYear := sumBetween(taken, yearStart, yearEnd)
+
+		if !yearEnd.After(asOf) {
+			// Completed year, carry the remaining days to the next one
+			remaining := carry + policy.DaysPerYear - takenInYear
+
+			if policy.MaxCarryOver != nil && remaining > *policy.MaxCarryOver {
+				remaining = *policy.MaxCarryOver
+			}
+
+			carry = remaining
+			yearStart = yearEnd
+			continue
+		}
+
+		elapsed := asOf.Sub(yearStart).Hours() / 24
+		length := yearEnd.Sub(yearStart).Hours() / 24
+
+		balance.ServiceYearStart = yearStart
+		balance.CarriedOver = round(carry)
+		balance.Accrued = round(policy.DaysPerYear * elapsed / length)
+		balance.Taken = takenInYear
+		break
+	}
+
+	for _, request := range pending {
+		balance.Pending += request.Days
+	}
+
+	balance.Available = round(balance.CarriedOver + balance.Accrued - balance.Taken - balance.Pending)
+
+	return balance
+}
+
+// Overlaps reports whether the ranges [startA, endA] and [startB, endB]
+// share at least one day.
+func Overlaps(startA time.Time, endA time.Time, startB time.Time, endB time.Time) bool {
+	return !truncate(startA).After(truncate(endB)) && !truncate(startB).After(truncate(endA))
+}
+
+func sumBetween(taken []Taken, from time.Time, to time.Time) float64 {
+	total := 0.0
+
+	for _, t := range taken {
+		date := truncate(t.Date)
+		if !date.Before(from) && date.Before(to) {
+			total += t.Days
+		}
+	}
+
+	return total
+}
+
+func truncate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileSender writes the messages as .eml files to a directory instead of
+// sending them, for development. Mail clients open the files.
+type FileSender struct {
+	dir  string
+	from string
+}
+
+func NewFileSender(dir string, from string) *FileSender {
+	return &FileSender{dir: dir, from: from}
+}
+
+func (s *FileSender) Send(ctx context.Context, message *Message) error {
+	now := time.Now()
+	data, err := encode(s.from, message, now)
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+
+	recipient := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == '<' || r == '>' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, message.To)
+
+	path := filepath.Join(s.dir, now.Format("20060102T150405.000000000")+"-"+recipient+".eml")
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return err
+	}
+
+	log.Printf("mail: %q to %s written to %s", message.Subject, message.To, path)
+
+	return nil
+}
@@ -0,0 +1,98 @@
+// Package mailer renders the emails of the API from templates localized in
+// Spanish and English and sends them over SMTP or, in development, to files.
+package mailer
+
+import (
+	"context"
+	"errors"
+	"net/textproto"
+	"slices"
+	"time"
+)
+
+// Message is a rendered email. HTML and Text are sent as alternatives of
+// the same content.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Sender delivers messages. Errors marked with Permanent won't succeed on
+// a retry.
+type Sender interface {
+	Send(ctx context.Context, message *Message) error
+}
+
+const (
+	LanguageSpanish = "es"
+	LanguageEnglish = "en"
+
+	DefaultLanguage = LanguageSpanish
+)
+
+var Languages = []string{LanguageSpanish, LanguageEnglish}
+
+// Language returns the language when there are templates for it and the
+// default language otherwise
+func Language(language string) string {
+	if slices.Contains(Languages, language) {
+		return language
+	}
+
+	return DefaultLanguage
+}
+
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	// Emails that failed every attempt
+	StatusDead = "dead"
+)
+
+// MaxAttempts of an email before it is dead
+const MaxAttempts = 8
+
+// Backoff is the wait after the failed attempt of an email: a minute after
+// the first, doubling up to an hour
+func Backoff(attempt int) time.Duration {
+	wait := time.Minute
+
+	for i := 1; i < attempt && wait < time.Hour; i++ {
+		wait *= 2
+	}
+
+	return min(wait, time.Hour)
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks an error that retrying won't fix, as a missing template
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// IsPermanent reports whether the error was marked with Permanent or is a
+// permanent SMTP reply (5xx), as an unknown recipient
+func IsPermanent(err error) bool {
+	var permanent permanentError
+
+	if errors.As(err, &permanent) {
+		return true
+	}
+
+	var reply *textproto.Error
+
+	return errors.As(err, &reply) && reply.Code >= 500
+}
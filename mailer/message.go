@@ -0,0 +1,102 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// encode builds the MIME message, a multipart/alternative with the text
+// and HTML versions
+func encode(from string, message *Message, now time.Time) ([]byte, error) {
+	sender, err := mail.ParseAddress(from)
+
+	if err != nil {
+		return nil, Permanent(fmt.Errorf("invalid sender %q: %w", from, err))
+	}
+
+	recipient, err := mail.ParseAddress(message.To)
+
+	if err != nil {
+		return nil, Permanent(fmt.Errorf("invalid recipient %q: %w", message.To, err))
+	}
+
+	var buffer bytes.Buffer
+	body := multipart.NewWriter(&buffer)
+
+	headers := []string{
+		"From: " + sender.String(),
+		"To: " + recipient.String(),
+		"Subject: " + mime.QEncoding.Encode("utf-8", message.Subject),
+		"Date: " + now.Format(time.RFC1123Z),
+		"Message-ID: " + messageId(sender.Address),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + body.Boundary(),
+	}
+
+	var encoded bytes.Buffer
+	encoded.WriteString(strings.Join(headers, "\r\n"))
+	encoded.WriteString("\r\n\r\n")
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", message.Text},
+		{"text/html; charset=utf-8", message.HTML},
+	}
+
+	for _, part := range parts {
+		if part.content == "" {
+			continue
+		}
+
+		writer, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		qp := quotedprintable.NewWriter(writer)
+
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+
+	encoded.Write(buffer.Bytes())
+
+	return encoded.Bytes(), nil
+}
+
+func messageId(from string) string {
+	domain := "localhost"
+
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = from[at+1:]
+	}
+
+	buf := make([]byte, 16)
+	rand.Read(buf)
+
+	return "<" + hex.EncodeToString(buf) + "@" + domain + ">"
+}
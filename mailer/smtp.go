@@ -0,0 +1,133 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+)
+
+const (
+	// SecurityStartTLS upgrades the connection when the server offers it
+	SecurityStartTLS = "starttls"
+	// SecurityTLS connects with TLS, as to port 465
+	SecurityTLS = "tls"
+	// SecurityNone sends in the clear, as to a local fake server
+	SecurityNone = "none"
+)
+
+// sendTimeout bounds sending a message when the context has no deadline
+const sendTimeout = 30 * time.Second
+
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	Security string
+}
+
+// SMTPSender sends each message over its own connection to the server
+type SMTPSender struct {
+	config SMTPConfig
+}
+
+func NewSMTPSender(config SMTPConfig) *SMTPSender {
+	if config.Security == "" {
+		config.Security = SecurityStartTLS
+	}
+
+	return &SMTPSender{config: config}
+}
+
+func (s *SMTPSender) Send(ctx context.Context, message *Message) error {
+	data, err := encode(s.config.From, message, time.Now())
+
+	if err != nil {
+		return err
+	}
+
+	// encode already validated both addresses
+	from, _ := mail.ParseAddress(s.config.From)
+	to, _ := mail.ParseAddress(message.To)
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(sendTimeout)
+	}
+
+	conn, err := s.dial(ctx, deadline)
+
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if s.config.Security == SecurityStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: s.config.Host}); err != nil {
+				return err
+			}
+		}
+	}
+
+	if s.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+
+	if err := client.Rcpt(to.Address); err != nil {
+		return err
+	}
+
+	writer, err := client.Data()
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := writer.Write(data); err != nil {
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+func (s *SMTPSender) dial(ctx context.Context, deadline time.Time) (net.Conn, error) {
+	address := net.JoinHostPort(s.config.Host, s.config.Port)
+	dialer := &net.Dialer{Deadline: deadline}
+
+	switch s.config.Security {
+	case SecurityTLS:
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: s.config.Host}}
+		return tlsDialer.DialContext(ctx, "tcp", address)
+	case SecurityStartTLS, SecurityNone:
+		return dialer.DialContext(ctx, "tcp", address)
+	default:
+		return nil, Permanent(fmt.Errorf("unknown SMTP security %q", s.config.Security))
+	}
+}
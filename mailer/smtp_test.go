@@ -0,0 +1,339 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type receivedMail struct {
+	from string
+	to   []string
+	data []byte
+}
+
+// fakeSMTP is a local SMTP server that keeps the messages it receives. The
+// recipients in replies are answered with their reply instead of accepted.
+type fakeSMTP struct {
+	listener net.Listener
+	replies  map[string]string
+
+	mu       sync.Mutex
+	received []receivedMail
+}
+
+func newFakeSMTP(t *testing.T, replies map[string]string) *fakeSMTP {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &fakeSMTP{listener: listener, replies: replies}
+	t.Cleanup(func() { listener.Close() })
+
+	go server.serve()
+
+	return server
+}
+
+func (f *fakeSMTP) serve() {
+	for {
+		conn, err := f.listener.Accept()
+
+		if err != nil {
+			return
+		}
+
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeSMTP) handle(conn net.Conn) {
+	defer conn.Close()
+
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 fake ESMTP")
+
+	var current receivedMail
+
+	for {
+		line, err := text.ReadLine()
+
+		if err != nil {
+			return
+		}
+
+		command, argument, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(command) {
+		case "EHLO", "HELO":
+			text.PrintfLine("250-fake")
+			text.PrintfLine("250 8BITMIME")
+		case "MAIL":
+			current = receivedMail{from: address(argument)}
+			text.PrintfLine("250 2.1.0 OK")
+		case "RCPT":
+			to := address(argument)
+
+			if reply, ok := f.replies[to]; ok {
+				text.PrintfLine("%s", reply)
+				continue
+			}
+
+			current.to = append(current.to, to)
+			text.PrintfLine("250 2.1.5 OK")
+		case "DATA":
+			text.PrintfLine("354 end data with <CR><LF>.<CR><LF>")
+
+			data, err := text.ReadDotBytes()
+
+			if err != nil {
+				return
+			}
+
+			current.data = data
+
+			f.mu.Lock()
+			f.received = append(f.received, current)
+			f.mu.Unlock()
+
+			text.PrintfLine("250 2.0.0 queued")
+		case "RSET", "NOOP":
+			text.PrintfLine("250 2.0.0 OK")
+		case "QUIT":
+			text.PrintfLine("221 2.0.0 bye")
+			return
+		default:
+			text.PrintfLine("502 5.5.2 command not recognized")
+		}
+	}
+}
+
+func (f *fakeSMTP) messages() []receivedMail {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]receivedMail(nil), f.received...)
+}
+
+// address takes the address of a FROM:<...> or TO:<...> argument
+func address(argument string) string {
+	start := strings.Index(argument, "<")
+	end := strings.Index(argument, ">")
+
+	if start < 0 || end < start {
+		return argument
+	}
+
+	return argument[start+1 : end]
+}
+
+func newTestSMTPSender(server *fakeSMTP) *SMTPSender {
+	host, port, _ := net.SplitHostPort(server.listener.Addr().String())
+
+	return NewSMTPSender(SMTPConfig{
+		Host:     host,
+		Port:     port,
+		From:     "Employees Manager <noreply@example.com>",
+		Security: SecurityNone,
+	})
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	return ctx
+}
+
+func TestSMTPSenderSend(t *testing.T) {
+	server := newFakeSMTP(t, nil)
+	sender := newTestSMTPSender(server)
+
+	message, err := Render("email_verification", LanguageEnglish, "Ana Pérez <ana@example.com>", map[string]any{
+		"full_name": "Ana Pérez",
+		"link":      "https://app.example.com/verify-email?token=abc123",
+		// As read back from the JSON of the outbox
+		"hours": float64(24),
+	})
+
+	if err != nil {
+		t.Fatalf("Render() = %v", err)
+	}
+
+	if err := sender.Send(testContext(t), message); err != nil {
+		t.Fatalf("Send() = %v", err)
+	}
+
+	received := server.messages()
+
+	if len(received) != 1 {
+		t.Fatalf("the server received %d messages, want 1", len(received))
+	}
+
+	if received[0].from != "noreply@example.com" {
+		t.Errorf("MAIL FROM %q, want noreply@example.com", received[0].from)
+	}
+
+	if len(received[0].to) != 1 || received[0].to[0] != "ana@example.com" {
+		t.Errorf("RCPT TO %q, want ana@example.com", received[0].to)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(string(received[0].data)))
+
+	if err != nil {
+		t.Fatalf("the message can't be parsed: %v", err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+
+	if err != nil || subject != "Confirm your email for Employees Manager" {
+		t.Errorf("Subject = %q, %v", subject, err)
+	}
+
+	to, err := parsed.Header.AddressList("To")
+
+	if err != nil || len(to) != 1 || to[0].Name != "Ana Pérez" || to[0].Address != "ana@example.com" {
+		t.Errorf("To = %v, %v", to, err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, %v", parsed.Header.Get("Content-Type"), err)
+	}
+
+	parts := make(map[string]string)
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+
+	for {
+		part, err := reader.NextPart()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatalf("reading the parts: %v", err)
+		}
+
+		// The reader already decoded the quoted-printable
+		content, err := io.ReadAll(part)
+
+		if err != nil {
+			t.Fatalf("reading the parts: %v", err)
+		}
+
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[contentType] = string(content)
+	}
+
+	text := parts["text/plain"]
+
+	for _, want := range []string{"Hi Ana Pérez,", "https://app.example.com/verify-email?token=abc123", "expires in 24 hours"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("the text part doesn't contain %q:\n%s", want, text)
+		}
+	}
+
+	html := parts["text/html"]
+
+	for _, want := range []string{"<p>Hi Ana Pérez,</p>", `href="https://app.example.com/verify-email?token=abc123"`, "expires in 24 hours"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("the HTML part doesn't contain %q:\n%s", want, html)
+		}
+	}
+}
+
+func TestSMTPSenderErrors(t *testing.T) {
+	server := newFakeSMTP(t, map[string]string{
+		"unknown@example.com": "550 5.1.1 user unknown",
+		"full@example.com":    "452 4.2.2 mailbox full",
+		"busy@example.com":    "451 4.3.0 try again later",
+	})
+	sender := newTestSMTPSender(server)
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	closedHost, closedPort, _ := net.SplitHostPort(closed.Addr().String())
+	closed.Close()
+
+	unreachable := NewSMTPSender(SMTPConfig{Host: closedHost, Port: closedPort, From: "noreply@example.com", Security: SecurityNone})
+
+	tests := []struct {
+		name      string
+		sender    *SMTPSender
+		to        string
+		permanent bool
+	}{
+		{"unknown recipient", sender, "unknown@example.com", true},
+		{"full mailbox", sender, "full@example.com", false},
+		{"server busy", sender, "busy@example.com", false},
+		{"invalid recipient", sender, "not an address", true},
+		{"server down", unreachable, "ana@example.com", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.sender.Send(testContext(t), &Message{To: test.to, Subject: "Hi", Text: "Hi\n"})
+
+			if err == nil {
+				t.Fatal("Send() = nil, want an error")
+			}
+
+			if got := IsPermanent(err); got != test.permanent {
+				t.Errorf("IsPermanent(%v) = %v, want %v", err, got, test.permanent)
+			}
+		})
+	}
+
+	if received := server.messages(); len(received) != 0 {
+		t.Errorf("the server received %d messages, want none", len(received))
+	}
+}
+
+func TestSMTPSenderUnknownSecurity(t *testing.T) {
+	sender := NewSMTPSender(SMTPConfig{Host: "127.0.0.1", Port: "25", From: "noreply@example.com", Security: "ssl"})
+
+	if err := sender.Send(testContext(t), &Message{To: "ana@example.com", Subject: "Hi", Text: "Hi\n"}); !IsPermanent(err) {
+		t.Errorf("Send() with an unknown security = %v, want a permanent error", err)
+	}
+}
+
+func TestIsPermanent(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"marked", Permanent(errors.New("unknown email template")), true},
+		{"wrapped mark", errors.Join(errors.New("sending"), Permanent(errors.New("invalid recipient"))), true},
+		{"5xx reply", &textproto.Error{Code: 554, Msg: "transaction failed"}, true},
+		{"4xx reply", &textproto.Error{Code: 421, Msg: "service not available"}, false},
+		{"network", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, false},
+	}
+
+	for _, test := range tests {
+		if got := IsPermanent(test.err); got != test.want {
+			t.Errorf("IsPermanent(%s) = %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	if _, err := Render("missing", LanguageEnglish, "ana@example.com", nil); !IsPermanent(err) {
+		t.Errorf("Render() of a missing template = %v, want a permanent error", err)
+	}
+}
@@ -0,0 +1,108 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Each email has a file per language in templates/<language>/<name>.tmpl
+// defining its "subject", "text" and "content", the HTML body that goes in
+// the layout of the language.
+//
+//go:embed templates
+var files embed.FS
+
+type emailTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// templates by language and name
+var templates = make(map[string]map[string]*emailTemplate)
+
+var funcs = map[string]any{
+	// Numbers in data read back from JSON are float64
+	"int": func(value any) int {
+		switch number := value.(type) {
+		case int:
+			return number
+		case int64:
+			return int(number)
+		case float64:
+			return int(number)
+		}
+		return 0
+	},
+	"neg": func(value int) int {
+		return -value
+	},
+}
+
+func init() {
+	for _, language := range Languages {
+		templates[language] = make(map[string]*emailTemplate)
+
+		entries, err := files.ReadDir("templates/" + language)
+
+		if err != nil {
+			panic(err)
+		}
+
+		for _, entry := range entries {
+			name, ok := strings.CutSuffix(entry.Name(), ".tmpl")
+
+			if !ok {
+				continue
+			}
+
+			path := "templates/" + language + "/" + entry.Name()
+			layout := "templates/" + language + "/layout.html"
+
+			templates[language][name] = &emailTemplate{
+				text: texttemplate.Must(texttemplate.New(entry.Name()).Funcs(funcs).ParseFS(files, path)),
+				html: htmltemplate.Must(htmltemplate.New(entry.Name()).Funcs(funcs).ParseFS(files, layout, path)),
+			}
+		}
+	}
+}
+
+// HasTemplate reports whether there is an email with the name
+func HasTemplate(name string) bool {
+	_, ok := templates[DefaultLanguage][name]
+	return ok
+}
+
+// Render renders the email with the name in the language, or the default
+// language when it has none, for the recipient
+func Render(name string, language string, to string, data map[string]any) (*Message, error) {
+	template, ok := templates[Language(language)][name]
+
+	if !ok {
+		return nil, Permanent(fmt.Errorf("unknown email template %s", name))
+	}
+
+	var subject, text, html bytes.Buffer
+
+	if err := template.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, Permanent(err)
+	}
+
+	if err := template.text.ExecuteTemplate(&text, "text", data); err != nil {
+		return nil, Permanent(err)
+	}
+
+	if err := template.html.ExecuteTemplate(&html, "layout", data); err != nil {
+		return nil, Permanent(err)
+	}
+
+	return &Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
-	"fmt"
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gioCuesta25/employees-manager-backend/api"
 	"github.com/gioCuesta25/employees-manager-backend/config"
@@ -22,9 +25,14 @@ func main() {
 		log.Fatal("Error connecting to database: ", err.Error())
 	}
 
-	server := api.NewServer(env, db)
+	// Stop on Ctrl+C and on the SIGTERM of deploys, draining requests and
+	// jobs first
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	server.Run()
+	server := api.NewServer(env, db)
 
-	fmt.Println(env)
+	if err := server.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
 }
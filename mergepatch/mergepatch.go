@@ -0,0 +1,84 @@
+// Package mergepatch applies JSON Merge Patch documents (RFC 7396), the
+// partial updates of the PATCH routes.
+package mergepatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Apply merges the patch into the target document. Members of the patch
+// replace the ones of the target, objects are merged member by member and
+// null removes the member.
+func Apply(target []byte, patch []byte) ([]byte, error) {
+	var targetValue, patchValue any
+
+	if err := json.Unmarshal(target, &targetValue); err != nil {
+		return nil, fmt.Errorf("invalid target: %w", err)
+	}
+
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, fmt.Errorf("invalid patch: %w", err)
+	}
+
+	return json.Marshal(merge(targetValue, patchValue))
+}
+
+func merge(target any, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]any)
+
+	if !ok {
+		targetObject = make(map[string]any)
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+
+		targetObject[key] = merge(targetObject[key], value)
+	}
+
+	return targetObject
+}
+
+// Changed returns, sorted, the top-level members that differ between two
+// JSON objects
+func Changed(before []byte, after []byte) ([]string, error) {
+	var beforeObject, afterObject map[string]any
+
+	if err := json.Unmarshal(before, &beforeObject); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(after, &afterObject); err != nil {
+		return nil, err
+	}
+
+	changed := make([]string, 0)
+
+	for key, value := range afterObject {
+		if !reflect.DeepEqual(beforeObject[key], value) {
+			changed = append(changed, key)
+		}
+	}
+
+	for key := range beforeObject {
+		if _, ok := afterObject[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+
+	sort.Strings(changed)
+
+	return changed, nil
+}
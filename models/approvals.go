@@ -0,0 +1,116 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gioCuesta25/employees-manager-backend/approvals"
+)
+
+// CreateApprovalPolicyBody sets the chain of a subject for the company. The
+// subjects without a policy are approved by HR.
+type CreateApprovalPolicyBody struct {
+	CompanyId string           `json:"company_id" binding:"required"`
+	Subject   string           `json:"subject" binding:"required,oneof=salary_change termination profile_change"`
+	Steps     []approvals.Step `json:"steps" binding:"required"`
+}
+
+type UpdateApprovalPolicyBody struct {
+	Steps []approvals.Step `json:"steps" binding:"required"`
+}
+
+type GetApprovalPolicyParams struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+type ApprovalPolicyResponse struct {
+	ID        string           `json:"id"`
+	CompanyId string           `json:"company_id"`
+	Subject   string           `json:"subject"`
+	Steps     []approvals.Step `json:"steps"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt *time.Time       `json:"updated_at"`
+}
+
+type GetApprovalParams struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+type DecideApprovalBody struct {
+	Comment *string `json:"comment"`
+}
+
+type ApprovalStepResponse struct {
+	Step           int        `json:"step"`
+	ApproverRole   string     `json:"approver_role"`
+	ApproverLevel  int        `json:"approver_level"`
+	ApproverUserId *string    `json:"approver_user_id"`
+	Status         string     `json:"status"`
+	TimeoutHours   int        `json:"timeout_hours"`
+	DueAt          *time.Time `json:"due_at"`
+	EscalatedAt    *time.Time `json:"escalated_at"`
+	DecidedBy      *string    `json:"decided_by"`
+	DecidedAt      *time.Time `json:"decided_at"`
+	Comment        *string    `json:"comment"`
+}
+
+type ApprovalResponse struct {
+	ID          string                  `json:"id"`
+	CompanyId   string                  `json:"company_id"`
+	Subject     string                  `json:"subject"`
+	SubjectId   *string                 `json:"subject_id"`
+	EmployeeId  string                  `json:"employee_id"`
+	Amount      *float64                `json:"amount"`
+	Payload     map[string]any          `json:"payload"`
+	Status      string                  `json:"status"`
+	CurrentStep int                     `json:"current_step"`
+	RequestedBy string                  `json:"requested_by"`
+	Steps       []*ApprovalStepResponse `json:"steps,omitempty"`
+	CreatedAt   time.Time               `json:"created_at"`
+	UpdatedAt   *time.Time              `json:"updated_at"`
+}
+
+// ApprovalInboxItem is a request waiting for a decision of the user. Kind
+// is approval or leave_request, which keep their own chains.
+type ApprovalInboxItem struct {
+	Kind         string     `json:"kind"`
+	ID           string     `json:"id"`
+	CompanyId    string     `json:"company_id"`
+	Subject      string     `json:"subject"`
+	EmployeeId   string     `json:"employee_id"`
+	EmployeeName string     `json:"employee_name"`
+	Step         int        `json:"step"`
+	ApproverRole string     `json:"approver_role"`
+	Status       string     `json:"status"`
+	DueAt        *time.Time `json:"due_at"`
+	OnBehalfOf   *string    `json:"on_behalf_of"`
+	Link         string     `json:"link"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// CreateApprovalDelegationBody lets another user decide the steps of the
+// current user between the dates, as while they are on leave.
+type CreateApprovalDelegationBody struct {
+	CompanyId  string    `json:"company_id" binding:"required"`
+	DelegateId string    `json:"delegate_id" binding:"required"`
+	StartsOn   time.Time `json:"starts_on" binding:"required"`
+	EndsOn     time.Time `json:"ends_on" binding:"required"`
+}
+
+type GetApprovalDelegationParams struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+type ApprovalDelegationResponse struct {
+	ID         string    `json:"id"`
+	CompanyId  string    `json:"company_id"`
+	UserId     string    `json:"user_id"`
+	DelegateId string    `json:"delegate_id"`
+	StartsOn   time.Time `json:"starts_on"`
+	EndsOn     time.Time `json:"ends_on"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type CreateSalaryChangeBody struct {
+	Salary float64 `json:"salary" binding:"required,gt=0"`
+	Reason *string `json:"reason"`
+}
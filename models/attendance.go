@@ -0,0 +1,88 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gioCuesta25/employees-manager-backend/attendance"
+)
+
+const (
+	AttendanceSourceWeb   = "web"
+	AttendanceSourceKiosk = "kiosk"
+	AttendanceSourceApi   = "api"
+)
+
+const (
+	TimesheetStatusOpen     = "open"
+	TimesheetStatusApproved = "approved"
+)
+
+type ClockBody struct {
+	EmployeeId string     `json:"employee_id" binding:"required"`
+	Source     string     `json:"source" binding:"required,oneof=web kiosk api"`
+	OccurredAt *time.Time `json:"occurred_at"`
+}
+
+type AttendanceEventResponse struct {
+	ID         string    `json:"id"`
+	CompanyId  string    `json:"company_id"`
+	EmployeeId string    `json:"employee_id"`
+	Kind       string    `json:"kind"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Source     string    `json:"source"`
+	CreatedBy  string    `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type AttendanceSettingsBody struct {
+	RoundingMinutes int    `json:"rounding_minutes" binding:"gte=0,lte=60"`
+	RoundingMode    string `json:"rounding_mode" binding:"required,oneof=nearest up down"`
+}
+
+type AttendanceSettingsResponse struct {
+	CompanyId       string `json:"company_id"`
+	RoundingMinutes int    `json:"rounding_minutes"`
+	RoundingMode    string `json:"rounding_mode"`
+}
+
+type GetTimesheetParams struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+type CorrectTimesheetBody struct {
+	Intervals []attendance.Interval `json:"intervals" binding:"required"`
+	Reason    string                `json:"reason" binding:"required"`
+}
+
+type ApproveTimesheetsBody struct {
+	EmployeeId string    `json:"employee_id" binding:"required"`
+	From       time.Time `json:"from" binding:"required"`
+	To         time.Time `json:"to" binding:"required"`
+}
+
+type TimesheetResponse struct {
+	ID            string                         `json:"id"`
+	CompanyId     string                         `json:"company_id"`
+	EmployeeId    string                         `json:"employee_id"`
+	Date          time.Time                      `json:"date"`
+	Intervals     []attendance.Interval          `json:"intervals"`
+	WorkedMinutes int                            `json:"worked_minutes"`
+	MissingPunch  bool                           `json:"missing_punch"`
+	Corrected     bool                           `json:"corrected"`
+	Status        string                         `json:"status"`
+	ApprovedBy    *string                        `json:"approved_by"`
+	ApprovedAt    *time.Time                     `json:"approved_at"`
+	Events        []*AttendanceEventResponse     `json:"events,omitempty"`
+	Corrections   []*TimesheetCorrectionResponse `json:"corrections,omitempty"`
+	CreatedAt     time.Time                      `json:"created_at"`
+	UpdatedAt     *time.Time                     `json:"updated_at"`
+}
+
+type TimesheetCorrectionResponse struct {
+	ID        string                `json:"id"`
+	ChangedBy string                `json:"changed_by"`
+	Reason    string                `json:"reason"`
+	Before    []attendance.Interval `json:"before"`
+	After     []attendance.Interval `json:"after"`
+	CreatedAt time.Time             `json:"created_at"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gioCuesta25/employees-manager-backend/audit"
+)
+
+type AuditEntryResponse struct {
+	ID        int64                   `json:"id"`
+	CompanyId *string                 `json:"company_id"`
+	ActorId   *string                 `json:"actor_id"`
+	ActorName *string                 `json:"actor_name"`
+	IP        *string                 `json:"ip"`
+	RequestId *string                 `json:"request_id"`
+	Entity    string                  `json:"entity"`
+	EntityId  *string                 `json:"entity_id"`
+	Action    string                  `json:"action"`
+	Changes   map[string]audit.Change `json:"changes"`
+	CreatedAt time.Time               `json:"created_at"`
+}
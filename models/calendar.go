@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gioCuesta25/employees-manager-backend/calendar"
+)
+
+type UpdateCalendarBody struct {
+	WorkingWeek      []int `json:"working_week" binding:"required,dive,min=0,max=6"`
+	NationalHolidays *bool `json:"national_holidays"`
+}
+
+type CreateCalendarClosureBody struct {
+	Date time.Time `json:"date" binding:"required"`
+	Name string    `json:"name" binding:"required"`
+}
+
+type GetCalendarClosureParams struct {
+	ID        string `uri:"id" binding:"required"`
+	ClosureId string `uri:"closureId" binding:"required"`
+}
+
+type CalendarClosureResponse struct {
+	ID        string    `json:"id"`
+	CompanyId string    `json:"company_id"`
+	Date      time.Time `json:"date"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CalendarResponse struct {
+	CompanyId        string                     `json:"company_id"`
+	Year             int                        `json:"year"`
+	WorkingWeek      []int                      `json:"working_week"`
+	NationalHolidays bool                       `json:"national_holidays"`
+	Holidays         []calendar.Holiday         `json:"holidays"`
+	Closures         []*CalendarClosureResponse `json:"closures"`
+	WorkingDays      int                        `json:"working_days"`
+}
+
+type WorkingDaysResponse struct {
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	WorkingDays int       `json:"working_days"`
+}
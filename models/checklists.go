@@ -0,0 +1,84 @@
+package models
+
+import "time"
+
+const (
+	ChecklistKindOnboarding  = "onboarding"
+	ChecklistKindOffboarding = "offboarding"
+)
+
+type ChecklistTemplateTaskBody struct {
+	Title         string  `json:"title" binding:"required"`
+	Description   *string `json:"description"`
+	AssigneeRole  string  `json:"assignee_role" binding:"required,oneof=owner hr manager"`
+	DueOffsetDays int     `json:"due_offset_days"`
+}
+
+// CreateChecklistTemplateBody defines the tasks created for every employee
+// hired (onboarding) or terminated (offboarding). The due date of a task is
+// the admission or termination date plus its offset, which can be negative.
+type CreateChecklistTemplateBody struct {
+	CompanyId string                      `json:"company_id" binding:"required"`
+	Name      string                      `json:"name" binding:"required"`
+	Kind      string                      `json:"kind" binding:"required,oneof=onboarding offboarding"`
+	Tasks     []ChecklistTemplateTaskBody `json:"tasks" binding:"required,min=1,dive"`
+}
+
+type GetChecklistTemplateParams struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+type ChecklistTemplateTaskResponse struct {
+	ID            string  `json:"id"`
+	Position      int     `json:"position"`
+	Title         string  `json:"title"`
+	Description   *string `json:"description"`
+	AssigneeRole  string  `json:"assignee_role"`
+	DueOffsetDays int     `json:"due_offset_days"`
+}
+
+type ChecklistTemplateResponse struct {
+	ID        string                           `json:"id"`
+	CompanyId string                           `json:"company_id"`
+	Name      string                           `json:"name"`
+	Kind      string                           `json:"kind"`
+	Tasks     []*ChecklistTemplateTaskResponse `json:"tasks"`
+	CreatedAt time.Time                        `json:"created_at"`
+	UpdatedAt *time.Time                       `json:"updated_at"`
+}
+
+type TerminateEmployeeBody struct {
+	Date   time.Time `json:"date" binding:"required"`
+	Reason string    `json:"reason" binding:"required"`
+}
+
+type CompleteChecklistTaskBody struct {
+	Notes *string `json:"notes"`
+}
+
+type GetChecklistTaskParams struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+type ChecklistTaskResponse struct {
+	ID           string     `json:"id"`
+	CompanyId    string     `json:"company_id"`
+	EmployeeId   string     `json:"employee_id"`
+	EmployeeName string     `json:"employee_name"`
+	TemplateId   *string    `json:"template_id"`
+	Kind         string     `json:"kind"`
+	Title        string     `json:"title"`
+	Description  *string    `json:"description"`
+	AssigneeRole string     `json:"assignee_role"`
+	DueDate      time.Time  `json:"due_date"`
+	CompletedAt  *time.Time `json:"completed_at"`
+	CompletedBy  *string    `json:"completed_by"`
+	Notes        *string    `json:"notes"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type OverdueChecklistSummary struct {
+	Total      int            `json:"total"`
+	ByRole     map[string]int `json:"by_role"`
+	ByEmployee map[string]int `json:"by_employee"`
+}
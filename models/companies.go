@@ -4,10 +4,12 @@ import "time"
 
 type CreateCompanyBody struct {
 	Name    string `json:"name" binding:"required"`
-	Owner   int    `json:"owner" binding:"required"`
 	Address string `json:"address" binding:"required"`
 	Phone   string `json:"phone" binding:"required"`
 	Email   string `json:"email" binding:"required"`
+	// RequireIfMatch rejects the writes to the company records that don't
+	// send the version they read
+	RequireIfMatch bool `json:"require_if_match"`
 }
 
 type GetCompanyParams struct {
@@ -15,12 +17,14 @@ type GetCompanyParams struct {
 }
 
 type CompanyResponse struct {
-	ID        string     `json:"id"`
-	Name      string     `json:"name"`
-	Owner     int        `json:"owner"`
-	Address   string     `json:"address"`
-	Phone     string     `json:"phone"`
-	Email     string     `json:"email"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt *time.Time `json:"updated_at"`
+	ID             string     `json:"id"`
+	Name           string     `json:"name"`
+	Owner          string     `json:"owner"`
+	Address        string     `json:"address"`
+	Phone          string     `json:"phone"`
+	Email          string     `json:"email"`
+	RequireIfMatch bool       `json:"require_if_match"`
+	Version        int        `json:"version"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      *time.Time `json:"updated_at"`
 }
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+const (
+	RoleOwner   = "owner"
+	RoleHR      = "hr"
+	RoleManager = "manager"
+)
+
+type AddCompanyMemberBody struct {
+	UserId string `json:"user_id" binding:"required"`
+	// The owner is the one of the company, it isn't granted as a member
+	Role string `json:"role" binding:"required,oneof=hr manager"`
+}
+
+type GetCompanyMemberParams struct {
+	ID     string `uri:"id" binding:"required"`
+	UserId string `uri:"userId" binding:"required"`
+}
+
+type CompanyMemberResponse struct {
+	ID        string     `json:"id"`
+	CompanyId string     `json:"company_id"`
+	UserId    string     `json:"user_id"`
+	FullName  string     `json:"full_name"`
+	Email     string     `json:"email"`
+	Role      string     `json:"role"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+const (
+	ContractStatusActive     = "active"
+	ContractStatusRenewed    = "renewed"
+	ContractStatusTerminated = "terminated"
+	ContractStatusEnded      = "ended"
+)
+
+type CreateContractBody struct {
+	EmployeeId    string     `json:"employee_id" binding:"required"`
+	Type          string     `json:"type" binding:"required,oneof=indefinite fixed_term work_or_labor apprenticeship service"`
+	StartDate     time.Time  `json:"start_date" binding:"required"`
+	EndDate       *time.Time `json:"end_date"`
+	Salary        float64    `json:"salary" binding:"required,gt=0"`
+	WeeklyHours   float64    `json:"weekly_hours" binding:"required,gt=0,lte=60"`
+	ProbationDays int        `json:"probation_days" binding:"gte=0"`
+	Notes         *string    `json:"notes"`
+}
+
+// RenewContractBody sets the terms of the renewal. Without an end date the
+// contract renews for the same term, and without a salary it keeps the
+// current one.
+type RenewContractBody struct {
+	EndDate *time.Time `json:"end_date"`
+	Salary  *float64   `json:"salary" binding:"omitempty,gt=0"`
+}
+
+type TerminateContractBody struct {
+	Date   time.Time `json:"date" binding:"required"`
+	Reason string    `json:"reason" binding:"required"`
+}
+
+type GetContractParams struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+type ContractResponse struct {
+	ID                 string     `json:"id"`
+	CompanyId          string     `json:"company_id"`
+	EmployeeId         string     `json:"employee_id"`
+	Type               string     `json:"type"`
+	StartDate          time.Time  `json:"start_date"`
+	EndDate            *time.Time `json:"end_date"`
+	Salary             float64    `json:"salary"`
+	WeeklyHours        float64    `json:"weekly_hours"`
+	ProbationDays      int        `json:"probation_days"`
+	ProbationEnd       *time.Time `json:"probation_end"`
+	NoticeDeadline     *time.Time `json:"notice_deadline"`
+	Status             string     `json:"status"`
+	PreviousContractId *string    `json:"previous_contract_id"`
+	RenewalNumber      int        `json:"renewal_number"`
+	Notes              *string    `json:"notes"`
+	TerminatedAt       *time.Time `json:"terminated_at"`
+	TerminationReason  *string    `json:"termination_reason"`
+	CreatedBy          string     `json:"created_by"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          *time.Time `json:"updated_at"`
+}
+
+type CreateContractTemplateBody struct {
+	CompanyId    string  `json:"company_id" binding:"required"`
+	Name         string  `json:"name" binding:"required"`
+	ContractType *string `json:"contract_type" binding:"omitempty,oneof=indefinite fixed_term work_or_labor apprenticeship service"`
+	Body         string  `json:"body" binding:"required"`
+}
+
+type GetContractTemplateParams struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+type ContractTemplateResponse struct {
+	ID           string     `json:"id"`
+	CompanyId    string     `json:"company_id"`
+	Name         string     `json:"name"`
+	ContractType *string    `json:"contract_type"`
+	Body         string     `json:"body"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    *time.Time `json:"updated_at"`
+}
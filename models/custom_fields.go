@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+type CreateCustomFieldBody struct {
+	CompanyId string   `json:"company_id" binding:"required"`
+	Entity    string   `json:"entity" binding:"required,oneof=employee department position"`
+	Key       string   `json:"key" binding:"required"`
+	Label     string   `json:"label" binding:"required"`
+	Type      string   `json:"type" binding:"required,oneof=text number boolean date enum"`
+	Required  bool     `json:"required"`
+	Options   []string `json:"options"`
+	Pattern   *string  `json:"pattern"`
+	Position  int      `json:"position"`
+}
+
+// UpdateCustomFieldBody leaves out the key, entity and type, which the
+// stored values depend on.
+type UpdateCustomFieldBody struct {
+	Label    string   `json:"label" binding:"required"`
+	Required bool     `json:"required"`
+	Options  []string `json:"options"`
+	Pattern  *string  `json:"pattern"`
+	Position int      `json:"position"`
+}
+
+type GetCustomFieldParams struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+type CustomFieldResponse struct {
+	ID        string     `json:"id"`
+	CompanyId string     `json:"company_id"`
+	Entity    string     `json:"entity"`
+	Key       string     `json:"key"`
+	Label     string     `json:"label"`
+	Type      string     `json:"type"`
+	Required  bool       `json:"required"`
+	Options   []string   `json:"options"`
+	Pattern   *string    `json:"pattern"`
+	Position  int        `json:"position"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
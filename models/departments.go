@@ -3,20 +3,25 @@ package models
 import "time"
 
 type CreateDepartmentBody struct {
-	Name      string `json:"name" binding:"required"`
-	CompanyId string `json:"company_id" binding:"required"`
+	Name         string         `json:"name" binding:"required"`
+	CompanyId    string         `json:"company_id" binding:"required"`
+	ManagerId    *string        `json:"manager_id"`
+	CustomFields map[string]any `json:"custom_fields"`
 }
 
 type DepartmentsResponse struct {
-	ID        string
-	Name      string
-	CompanyId string
-	CreatedAt time.Time
-	UpdatedAt *time.Time
+	ID           string
+	Name         string
+	CompanyId    string
+	ManagerId    *string
+	CustomFields map[string]any
+	Version      int
+	CreatedAt    time.Time
+	UpdatedAt    *time.Time
 }
 
 type GetCompanyDepartmentsParams struct {
-	CompanyId string `uri:"companyId" binding:"required"`
+	CompanyId string `uri:"id" binding:"required"`
 }
 
 type GetDepartmentsParams struct {
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+const (
+	DocumentCategoryContract       = "contract"
+	DocumentCategoryIdentification = "identification"
+	DocumentCategoryCertificate    = "certificate"
+	DocumentCategoryMedical        = "medical"
+	DocumentCategoryOther          = "other"
+)
+
+// UploadDocumentBody holds the form fields sent with the file
+type UploadDocumentBody struct {
+	Category  string `form:"category" binding:"required,oneof=contract identification certificate medical other"`
+	Name      string `form:"name"`
+	ExpiresAt string `form:"expires_at"`
+}
+
+type GetEmployeeDocumentParams struct {
+	ID         string `uri:"id" binding:"required"`
+	DocumentId string `uri:"documentId" binding:"required"`
+}
+
+type DocumentResponse struct {
+	ID          string     `json:"id"`
+	CompanyId   string     `json:"company_id"`
+	EmployeeId  string     `json:"employee_id"`
+	Category    string     `json:"category"`
+	Name        string     `json:"name"`
+	ContentType string     `json:"content_type"`
+	Size        int64      `json:"size"`
+	Sha256      string     `json:"sha256"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	UploadedBy  string     `json:"uploaded_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
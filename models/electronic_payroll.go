@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+const (
+	ElectronicPayrollStatusGenerated = "generated"
+	ElectronicPayrollStatusSubmitted = "submitted"
+	ElectronicPayrollStatusFailed    = "failed"
+)
+
+type GetElectronicPayrollParams struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+type ElectronicPayrollResponse struct {
+	ID           string     `json:"id"`
+	CompanyId    string     `json:"company_id"`
+	EmployeeId   string     `json:"employee_id"`
+	PayrollRunId string     `json:"payroll_run_id"`
+	PeriodStart  time.Time  `json:"period_start"`
+	PeriodEnd    time.Time  `json:"period_end"`
+	Number       string     `json:"number"`
+	Cune         string     `json:"cune"`
+	Status       string     `json:"status"`
+	SubmittedAt  *time.Time `json:"submitted_at"`
+	Error        *string    `json:"error"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    *time.Time `json:"updated_at"`
+}
@@ -1,20 +1,24 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type CreateEmployeeBody struct {
-	Name          string    `json:"name" binding:"required"`
-	LastName      string    `json:"last_name" binding:"required"`
-	PhoneNumber   string    `json:"phone_number" binding:"required"`
-	Email         string    `json:"email" binding:"required"`
-	IdType        string    `json:"id_type" binding:"required"`
-	IdNumber      string    `json:"id_number" binding:"required"`
-	AdmissionDate time.Time `json:"admission_date" binding:"required"`
-	Salary        float64   `json:"salary" binding:"required"`
-	PositionId    string    `json:"position_id" binding:"required"`
-	DepartmentId  string    `json:"department_id" binding:"required"`
-	CompanyId     string    `json:"company_id" binding:"required"`
-	PictureUrl    *string   `json:"picture_url"`
+	Name          string         `json:"name" binding:"required"`
+	LastName      string         `json:"last_name" binding:"required"`
+	PhoneNumber   string         `json:"phone_number" binding:"required"`
+	Email         string         `json:"email" binding:"required"`
+	IdType        string         `json:"id_type" binding:"required"`
+	IdNumber      string         `json:"id_number" binding:"required"`
+	AdmissionDate time.Time      `json:"admission_date" binding:"required"`
+	Salary        float64        `json:"salary" binding:"required"`
+	PositionId    string         `json:"position_id" binding:"required"`
+	DepartmentId  string         `json:"department_id" binding:"required"`
+	CompanyId     string         `json:"company_id" binding:"required"`
+	PictureUrl    *string        `json:"picture_url"`
+	CustomFields  map[string]any `json:"custom_fields"`
 }
 
 type EmployeeResponse struct {
@@ -31,6 +35,8 @@ type EmployeeResponse struct {
 	DepartmentId  string
 	CompanyId     string
 	PictureUrl    *string
+	CustomFields  map[string]any
+	Version       int
 	CreatedAt     time.Time
 	UpdatedAt     *time.Time
 }
@@ -42,3 +48,68 @@ type GetEmployeeParams struct {
 type GetCompanyEmployeesParams struct {
 	CompanyId string `uri:"companyId" binding:"required"`
 }
+
+type GetEmployeePictureParams struct {
+	ID      string `uri:"id" binding:"required,uuid"`
+	Version string `uri:"version" binding:"required,hexadecimal,len=16"`
+	Size    int    `uri:"size" binding:"required"`
+}
+
+type EmployeePictureResponse struct {
+	PictureUrl string         `json:"picture_url"`
+	Thumbnails map[int]string `json:"thumbnails"`
+}
+
+const (
+	BulkModeTransactional = "transactional"
+	BulkModeBestEffort    = "best_effort"
+)
+
+const (
+	BulkOperationUpdate    = "update"
+	BulkOperationTransfer  = "transfer"
+	BulkOperationTerminate = "terminate"
+)
+
+// Results of the operations of a bulk request. Operations of a failed
+// transactional request that were valid are not_applied.
+const (
+	BulkResultApplied         = "applied"
+	BulkResultPendingApproval = "pending_approval"
+	BulkResultFailed          = "failed"
+	BulkResultNotApplied      = "not_applied"
+)
+
+// BulkEmployeesBody applies the operations to employees of the company, in
+// order. Transactional requests apply all of them or none; best effort
+// requests apply every operation they can.
+type BulkEmployeesBody struct {
+	CompanyId  string                  `json:"company_id" binding:"required"`
+	Mode       string                  `json:"mode" binding:"required,oneof=transactional best_effort"`
+	Operations []BulkEmployeeOperation `json:"operations" binding:"required,min=1,max=500"`
+}
+
+// BulkEmployeeOperation is an update, with Changes as the merge patch of
+// PATCH /employees/:id; a transfer to a department, a position or both; or
+// a termination, through the termination chain of the company. Version,
+// when sent, is the If-Match of the employee.
+type BulkEmployeeOperation struct {
+	Type         string          `json:"type"`
+	EmployeeId   string          `json:"employee_id"`
+	Version      int             `json:"version"`
+	Changes      json.RawMessage `json:"changes"`
+	DepartmentId *string         `json:"department_id"`
+	PositionId   *string         `json:"position_id"`
+	Date         *time.Time      `json:"date"`
+	Reason       string          `json:"reason"`
+}
+
+type BulkEmployeeResult struct {
+	Index      int               `json:"index"`
+	Type       string            `json:"type"`
+	EmployeeId string            `json:"employee_id"`
+	Status     string            `json:"status"`
+	Error      *string           `json:"error,omitempty"`
+	Employee   *EmployeeResponse `json:"employee,omitempty"`
+	Approval   *ApprovalResponse `json:"approval,omitempty"`
+}
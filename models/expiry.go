@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+const (
+	ExpiringKindDocument = "document"
+	ExpiringKindContract = "contract"
+)
+
+// ExpiringItemResponse is a document or contract close to its expiry date.
+// NoticeBy is set for fixed-term contracts, which need notice before they
+// end; the days left count until it instead of the expiry date.
+type ExpiringItemResponse struct {
+	Kind         string     `json:"kind"`
+	ID           string     `json:"id"`
+	CompanyId    string     `json:"company_id"`
+	EmployeeId   string     `json:"employee_id"`
+	EmployeeName string     `json:"employee_name"`
+	DepartmentId string     `json:"department_id"`
+	Category     string     `json:"category"`
+	Name         string     `json:"name"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	NoticeBy     *time.Time `json:"notice_by"`
+	DaysLeft     int        `json:"days_left"`
+}
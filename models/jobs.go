@@ -0,0 +1,30 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type GetJobParams struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+// JobResponse is the status of a background job. Result is set by the jobs
+// that succeeded, with what each kind makes.
+type JobResponse struct {
+	ID              string          `json:"id"`
+	Kind            string          `json:"kind"`
+	Status          string          `json:"status"`
+	Attempts        int             `json:"attempts"`
+	MaxAttempts     int             `json:"max_attempts"`
+	RunAt           time.Time       `json:"run_at"`
+	CompanyId       *string         `json:"company_id"`
+	CreatedBy       *string         `json:"created_by"`
+	Result          json.RawMessage `json:"result"`
+	LastError       *string         `json:"last_error"`
+	CancelRequested bool            `json:"cancel_requested"`
+	StartedAt       *time.Time      `json:"started_at"`
+	FinishedAt      *time.Time      `json:"finished_at"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       *time.Time      `json:"updated_at"`
+}
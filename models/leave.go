@@ -0,0 +1,104 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gioCuesta25/employees-manager-backend/leave"
+)
+
+const (
+	LeaveCategoryVacation  = "vacation"
+	LeaveCategorySick      = "sick"
+	LeaveCategoryMaternity = "maternity"
+	LeaveCategoryPaternity = "paternity"
+	LeaveCategoryUnpaid    = "unpaid"
+	LeaveCategoryOther     = "other"
+)
+
+const (
+	LeaveStatusPending   = "pending"
+	LeaveStatusApproved  = "approved"
+	LeaveStatusRejected  = "rejected"
+	LeaveStatusCancelled = "cancelled"
+	LeaveStatusSkipped   = "skipped"
+)
+
+type CreateLeaveTypeBody struct {
+	CompanyId     string   `json:"company_id" binding:"required"`
+	Name          string   `json:"name" binding:"required"`
+	Category      string   `json:"category" binding:"required,oneof=vacation sick maternity paternity unpaid other"`
+	Paid          *bool    `json:"paid"`
+	DaysPerYear   *float64 `json:"days_per_year" binding:"omitempty,gte=0"`
+	MaxCarryOver  *float64 `json:"max_carry_over" binding:"omitempty,gte=0"`
+	ApprovalChain []string `json:"approval_chain" binding:"omitempty,dive,oneof=manager hr owner"`
+}
+
+type CreateDefaultLeaveTypesBody struct {
+	CompanyId string `json:"company_id" binding:"required"`
+}
+
+type GetLeaveTypeParams struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+type LeaveTypeResponse struct {
+	ID            string     `json:"id"`
+	CompanyId     string     `json:"company_id"`
+	Name          string     `json:"name"`
+	Category      string     `json:"category"`
+	Paid          bool       `json:"paid"`
+	DaysPerYear   float64    `json:"days_per_year"`
+	MaxCarryOver  *float64   `json:"max_carry_over"`
+	ApprovalChain []string   `json:"approval_chain"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     *time.Time `json:"updated_at"`
+}
+
+type CreateLeaveRequestBody struct {
+	EmployeeId  string    `json:"employee_id" binding:"required"`
+	LeaveTypeId string    `json:"leave_type_id" binding:"required"`
+	StartDate   time.Time `json:"start_date" binding:"required"`
+	EndDate     time.Time `json:"end_date" binding:"required"`
+	Reason      *string   `json:"reason"`
+}
+
+type DecideLeaveRequestBody struct {
+	Comment *string `json:"comment"`
+}
+
+type GetLeaveRequestParams struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+type LeaveRequestResponse struct {
+	ID          string                   `json:"id"`
+	CompanyId   string                   `json:"company_id"`
+	EmployeeId  string                   `json:"employee_id"`
+	LeaveTypeId string                   `json:"leave_type_id"`
+	StartDate   time.Time                `json:"start_date"`
+	EndDate     time.Time                `json:"end_date"`
+	Days        float64                  `json:"days"`
+	Reason      *string                  `json:"reason"`
+	Status      string                   `json:"status"`
+	CurrentStep int                      `json:"current_step"`
+	RequestedBy string                   `json:"requested_by"`
+	Approvals   []*LeaveApprovalResponse `json:"approvals,omitempty"`
+	CreatedAt   time.Time                `json:"created_at"`
+	UpdatedAt   *time.Time               `json:"updated_at"`
+}
+
+type LeaveApprovalResponse struct {
+	Step      int        `json:"step"`
+	Role      string     `json:"role"`
+	Status    string     `json:"status"`
+	DecidedBy *string    `json:"decided_by"`
+	DecidedAt *time.Time `json:"decided_at"`
+	Comment   *string    `json:"comment"`
+}
+
+type LeaveBalanceResponse struct {
+	LeaveTypeId string `json:"leave_type_id"`
+	Name        string `json:"name"`
+	Category    string `json:"category"`
+	leave.Balance
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	NotificationKindDocumentExpiry = "document_expiry"
+	NotificationKindContractExpiry = "contract_expiry"
+
+	NotificationKindApprovalRequested = "approval_requested"
+	NotificationKindApprovalEscalated = "approval_escalated"
+
+	NotificationKindLeaveRequestDecided = "leave_request_decided"
+)
+
+// NotificationKinds users choose to get by email or not
+var NotificationKinds = []string{
+	NotificationKindDocumentExpiry,
+	NotificationKindContractExpiry,
+	NotificationKindApprovalRequested,
+	NotificationKindApprovalEscalated,
+	NotificationKindLeaveRequestDecided,
+}
+
+// Notification is a message for a user. DedupKey makes creating it
+// idempotent: a user never gets two notifications with the same key.
+type Notification struct {
+	UserId    string
+	CompanyId *string
+	Kind      string
+	Title     string
+	Body      string
+	Link      *string
+	Data      map[string]any
+	DedupKey  *string
+}
+
+// Email is an email waiting in the outbox. Data is what its template is
+// rendered with; a "link" in it relative to the web application is made
+// absolute.
+type Email struct {
+	UserId   *string
+	To       string
+	Template string
+	Language string
+	Data     map[string]any
+}
+
+// NotificationPreferencesResponse has for each notification kind whether
+// the user gets it by email
+type NotificationPreferencesResponse struct {
+	Language string          `json:"language"`
+	Email    map[string]bool `json:"email"`
+}
+
+type UpdateNotificationPreferencesBody struct {
+	Language *string         `json:"language" binding:"omitempty,oneof=es en"`
+	Email    map[string]bool `json:"email"`
+}
+
+type GetNotificationParams struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+type NotificationResponse struct {
+	ID        string          `json:"id"`
+	CompanyId *string         `json:"company_id"`
+	Kind      string          `json:"kind"`
+	Title     string          `json:"title"`
+	Body      string          `json:"body"`
+	Link      *string         `json:"link"`
+	Data      json.RawMessage `json:"data"`
+	ReadAt    *time.Time      `json:"read_at"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// NotificationsPage is a page of the notifications of the user with how
+// many of all of them are unread
+type NotificationsPage struct {
+	PaginatedResult
+	UnreadCount int `json:"unreadCount"`
+}
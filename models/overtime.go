@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+type OvertimeSettingsBody struct {
+	NightStart        string  `json:"night_start" binding:"required"`
+	NightEnd          string  `json:"night_end" binding:"required"`
+	WeeklyLimitHours  float64 `json:"weekly_limit_hours" binding:"required,gt=0,lte=168"`
+	NightRate         float64 `json:"night_rate" binding:"gte=0"`
+	HolidayRate       float64 `json:"holiday_rate" binding:"gte=0"`
+	OvertimeDayRate   float64 `json:"overtime_day_rate" binding:"gte=0"`
+	OvertimeNightRate float64 `json:"overtime_night_rate" binding:"gte=0"`
+}
+
+type OvertimeSettingsResponse struct {
+	CompanyId         string  `json:"company_id"`
+	NightStart        string  `json:"night_start"`
+	NightEnd          string  `json:"night_end"`
+	WeeklyLimitHours  float64 `json:"weekly_limit_hours"`
+	NightRate         float64 `json:"night_rate"`
+	HolidayRate       float64 `json:"holiday_rate"`
+	OvertimeDayRate   float64 `json:"overtime_day_rate"`
+	OvertimeNightRate float64 `json:"overtime_night_rate"`
+}
+
+type SurchargeBucketResponse struct {
+	Bucket  string  `json:"bucket"`
+	Label   string  `json:"label"`
+	Minutes int     `json:"minutes"`
+	Hours   float64 `json:"hours"`
+	Rate    float64 `json:"rate"`
+	Amount  float64 `json:"amount"`
+}
+
+type DaySurchargesResponse struct {
+	Date    time.Time      `json:"date"`
+	Minutes map[string]int `json:"minutes"`
+}
+
+type EmployeeSurchargesResponse struct {
+	EmployeeId  string                    `json:"employee_id"`
+	From        time.Time                 `json:"from"`
+	To          time.Time                 `json:"to"`
+	HourlyRate  float64                   `json:"hourly_rate"`
+	Days        []DaySurchargesResponse   `json:"days,omitempty"`
+	Buckets     []SurchargeBucketResponse `json:"buckets"`
+	TotalAmount float64                   `json:"total_amount"`
+}
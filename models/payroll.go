@@ -0,0 +1,99 @@
+package models
+
+import "time"
+
+const (
+	PayrollStatusDraft    = "draft"
+	PayrollStatusReview   = "review"
+	PayrollStatusApproved = "approved"
+	PayrollStatusClosed   = "closed"
+)
+
+const (
+	AdjustmentKindBonus     = "bonus"
+	AdjustmentKindDeduction = "deduction"
+)
+
+type CreatePayrollRunBody struct {
+	CompanyId   string    `json:"company_id" binding:"required"`
+	PeriodStart time.Time `json:"period_start" binding:"required"`
+	PeriodEnd   time.Time `json:"period_end" binding:"required"`
+}
+
+type GetPayrollRunParams struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+type GetPayslipParams struct {
+	ID         string `uri:"id" binding:"required"`
+	EmployeeId string `uri:"employeeId" binding:"required"`
+}
+
+type DeletePayrollAdjustmentParams struct {
+	ID           string `uri:"id" binding:"required"`
+	AdjustmentId string `uri:"adjustmentId" binding:"required"`
+}
+
+type CreatePayrollAdjustmentBody struct {
+	EmployeeId string  `json:"employee_id" binding:"required"`
+	Kind       string  `json:"kind" binding:"required,oneof=bonus deduction"`
+	Concept    string  `json:"concept" binding:"required"`
+	Amount     float64 `json:"amount" binding:"required,gt=0"`
+}
+
+type PayrollRunResponse struct {
+	ID          string     `json:"id"`
+	CompanyId   string     `json:"company_id"`
+	PeriodStart time.Time  `json:"period_start"`
+	PeriodEnd   time.Time  `json:"period_end"`
+	Status      string     `json:"status"`
+	CreatedBy   string     `json:"created_by"`
+	ApprovedBy  *string    `json:"approved_by"`
+	ApprovedAt  *time.Time `json:"approved_at"`
+	ClosedAt    *time.Time `json:"closed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at"`
+}
+
+// EmployeeSnapshot is the copy of the employee data a payroll run was
+// calculated with. It is stored as JSON so later edits to the employee
+// don't change the results of a run that is already in review.
+type EmployeeSnapshot struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	LastName      string    `json:"last_name"`
+	Email         string    `json:"email"`
+	IdType        string    `json:"id_type"`
+	IdNumber      string    `json:"id_number"`
+	AdmissionDate time.Time `json:"admission_date"`
+	// The last day of the employee, when it falls in the period
+	TerminationDate *time.Time `json:"termination_date,omitempty"`
+	Salary          float64    `json:"salary"`
+	PositionId      string     `json:"position_id"`
+	DepartmentId    string     `json:"department_id"`
+}
+
+type PayrollRunItemResponse struct {
+	ID                  string           `json:"id"`
+	PayrollRunId        string           `json:"payroll_run_id"`
+	EmployeeId          string           `json:"employee_id"`
+	Snapshot            EmployeeSnapshot `json:"snapshot"`
+	WorkedDays          int              `json:"worked_days"`
+	BasePay             float64          `json:"base_pay"`
+	Bonuses             float64          `json:"bonuses"`
+	Deductions          float64          `json:"deductions"`
+	HealthContribution  float64          `json:"health_contribution"`
+	PensionContribution float64          `json:"pension_contribution"`
+	NetPay              float64          `json:"net_pay"`
+}
+
+type PayrollAdjustmentResponse struct {
+	ID           string    `json:"id"`
+	PayrollRunId string    `json:"payroll_run_id"`
+	EmployeeId   string    `json:"employee_id"`
+	Kind         string    `json:"kind"`
+	Concept      string    `json:"concept"`
+	Amount       float64   `json:"amount"`
+	CreatedBy    string    `json:"created_by"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -3,15 +3,18 @@ package models
 import "time"
 
 type CreatePositionBody struct {
-	Name         string `json:"name" binding:"required"`
-	CompanyId    string `json:"company_id" binding:"required"`
-	DepartmentId string `json:"department_id" binding:"required"`
+	Name         string         `json:"name" binding:"required"`
+	CompanyId    string         `json:"company_id" binding:"required"`
+	DepartmentId string         `json:"department_id" binding:"required"`
+	CustomFields map[string]any `json:"custom_fields"`
 }
 
 type PositionResponse struct {
 	ID           string
 	Name         string
 	CompanyId    string
+	CustomFields map[string]any
+	Version      int
 	CreatedAt    time.Time
 	DepartmentId string
 	UpdatedAt    *time.Time
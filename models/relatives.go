@@ -0,0 +1,96 @@
+package models
+
+import "time"
+
+type CreateEmergencyContactBody struct {
+	Name                 string  `json:"name" binding:"required"`
+	Relationship         string  `json:"relationship" binding:"required,oneof=spouse partner child stepchild parent sibling grandparent friend other"`
+	PhoneNumber          string  `json:"phone_number" binding:"required,min=7,max=20"`
+	AlternatePhoneNumber *string `json:"alternate_phone_number" binding:"omitempty,min=7,max=20"`
+	Email                *string `json:"email" binding:"omitempty,email"`
+	IsPrimary            bool    `json:"is_primary"`
+}
+
+type GetEmergencyContactParams struct {
+	ID        string `uri:"id" binding:"required"`
+	ContactId string `uri:"contactId" binding:"required"`
+}
+
+type EmergencyContactResponse struct {
+	ID                   string     `json:"id"`
+	EmployeeId           string     `json:"employee_id"`
+	Name                 string     `json:"name"`
+	Relationship         string     `json:"relationship"`
+	PhoneNumber          string     `json:"phone_number"`
+	AlternatePhoneNumber *string    `json:"alternate_phone_number"`
+	Email                *string    `json:"email"`
+	IsPrimary            bool       `json:"is_primary"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            *time.Time `json:"updated_at"`
+}
+
+// CreateDependentBody registers a relative affiliated by the employee to the
+// health system (EPS) or to the compensation fund.
+type CreateDependentBody struct {
+	Name                   string    `json:"name" binding:"required"`
+	LastName               string    `json:"last_name" binding:"required"`
+	Relationship           string    `json:"relationship" binding:"required,oneof=spouse partner child stepchild parent sibling"`
+	IdType                 *string   `json:"id_type" binding:"required_with=IdNumber"`
+	IdNumber               *string   `json:"id_number" binding:"required_with=IdType"`
+	BirthDate              time.Time `json:"birth_date" binding:"required"`
+	Disabled               bool      `json:"disabled"`
+	HealthAffiliated       bool      `json:"health_affiliated"`
+	CompensationAffiliated bool      `json:"compensation_affiliated"`
+}
+
+type GetDependentParams struct {
+	ID          string `uri:"id" binding:"required"`
+	DependentId string `uri:"dependentId" binding:"required"`
+}
+
+type DependentResponse struct {
+	ID                     string     `json:"id"`
+	EmployeeId             string     `json:"employee_id"`
+	Name                   string     `json:"name"`
+	LastName               string     `json:"last_name"`
+	Relationship           string     `json:"relationship"`
+	IdType                 *string    `json:"id_type"`
+	IdNumber               *string    `json:"id_number"`
+	BirthDate              time.Time  `json:"birth_date"`
+	Age                    int        `json:"age"`
+	Disabled               bool       `json:"disabled"`
+	HealthAffiliated       bool       `json:"health_affiliated"`
+	CompensationAffiliated bool       `json:"compensation_affiliated"`
+	CreatedAt              time.Time  `json:"created_at"`
+	UpdatedAt              *time.Time `json:"updated_at"`
+}
+
+// CreateBeneficiaryBody names who receives the benefits owed to the employee
+// on their death. The percentages of an employee can't add up to more than
+// 100.
+type CreateBeneficiaryBody struct {
+	Name         string  `json:"name" binding:"required"`
+	LastName     string  `json:"last_name" binding:"required"`
+	Relationship string  `json:"relationship" binding:"required,oneof=spouse partner child stepchild parent sibling grandparent friend other"`
+	IdType       *string `json:"id_type" binding:"required_with=IdNumber"`
+	IdNumber     *string `json:"id_number" binding:"required_with=IdType"`
+	Percentage   float64 `json:"percentage" binding:"required,gt=0,lte=100"`
+}
+
+type GetBeneficiaryParams struct {
+	ID            string `uri:"id" binding:"required"`
+	BeneficiaryId string `uri:"beneficiaryId" binding:"required"`
+}
+
+type BeneficiaryResponse struct {
+	ID           string     `json:"id"`
+	EmployeeId   string     `json:"employee_id"`
+	Name         string     `json:"name"`
+	LastName     string     `json:"last_name"`
+	Relationship string     `json:"relationship"`
+	IdType       *string    `json:"id_type"`
+	IdNumber     *string    `json:"id_number"`
+	Percentage   float64    `json:"percentage"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    *time.Time `json:"updated_at"`
+}
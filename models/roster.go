@@ -0,0 +1,87 @@
+package models
+
+import "time"
+
+type CreateShiftTemplateBody struct {
+	CompanyId    string `json:"company_id" binding:"required"`
+	Name         string `json:"name" binding:"required"`
+	StartTime    string `json:"start_time" binding:"required"`
+	EndTime      string `json:"end_time" binding:"required"`
+	BreakMinutes int    `json:"break_minutes" binding:"gte=0,lte=240"`
+}
+
+type GetShiftTemplateParams struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+type ShiftTemplateResponse struct {
+	ID           string     `json:"id"`
+	CompanyId    string     `json:"company_id"`
+	Name         string     `json:"name"`
+	StartTime    string     `json:"start_time"`
+	EndTime      string     `json:"end_time"`
+	BreakMinutes int        `json:"break_minutes"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    *time.Time `json:"updated_at"`
+}
+
+type RosterSettingsBody struct {
+	MinRestHours   float64 `json:"min_rest_hours" binding:"gte=0,lte=48"`
+	MaxWeeklyHours float64 `json:"max_weekly_hours" binding:"required,gt=0,lte=168"`
+}
+
+type RosterSettingsResponse struct {
+	CompanyId      string  `json:"company_id"`
+	MinRestHours   float64 `json:"min_rest_hours"`
+	MaxWeeklyHours float64 `json:"max_weekly_hours"`
+}
+
+// AssignShiftsBody assigns a template to the employees on every day of the
+// range. Weekdays limits the days, 0 being Sunday, and defaults to all of
+// them.
+type AssignShiftsBody struct {
+	DepartmentId    string    `json:"department_id" binding:"required"`
+	ShiftTemplateId string    `json:"shift_template_id" binding:"required"`
+	EmployeeIds     []string  `json:"employee_ids" binding:"required,min=1"`
+	From            time.Time `json:"from" binding:"required"`
+	To              time.Time `json:"to" binding:"required"`
+	Weekdays        []int     `json:"weekdays" binding:"omitempty,dive,gte=0,lte=6"`
+}
+
+// CopyRosterWeekBody copies the shifts of the week that starts on Week to
+// the following weeks. Replace removes the shifts those weeks already have.
+type CopyRosterWeekBody struct {
+	DepartmentId string    `json:"department_id" binding:"required"`
+	Week         time.Time `json:"week" binding:"required"`
+	Weeks        int       `json:"weeks" binding:"omitempty,gte=1,lte=12"`
+	Replace      bool      `json:"replace"`
+}
+
+type GetShiftParams struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+type GetShiftFeedParams struct {
+	Token string `uri:"token" binding:"required"`
+}
+
+type ShiftResponse struct {
+	ID              string    `json:"id"`
+	CompanyId       string    `json:"company_id"`
+	DepartmentId    string    `json:"department_id"`
+	EmployeeId      string    `json:"employee_id"`
+	ShiftTemplateId *string   `json:"shift_template_id"`
+	Name            string    `json:"name"`
+	Date            time.Time `json:"date"`
+	StartsAt        time.Time `json:"starts_at"`
+	EndsAt          time.Time `json:"ends_at"`
+	BreakMinutes    int       `json:"break_minutes"`
+	CreatedBy       string    `json:"created_by"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+type ShiftFeedResponse struct {
+	EmployeeId string `json:"employee_id"`
+	Token      string `json:"token"`
+	Url        string `json:"url"`
+}
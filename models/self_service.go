@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+const (
+	ProfileChangeStatusPending   = "pending"
+	ProfileChangeStatusApproved  = "approved"
+	ProfileChangeStatusRejected  = "rejected"
+	ProfileChangeStatusCancelled = "cancelled"
+)
+
+// CreateEmployeeInvitationBody can send the invitation to another address
+// than the email of the employee record.
+type CreateEmployeeInvitationBody struct {
+	Email *string `json:"email" binding:"omitempty,email"`
+	// Language of the invitation email, Spanish by default
+	Language *string `json:"language" binding:"omitempty,oneof=es en"`
+}
+
+type EmployeeInvitationResponse struct {
+	ID         string     `json:"id"`
+	EmployeeId string     `json:"employee_id"`
+	Email      string     `json:"email"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// AcceptInvitationBody creates the account of the employee. When a user
+// with the invitation email already exists, the password must be theirs.
+type AcceptInvitationBody struct {
+	Token    string `json:"token" binding:"required"`
+	FullName string `json:"full_name"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+type CreateProfileChangeRequestBody struct {
+	PhoneNumber *string `json:"phone_number" binding:"omitempty,min=7,max=20"`
+	Email       *string `json:"email" binding:"omitempty,email"`
+}
+
+type DecideProfileChangeRequestBody struct {
+	Comment *string `json:"comment"`
+}
+
+type GetProfileChangeRequestParams struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+type ProfileChangeRequestResponse struct {
+	ID          string            `json:"id"`
+	CompanyId   string            `json:"company_id"`
+	EmployeeId  string            `json:"employee_id"`
+	Changes     map[string]string `json:"changes"`
+	Status      string            `json:"status"`
+	Comment     *string           `json:"comment"`
+	RequestedBy string            `json:"requested_by"`
+	DecidedBy   *string           `json:"decided_by"`
+	DecidedAt   *time.Time        `json:"decided_at"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+type GetMyPayslipParams struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+type MyPayslipResponse struct {
+	PayrollRunId string    `json:"payroll_run_id"`
+	PeriodStart  time.Time `json:"period_start"`
+	PeriodEnd    time.Time `json:"period_end"`
+	Status       string    `json:"status"`
+	NetPay       float64   `json:"net_pay"`
+}
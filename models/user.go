@@ -4,6 +4,8 @@ type CreateUserRequest struct {
 	FullName string `json:"full_name" binding:"required"`
 	Email    string `json:"email" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	// Language of the emails of the user, Spanish by default
+	Language *string `json:"language" binding:"omitempty,oneof=es en"`
 }
 
 type CompleteUserResponse struct {
@@ -14,8 +16,9 @@ type CompleteUserResponse struct {
 }
 
 type CreateUserResponse struct {
-	FullName string `json:"full_name"`
-	Email    string `json:"email"`
+	FullName      string `json:"full_name"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
 }
 
 type ListUsersParams struct {
@@ -37,3 +40,16 @@ type LoginBody struct {
 	Email    string `json:"email" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
+
+type VerifyEmailBody struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ForgotPasswordBody struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordBody struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// CreateWebhookBody subscribes the URL to the events of the company. Events
+// take the event names, <entity>.* and *.
+type CreateWebhookBody struct {
+	CompanyId   string   `json:"company_id" binding:"required"`
+	URL         string   `json:"url" binding:"required"`
+	Events      []string `json:"events" binding:"required"`
+	Description *string  `json:"description"`
+}
+
+type UpdateWebhookBody struct {
+	URL         string   `json:"url" binding:"required"`
+	Events      []string `json:"events" binding:"required"`
+	Description *string  `json:"description"`
+	Active      bool     `json:"active"`
+}
+
+type GetWebhookParams struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+type GetWebhookDeliveryParams struct {
+	ID         string `uri:"id" binding:"required"`
+	DeliveryId string `uri:"deliveryId" binding:"required"`
+}
+
+// WebhookResponse leaves out the secret, which is only returned when the
+// subscription is created or the secret rotated
+type WebhookResponse struct {
+	ID          string     `json:"id"`
+	CompanyId   string     `json:"company_id"`
+	URL         string     `json:"url"`
+	Events      []string   `json:"events"`
+	Description *string    `json:"description"`
+	Active      bool       `json:"active"`
+	CreatedBy   *string    `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID             string     `json:"id"`
+	SubscriptionId string     `json:"subscription_id"`
+	EventId        int64      `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	ResponseStatus *int       `json:"response_status"`
+	LastError      *string    `json:"last_error"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      *time.Time `json:"updated_at"`
+}
+
+// WebhookEvent is the body of a delivery
+type WebhookEvent struct {
+	ID        int64          `json:"id"`
+	Type      string         `json:"type"`
+	CompanyId string         `json:"company_id"`
+	CreatedAt time.Time      `json:"created_at"`
+	Data      map[string]any `json:"data"`
+	Previous  map[string]any `json:"previous,omitempty"`
+}
@@ -0,0 +1,146 @@
+package nomina
+
+import "encoding/xml"
+
+// NominaIndividual mirrors the elements of the DIAN NominaIndividual schema
+// that apply to an ordinary employee settlement.
+type NominaIndividual struct {
+	XMLName            xml.Name           `xml:"NominaIndividual"`
+	Xmlns              string             `xml:"xmlns,attr"`
+	Novedad            Novedad            `xml:"Novedad"`
+	Periodo            Periodo            `xml:"Periodo"`
+	NumeroSecuenciaXML NumeroSecuenciaXML `xml:"NumeroSecuenciaXML"`
+	LugarGeneracionXML Lugar              `xml:"LugarGeneracionXML"`
+	ProveedorXML       ProveedorXML       `xml:"ProveedorXML"`
+	CodigoQR           string             `xml:"CodigoQR"`
+	InformacionGeneral InformacionGeneral `xml:"InformacionGeneral"`
+	Empleador          Empleador          `xml:"Empleador"`
+	Trabajador         Trabajador         `xml:"Trabajador"`
+	Pago               Pago               `xml:"Pago"`
+	FechasPagos        FechasPagos        `xml:"FechasPagos"`
+	Devengados         Devengados         `xml:"Devengados"`
+	Deducciones        Deducciones        `xml:"Deducciones"`
+	DevengadosTotal    string             `xml:"DevengadosTotal"`
+	DeduccionesTotal   string             `xml:"DeduccionesTotal"`
+	ComprobanteTotal   string             `xml:"ComprobanteTotal"`
+}
+
+type Novedad struct {
+	CUNENov string `xml:"CUNENov,attr,omitempty"`
+	Value   bool   `xml:",chardata"`
+}
+
+type Periodo struct {
+	FechaIngreso           string `xml:"FechaIngreso,attr"`
+	FechaRetiro            string `xml:"FechaRetiro,attr,omitempty"`
+	FechaLiquidacionInicio string `xml:"FechaLiquidacionInicio,attr"`
+	FechaLiquidacionFin    string `xml:"FechaLiquidacionFin,attr"`
+	TiempoLaborado         string `xml:"TiempoLaborado,attr"`
+	FechaGen               string `xml:"FechaGen,attr"`
+}
+
+type NumeroSecuenciaXML struct {
+	CodigoTrabajador string `xml:"CodigoTrabajador,attr,omitempty"`
+	Prefijo          string `xml:"Prefijo,attr"`
+	Consecutivo      string `xml:"Consecutivo,attr"`
+	Numero           string `xml:"Numero,attr"`
+}
+
+type Lugar struct {
+	Pais               string `xml:"Pais,attr"`
+	DepartamentoEstado string `xml:"DepartamentoEstado,attr"`
+	MunicipioCiudad    string `xml:"MunicipioCiudad,attr"`
+	Idioma             string `xml:"Idioma,attr"`
+}
+
+type ProveedorXML struct {
+	NIT        string `xml:"NIT,attr"`
+	DV         string `xml:"DV,attr"`
+	SoftwareID string `xml:"SoftwareID,attr"`
+	SoftwareSC string `xml:"SoftwareSC,attr"`
+}
+
+type InformacionGeneral struct {
+	Version       string `xml:"Version,attr"`
+	Ambiente      string `xml:"Ambiente,attr"`
+	TipoXML       string `xml:"TipoXML,attr"`
+	CUNE          string `xml:"CUNE,attr"`
+	EncripCUNE    string `xml:"EncripCUNE,attr"`
+	FechaGen      string `xml:"FechaGen,attr"`
+	HoraGen       string `xml:"HoraGen,attr"`
+	PeriodoNomina string `xml:"PeriodoNomina,attr"`
+	TipoMoneda    string `xml:"TipoMoneda,attr"`
+}
+
+type Empleador struct {
+	RazonSocial        string `xml:"RazonSocial,attr"`
+	NIT                string `xml:"NIT,attr"`
+	DV                 string `xml:"DV,attr"`
+	Pais               string `xml:"Pais,attr"`
+	DepartamentoEstado string `xml:"DepartamentoEstado,attr"`
+	MunicipioCiudad    string `xml:"MunicipioCiudad,attr"`
+	Direccion          string `xml:"Direccion,attr"`
+}
+
+type Trabajador struct {
+	TipoTrabajador           string `xml:"TipoTrabajador,attr"`
+	SubTipoTrabajador        string `xml:"SubTipoTrabajador,attr"`
+	AltoRiesgoPension        bool   `xml:"AltoRiesgoPension,attr"`
+	TipoDocumento            string `xml:"TipoDocumento,attr"`
+	NumeroDocumento          string `xml:"NumeroDocumento,attr"`
+	PrimerApellido           string `xml:"PrimerApellido,attr"`
+	SegundoApellido          string `xml:"SegundoApellido,attr"`
+	PrimerNombre             string `xml:"PrimerNombre,attr"`
+	OtrosNombres             string `xml:"OtrosNombres,attr,omitempty"`
+	LugarTrabajoPais         string `xml:"LugarTrabajoPais,attr"`
+	LugarTrabajoDepartamento string `xml:"LugarTrabajoDepartamentoEstado,attr"`
+	LugarTrabajoMunicipio    string `xml:"LugarTrabajoMunicipioCiudad,attr"`
+	LugarTrabajoDireccion    string `xml:"LugarTrabajoDireccion,attr"`
+	SalarioIntegral          bool   `xml:"SalarioIntegral,attr"`
+	TipoContrato             string `xml:"TipoContrato,attr"`
+	Sueldo                   string `xml:"Sueldo,attr"`
+	CodigoTrabajador         string `xml:"CodigoTrabajador,attr,omitempty"`
+}
+
+type Pago struct {
+	Forma  string `xml:"Forma,attr"`
+	Metodo string `xml:"Metodo,attr"`
+}
+
+type FechasPagos struct {
+	FechaPago []string `xml:"FechaPago"`
+}
+
+type Devengados struct {
+	Basico         Basico          `xml:"Basico"`
+	Bonificaciones *Bonificaciones `xml:"Bonificaciones,omitempty"`
+}
+
+type Basico struct {
+	DiasTrabajados  string `xml:"DiasTrabajados,attr"`
+	SueldoTrabajado string `xml:"SueldoTrabajado,attr"`
+}
+
+type Bonificaciones struct {
+	Bonificacion []Bonificacion `xml:"Bonificacion"`
+}
+
+type Bonificacion struct {
+	BonificacionS  string `xml:"BonificacionS,attr,omitempty"`
+	BonificacionNS string `xml:"BonificacionNS,attr,omitempty"`
+}
+
+type Deducciones struct {
+	Salud            Aporte            `xml:"Salud"`
+	FondoPension     Aporte            `xml:"FondoPension"`
+	OtrasDeducciones *OtrasDeducciones `xml:"OtrasDeducciones,omitempty"`
+}
+
+type Aporte struct {
+	Porcentaje string `xml:"Porcentaje,attr"`
+	Deduccion  string `xml:"Deduccion,attr"`
+}
+
+type OtrasDeducciones struct {
+	OtraDeduccion []string `xml:"OtraDeduccion"`
+}
@@ -0,0 +1,330 @@
+// Package nomina builds the individual payroll support documents (nómina
+// electrónica) that Colombian employers report to DIAN.
+package nomina
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Namespace = "dian:gov:co:facturaelectronica:NominaIndividual"
+	Version   = "V1.0: Documento Soporte de Pago de Nómina Electrónica"
+
+	// TipoXML of the individual payroll document
+	TypeIndividual = "102"
+
+	AmbienteProduction = "1"
+	AmbienteTest       = "2"
+)
+
+// Bogotá has no daylight saving, so every document is generated in UTC-5
+var colombia = time.FixedZone("COT", -5*60*60)
+
+// Employer holds the company data reported in the Empleador element
+type Employer struct {
+	Name              string
+	Nit               string
+	VerificationDigit string
+	DepartmentCode    string
+	MunicipalityCode  string
+	Address           string
+}
+
+// Worker holds the employee data reported in the Trabajador element
+type Worker struct {
+	Code          string
+	DocumentType  string
+	DocumentId    string
+	FirstName     string
+	OtherNames    string
+	FirstSurname  string
+	SecondSurname string
+	AdmissionDate time.Time
+	Salary        float64
+}
+
+type Concept struct {
+	Description string
+	Amount      float64
+}
+
+// Settlement is the closed payroll result of one employee for a period
+type Settlement struct {
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	PaymentDate time.Time
+	WorkedDays  int
+	TotalDays   int
+	BasePay     float64
+	Bonuses     []Concept
+	Health      float64
+	Pension     float64
+	Deductions  []Concept
+}
+
+// Software identifies the payroll software registered before DIAN
+type Software struct {
+	ProviderNit               string
+	ProviderVerificationDigit string
+	ID                        string
+	Pin                       string
+	Ambiente                  string
+}
+
+type Params struct {
+	Prefix      string
+	Consecutive int64
+	GeneratedAt time.Time
+	Employer    Employer
+	Worker      Worker
+	Settlement  Settlement
+	Software    Software
+}
+
+// Build assembles the document, computes its CUNE and validates it. The
+// returned document is ready to be signed and transmitted.
+func Build(params Params) (*NominaIndividual, error) {
+	generatedAt := params.GeneratedAt.In(colombia)
+	settlement := params.Settlement
+	number := params.Prefix + strconv.FormatInt(params.Consecutive, 10)
+
+	doc := &NominaIndividual{
+		Xmlns:   Namespace,
+		Novedad: Novedad{Value: false},
+		Periodo: Periodo{
+			FechaIngreso:           formatDate(params.Worker.AdmissionDate),
+			FechaLiquidacionInicio: formatDate(settlement.PeriodStart),
+			FechaLiquidacionFin:    formatDate(settlement.PeriodEnd),
+			TiempoLaborado:         strconv.Itoa(settlement.TotalDays),
+			FechaGen:               formatDate(generatedAt),
+		},
+		NumeroSecuenciaXML: NumeroSecuenciaXML{
+			CodigoTrabajador: params.Worker.Code,
+			Prefijo:          params.Prefix,
+			Consecutivo:      strconv.FormatInt(params.Consecutive, 10),
+			Numero:           number,
+		},
+		LugarGeneracionXML: Lugar{
+			Pais:               "CO",
+			DepartamentoEstado: params.Employer.DepartmentCode,
+			MunicipioCiudad:    params.Employer.MunicipalityCode,
+			Idioma:             "es",
+		},
+		ProveedorXML: ProveedorXML{
+			NIT:        params.Software.ProviderNit,
+			DV:         params.Software.ProviderVerificationDigit,
+			SoftwareID: params.Software.ID,
+			SoftwareSC: SoftwareSecurityCode(params.Software.ID, params.Software.Pin, number),
+		},
+		InformacionGeneral: InformacionGeneral{
+			Version:       Version,
+			Ambiente:      params.Software.Ambiente,
+			TipoXML:       TypeIndividual,
+			EncripCUNE:    "CUNE-SHA384",
+			FechaGen:      formatDate(generatedAt),
+			HoraGen:       formatTime(generatedAt),
+			PeriodoNomina: "5",
+			TipoMoneda:    "COP",
+		},
+		Empleador: Empleador{
+			RazonSocial:        params.Employer.Name,
+			NIT:                params.Employer.Nit,
+			DV:                 params.Employer.VerificationDigit,
+			Pais:               "CO",
+			DepartamentoEstado: params.Employer.DepartmentCode,
+			MunicipioCiudad:    params.Employer.MunicipalityCode,
+			Direccion:          params.Employer.Address,
+		},
+		Trabajador: Trabajador{
+			TipoTrabajador:           "01",
+			SubTipoTrabajador:        "00",
+			AltoRiesgoPension:        false,
+			TipoDocumento:            params.Worker.DocumentType,
+			NumeroDocumento:          params.Worker.DocumentId,
+			PrimerApellido:           params.Worker.FirstSurname,
+			SegundoApellido:          params.Worker.SecondSurname,
+			PrimerNombre:             params.Worker.FirstName,
+			OtrosNombres:             params.Worker.OtherNames,
+			LugarTrabajoPais:         "CO",
+			LugarTrabajoDepartamento: params.Employer.DepartmentCode,
+			LugarTrabajoMunicipio:    params.Employer.MunicipalityCode,
+			LugarTrabajoDireccion:    params.Employer.Address,
+			SalarioIntegral:          false,
+			TipoContrato:             "1",
+			Sueldo:                   formatAmount(params.Worker.Salary),
+			CodigoTrabajador:         params.Worker.Code,
+		},
+		Pago:        Pago{Forma: "1", Metodo: "10"},
+		FechasPagos: FechasPagos{FechaPago: []string{formatDate(settlement.PaymentDate)}},
+		Devengados: Devengados{
+			Basico: Basico{
+				DiasTrabajados:  strconv.Itoa(settlement.WorkedDays),
+				SueldoTrabajado: formatAmount(settlement.BasePay),
+			},
+		},
+		Deducciones: Deducciones{
+			Salud:        Aporte{Porcentaje: "4.00", Deduccion: formatAmount(settlement.Health)},
+			FondoPension: Aporte{Porcentaje: "4.00", Deduccion: formatAmount(settlement.Pension)},
+		},
+	}
+
+	accrued := settlement.BasePay
+	if len(settlement.Bonuses) > 0 {
+		doc.Devengados.Bonificaciones = &Bonificaciones{}
+		for _, bonus := range settlement.Bonuses {
+			doc.Devengados.Bonificaciones.Bonificacion = append(doc.Devengados.Bonificaciones.Bonificacion,
+				Bonificacion{BonificacionS: formatAmount(bonus.Amount)})
+			accrued += bonus.Amount
+		}
+	}
+
+	deducted := settlement.Health + settlement.Pension
+	if len(settlement.Deductions) > 0 {
+		doc.Deducciones.OtrasDeducciones = &OtrasDeducciones{}
+		for _, deduction := range settlement.Deductions {
+			doc.Deducciones.OtrasDeducciones.OtraDeduccion = append(doc.Deducciones.OtrasDeducciones.OtraDeduccion,
+				formatAmount(deduction.Amount))
+			deducted += deduction.Amount
+		}
+	}
+
+	doc.DevengadosTotal = formatAmount(accrued)
+	doc.DeduccionesTotal = formatAmount(deducted)
+	doc.ComprobanteTotal = formatAmount(accrued - deducted)
+
+	doc.InformacionGeneral.CUNE = CUNE(CUNEInput{
+		Number:      number,
+		GeneratedAt: generatedAt,
+		Accrued:     doc.DevengadosTotal,
+		Deducted:    doc.DeduccionesTotal,
+		Total:       doc.ComprobanteTotal,
+		EmployerNit: params.Employer.Nit,
+		WorkerId:    params.Worker.DocumentId,
+		Type:        TypeIndividual,
+		SoftwarePin: params.Software.Pin,
+		Ambiente:    params.Software.Ambiente,
+	})
+	doc.CodigoQR = QRUrl(params.Software.Ambiente, doc.InformacionGeneral.CUNE)
+
+	if err := Validate(doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+type CUNEInput struct {
+	Number      string
+	GeneratedAt time.Time
+	Accrued     string
+	Deducted    string
+	Total       string
+	EmployerNit string
+	WorkerId    string
+	Type        string
+	SoftwarePin string
+	Ambiente    string
+}
+
+// CUNE computes the unique electronic payroll code as defined by the DIAN
+// technical annex: the SHA-384 of the concatenation of the document number,
+// generation date and time, totals, employer NIT, worker document, document
+// type, software PIN and environment.
+func CUNE(input CUNEInput) string {
+	generatedAt := input.GeneratedAt.In(colombia)
+
+	value := input.Number +
+		formatDate(generatedAt) +
+		formatTime(generatedAt) +
+		input.Accrued +
+		input.Deducted +
+		input.Total +
+		input.EmployerNit +
+		input.WorkerId +
+		input.Type +
+		input.SoftwarePin +
+		input.Ambiente
+
+	sum := sha512.Sum384([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// SoftwareSecurityCode is the SHA-384 of the software id, its PIN and the
+// document number.
+func SoftwareSecurityCode(softwareId string, pin string, number string) string {
+	sum := sha512.Sum384([]byte(softwareId + pin + number))
+	return hex.EncodeToString(sum[:])
+}
+
+func QRUrl(ambiente string, cune string) string {
+	host := "catalogo-vpfe.dian.gov.co"
+	if ambiente == AmbienteTest {
+		host = "catalogo-vpfe-hab.dian.gov.co"
+	}
+
+	return fmt.Sprintf("https://%s/document/searchqr?documentkey=%s", host, cune)
+}
+
+// Marshal renders the document with the XML declaration
+func Marshal(doc *NominaIndividual) ([]byte, error) {
+	data, err := xml.MarshalIndent(doc, "", "  ")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), data...), nil
+}
+
+// SplitName splits a full name or surname in its first word and the rest
+func SplitName(name string) (string, string) {
+	parts := strings.Fields(name)
+
+	if len(parts) == 0 {
+		return "", ""
+	}
+
+	return parts[0], strings.Join(parts[1:], " ")
+}
+
+// DocumentType maps the id_types codes to the DIAN document type codes
+func DocumentType(code string) string {
+	switch strings.ToUpper(code) {
+	case "RC":
+		return "11"
+	case "TI":
+		return "12"
+	case "CC":
+		return "13"
+	case "TE":
+		return "21"
+	case "CE":
+		return "22"
+	case "NIT":
+		return "31"
+	case "PA", "PAS":
+		return "41"
+	case "PEP", "PPT":
+		return "47"
+	default:
+		return ""
+	}
+}
+
+func formatDate(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+func formatTime(t time.Time) string {
+	return t.Format("15:04:05-07:00")
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
@@ -0,0 +1,171 @@
+package nomina
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCUNE(t *testing.T) {
+	input := CUNEInput{
+		Number:      "NE50",
+		GeneratedAt: time.Date(2021, time.January, 22, 15, 32, 50, 0, colombia),
+		Accrued:     "3000000.00",
+		Deducted:    "240000.00",
+		Total:       "2760000.00",
+		EmployerNit: "900373076",
+		WorkerId:    "1033725432",
+		Type:        TypeIndividual,
+		SoftwarePin: "75315",
+		Ambiente:    AmbienteTest,
+	}
+
+	// The SHA-384 of NE502021-01-2215:32:50-05:003000000.00240000.002760000.009003730761033725432102753152
+	want := "f292e621cc5ea1bad3672e0954aaa190a556ed6a5d1684be1c3af6de0f0649391ca08c0acedc41e5f111926dc0081c53"
+
+	if got := CUNE(input); got != want {
+		t.Errorf("CUNE() = %s, want %s", got, want)
+	}
+
+	// The date and time are those of Colombia whatever the zone of the input
+	input.GeneratedAt = time.Date(2021, time.January, 22, 20, 32, 50, 0, time.UTC)
+
+	if got := CUNE(input); got != want {
+		t.Errorf("CUNE() in UTC = %s, want %s", got, want)
+	}
+
+	input.Total = "2760000.01"
+
+	if got := CUNE(input); got == want {
+		t.Errorf("CUNE() didn't change with the total")
+	}
+}
+
+func validParams() Params {
+	return Params{
+		Prefix:      "NE",
+		Consecutive: 50,
+		GeneratedAt: time.Date(2026, time.October, 1, 9, 0, 0, 0, colombia),
+		Employer: Employer{
+			Name:              "Empresa de Prueba S.A.S.",
+			Nit:               "900373076",
+			VerificationDigit: "1",
+			DepartmentCode:    "11",
+			MunicipalityCode:  "11001",
+			Address:           "Calle 100 # 10-20",
+		},
+		Worker: Worker{
+			DocumentType:  DocumentType("CC"),
+			DocumentId:    "1033725432",
+			FirstName:     "Ana",
+			FirstSurname:  "Pérez",
+			SecondSurname: "Gómez",
+			AdmissionDate: time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC),
+			Salary:        3000000,
+		},
+		Settlement: Settlement{
+			PeriodStart: time.Date(2026, time.September, 1, 0, 0, 0, 0, time.UTC),
+			PeriodEnd:   time.Date(2026, time.September, 30, 0, 0, 0, 0, time.UTC),
+			PaymentDate: time.Date(2026, time.September, 30, 0, 0, 0, 0, time.UTC),
+			WorkedDays:  30,
+			TotalDays:   960,
+			BasePay:     3000000,
+			Bonuses:     []Concept{{Description: "Bonificación", Amount: 200000}},
+			Health:      120000,
+			Pension:     120000,
+			Deductions:  []Concept{{Description: "Libranza", Amount: 50000}},
+		},
+		Software: Software{
+			ProviderNit:               "900373076",
+			ProviderVerificationDigit: "1",
+			ID:                        "56f2ae4e-9812-4fad-9255-08fcfcd5ccb0",
+			Pin:                       "75315",
+			Ambiente:                  AmbienteTest,
+		},
+	}
+}
+
+func TestBuild(t *testing.T) {
+	doc, err := Build(validParams())
+
+	if err != nil {
+		t.Fatalf("Build() = %v", err)
+	}
+
+	if doc.DevengadosTotal != "3200000.00" || doc.DeduccionesTotal != "290000.00" || doc.ComprobanteTotal != "2910000.00" {
+		t.Errorf("totals = %s - %s = %s, want 3200000.00 - 290000.00 = 2910000.00",
+			doc.DevengadosTotal, doc.DeduccionesTotal, doc.ComprobanteTotal)
+	}
+
+	if doc.InformacionGeneral.HoraGen != "09:00:00-05:00" {
+		t.Errorf("HoraGen = %s, want 09:00:00-05:00", doc.InformacionGeneral.HoraGen)
+	}
+
+	if !strings.HasSuffix(doc.CodigoQR, "documentkey="+doc.InformacionGeneral.CUNE) {
+		t.Errorf("CodigoQR %s doesn't point to the CUNE", doc.CodigoQR)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(doc *NominaIndividual)
+		problem string
+	}{
+		{"valid", func(doc *NominaIndividual) {}, ""},
+		{"missing employer NIT", func(doc *NominaIndividual) { doc.Empleador.NIT = "" }, "Empleador/@NIT is required"},
+		{"NIT with its digit", func(doc *NominaIndividual) { doc.Empleador.NIT = "900373076-1" }, "Empleador/@NIT"},
+		{"date with slashes", func(doc *NominaIndividual) { doc.Periodo.FechaIngreso = "2024/02/01" }, "Periodo/@FechaIngreso"},
+		{"time without the offset", func(doc *NominaIndividual) { doc.InformacionGeneral.HoraGen = "09:00:00" }, "InformacionGeneral/@HoraGen"},
+		{"amount without cents", func(doc *NominaIndividual) { doc.Trabajador.Sueldo = "3000000" }, "Trabajador/@Sueldo"},
+		{"unknown document type", func(doc *NominaIndividual) { doc.Trabajador.TipoDocumento = "" }, "Trabajador/@TipoDocumento is required"},
+		{"document type off the list", func(doc *NominaIndividual) { doc.Trabajador.TipoDocumento = "99" }, "is not one of"},
+		{"unknown environment", func(doc *NominaIndividual) { doc.InformacionGeneral.Ambiente = "3" }, "InformacionGeneral/@Ambiente"},
+		{"short CUNE", func(doc *NominaIndividual) { doc.InformacionGeneral.CUNE = "abc" }, "InformacionGeneral/@CUNE"},
+		{"long first name", func(doc *NominaIndividual) { doc.Trabajador.PrimerNombre = strings.Repeat("ñ", 61) }, "longer than 60 characters"},
+		{"municipality of four digits", func(doc *NominaIndividual) { doc.Empleador.MunicipioCiudad = "1100" }, "Empleador/@MunicipioCiudad"},
+		{"no payment date", func(doc *NominaIndividual) { doc.FechasPagos.FechaPago = nil }, "FechasPagos/FechaPago is required"},
+		{"bad payment date", func(doc *NominaIndividual) { doc.FechasPagos.FechaPago = []string{"30-09-2026"} }, "is not a date"},
+		{"inconsistent total", func(doc *NominaIndividual) { doc.ComprobanteTotal = "2910000.01" }, "ComprobanteTotal must be"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc, err := Build(validParams())
+
+			if err != nil {
+				t.Fatalf("Build() = %v", err)
+			}
+
+			test.change(doc)
+			err = Validate(doc)
+
+			if test.problem == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Validate() = %v, want a ValidationError", err)
+			}
+
+			if !strings.Contains(validationErr.Error(), test.problem) {
+				t.Errorf("Validate() = %v, want a problem with %q", err, test.problem)
+			}
+		})
+	}
+}
+
+func TestBuildInvalid(t *testing.T) {
+	params := validParams()
+	params.Employer.DepartmentCode = ""
+
+	if _, err := Build(params); err == nil || !strings.Contains(err.Error(), "DepartamentoEstado is required") {
+		t.Errorf("Build() without a department = %v, want a validation error", err)
+	}
+}
@@ -0,0 +1,41 @@
+package nomina
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+)
+
+// Transmitter sends a generated document to DIAN or to the technology
+// provider in charge of signing and submitting it.
+type Transmitter interface {
+	Transmit(ctx context.Context, companyNit string, number string, xml []byte) error
+}
+
+// FileDropTransmitter writes every document to a directory, one folder per
+// employer, so an external process can pick them up.
+type FileDropTransmitter struct {
+	Dir string
+}
+
+func NewFileDropTransmitter(dir string) *FileDropTransmitter {
+	return &FileDropTransmitter{Dir: dir}
+}
+
+func (t *FileDropTransmitter) Transmit(ctx context.Context, companyNit string, number string, xml []byte) error {
+	dir := filepath.Join(t.Dir, filepath.Base(companyNit))
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary name first so readers never see half a document
+	path := filepath.Join(dir, filepath.Base(number)+".xml")
+	tmp := path + ".tmp"
+
+	if err := os.WriteFile(tmp, xml, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
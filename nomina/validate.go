@@ -0,0 +1,149 @@
+package nomina
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	datePattern    = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	timePattern    = regexp.MustCompile(`^\d{2}:\d{2}:\d{2}-05:00$`)
+	amountPattern  = regexp.MustCompile(`^-?\d{1,16}\.\d{2}$`)
+	integerPattern = regexp.MustCompile(`^\d+$`)
+	nitPattern     = regexp.MustCompile(`^\d{5,15}$`)
+	digitPattern   = regexp.MustCompile(`^\d$`)
+	sha384Pattern  = regexp.MustCompile(`^[0-9a-f]{96}$`)
+	depPattern     = regexp.MustCompile(`^\d{2}$`)
+	munPattern     = regexp.MustCompile(`^\d{5}$`)
+)
+
+var documentTypes = []string{"11", "12", "13", "21", "22", "31", "41", "42", "47", "50"}
+
+// rule is one restriction of the XSD over an element or attribute
+type rule struct {
+	path      string
+	value     string
+	required  bool
+	pattern   *regexp.Regexp
+	maxLength int
+	enum      []string
+}
+
+// ValidationError lists every restriction the document violates
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid NominaIndividual: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks the document against the restrictions the DIAN XSD puts on
+// the elements we generate: required attributes, data type patterns, lengths
+// and code lists, plus the consistency of the totals.
+func Validate(doc *NominaIndividual) error {
+	rules := []rule{
+		{path: "Periodo/@FechaIngreso", value: doc.Periodo.FechaIngreso, required: true, pattern: datePattern},
+		{path: "Periodo/@FechaLiquidacionInicio", value: doc.Periodo.FechaLiquidacionInicio, required: true, pattern: datePattern},
+		{path: "Periodo/@FechaLiquidacionFin", value: doc.Periodo.FechaLiquidacionFin, required: true, pattern: datePattern},
+		{path: "Periodo/@TiempoLaborado", value: doc.Periodo.TiempoLaborado, required: true, pattern: integerPattern},
+		{path: "Periodo/@FechaGen", value: doc.Periodo.FechaGen, required: true, pattern: datePattern},
+		{path: "NumeroSecuenciaXML/@Prefijo", value: doc.NumeroSecuenciaXML.Prefijo, required: true, maxLength: 10},
+		{path: "NumeroSecuenciaXML/@Consecutivo", value: doc.NumeroSecuenciaXML.Consecutivo, required: true, pattern: integerPattern},
+		{path: "NumeroSecuenciaXML/@Numero", value: doc.NumeroSecuenciaXML.Numero, required: true, maxLength: 20},
+		{path: "LugarGeneracionXML/@DepartamentoEstado", value: doc.LugarGeneracionXML.DepartamentoEstado, required: true, pattern: depPattern},
+		{path: "LugarGeneracionXML/@MunicipioCiudad", value: doc.LugarGeneracionXML.MunicipioCiudad, required: true, pattern: munPattern},
+		{path: "ProveedorXML/@NIT", value: doc.ProveedorXML.NIT, required: true, pattern: nitPattern},
+		{path: "ProveedorXML/@DV", value: doc.ProveedorXML.DV, required: true, pattern: digitPattern},
+		{path: "ProveedorXML/@SoftwareID", value: doc.ProveedorXML.SoftwareID, required: true},
+		{path: "ProveedorXML/@SoftwareSC", value: doc.ProveedorXML.SoftwareSC, required: true, pattern: sha384Pattern},
+		{path: "CodigoQR", value: doc.CodigoQR, required: true},
+		{path: "InformacionGeneral/@Ambiente", value: doc.InformacionGeneral.Ambiente, required: true, enum: []string{AmbienteProduction, AmbienteTest}},
+		{path: "InformacionGeneral/@TipoXML", value: doc.InformacionGeneral.TipoXML, required: true, enum: []string{TypeIndividual}},
+		{path: "InformacionGeneral/@CUNE", value: doc.InformacionGeneral.CUNE, required: true, pattern: sha384Pattern},
+		{path: "InformacionGeneral/@FechaGen", value: doc.InformacionGeneral.FechaGen, required: true, pattern: datePattern},
+		{path: "InformacionGeneral/@HoraGen", value: doc.InformacionGeneral.HoraGen, required: true, pattern: timePattern},
+		{path: "InformacionGeneral/@PeriodoNomina", value: doc.InformacionGeneral.PeriodoNomina, required: true, enum: []string{"1", "2", "3", "4", "5", "6"}},
+		{path: "Empleador/@RazonSocial", value: doc.Empleador.RazonSocial, required: true, maxLength: 450},
+		{path: "Empleador/@NIT", value: doc.Empleador.NIT, required: true, pattern: nitPattern},
+		{path: "Empleador/@DV", value: doc.Empleador.DV, required: true, pattern: digitPattern},
+		{path: "Empleador/@DepartamentoEstado", value: doc.Empleador.DepartamentoEstado, required: true, pattern: depPattern},
+		{path: "Empleador/@MunicipioCiudad", value: doc.Empleador.MunicipioCiudad, required: true, pattern: munPattern},
+		{path: "Empleador/@Direccion", value: doc.Empleador.Direccion, required: true},
+		{path: "Trabajador/@TipoDocumento", value: doc.Trabajador.TipoDocumento, required: true, enum: documentTypes},
+		{path: "Trabajador/@NumeroDocumento", value: doc.Trabajador.NumeroDocumento, required: true, maxLength: 15},
+		{path: "Trabajador/@PrimerApellido", value: doc.Trabajador.PrimerApellido, required: true, maxLength: 60},
+		{path: "Trabajador/@PrimerNombre", value: doc.Trabajador.PrimerNombre, required: true, maxLength: 60},
+		{path: "Trabajador/@TipoContrato", value: doc.Trabajador.TipoContrato, required: true, enum: []string{"1", "2", "3", "4", "5"}},
+		{path: "Trabajador/@Sueldo", value: doc.Trabajador.Sueldo, required: true, pattern: amountPattern},
+		{path: "Devengados/Basico/@DiasTrabajados", value: doc.Devengados.Basico.DiasTrabajados, required: true, pattern: integerPattern},
+		{path: "Devengados/Basico/@SueldoTrabajado", value: doc.Devengados.Basico.SueldoTrabajado, required: true, pattern: amountPattern},
+		{path: "Deducciones/Salud/@Deduccion", value: doc.Deducciones.Salud.Deduccion, required: true, pattern: amountPattern},
+		{path: "Deducciones/FondoPension/@Deduccion", value: doc.Deducciones.FondoPension.Deduccion, required: true, pattern: amountPattern},
+		{path: "DevengadosTotal", value: doc.DevengadosTotal, required: true, pattern: amountPattern},
+		{path: "DeduccionesTotal", value: doc.DeduccionesTotal, required: true, pattern: amountPattern},
+		{path: "ComprobanteTotal", value: doc.ComprobanteTotal, required: true, pattern: amountPattern},
+	}
+
+	var problems []string
+
+	for _, r := range rules {
+		if problem := r.check(); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+
+	if len(doc.FechasPagos.FechaPago) == 0 {
+		problems = append(problems, "FechasPagos/FechaPago is required")
+	}
+
+	for _, date := range doc.FechasPagos.FechaPago {
+		if !datePattern.MatchString(date) {
+			problems = append(problems, fmt.Sprintf("FechasPagos/FechaPago %q is not a date", date))
+		}
+	}
+
+	accrued, _ := strconv.ParseFloat(doc.DevengadosTotal, 64)
+	deducted, _ := strconv.ParseFloat(doc.DeduccionesTotal, 64)
+	total, _ := strconv.ParseFloat(doc.ComprobanteTotal, 64)
+
+	if formatAmount(accrued-deducted) != formatAmount(total) {
+		problems = append(problems, "ComprobanteTotal must be DevengadosTotal minus DeduccionesTotal")
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+
+	return nil
+}
+
+func (r rule) check() string {
+	if r.value == "" {
+		if r.required {
+			return r.path + " is required"
+		}
+		return ""
+	}
+
+	if r.pattern != nil && !r.pattern.MatchString(r.value) {
+		return fmt.Sprintf("%s %q doesn't match %s", r.path, r.value, r.pattern.String())
+	}
+
+	if r.maxLength > 0 && len([]rune(r.value)) > r.maxLength {
+		return fmt.Sprintf("%s is longer than %d characters", r.path, r.maxLength)
+	}
+
+	if len(r.enum) > 0 {
+		for _, option := range r.enum {
+			if option == r.value {
+				return ""
+			}
+		}
+		return fmt.Sprintf("%s %q is not one of %s", r.path, r.value, strings.Join(r.enum, ", "))
+	}
+
+	return ""
+}
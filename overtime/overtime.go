@@ -0,0 +1,203 @@
+// Package overtime classifies worked time in the buckets Colombian labor law
+// pays with different surcharges: night hours, Sundays and holidays, and
+// the overtime beyond the weekly limit, including their combinations.
+package overtime
+
+import (
+	"math"
+	"time"
+
+	"github.com/gioCuesta25/employees-manager-backend/attendance"
+	"github.com/gioCuesta25/employees-manager-backend/calendar"
+)
+
+const (
+	BucketOrdinary             = "ordinary"
+	BucketNight                = "night"
+	BucketHoliday              = "holiday"
+	BucketHolidayNight         = "holiday_night"
+	BucketOvertimeDay          = "overtime_day"
+	BucketOvertimeNight        = "overtime_night"
+	BucketOvertimeHolidayDay   = "overtime_holiday_day"
+	BucketOvertimeHolidayNight = "overtime_holiday_night"
+)
+
+// Buckets lists every bucket in the order they are reported
+var Buckets = []string{
+	BucketOrdinary,
+	BucketNight,
+	BucketHoliday,
+	BucketHolidayNight,
+	BucketOvertimeDay,
+	BucketOvertimeNight,
+	BucketOvertimeHolidayDay,
+	BucketOvertimeHolidayNight,
+}
+
+// Labels are the concept names used on payslips
+var Labels = map[string]string{
+	BucketOrdinary:             "Horas ordinarias",
+	BucketNight:                "Recargo nocturno",
+	BucketHoliday:              "Recargo dominical y festivo",
+	BucketHolidayNight:         "Recargo nocturno dominical y festivo",
+	BucketOvertimeDay:          "Horas extra diurnas",
+	BucketOvertimeNight:        "Horas extra nocturnas",
+	BucketOvertimeHolidayDay:   "Horas extra diurnas dominicales y festivas",
+	BucketOvertimeHolidayNight: "Horas extra nocturnas dominicales y festivas",
+}
+
+// Rules configures the classification. Night start and end are minutes
+// after midnight, the night window wraps around midnight.
+type Rules struct {
+	NightStart         int
+	NightEnd           int
+	WeeklyLimitMinutes int
+	NightRate          float64
+	HolidayRate        float64
+	OvertimeDayRate    float64
+	OvertimeNightRate  float64
+}
+
+// DefaultRules follow the rules in force in 2026: night work from 19:00
+// (Ley 2466 de 2025), a 42 hour week (Ley 2101 de 2021) and a 90% Sunday and
+// holiday surcharge.
+func DefaultRules() Rules {
+	return Rules{
+		NightStart:         19 * 60,
+		NightEnd:           6 * 60,
+		WeeklyLimitMinutes: 42 * 60,
+		NightRate:          0.35,
+		HolidayRate:        0.90,
+		OvertimeDayRate:    0.25,
+		OvertimeNightRate:  0.75,
+	}
+}
+
+// Rate is the surcharge of the bucket over the ordinary hour. Sunday and
+// holiday surcharges add up with the night and overtime ones.
+func (r Rules) Rate(bucket string) float64 {
+	switch bucket {
+	case BucketNight:
+		return r.NightRate
+	case BucketHoliday:
+		return r.HolidayRate
+	case BucketHolidayNight:
+		return r.HolidayRate + r.NightRate
+	case BucketOvertimeDay:
+		return r.OvertimeDayRate
+	case BucketOvertimeNight:
+		return r.OvertimeNightRate
+	case BucketOvertimeHolidayDay:
+		return r.HolidayRate + r.OvertimeDayRate
+	case BucketOvertimeHolidayNight:
+		return r.HolidayRate + r.OvertimeNightRate
+	default:
+		return 0
+	}
+}
+
+// IsOvertime reports whether the bucket is paid on top of the salary in
+// full, not only its surcharge.
+func IsOvertime(bucket string) bool {
+	switch bucket {
+	case BucketOvertimeDay, BucketOvertimeNight, BucketOvertimeHolidayDay, BucketOvertimeHolidayNight:
+		return true
+	default:
+		return false
+	}
+}
+
+// Minutes per bucket
+type Minutes map[string]int
+
+func (m Minutes) Add(other Minutes) {
+	for bucket, minutes := range other {
+		m[bucket] += minutes
+	}
+}
+
+// Classify splits the intervals minute by minute and returns the minutes of
+// every bucket per local day. The weekly limit is counted from Monday, so
+// the intervals should start on the Monday of the first week of interest.
+func Classify(intervals []attendance.Interval, cal *calendar.Calendar, rules Rules) map[time.Time]Minutes {
+	days := make(map[time.Time]Minutes)
+	weekly := make(map[time.Time]int)
+
+	for _, interval := range intervals {
+		for t := interval.Start; t.Before(interval.End); t = t.Add(time.Minute) {
+			local := t.In(attendance.Location)
+			day := attendance.Day(t)
+			week := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+
+			minute := local.Hour()*60 + local.Minute()
+			night := isNight(minute, rules)
+			_, holiday := cal.Holiday(day)
+			holiday = holiday || day.Weekday() == time.Sunday
+			overtime := rules.WeeklyLimitMinutes > 0 && weekly[week] >= rules.WeeklyLimitMinutes
+
+			weekly[week]++
+
+			if days[day] == nil {
+				days[day] = make(Minutes)
+			}
+
+			days[day][bucketFor(night, holiday, overtime)]++
+		}
+	}
+
+	return days
+}
+
+// HourlyRate is the value of an ordinary hour for a monthly salary, with the
+// month counted as 30 days and the week as 6 working days.
+func HourlyRate(monthlySalary float64, rules Rules) float64 {
+	monthlyMinutes := float64(rules.WeeklyLimitMinutes) / 6 * 30
+
+	if monthlyMinutes == 0 {
+		return 0
+	}
+
+	return monthlySalary / (monthlyMinutes / 60)
+}
+
+// Amount is the value to pay for the minutes of the bucket. Ordinary time is
+// already included in the salary, so only its surcharge is paid; overtime is
+// paid in full plus the surcharge.
+func Amount(bucket string, minutes int, hourlyRate float64, rules Rules) float64 {
+	factor := rules.Rate(bucket)
+
+	if IsOvertime(bucket) {
+		factor++
+	}
+
+	return math.Round(float64(minutes)/60*hourlyRate*factor*100) / 100
+}
+
+func isNight(minute int, rules Rules) bool {
+	if rules.NightStart > rules.NightEnd {
+		return minute >= rules.NightStart || minute < rules.NightEnd
+	}
+
+	return minute >= rules.NightStart && minute < rules.NightEnd
+}
+
+func bucketFor(night bool, holiday bool, overtime bool) string {
+	switch {
+	case overtime && holiday && night:
+		return BucketOvertimeHolidayNight
+	case overtime && holiday:
+		return BucketOvertimeHolidayDay
+	case overtime && night:
+		return BucketOvertimeNight
+	case overtime:
+		return BucketOvertimeDay
+	case holiday && night:
+		return BucketHolidayNight
+	case holiday:
+		return BucketHoliday
+	case night:
+		return BucketNight
+	default:
+		return BucketOrdinary
+	}
+}
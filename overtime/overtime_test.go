@@ -0,0 +1,161 @@
+package overtime
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gioCuesta25/employees-manager-backend/attendance"
+	"github.com/gioCuesta25/employees-manager-backend/calendar"
+)
+
+func at(month time.Month, day int, hour int, minute int) time.Time {
+	return time.Date(2026, month, day, hour, minute, 0, 0, attendance.Location)
+}
+
+func day(month time.Month, day int) time.Time {
+	return time.Date(2026, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func interval(start time.Time, end time.Time) attendance.Interval {
+	return attendance.Interval{Start: start, End: end}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name      string
+		intervals []attendance.Interval
+		want      map[time.Time]Minutes
+	}{
+		{
+			name:      "ordinary day",
+			intervals: []attendance.Interval{interval(at(time.October, 20, 8, 0), at(time.October, 20, 12, 0))},
+			want: map[time.Time]Minutes{
+				day(time.October, 20): {BucketOrdinary: 240},
+			},
+		},
+		{
+			name:      "into the night",
+			intervals: []attendance.Interval{interval(at(time.October, 20, 17, 0), at(time.October, 20, 21, 0))},
+			want: map[time.Time]Minutes{
+				day(time.October, 20): {BucketOrdinary: 120, BucketNight: 120},
+			},
+		},
+		{
+			name:      "night until the morning",
+			intervals: []attendance.Interval{interval(at(time.October, 21, 4, 0), at(time.October, 21, 8, 0))},
+			want: map[time.Time]Minutes{
+				day(time.October, 21): {BucketNight: 120, BucketOrdinary: 120},
+			},
+		},
+		{
+			name:      "across midnight",
+			intervals: []attendance.Interval{interval(at(time.October, 20, 22, 0), at(time.October, 21, 2, 0))},
+			want: map[time.Time]Minutes{
+				day(time.October, 20): {BucketNight: 120},
+				day(time.October, 21): {BucketNight: 120},
+			},
+		},
+		{
+			name: "sunday",
+			intervals: []attendance.Interval{
+				interval(at(time.October, 25, 8, 0), at(time.October, 25, 10, 0)),
+				interval(at(time.October, 25, 18, 0), at(time.October, 25, 20, 0)),
+			},
+			want: map[time.Time]Minutes{
+				day(time.October, 25): {BucketHoliday: 180, BucketHolidayNight: 60},
+			},
+		},
+		{
+			name:      "saturday night into sunday",
+			intervals: []attendance.Interval{interval(at(time.October, 24, 23, 0), at(time.October, 25, 1, 0))},
+			want: map[time.Time]Minutes{
+				day(time.October, 24): {BucketNight: 60},
+				day(time.October, 25): {BucketHolidayNight: 60},
+			},
+		},
+		{
+			name:      "holiday moved to monday",
+			intervals: []attendance.Interval{interval(at(time.November, 2, 10, 0), at(time.November, 2, 12, 0))},
+			want: map[time.Time]Minutes{
+				day(time.November, 2): {BucketHoliday: 120},
+			},
+		},
+		{
+			name: "beyond the weekly limit",
+			intervals: []attendance.Interval{
+				interval(at(time.October, 19, 8, 0), at(time.October, 19, 17, 0)),
+				interval(at(time.October, 20, 8, 0), at(time.October, 20, 17, 0)),
+				interval(at(time.October, 21, 8, 0), at(time.October, 21, 17, 0)),
+				interval(at(time.October, 22, 8, 0), at(time.October, 22, 17, 0)),
+				interval(at(time.October, 23, 8, 0), at(time.October, 23, 17, 0)),
+				interval(at(time.October, 24, 18, 0), at(time.October, 24, 20, 0)),
+				interval(at(time.October, 25, 10, 0), at(time.October, 25, 11, 0)),
+				interval(at(time.October, 25, 20, 0), at(time.October, 25, 21, 0)),
+				interval(at(time.October, 26, 8, 0), at(time.October, 26, 9, 0)),
+			},
+			want: map[time.Time]Minutes{
+				day(time.October, 19): {BucketOrdinary: 540},
+				day(time.October, 20): {BucketOrdinary: 540},
+				day(time.October, 21): {BucketOrdinary: 540},
+				day(time.October, 22): {BucketOrdinary: 540},
+				day(time.October, 23): {BucketOrdinary: 360, BucketOvertimeDay: 180},
+				day(time.October, 24): {BucketOvertimeDay: 60, BucketOvertimeNight: 60},
+				day(time.October, 25): {BucketOvertimeHolidayDay: 60, BucketOvertimeHolidayNight: 60},
+				// The limit starts again on Monday
+				day(time.October, 26): {BucketOrdinary: 60},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Classify(test.intervals, calendar.Default(), DefaultRules())
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Classify() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestClassifyWithoutHolidays(t *testing.T) {
+	intervals := []attendance.Interval{interval(at(time.November, 2, 10, 0), at(time.November, 2, 12, 0))}
+
+	got := Classify(intervals, calendar.New(calendar.DefaultWorkingWeek, false), DefaultRules())
+	want := map[time.Time]Minutes{day(time.November, 2): {BucketOrdinary: 120}}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Classify() = %v, want %v", got, want)
+	}
+}
+
+func TestAmount(t *testing.T) {
+	rules := DefaultRules()
+	// 2.100.000 over the 210 hours of a month of 42 hour weeks
+	hourlyRate := HourlyRate(2100000, rules)
+
+	if hourlyRate != 10000 {
+		t.Fatalf("HourlyRate() = %v, want 10000", hourlyRate)
+	}
+
+	tests := []struct {
+		bucket string
+		want   float64
+	}{
+		{BucketOrdinary, 0},
+		{BucketNight, 3500},
+		{BucketHoliday, 9000},
+		{BucketHolidayNight, 12500},
+		{BucketOvertimeDay, 12500},
+		{BucketOvertimeNight, 17500},
+		{BucketOvertimeHolidayDay, 21500},
+		{BucketOvertimeHolidayNight, 26500},
+	}
+
+	for _, test := range tests {
+		if got := Amount(test.bucket, 60, hourlyRate, rules); got != test.want {
+			t.Errorf("Amount(%s) = %v, want %v", test.bucket, got, test.want)
+		}
+	}
+}
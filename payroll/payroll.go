@@ -0,0 +1,100 @@
+package payroll
+
+import (
+	"math"
+	"time"
+
+	"github.com/gioCuesta25/employees-manager-backend/models"
+)
+
+// Employee contributions withheld from the salary
+const (
+	HealthRate  = 0.04
+	PensionRate = 0.04
+)
+
+type Adjustment struct {
+	Kind   string
+	Amount float64
+}
+
+type Result struct {
+	WorkedDays          int
+	BasePay             float64
+	Bonuses             float64
+	Deductions          float64
+	HealthContribution  float64
+	PensionContribution float64
+	NetPay              float64
+}
+
+// Calculate computes the pay of one employee for the given period using the
+// commercial 30 day month, so a full month always pays the monthly salary.
+// The days before the admission and after the termination aren't paid.
+func Calculate(employee models.EmployeeSnapshot, periodStart time.Time, periodEnd time.Time, adjustments []Adjustment) Result {
+	var result Result
+
+	start := periodStart
+	if employee.AdmissionDate.After(start) {
+		start = employee.AdmissionDate
+	}
+
+	end := periodEnd
+	if employee.TerminationDate != nil && employee.TerminationDate.Before(end) {
+		end = *employee.TerminationDate
+	}
+
+	result.WorkedDays = Days360(start, end)
+	result.BasePay = round(employee.Salary / 30 * float64(result.WorkedDays))
+
+	for _, adjustment := range adjustments {
+		switch adjustment.Kind {
+		case models.AdjustmentKindBonus:
+			result.Bonuses += adjustment.Amount
+		case models.AdjustmentKindDeduction:
+			result.Deductions += adjustment.Amount
+		}
+	}
+
+	contributionBase := result.BasePay + result.Bonuses
+	result.HealthContribution = round(contributionBase * HealthRate)
+	result.PensionContribution = round(contributionBase * PensionRate)
+
+	result.NetPay = round(contributionBase - result.Deductions - result.HealthContribution - result.PensionContribution)
+
+	return result
+}
+
+// Days360 returns the inclusive number of days between start and end counting
+// every month as 30 days. It returns 0 when end is before start.
+func Days360(start time.Time, end time.Time) int {
+	if end.Before(start) {
+		return 0
+	}
+
+	startDay := start.Day()
+	if startDay == 31 {
+		startDay = 30
+	}
+
+	endDay := end.Day()
+	if endDay == 31 || isLastDayOfFebruary(end) {
+		endDay = 30
+	}
+
+	days := (end.Year()-start.Year())*360 + (int(end.Month())-int(start.Month()))*30 + (endDay - startDay) + 1
+
+	if days < 0 {
+		return 0
+	}
+
+	return days
+}
+
+func isLastDayOfFebruary(t time.Time) bool {
+	return t.Month() == time.February && t.AddDate(0, 0, 1).Month() == time.March
+}
+
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
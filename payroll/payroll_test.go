@@ -0,0 +1,80 @@
+package payroll
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gioCuesta25/employees-manager-backend/models"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestDays360(t *testing.T) {
+	tests := []struct {
+		name  string
+		start time.Time
+		end   time.Time
+		want  int
+	}{
+		{"same day", date(2024, time.March, 15), date(2024, time.March, 15), 1},
+		{"first half", date(2024, time.March, 1), date(2024, time.March, 15), 15},
+		{"month of 31 days", date(2024, time.January, 1), date(2024, time.January, 31), 30},
+		{"second half of a month of 31 days", date(2024, time.January, 16), date(2024, time.January, 31), 15},
+		{"from the 31st", date(2024, time.March, 31), date(2024, time.March, 31), 1},
+		{"february of a leap year", date(2024, time.February, 1), date(2024, time.February, 29), 30},
+		{"february of a common year", date(2025, time.February, 1), date(2025, time.February, 28), 30},
+		{"28 of february in a leap year", date(2024, time.February, 1), date(2024, time.February, 28), 28},
+		{"second half of february", date(2024, time.February, 16), date(2024, time.February, 29), 15},
+		{"across the end of february", date(2025, time.February, 16), date(2025, time.March, 15), 30},
+		{"semester", date(2024, time.January, 1), date(2024, time.June, 30), 180},
+		{"year", date(2024, time.January, 1), date(2024, time.December, 31), 360},
+		{"across years", date(2024, time.December, 16), date(2025, time.January, 15), 30},
+		{"end before start", date(2024, time.January, 16), date(2024, time.January, 15), 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Days360(test.start, test.end); got != test.want {
+				t.Errorf("Days360(%s, %s) = %d, want %d", test.start.Format("2006-01-02"), test.end.Format("2006-01-02"), got, test.want)
+			}
+		})
+	}
+}
+
+func TestCalculate(t *testing.T) {
+	terminated := date(2024, time.March, 10)
+	afterPeriod := date(2024, time.April, 5)
+
+	tests := []struct {
+		name     string
+		employee models.EmployeeSnapshot
+		days     int
+		basePay  float64
+	}{
+		{"whole period", models.EmployeeSnapshot{AdmissionDate: date(2023, time.May, 2), Salary: 3000000}, 30, 3000000},
+		{"admitted in the period", models.EmployeeSnapshot{AdmissionDate: date(2024, time.March, 16), Salary: 3000000}, 15, 1500000},
+		{"terminated in the period", models.EmployeeSnapshot{AdmissionDate: date(2023, time.May, 2), TerminationDate: &terminated, Salary: 3000000}, 10, 1000000},
+		{"terminated after the period", models.EmployeeSnapshot{AdmissionDate: date(2023, time.May, 2), TerminationDate: &afterPeriod, Salary: 3000000}, 30, 3000000},
+		{"admitted and terminated in the period", models.EmployeeSnapshot{AdmissionDate: date(2024, time.March, 4), TerminationDate: &terminated, Salary: 3000000}, 7, 700000},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := Calculate(test.employee, date(2024, time.March, 1), date(2024, time.March, 31), nil)
+
+			if result.WorkedDays != test.days || result.BasePay != test.basePay {
+				t.Errorf("Calculate() = %d days and %v, want %d days and %v", result.WorkedDays, result.BasePay, test.days, test.basePay)
+			}
+		})
+	}
+
+	adjustments := []Adjustment{{Kind: models.AdjustmentKindBonus, Amount: 500000}, {Kind: models.AdjustmentKindDeduction, Amount: 100000}}
+	result := Calculate(models.EmployeeSnapshot{AdmissionDate: date(2023, time.May, 2), Salary: 3000000}, date(2024, time.March, 1), date(2024, time.March, 31), adjustments)
+
+	// 3.500.000 of base and bonus, less 4% of health, 4% of pension and the deduction
+	if result.HealthContribution != 140000 || result.PensionContribution != 140000 || result.NetPay != 3120000 {
+		t.Errorf("Calculate() with adjustments = %+v", result)
+	}
+}
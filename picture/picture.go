@@ -0,0 +1,129 @@
+// Package picture turns uploaded photos into square JPEG thumbnails. The
+// thumbnails are encoded from the decoded pixels, so EXIF and any other
+// metadata of the original never reach them.
+package picture
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Sizes are the side lengths, in pixels, of the generated thumbnails
+var Sizes = []int{64, 128, 256, 512}
+
+// DefaultSize is the thumbnail picture_url points to
+const DefaultSize = 256
+
+// maxPixels protects the server from images that are small to upload but
+// huge once decoded.
+const maxPixels = 40_000_000
+
+var ErrUnsupported = errors.New("the picture must be a JPEG, PNG or WebP image")
+
+// Decode checks the content type from the bytes and decodes the image,
+// returning the EXIF orientation of JPEG files (1 when there is none).
+func Decode(data []byte) (image.Image, int, error) {
+	switch http.DetectContentType(data) {
+	case "image/jpeg", "image/png", "image/webp":
+	default:
+		return nil, 0, ErrUnsupported
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid image: %w", err)
+	}
+
+	if config.Width*config.Height > maxPixels {
+		return nil, 0, fmt.Errorf("the picture can't have more than %d pixels", maxPixels)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid image: %w", err)
+	}
+
+	return img, Orientation(data), nil
+}
+
+// Thumbnail crops the center square of the image, scales it to size and
+// applies the EXIF orientation. Transparent areas become white.
+func Thumbnail(img image.Image, orientation int, size int) *image.RGBA {
+	bounds := img.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+
+	x := bounds.Min.X + (bounds.Dx()-side)/2
+	y := bounds.Min.Y + (bounds.Dy()-side)/2
+	crop := image.Rect(x, y, x+side, y+side)
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, crop, draw.Over, nil)
+
+	// Cropping and scaling a square commute with rotations and flips, so
+	// orienting the small thumbnail is the same as orienting the original
+	return orient(dst, orientation)
+}
+
+// Encode writes the thumbnail as a JPEG
+func Encode(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// orient applies one of the eight EXIF orientations to a square image
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+
+	n := src.Bounds().Dx()
+	dst := image.NewRGBA(src.Bounds())
+	last := n - 1
+
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			var sx, sy int
+
+			switch orientation {
+			case 2:
+				sx, sy = last-x, y
+			case 3:
+				sx, sy = last-x, last-y
+			case 4:
+				sx, sy = x, last-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, last-x
+			case 7:
+				sx, sy = last-y, last-x
+			case 8:
+				sx, sy = last-y, x
+			}
+
+			dst.SetRGBA(x, y, src.RGBAAt(sx, sy))
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,195 @@
+// Package roster builds shifts from templates and checks a schedule against
+// the rest and weekly hours rules and the approved leave of the employees.
+package roster
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gioCuesta25/employees-manager-backend/attendance"
+)
+
+const (
+	ViolationOverlap     = "overlap"
+	ViolationMinRest     = "min_rest"
+	ViolationWeeklyHours = "max_weekly_hours"
+	ViolationLeave       = "leave"
+)
+
+// Template is a shift pattern. Start and end are minutes after midnight; a
+// template that ends at or before its start finishes on the next day.
+type Template struct {
+	StartMinute  int
+	EndMinute    int
+	BreakMinutes int
+}
+
+// On returns when the template starts and ends on the given local day
+func (t Template) On(day time.Time) (time.Time, time.Time) {
+	midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, attendance.Location)
+	start := midnight.Add(time.Duration(t.StartMinute) * time.Minute)
+	end := midnight.Add(time.Duration(t.EndMinute) * time.Minute)
+
+	if !end.After(start) {
+		end = end.AddDate(0, 0, 1)
+	}
+
+	return start, end
+}
+
+type Shift struct {
+	EmployeeId   string
+	Start        time.Time
+	End          time.Time
+	BreakMinutes int
+}
+
+// Minutes is the paid time of the shift, without the break
+func (s Shift) Minutes() int {
+	return int(s.End.Sub(s.Start).Minutes()) - s.BreakMinutes
+}
+
+// Leave is an approved absence, both dates included
+type Leave struct {
+	EmployeeId string
+	StartDate  time.Time
+	EndDate    time.Time
+}
+
+type Rules struct {
+	MinRestMinutes   int
+	MaxWeeklyMinutes int
+}
+
+// DefaultRules leave 10 hours between shifts and allow up to 54 hours a
+// week, the 42 hour limit plus the 12 overtime hours the law permits.
+func DefaultRules() Rules {
+	return Rules{
+		MinRestMinutes:   10 * 60,
+		MaxWeeklyMinutes: 54 * 60,
+	}
+}
+
+type Violation struct {
+	EmployeeId string    `json:"employee_id"`
+	Date       time.Time `json:"date"`
+	Rule       string    `json:"rule"`
+	Message    string    `json:"message"`
+}
+
+// WeekStart returns the Monday of the week the local day belongs to
+func WeekStart(day time.Time) time.Time {
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// Check validates the proposed shifts against each other and against the
+// existing shifts of the same employees. Existing shifts are only used as
+// context: a schedule that was already invalid doesn't report violations
+// unless a proposed shift is involved.
+func Check(existing []Shift, proposed []Shift, leaves []Leave, rules Rules) []Violation {
+	type entry struct {
+		Shift
+		proposed bool
+	}
+
+	byEmployee := make(map[string][]entry)
+
+	for _, shift := range existing {
+		byEmployee[shift.EmployeeId] = append(byEmployee[shift.EmployeeId], entry{shift, false})
+	}
+
+	for _, shift := range proposed {
+		byEmployee[shift.EmployeeId] = append(byEmployee[shift.EmployeeId], entry{shift, true})
+	}
+
+	violations := make([]Violation, 0)
+
+	for _, shift := range proposed {
+		day := attendance.Day(shift.Start)
+
+		for _, l := range leaves {
+			if l.EmployeeId == shift.EmployeeId && !day.Before(l.StartDate) && !day.After(l.EndDate) {
+				violations = append(violations, Violation{
+					EmployeeId: shift.EmployeeId,
+					Date:       day,
+					Rule:       ViolationLeave,
+					Message:    "the employee has approved leave on this day",
+				})
+				break
+			}
+		}
+	}
+
+	employees := make([]string, 0, len(byEmployee))
+	for employeeId := range byEmployee {
+		employees = append(employees, employeeId)
+	}
+	sort.Strings(employees)
+
+	for _, employeeId := range employees {
+		entries := byEmployee[employeeId]
+
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Start.Before(entries[j].Start)
+		})
+
+		for i := 1; i < len(entries); i++ {
+			prev, next := entries[i-1], entries[i]
+
+			if !prev.proposed && !next.proposed {
+				continue
+			}
+
+			rest := next.Start.Sub(prev.End)
+
+			if rest < 0 {
+				violations = append(violations, Violation{
+					EmployeeId: employeeId,
+					Date:       attendance.Day(next.Start),
+					Rule:       ViolationOverlap,
+					Message:    "the shift overlaps another shift of the employee",
+				})
+			} else if rest < time.Duration(rules.MinRestMinutes)*time.Minute {
+				violations = append(violations, Violation{
+					EmployeeId: employeeId,
+					Date:       attendance.Day(next.Start),
+					Rule:       ViolationMinRest,
+					Message:    fmt.Sprintf("only %d minutes of rest since the previous shift, %d required", int(rest.Minutes()), rules.MinRestMinutes),
+				})
+			}
+		}
+
+		if rules.MaxWeeklyMinutes <= 0 {
+			continue
+		}
+
+		weekly := make(map[time.Time]int)
+		touched := make(map[time.Time]bool)
+		weeks := make([]time.Time, 0)
+
+		for _, e := range entries {
+			week := WeekStart(attendance.Day(e.Start))
+
+			if _, ok := weekly[week]; !ok {
+				weeks = append(weeks, week)
+			}
+
+			weekly[week] += e.Minutes()
+			touched[week] = touched[week] || e.proposed
+		}
+
+		for _, week := range weeks {
+			if touched[week] && weekly[week] > rules.MaxWeeklyMinutes {
+				violations = append(violations, Violation{
+					EmployeeId: employeeId,
+					Date:       week,
+					Rule:       ViolationWeeklyHours,
+					Message:    fmt.Sprintf("%.1f hours scheduled in the week, the maximum is %.1f", float64(weekly[week])/60, float64(rules.MaxWeeklyMinutes)/60),
+				})
+			}
+		}
+	}
+
+	return violations
+}
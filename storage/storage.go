@@ -0,0 +1,21 @@
+// Package storage keeps binary objects, like employee documents, outside of
+// the database.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when the key doesn't exist in the store
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore saves objects under a key. Keys use "/" as separator and never
+// start with one.
+type BlobStore interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object, deleting a missing key is not an error
+	Delete(ctx context.Context, key string) error
+}
@@ -6,10 +6,22 @@ import (
 	"github.com/golang-jwt/jwt"
 )
 
-func GetToken(userID string, secret string) (string, error) {
+const (
+	// ScopeFull is the scope of company owners and members
+	ScopeFull = "full"
+	// ScopeSelfService is the scope of employees without a company role,
+	// limited to their own data.
+	ScopeSelfService = "self_service"
+)
+
+// GetToken signs a token for the session of the user. Tokens of older
+// session versions are rejected, which ends the sessions started before.
+func GetToken(userID string, scope string, session int, secret string) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub": userID,
-		"exp": time.Now().Add(time.Hour * 24 * 30).Unix(),
+		"sub":     userID,
+		"scope":   scope,
+		"session": session,
+		"exp":     time.Now().Add(time.Hour * 24 * 30).Unix(),
 	})
 
 	tokenString, err := token.SignedString([]byte(secret))
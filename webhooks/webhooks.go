@@ -0,0 +1,124 @@
+// Package webhooks describes the events companies subscribe to and how
+// their deliveries are signed and retried.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Events emitted by the outbox triggers
+var Events = []string{
+	"company.updated",
+	"department.created",
+	"department.updated",
+	"department.deleted",
+	"position.created",
+	"position.updated",
+	"position.deleted",
+	"employee.created",
+	"employee.updated",
+	"employee.deleted",
+	"employee.transferred",
+	"employee.terminated",
+}
+
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	// Deliveries that failed every attempt, the dead-letter list
+	StatusDead = "dead"
+)
+
+// MaxAttempts of a delivery before it is dead. With the backoff the last
+// attempt is about a day after the event.
+const MaxAttempts = 10
+
+// SignatureHeader carries t=<unix seconds>,v1=<hex HMAC-SHA256> of
+// "<t>.<body>" with the secret of the subscription
+const SignatureHeader = "X-Webhook-Signature"
+
+// ValidateEvents checks the events of a subscription. Besides the event
+// names it takes <entity>.* and *.
+func ValidateEvents(events []string) error {
+	if len(events) == 0 {
+		return fmt.Errorf("events needs at least one event")
+	}
+
+	for _, event := range events {
+		if event == "*" || slices.Contains(Events, event) {
+			continue
+		}
+
+		if entity, ok := strings.CutSuffix(event, ".*"); ok && slices.ContainsFunc(Events, func(known string) bool {
+			return strings.HasPrefix(known, entity+".")
+		}) {
+			continue
+		}
+
+		return fmt.Errorf("unknown event %s", event)
+	}
+
+	return nil
+}
+
+// Matches reports whether a subscription to the events gets the event
+func Matches(events []string, event string) bool {
+	for _, pattern := range events {
+		if pattern == "*" || pattern == event {
+			return true
+		}
+
+		if entity, ok := strings.CutSuffix(pattern, ".*"); ok && strings.HasPrefix(event, entity+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateURL checks the endpoint of a subscription
+func ValidateURL(value string) error {
+	endpoint, err := url.Parse(value)
+
+	if err != nil || endpoint.Host == "" {
+		return fmt.Errorf("url must be an absolute URL")
+	}
+
+	if endpoint.Scheme != "https" && endpoint.Scheme != "http" {
+		return fmt.Errorf("url must be http or https")
+	}
+
+	return nil
+}
+
+// Sign returns the value of the signature header of a delivery of the body
+func Sign(secret string, at time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff is the wait after the failed attempt of a delivery: a minute
+// after the first, doubling up to six hours
+func Backoff(attempt int) time.Duration {
+	wait := time.Minute
+
+	for i := 1; i < attempt && wait < 6*time.Hour; i++ {
+		wait *= 2
+	}
+
+	return min(wait, 6*time.Hour)
+}